@@ -33,6 +33,7 @@ type bindingRequest struct {
 	transactionID  [stun.TransactionIDSize]byte
 	destination    net.Addr
 	isUseCandidate bool
+	local, remote  Candidate
 }
 
 // Agent represents the ICE agent.
@@ -75,6 +76,8 @@ type Agent struct {
 	tcpPriorityOffset uint16
 	disableActiveTCP  bool
 
+	tcpSimultaneousOpen bool
+
 	portMin uint16
 	portMax uint16
 
@@ -147,6 +150,17 @@ type Agent struct {
 	proxyDialer proxy.Dialer
 
 	enableUseCandidateCheckPriority bool
+
+	renomination         bool
+	renominationInterval time.Duration
+	selectionPolicy      CandidatePairSelectionPolicy
+
+	continualGatheringPolicy ContinualGatheringPolicy
+	networkMonitorInterval   time.Duration
+
+	component uint16
+
+	eventLog *eventLog
 }
 
 // NewAgent creates a new Agent.
@@ -220,9 +234,18 @@ func NewAgent(config *AgentConfig) (*Agent, error) { //nolint:gocognit,cyclop
 
 		disableActiveTCP: config.DisableActiveTCP,
 
+		tcpSimultaneousOpen: config.TCPSimultaneousOpen,
+
 		userBindingRequestHandler: config.BindingRequestHandler,
 
 		enableUseCandidateCheckPriority: config.EnableUseCandidateCheckPriority,
+
+		renomination:         config.Renomination,
+		renominationInterval: defaultRenominationInterval,
+
+		continualGatheringPolicy: config.ContinualGatheringPolicy,
+
+		eventLog: newEventLog(config.EventLogSize),
 	}
 	agent.connectionStateNotifier = &handlerNotifier{
 		connectionStateFunc: agent.onConnectionStateChange,
@@ -456,6 +479,7 @@ func (a *Agent) updateConnectionState(newState ConnectionState) {
 
 		a.log.Infof("Setting new connection state: %s", newState)
 		a.connectionState = newState
+		a.recordStateEvent(EventTypeConnectionStateChanged, nil, nil, newState.String())
 		a.connectionStateNotifier.EnqueueConnectionState(newState)
 	}
 }
@@ -472,6 +496,7 @@ func (a *Agent) setSelectedPair(pair *CandidatePair) {
 	pair.nominated = true
 	a.selectedPair.Store(pair)
 	a.log.Tracef("Set selected candidate pair: %s", pair)
+	a.recordEvent(EventTypePairSelected, pair.Local, pair.Remote)
 
 	a.updateConnectionState(ConnectionStateConnected)
 
@@ -491,14 +516,14 @@ func (a *Agent) pingAllCandidates() {
 
 	for _, p := range a.checklist {
 		if p.state == CandidatePairStateWaiting {
-			p.state = CandidatePairStateInProgress
+			a.setPairState(p, CandidatePairStateInProgress)
 		} else if p.state != CandidatePairStateInProgress {
 			continue
 		}
 
 		if p.bindingRequestCount > a.maxBindingRequests {
 			a.log.Tracef("Maximum requests reached for pair %s, marking it as failed", p)
-			p.state = CandidatePairStateFailed
+			a.setPairState(p, CandidatePairStateFailed)
 		} else {
 			a.selector.PingCandidate(p.Local, p.Remote)
 			p.bindingRequestCount++
@@ -543,6 +568,7 @@ func (a *Agent) getBestValidCandidatePair() *CandidatePair {
 func (a *Agent) addPair(local, remote Candidate) *CandidatePair {
 	p := newCandidatePair(local, remote, a.isControlling)
 	a.checklist = append(a.checklist, p)
+	a.recordEvent(EventTypePairCreated, local, remote)
 
 	return p
 }
@@ -724,7 +750,7 @@ func (a *Agent) addRemotePassiveTCPCandidate(remoteCandidate Candidate) {
 			Network:   remoteCandidate.NetworkType().String(),
 			Address:   localIPs[i].String(),
 			Port:      tcpAddr.Port,
-			Component: ComponentRTP,
+			Component: a.component,
 			TCPType:   TCPTypeActive,
 		})
 		if err != nil {
@@ -770,11 +796,16 @@ func (a *Agent) addRemoteCandidate(cand Candidate) { //nolint:cyclop
 
 	set = append(set, cand)
 	a.remoteCandidates[cand.NetworkType()] = set
+	a.recordEvent(EventTypeRemoteCandidateAdded, nil, cand)
+
+	for _, localCandidate := range a.localCandidates[cand.NetworkType()] {
+		a.permitRemoteCandidate(localCandidate, cand)
+	}
 
 	if cand.TCPType() != TCPTypePassive {
 		if localCandidates, ok := a.localCandidates[cand.NetworkType()]; ok {
 			for _, localCandidate := range localCandidates {
-				a.addPair(localCandidate, cand)
+				a.pairCandidates(localCandidate, cand)
 			}
 		}
 	}
@@ -804,10 +835,12 @@ func (a *Agent) addCandidate(ctx context.Context, cand Candidate, candidateConn
 
 		set = append(set, cand)
 		a.localCandidates[cand.NetworkType()] = set
+		a.recordEvent(EventTypeCandidateGathered, cand, nil)
 
 		if remoteCandidates, ok := a.remoteCandidates[cand.NetworkType()]; ok {
 			for _, remoteCandidate := range remoteCandidates {
-				a.addPair(cand, remoteCandidate)
+				a.permitRemoteCandidate(cand, remoteCandidate)
+				a.pairCandidates(cand, remoteCandidate)
 			}
 		}
 
@@ -983,13 +1016,20 @@ func (a *Agent) findRemoteCandidate(networkType NetworkType, addr net.Addr) Cand
 func (a *Agent) sendBindingRequest(msg *stun.Message, local, remote Candidate) {
 	a.log.Tracef("Ping STUN from %s to %s", local, remote)
 
+	isUseCandidate := msg.Contains(stun.AttrUseCandidate)
 	a.invalidatePendingBindingRequests(time.Now())
 	a.pendingBindingRequests = append(a.pendingBindingRequests, bindingRequest{
 		timestamp:      time.Now(),
 		transactionID:  msg.TransactionID,
 		destination:    remote.addr(),
-		isUseCandidate: msg.Contains(stun.AttrUseCandidate),
+		isUseCandidate: isUseCandidate,
+		local:          local,
+		remote:         remote,
 	})
+	a.recordCheckEvent(EventTypeCheckSent, local, remote, msg.TransactionID, isUseCandidate, 0)
+	if isUseCandidate {
+		a.recordEvent(EventTypeNomination, local, remote)
+	}
 
 	if pair := a.findPair(local, remote); pair != nil {
 		pair.UpdateRequestSent()
@@ -1040,6 +1080,9 @@ func (a *Agent) invalidatePendingBindingRequests(filterTime time.Time) {
 	for _, bindingRequest := range a.pendingBindingRequests {
 		if filterTime.Sub(bindingRequest.timestamp) < maxBindingRequestTimeout {
 			temp = append(temp, bindingRequest)
+		} else {
+			a.recordCheckEvent(EventTypeCheckTimeout, bindingRequest.local, bindingRequest.remote,
+				bindingRequest.transactionID, bindingRequest.isUseCandidate, 0)
 		}
 	}
 
@@ -1057,8 +1100,11 @@ func (a *Agent) handleInboundBindingSuccess(id [stun.TransactionIDSize]byte) (bo
 		if a.pendingBindingRequests[i].transactionID == id {
 			validBindingRequest := a.pendingBindingRequests[i]
 			a.pendingBindingRequests = append(a.pendingBindingRequests[:i], a.pendingBindingRequests[i+1:]...)
+			rtt := time.Since(validBindingRequest.timestamp)
+			a.recordCheckEvent(EventTypeCheckResponse, validBindingRequest.local, validBindingRequest.remote,
+				id, validBindingRequest.isUseCandidate, rtt)
 
-			return true, &validBindingRequest, time.Since(validBindingRequest.timestamp)
+			return true, &validBindingRequest, rtt
 		}
 	}
 
@@ -1084,6 +1130,7 @@ func (a *Agent) handleInbound(msg *stun.Message, local Candidate, remote net.Add
 	if a.isControlling {
 		if msg.Contains(stun.AttrICEControlling) {
 			a.log.Debug("Inbound STUN message: isControlling && a.isControlling == true")
+			a.recordStateEvent(EventTypeRoleConflict, local, nil, "controlling")
 
 			return
 		} else if msg.Contains(stun.AttrUseCandidate) {
@@ -1094,6 +1141,7 @@ func (a *Agent) handleInbound(msg *stun.Message, local Candidate, remote net.Add
 	} else {
 		if msg.Contains(stun.AttrICEControlled) {
 			a.log.Debug("Inbound STUN message: isControlled && a.isControlling == false")
+			a.recordStateEvent(EventTypeRoleConflict, local, nil, "controlled")
 
 			return
 		}
@@ -1161,6 +1209,12 @@ func (a *Agent) handleInbound(msg *stun.Message, local Candidate, remote net.Add
 			a.addRemoteCandidate(remoteCandidate)
 		}
 
+		useCandidate := msg.Contains(stun.AttrUseCandidate)
+		a.recordCheckEvent(EventTypeCheckReceived, local, remoteCandidate, msg.TransactionID, useCandidate, 0)
+		if useCandidate {
+			a.recordEvent(EventTypeNomination, local, remoteCandidate)
+		}
+
 		a.selector.HandleBindingRequest(msg, local, remoteCandidate)
 	}
 
@@ -1236,6 +1290,15 @@ func (a *Agent) SetRemoteCredentials(remoteUfrag, remotePwd string) error {
 	})
 }
 
+// SetRenomination enables or disables renomination, see AgentConfig.Renomination.
+// It lets the agent be created before the remote peer signaled whether it
+// supports the "renomination" ice-option, it must be called before Dial or Accept.
+func (a *Agent) SetRenomination(enabled bool) error {
+	return a.loop.Run(a.loop, func(_ context.Context) {
+		a.renomination = enabled
+	})
+}
+
 // Restart restarts the ICE Agent with the provided ufrag/pwd
 // If no ufrag/pwd is provided the Agent will generate one itself
 //
@@ -1268,7 +1331,7 @@ func (a *Agent) Restart(ufrag, pwd string) error { //nolint:cyclop
 
 	var err error
 	if runErr := a.loop.Run(a.loop, func(_ context.Context) {
-		if a.gatheringState == GatheringStateGathering {
+		if a.gatheringState == GatheringStateGathering || a.continualGatheringPolicy == GatherContinually {
 			a.gatherCandidateCancel()
 		}
 
@@ -1306,6 +1369,9 @@ func (a *Agent) setGatheringState(newState GatheringState) error {
 			a.candidateNotifier.EnqueueCandidate(nil)
 		}
 
+		if a.gatheringState != newState {
+			a.recordStateEvent(EventTypeGatheringStateChanged, nil, nil, newState.String())
+		}
 		a.gatheringState = newState
 		close(done)
 	}); err != nil {
@@ -53,6 +53,13 @@ const (
 
 	// maxBindingRequestTimeout is the wait time before binding requests can be deleted.
 	maxBindingRequestTimeout = 4000 * time.Millisecond
+
+	// defaultRenominationInterval is the minimum time between two renominations.
+	defaultRenominationInterval = 5 * time.Second
+
+	// defaultNetworkMonitorInterval is how often the network interfaces are
+	// polled when gathering continually.
+	defaultNetworkMonitorInterval = 2 * time.Second
 )
 
 func defaultCandidateTypes() []CandidateType {
@@ -194,6 +201,12 @@ type AgentConfig struct {
 	// Active TCP candidates will be created when a new passive TCP remote candidate is added.
 	DisableActiveTCP bool
 
+	// TCPSimultaneousOpen enables simultaneous-open TCP host candidates
+	// (tcptype so) when a TCP network type is enabled. They pair with the
+	// simultaneous-open candidates of the remote agent and both agents dial
+	// each other, which connects peers that have neither UDP nor TURN.
+	TCPSimultaneousOpen bool
+
 	// BindingRequestHandler allows applications to perform logic on incoming STUN Binding Requests
 	// This was implemented to allow users to
 	// * Log incoming Binding Requests for debugging
@@ -207,6 +220,39 @@ type AgentConfig struct {
 	// switched to that irrespective of relative priority between current selected pair
 	// and priority of the pair being switched to.
 	EnableUseCandidateCheckPriority bool
+
+	// Renomination enables continuous nomination (draft-thatcher-ice-renomination).
+	// When controlling, the agent keeps checking the other candidate pairs after
+	// one has been selected and nominates a better one when it appears, either a
+	// pair with a markedly lower round trip time or a higher priority pair (for
+	// example host instead of relay) with a comparable round trip time. It must
+	// only be enabled if both peers signaled the "renomination" ice-option.
+	Renomination bool
+
+	// CandidatePairSelectionPolicy decides which candidate pair is nominated
+	// when controlling, and which nominations are accepted when controlled.
+	// When nil, the highest priority pair is nominated and every nomination
+	// is accepted.
+	CandidatePairSelectionPolicy CandidatePairSelectionPolicy
+
+	// ContinualGatheringPolicy defines whether the agent keeps gathering after
+	// GatherCandidates completed, following changes of the network interfaces.
+	ContinualGatheringPolicy ContinualGatheringPolicy
+
+	// NetworkMonitorInterval is how often the network interfaces are polled
+	// with GatherContinually. It defaults to 2 seconds.
+	NetworkMonitorInterval *time.Duration
+
+	// Component is the component ID of the candidates gathered by the agent.
+	// An agent handles a single component, RTP and RTCP that are not
+	// multiplexed use an agent each. It defaults to ComponentRTP.
+	Component uint16
+
+	// EventLogSize is the number of events the agent keeps in its event log,
+	// a timeline of gathering, connectivity checks, nominations and state
+	// changes returned by GetEventLog. The oldest events are dropped once it
+	// is full. The event log is disabled when it is 0.
+	EventLogSize int
 }
 
 // initWithDefaults populates an agent and falls back to defaults if fields are unset.
@@ -277,6 +323,24 @@ func (config *AgentConfig) initWithDefaults(agent *Agent) { //nolint:cyclop
 		agent.checkInterval = *config.CheckInterval
 	}
 
+	if config.NetworkMonitorInterval == nil {
+		agent.networkMonitorInterval = defaultNetworkMonitorInterval
+	} else {
+		agent.networkMonitorInterval = *config.NetworkMonitorInterval
+	}
+
+	if config.Component == 0 {
+		agent.component = ComponentRTP
+	} else {
+		agent.component = config.Component
+	}
+
+	if config.CandidatePairSelectionPolicy == nil {
+		agent.selectionPolicy = defaultSelectionPolicy{}
+	} else {
+		agent.selectionPolicy = config.CandidatePairSelectionPolicy
+	}
+
 	if len(config.CandidateTypes) == 0 {
 		agent.candidateTypes = defaultCandidateTypes()
 	} else {
@@ -14,40 +14,7 @@ func (a *Agent) GetCandidatePairsStats() []CandidatePairStats {
 	err := a.loop.Run(a.loop, func(_ context.Context) {
 		result := make([]CandidatePairStats, 0, len(a.checklist))
 		for _, cp := range a.checklist {
-			stat := CandidatePairStats{
-				Timestamp:         time.Now(),
-				LocalCandidateID:  cp.Local.ID(),
-				RemoteCandidateID: cp.Remote.ID(),
-				State:             cp.state,
-				Nominated:         cp.nominated,
-				// PacketsSent uint32
-				// PacketsReceived uint32
-				// BytesSent uint64
-				// BytesReceived uint64
-				// LastPacketSentTimestamp time.Time
-				// LastPacketReceivedTimestamp time.Time
-				FirstRequestTimestamp:         cp.FirstRequestSentAt(),
-				LastRequestTimestamp:          cp.LastRequestSentAt(),
-				FirstResponseTimestamp:        cp.FirstReponseReceivedAt(),
-				LastResponseTimestamp:         cp.LastResponseReceivedAt(),
-				FirstRequestReceivedTimestamp: cp.FirstRequestReceivedAt(),
-				LastRequestReceivedTimestamp:  cp.LastRequestReceivedAt(),
-
-				TotalRoundTripTime:   cp.TotalRoundTripTime(),
-				CurrentRoundTripTime: cp.CurrentRoundTripTime(),
-				// AvailableOutgoingBitrate float64
-				// AvailableIncomingBitrate float64
-				// CircuitBreakerTriggerCount uint32
-				RequestsReceived:  cp.RequestsReceived(),
-				RequestsSent:      cp.RequestsSent(),
-				ResponsesReceived: cp.ResponsesReceived(),
-				ResponsesSent:     cp.ResponsesSent(),
-				// RetransmissionsReceived uint64
-				// RetransmissionsSent uint64
-				// ConsentRequestsSent uint64
-				// ConsentExpiredTimestamp time.Time
-			}
-			result = append(result, stat)
+			result = append(result, cp.stats())
 		}
 		res = result
 	})
@@ -181,3 +148,39 @@ func (a *Agent) GetRemoteCandidatesStats() []CandidateStats {
 
 	return res
 }
+
+func (p *CandidatePair) stats() CandidatePairStats {
+	return CandidatePairStats{
+		Timestamp:         time.Now(),
+		LocalCandidateID:  p.Local.ID(),
+		RemoteCandidateID: p.Remote.ID(),
+		State:             p.state,
+		Nominated:         p.nominated,
+		// PacketsSent uint32
+		// PacketsReceived uint32
+		// BytesSent uint64
+		// BytesReceived uint64
+		// LastPacketSentTimestamp time.Time
+		// LastPacketReceivedTimestamp time.Time
+		FirstRequestTimestamp:         p.FirstRequestSentAt(),
+		LastRequestTimestamp:          p.LastRequestSentAt(),
+		FirstResponseTimestamp:        p.FirstReponseReceivedAt(),
+		LastResponseTimestamp:         p.LastResponseReceivedAt(),
+		FirstRequestReceivedTimestamp: p.FirstRequestReceivedAt(),
+		LastRequestReceivedTimestamp:  p.LastRequestReceivedAt(),
+
+		TotalRoundTripTime:   p.TotalRoundTripTime(),
+		CurrentRoundTripTime: p.CurrentRoundTripTime(),
+		// AvailableOutgoingBitrate float64
+		// AvailableIncomingBitrate float64
+		// CircuitBreakerTriggerCount uint32
+		RequestsReceived:  p.RequestsReceived(),
+		RequestsSent:      p.RequestsSent(),
+		ResponsesReceived: p.ResponsesReceived(),
+		ResponsesSent:     p.ResponsesSent(),
+		// RetransmissionsReceived uint64
+		// RetransmissionsSent uint64
+		// ConsentRequestsSent uint64
+		// ConsentExpiredTimestamp time.Time
+	}
+}
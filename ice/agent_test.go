@@ -147,7 +147,7 @@ func TestHandlePeerReflexive(t *testing.T) { //nolint:cyclop
 			tID := [stun.TransactionIDSize]byte{}
 			copy(tID[:], "ABC")
 			agent.pendingBindingRequests = []bindingRequest{
-				{time.Now(), tID, &net.UDPAddr{}, false},
+				{time.Now(), tID, &net.UDPAddr{}, false, nil, nil},
 			}
 
 			hostConfig := CandidateHostConfig{
@@ -16,7 +16,7 @@ const (
 	// ComponentRTP indicates that the candidate is used for RTP.
 	ComponentRTP uint16 = 1
 	// ComponentRTCP indicates that the candidate is used for RTCP.
-	ComponentRTCP
+	ComponentRTCP uint16 = 2
 )
 
 // Candidate represents an ICE candidate.
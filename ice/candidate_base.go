@@ -822,6 +822,7 @@ func UnmarshalCandidate(raw string) (Candidate, error) { //nolint:cyclop
 			foundation,
 			raddr,
 			rport,
+			tcpType,
 		})
 		if err != nil {
 			return nil, err
@@ -862,6 +863,7 @@ func UnmarshalCandidate(raw string) (Candidate, error) { //nolint:cyclop
 			rport,
 			"",
 			nil,
+			tcpType,
 		})
 		if err != nil {
 			return nil, err
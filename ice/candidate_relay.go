@@ -4,7 +4,6 @@
 package ice
 
 import (
-	"net"
 	"net/netip"
 )
 
@@ -29,6 +28,9 @@ type CandidateRelayConfig struct {
 	RelPort       int
 	RelayProtocol string
 	OnClose       func() error
+	// TCPType is the type of relay candidates with a TCP relayed transport
+	// (RFC 6062).
+	TCPType TCPType
 }
 
 // NewCandidateRelay creates a new relay candidate.
@@ -51,16 +53,13 @@ func NewCandidateRelay(config *CandidateRelayConfig) (*CandidateRelay, error) {
 
 	return &CandidateRelay{
 		candidateBase: candidateBase{
-			id:            candidateID,
-			networkType:   networkType,
-			candidateType: CandidateTypeRelay,
-			address:       config.Address,
-			port:          config.Port,
-			resolvedAddr: &net.UDPAddr{
-				IP:   ipAddr.AsSlice(),
-				Port: config.Port,
-				Zone: ipAddr.Zone(),
-			},
+			id:                 candidateID,
+			networkType:        networkType,
+			candidateType:      CandidateTypeRelay,
+			address:            config.Address,
+			port:               config.Port,
+			tcpType:            config.TCPType,
+			resolvedAddr:       createAddr(networkType, ipAddr, config.Port),
 			component:          config.Component,
 			foundationOverride: config.Foundation,
 			priorityOverride:   config.Priority,
@@ -35,6 +35,7 @@ func TestRelayOnlyConnection(t *testing.T) {
 	server, err := turn.NewServer(turn.ServerConfig{
 		Realm:       "pion.ly",
 		AuthHandler: optimisticAuthHandler,
+		PeerACL:     turn.PeerACL{AllowInternalPeers: true},
 		PacketConnConfigs: []turn.PacketConnConfig{
 			{
 				PacketConn:            serverListener,
@@ -4,7 +4,6 @@
 package ice
 
 import (
-	"net"
 	"net/netip"
 )
 
@@ -24,6 +23,9 @@ type CandidateServerReflexiveConfig struct {
 	Foundation  string
 	RelAddr     string
 	RelPort     int
+	// TCPType is the type of TCP candidates, server reflexive TCP candidates
+	// are simultaneous-open (RFC 6544).
+	TCPType TCPType
 }
 
 // NewCandidateServerReflexive creates a new server reflective candidate.
@@ -45,16 +47,13 @@ func NewCandidateServerReflexive(config *CandidateServerReflexiveConfig) (*Candi
 
 	return &CandidateServerReflexive{
 		candidateBase: candidateBase{
-			id:            candidateID,
-			networkType:   networkType,
-			candidateType: CandidateTypeServerReflexive,
-			address:       config.Address,
-			port:          config.Port,
-			resolvedAddr: &net.UDPAddr{
-				IP:   ipAddr.AsSlice(),
-				Port: config.Port,
-				Zone: ipAddr.Zone(),
-			},
+			id:                 candidateID,
+			networkType:        networkType,
+			candidateType:      CandidateTypeServerReflexive,
+			address:            config.Address,
+			port:               config.Port,
+			tcpType:            config.TCPType,
+			resolvedAddr:       createAddr(networkType, ipAddr, config.Port),
 			component:          config.Component,
 			foundationOverride: config.Foundation,
 			priorityOverride:   config.Priority,
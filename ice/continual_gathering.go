@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package ice
+
+import (
+	"context"
+	"net/netip"
+	"time"
+)
+
+// ContinualGatheringPolicy defines the behavior of the agent once
+// GatherCandidates completed.
+type ContinualGatheringPolicy int
+
+const (
+	// GatherOnce gathers candidates once, in GatherCandidates.
+	GatherOnce ContinualGatheringPolicy = iota
+
+	// GatherContinually keeps polling the network interfaces after the initial
+	// gathering completed. Host and server reflexive candidates are gathered on
+	// new addresses and passed to OnCandidate. Candidates of addresses that
+	// disappeared are closed and removed with their pairs, if the selected pair
+	// is removed the agent goes back to checking. Relay candidates are not
+	// gathered again.
+	//
+	// The remote agent learns about new candidates through signaling; with
+	// AgentConfig.Renomination it switches to a pair nominated after the
+	// selected one, otherwise only to a pair of higher priority.
+	GatherContinually
+)
+
+func (p ContinualGatheringPolicy) String() string {
+	switch p {
+	case GatherOnce:
+		return "gather_once"
+	case GatherContinually:
+		return "gather_continually"
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// localAddrSet returns the addresses host candidates are gathered on, without zones.
+func (a *Agent) localAddrSet() (map[netip.Addr]struct{}, error) {
+	_, localAddrs, err := localInterfaces(a.net, a.interfaceFilter, a.ipFilter, a.networkTypes, a.includeLoopback)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make(map[netip.Addr]struct{}, len(localAddrs))
+	for _, addr := range localAddrs {
+		addrs[addr.Unmap().WithZone("")] = struct{}{}
+	}
+
+	return addrs, nil
+}
+
+// monitorNetwork polls the network interfaces until ctx is done, known are the
+// addresses the initial gathering used.
+func (a *Agent) monitorNetwork(ctx context.Context, known map[netip.Addr]struct{}) {
+	ticker := time.NewTicker(a.networkMonitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := a.localAddrSet()
+		if err != nil {
+			a.log.Warnf("Failed to iterate local interfaces: %v", err)
+
+			continue
+		}
+
+		var added, removed []netip.Addr
+		for addr := range current {
+			if _, ok := known[addr]; !ok {
+				added = append(added, addr)
+			}
+		}
+		for addr := range known {
+			if _, ok := current[addr]; !ok {
+				removed = append(removed, addr)
+			}
+		}
+		known = current
+
+		if len(removed) > 0 {
+			a.log.Infof("Local addresses removed: %v", removed)
+			a.removeLocalCandidates(ctx, removed)
+		}
+		if len(added) > 0 {
+			a.log.Infof("Local addresses added: %v", added)
+			a.gatherCandidatesOnAddrs(ctx, added)
+		}
+	}
+}
+
+// gatherCandidatesOnAddrs gathers the candidates of newly added local addresses.
+func (a *Agent) gatherCandidatesOnAddrs(ctx context.Context, addrs []netip.Addr) {
+	for _, t := range a.candidateTypes {
+		switch t {
+		case CandidateTypeHost:
+			networks := localNetworks(a.networkTypes)
+			// The UDPMux only serves the addresses it was created with.
+			if a.udpMux != nil {
+				delete(networks, udp)
+			}
+			a.gatherCandidatesLocalAddrs(ctx, addrs, networks)
+		case CandidateTypeServerReflexive:
+			// The mapping may have changed with the new network, duplicates are ignored.
+			if a.udpMuxSrflx != nil {
+				a.gatherCandidatesSrflxUDPMux(ctx, a.urls, a.networkTypes)
+			} else {
+				a.gatherCandidatesSrflx(ctx, a.urls, a.networkTypes)
+			}
+		case CandidateTypeRelay, CandidateTypePeerReflexive, CandidateTypeUnspecified:
+		}
+	}
+}
+
+// removeLocalCandidates removes the host candidates of the given addresses and
+// the server reflexive candidates based on them.
+func (a *Agent) removeLocalCandidates(ctx context.Context, addrs []netip.Addr) {
+	removed := make(map[netip.Addr]struct{}, len(addrs))
+	for _, addr := range addrs {
+		removed[addr] = struct{}{}
+	}
+
+	if err := a.loop.Run(ctx, func(context.Context) {
+		for networkType, candidates := range a.localCandidates {
+			kept := candidates[:0]
+			for _, cand := range candidates {
+				if base, ok := localCandidateBase(cand); !ok || !containsAddr(removed, base) {
+					kept = append(kept, cand)
+
+					continue
+				}
+
+				a.log.Debugf("Removing local candidate %s", cand)
+				a.removePairsOf(cand)
+				if err := cand.close(); err != nil {
+					a.log.Warnf("Failed to close candidate %s: %v", cand, err)
+				}
+			}
+			a.localCandidates[networkType] = kept
+		}
+
+		a.requestConnectivityCheck()
+	}); err != nil {
+		a.log.Warnf("Failed to remove local candidates: %v", err)
+	}
+}
+
+// removePairsOf removes the candidate pairs of a local candidate. If the
+// selected pair is one of them, the agent goes back to checking.
+func (a *Agent) removePairsOf(local Candidate) {
+	checklist := a.checklist[:0]
+	for _, p := range a.checklist {
+		if p.Local != local {
+			checklist = append(checklist, p)
+
+			continue
+		}
+
+		// A pair the selector still holds must not be nominated again.
+		a.setPairState(p, CandidatePairStateFailed)
+		if a.getSelectedPair() == p {
+			a.setSelectedPair(nil)
+			a.updateConnectionState(ConnectionStateChecking)
+		}
+	}
+	a.checklist = checklist
+}
+
+// localCandidateBase returns the local address a candidate was gathered on.
+func localCandidateBase(cand Candidate) (netip.Addr, bool) {
+	var (
+		addr netip.Addr
+		err  error
+	)
+	switch cand.Type() {
+	case CandidateTypeHost:
+		addr, _, _, err = parseAddr(cand.addr())
+	case CandidateTypeServerReflexive:
+		if cand.RelatedAddress() == nil {
+			return netip.Addr{}, false
+		}
+		addr, err = netip.ParseAddr(cand.RelatedAddress().Address)
+	default:
+		return netip.Addr{}, false
+	}
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr, true
+}
+
+func containsAddr(addrs map[netip.Addr]struct{}, addr netip.Addr) bool {
+	_, ok := addrs[addr.Unmap().WithZone("")]
+
+	return ok
+}
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package ice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/transport/v3/test"
+	"github.com/pion/transport/v3/vnet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContinualGathering(t *testing.T) {
+	defer test.CheckRoutines(t)()
+	defer test.TimeOut(time.Second * 30).Stop()
+
+	wan, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "192.168.0.0/24",
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	require.NoError(t, err)
+
+	net0, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"192.168.0.1"}})
+	require.NoError(t, err)
+	require.NoError(t, wan.AddNet(net0))
+
+	net1, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"192.168.0.2"}})
+	require.NoError(t, err)
+	require.NoError(t, wan.AddNet(net1))
+
+	require.NoError(t, wan.Start())
+
+	interval := 50 * time.Millisecond
+	newAgent := func(n *vnet.Net, policy ContinualGatheringPolicy) *Agent {
+		agent, agentErr := NewAgent(&AgentConfig{
+			NetworkTypes:             []NetworkType{NetworkTypeUDP4},
+			MulticastDNSMode:         MulticastDNSModeDisabled,
+			Net:                      n,
+			KeepaliveInterval:        &interval,
+			CheckInterval:            &interval,
+			Renomination:             true,
+			ContinualGatheringPolicy: policy,
+			NetworkMonitorInterval:   &interval,
+		})
+		require.NoError(t, agentErr)
+
+		return agent
+	}
+
+	aNotifier, aConnected := onConnected()
+	bNotifier, bConnected := onConnected()
+	controlledAgent, controllingAgent := newAgent(net1, GatherOnce), newAgent(net0, GatherContinually)
+	require.NoError(t, controlledAgent.OnConnectionStateChange(aNotifier))
+	require.NoError(t, controllingAgent.OnConnectionStateChange(bNotifier))
+
+	controlledConn, controllingConn := connect(controlledAgent, controllingAgent)
+	<-aConnected
+	<-bConnected
+	require.NoError(t, controlledAgent.OnConnectionStateChange(func(ConnectionState) {}))
+	require.NoError(t, controllingAgent.OnConnectionStateChange(func(ConnectionState) {}))
+
+	// Candidates gathered later are signaled to the remote agent.
+	gathered := make(chan Candidate, 10)
+	require.NoError(t, controllingAgent.OnCandidate(func(c Candidate) {
+		if c == nil {
+			return
+		}
+		candidateCopy, copyErr := c.copy()
+		assert.NoError(t, copyErr)
+		assert.NoError(t, controlledAgent.AddRemoteCandidate(candidateCopy))
+		gathered <- c
+	}))
+	controlledSelected := make(chan Candidate, 10)
+	require.NoError(t, controlledAgent.OnSelectedCandidatePairChange(func(_, remote Candidate) {
+		controlledSelected <- remote
+	}))
+	controllingSelected := make(chan Candidate, 10)
+	require.NoError(t, controllingAgent.OnSelectedCandidatePairChange(func(local, _ Candidate) {
+		controllingSelected <- local
+	}))
+
+	// The host of the controlling agent joins another network.
+	require.NoError(t, wan.AddNetInterface(net0, "eth1", "192.168.0.3"))
+	require.Equal(t, "192.168.0.3", (<-gathered).Address())
+
+	// And leaves the one in use.
+	require.NoError(t, wan.RemoveNetInterface(net0, "eth0"))
+	for local := range controllingSelected {
+		if local.Address() == "192.168.0.3" {
+			break
+		}
+	}
+	for remote := range controlledSelected {
+		if remote.Address() == "192.168.0.3" {
+			break
+		}
+	}
+
+	localCandidates, err := controllingAgent.GetLocalCandidates()
+	require.NoError(t, err)
+	require.Len(t, localCandidates, 1)
+	require.Equal(t, "192.168.0.3", localCandidates[0].Address())
+	require.True(t, sendUntilDone(t, controlledConn, controllingConn, 100))
+
+	closePipe(t, controlledConn, controllingConn)
+	require.NoError(t, wan.Stop())
+}
+
+func TestContinualGatheringPolicy_String(t *testing.T) {
+	assert.Equal(t, "gather_once", GatherOnce.String())
+	assert.Equal(t, "gather_continually", GatherContinually.String())
+	assert.Equal(t, ErrUnknownType.Error(), ContinualGatheringPolicy(42).String())
+}
@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package ice
+
+import (
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/pion/stun/v3"
+)
+
+// EventType is the type of an Event recorded in the event log of an Agent.
+type EventType int
+
+const (
+	// EventTypeUnknown is the type of an unknown event.
+	EventTypeUnknown EventType = iota
+
+	// EventTypeCandidateGathered means a local candidate has been gathered.
+	EventTypeCandidateGathered
+
+	// EventTypeRemoteCandidateAdded means a remote candidate has been added,
+	// signaled or peer reflexive.
+	EventTypeRemoteCandidateAdded
+
+	// EventTypePairCreated means a candidate pair has been added to the
+	// checklist.
+	EventTypePairCreated
+
+	// EventTypePairStateChanged means the state of a candidate pair changed.
+	EventTypePairStateChanged
+
+	// EventTypeCheckSent means a connectivity check (STUN Binding request)
+	// has been sent.
+	EventTypeCheckSent
+
+	// EventTypeCheckReceived means a connectivity check has been received.
+	EventTypeCheckReceived
+
+	// EventTypeCheckResponse means the success response to a connectivity
+	// check that was sent has been received.
+	EventTypeCheckResponse
+
+	// EventTypeCheckTimeout means a connectivity check that was sent got no
+	// response in time.
+	EventTypeCheckTimeout
+
+	// EventTypeRoleConflict means a connectivity check has been received from
+	// an agent with the same role.
+	EventTypeRoleConflict
+
+	// EventTypeNomination means a candidate pair has been nominated, the check
+	// carries USE-CANDIDATE.
+	EventTypeNomination
+
+	// EventTypePairSelected means the selected candidate pair changed.
+	EventTypePairSelected
+
+	// EventTypeConnectionStateChanged means the connection state changed.
+	EventTypeConnectionStateChanged
+
+	// EventTypeGatheringStateChanged means the gathering state changed.
+	EventTypeGatheringStateChanged
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventTypeCandidateGathered:
+		return "candidate-gathered"
+	case EventTypeRemoteCandidateAdded:
+		return "remote-candidate-added"
+	case EventTypePairCreated:
+		return "pair-created"
+	case EventTypePairStateChanged:
+		return "pair-state-changed"
+	case EventTypeCheckSent:
+		return "check-sent"
+	case EventTypeCheckReceived:
+		return "check-received"
+	case EventTypeCheckResponse:
+		return "check-response"
+	case EventTypeCheckTimeout:
+		return "check-timeout"
+	case EventTypeRoleConflict:
+		return "role-conflict"
+	case EventTypeNomination:
+		return "nomination"
+	case EventTypePairSelected:
+		return "pair-selected"
+	case EventTypeConnectionStateChanged:
+		return "connection-state-changed"
+	case EventTypeGatheringStateChanged:
+		return "gathering-state-changed"
+	case EventTypeUnknown:
+	}
+
+	return "unknown"
+}
+
+// MarshalText encodes an EventType as its name, so events are readable when
+// exported as JSON.
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// Event is an entry of the event log of an Agent. Events marshal to JSON, so
+// the timeline of a session can be exported and inspected after the fact.
+type Event struct {
+	Time time.Time `json:"time"`
+	Type EventType `json:"type"`
+
+	// Component is the component of the agent that recorded the event.
+	Component uint16 `json:"component"`
+
+	// LocalCandidate and RemoteCandidate are the candidates involved, if any.
+	LocalCandidate  string `json:"localCandidate,omitempty"`
+	RemoteCandidate string `json:"remoteCandidate,omitempty"`
+
+	// TransactionID is the hex encoded STUN transaction ID of a check.
+	TransactionID string `json:"transactionId,omitempty"`
+
+	// UseCandidate is set for checks carrying USE-CANDIDATE.
+	UseCandidate bool `json:"useCandidate,omitempty"`
+
+	// RoundTripTime is the round trip time of a check that got a response.
+	RoundTripTime time.Duration `json:"roundTripTime,omitempty"`
+
+	// State is the new state of state changes.
+	State string `json:"state,omitempty"`
+}
+
+// eventLog keeps the last events of an agent in a ring buffer. A nil
+// eventLog records nothing.
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+	full   bool
+}
+
+func newEventLog(size int) *eventLog {
+	if size <= 0 {
+		return nil
+	}
+
+	return &eventLog{events: make([]Event, size)}
+}
+
+func (l *eventLog) record(event Event) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events[l.next] = event
+	l.next++
+	if l.next == len(l.events) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// get returns the recorded events, oldest first.
+func (l *eventLog) get() []Event {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.full {
+		return append([]Event{}, l.events[:l.next]...)
+	}
+
+	return append(append([]Event{}, l.events[l.next:]...), l.events[:l.next]...)
+}
+
+// GetEventLog returns the events recorded by the agent, oldest first. It
+// returns nil if AgentConfig.EventLogSize is not set.
+func (a *Agent) GetEventLog() []Event {
+	return a.eventLog.get()
+}
+
+func (a *Agent) recordEvent(eventType EventType, local, remote Candidate) {
+	if a.eventLog == nil {
+		return
+	}
+
+	a.eventLog.record(a.newEvent(eventType, local, remote))
+}
+
+func (a *Agent) recordCheckEvent(
+	eventType EventType,
+	local, remote Candidate,
+	transactionID [stun.TransactionIDSize]byte,
+	useCandidate bool,
+	rtt time.Duration,
+) {
+	if a.eventLog == nil {
+		return
+	}
+
+	event := a.newEvent(eventType, local, remote)
+	event.TransactionID = hex.EncodeToString(transactionID[:])
+	event.UseCandidate = useCandidate
+	event.RoundTripTime = rtt
+	a.eventLog.record(event)
+}
+
+func (a *Agent) recordStateEvent(eventType EventType, local, remote Candidate, state string) {
+	if a.eventLog == nil {
+		return
+	}
+
+	event := a.newEvent(eventType, local, remote)
+	event.State = state
+	a.eventLog.record(event)
+}
+
+func (a *Agent) newEvent(eventType EventType, local, remote Candidate) Event {
+	event := Event{
+		Time:      time.Now(),
+		Type:      eventType,
+		Component: a.component,
+	}
+	if local != nil {
+		event.LocalCandidate = local.String()
+	}
+	if remote != nil {
+		event.RemoteCandidate = remote.String()
+	}
+
+	return event
+}
+
+// setPairState changes the state of a candidate pair and records it.
+func (a *Agent) setPairState(pair *CandidatePair, state CandidatePairState) {
+	if pair.state == state {
+		return
+	}
+
+	pair.state = state
+	a.recordStateEvent(EventTypePairStateChanged, pair.Local, pair.Remote, state.String())
+}
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package ice
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/v3/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLogRingBuffer(t *testing.T) {
+	require.Nil(t, newEventLog(0))
+	require.Nil(t, newEventLog(0).get())
+
+	log := newEventLog(3)
+	log.record(Event{Type: EventTypeCandidateGathered})
+	log.record(Event{Type: EventTypePairCreated})
+	require.Equal(t, []Event{{Type: EventTypeCandidateGathered}, {Type: EventTypePairCreated}}, log.get())
+
+	log.record(Event{Type: EventTypeCheckSent})
+	log.record(Event{Type: EventTypeCheckResponse})
+	log.record(Event{Type: EventTypePairSelected})
+	require.Equal(t, []Event{
+		{Type: EventTypeCheckSent},
+		{Type: EventTypeCheckResponse},
+		{Type: EventTypePairSelected},
+	}, log.get())
+}
+
+func TestEventLogDisabled(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	agent, err := NewAgent(&AgentConfig{})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, agent.Close())
+	}()
+
+	require.NoError(t, agent.OnCandidate(func(Candidate) {}))
+	require.NoError(t, agent.GatherCandidates())
+	require.Nil(t, agent.GetEventLog())
+}
+
+func TestEventLogConnectivity(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 30).Stop()
+
+	aConn, bConn := pipe(&AgentConfig{EventLogSize: 1000})
+	defer closePipe(t, aConn, bConn)
+
+	eventTypes := func(agent *Agent) map[EventType]bool {
+		types := map[EventType]bool{}
+		for _, event := range agent.GetEventLog() {
+			types[event.Type] = true
+		}
+
+		return types
+	}
+
+	for _, agent := range []*Agent{aConn.agent, bConn.agent} {
+		types := eventTypes(agent)
+		for _, eventType := range []EventType{
+			EventTypeCandidateGathered,
+			EventTypeRemoteCandidateAdded,
+			EventTypePairCreated,
+			EventTypePairStateChanged,
+			EventTypeCheckSent,
+			EventTypeCheckReceived,
+			EventTypeCheckResponse,
+			EventTypeNomination,
+			EventTypePairSelected,
+			EventTypeConnectionStateChanged,
+			EventTypeGatheringStateChanged,
+		} {
+			require.True(t, types[eventType], "missing %s event", eventType)
+		}
+	}
+
+	events := bConn.agent.GetEventLog()
+	for i := 1; i < len(events); i++ {
+		require.False(t, events[i].Time.Before(events[i-1].Time))
+	}
+
+	var response Event
+	for _, event := range events {
+		if event.Type == EventTypeCheckResponse {
+			response = event
+		}
+	}
+	require.NotEmpty(t, response.LocalCandidate)
+	require.NotEmpty(t, response.RemoteCandidate)
+	require.Len(t, response.TransactionID, 24)
+	require.Positive(t, response.RoundTripTime)
+
+	out, err := json.Marshal(events)
+	require.NoError(t, err)
+
+	var exported []map[string]any
+	require.NoError(t, json.Unmarshal(out, &exported))
+	require.Len(t, exported, len(events))
+	require.Equal(t, events[0].Type.String(), exported[0]["type"])
+}
@@ -14,7 +14,6 @@ import (
 	"sync"
 
 	"github.com/pion/dtls/v3"
-	"github.com/pion/ice/v4/internal/fakenet"
 	stunx "github.com/pion/ice/v4/internal/stun"
 	"github.com/pion/logging"
 	"github.com/pion/stun/v3"
@@ -72,6 +71,16 @@ func (a *Agent) gatherCandidates(ctx context.Context, done chan struct{}) { //no
 		return
 	}
 
+	// Interfaces are listed before gathering so that changes during the
+	// gathering are picked up by the network monitor.
+	var knownAddrs map[netip.Addr]struct{}
+	if a.continualGatheringPolicy == GatherContinually {
+		var err error
+		if knownAddrs, err = a.localAddrSet(); err != nil {
+			a.log.Warnf("Failed to iterate local interfaces: %v", err)
+		}
+	}
+
 	var wg sync.WaitGroup
 	for _, t := range a.candidateTypes {
 		switch t {
@@ -114,10 +123,14 @@ func (a *Agent) gatherCandidates(ctx context.Context, done chan struct{}) { //no
 	if err := a.setGatheringState(GatheringStateComplete); err != nil { //nolint:contextcheck
 		a.log.Warnf("Failed to set gatheringState to GatheringStateComplete: %v", err)
 	}
+
+	if a.continualGatheringPolicy == GatherContinually {
+		a.monitorNetwork(ctx, knownAddrs)
+	}
 }
 
-//nolint:gocognit,gocyclo,cyclop
-func (a *Agent) gatherCandidatesLocal(ctx context.Context, networkTypes []NetworkType) {
+// localNetworks returns the networks (udp and/or tcp) of the given network types.
+func localNetworks(networkTypes []NetworkType) map[string]struct{} {
 	networks := map[string]struct{}{}
 	for _, networkType := range networkTypes {
 		if networkType.IsTCP() {
@@ -127,6 +140,12 @@ func (a *Agent) gatherCandidatesLocal(ctx context.Context, networkTypes []Networ
 		}
 	}
 
+	return networks
+}
+
+func (a *Agent) gatherCandidatesLocal(ctx context.Context, networkTypes []NetworkType) {
+	networks := localNetworks(networkTypes)
+
 	// When UDPMux is enabled, skip other UDP candidates
 	if a.udpMux != nil {
 		if err := a.gatherCandidatesLocalUDPMux(ctx); err != nil {
@@ -142,6 +161,15 @@ func (a *Agent) gatherCandidatesLocal(ctx context.Context, networkTypes []Networ
 		return
 	}
 
+	a.gatherCandidatesLocalAddrs(ctx, localAddrs, networks)
+}
+
+// gatherCandidatesLocalAddrs gathers host candidates on the given local addresses
+// for the given networks (udp and/or tcp).
+//
+//nolint:gocognit,gocyclo,cyclop
+func (a *Agent) gatherCandidatesLocalAddrs(ctx context.Context, localAddrs []netip.Addr, networks map[string]struct{}) {
+	var err error
 	for _, addr := range localAddrs {
 		mappedIP := addr
 		if a.mDNSMode != MulticastDNSModeQueryAndGather &&
@@ -183,6 +211,9 @@ func (a *Agent) gatherCandidatesLocal(ctx context.Context, networkTypes []Networ
 
 			switch network {
 			case tcp:
+				if a.tcpSimultaneousOpen {
+					a.gatherCandidateSimultaneousOpen(ctx, addr, address, isLocationTracked)
+				}
 				if a.tcpMux == nil {
 					continue
 				}
@@ -251,7 +282,7 @@ func (a *Agent) gatherCandidatesLocal(ctx context.Context, networkTypes []Networ
 					Network:   network,
 					Address:   address,
 					Port:      connAndPort.port,
-					Component: ComponentRTP,
+					Component: a.component,
 					TCPType:   tcpType,
 					// we will still process this candidate so that we start up the right
 					// listeners.
@@ -371,7 +402,7 @@ func (a *Agent) gatherCandidatesLocalUDPMux(ctx context.Context) error { //nolin
 			Network:           udp,
 			Address:           address,
 			Port:              udpAddr.Port,
-			Component:         ComponentRTP,
+			Component:         a.component,
 			IsLocationTracked: isLocationTracked,
 		}
 
@@ -463,7 +494,7 @@ func (a *Agent) gatherCandidatesSrflxMapped(ctx context.Context, networkTypes []
 				Network:   network,
 				Address:   mappedIP.String(),
 				Port:      lAddr.Port,
-				Component: ComponentRTP,
+				Component: a.component,
 				RelAddr:   lAddr.IP.String(),
 				RelPort:   lAddr.Port,
 			}
@@ -545,7 +576,7 @@ func (a *Agent) gatherCandidatesSrflxUDPMux(ctx context.Context, urls []*stun.UR
 						Network:   network,
 						Address:   ip.String(),
 						Port:      port,
-						Component: ComponentRTP,
+						Component: a.component,
 						RelAddr:   localAddr.IP.String(),
 						RelPort:   localAddr.Port,
 					}
@@ -638,7 +669,7 @@ func (a *Agent) gatherCandidatesSrflx(ctx context.Context, urls []*stun.URI, net
 					Network:   network,
 					Address:   ip.String(),
 					Port:      port,
-					Component: ComponentRTP,
+					Component: a.component,
 					RelAddr:   lAddr.IP.String(),
 					RelPort:   lAddr.Port,
 				}
@@ -674,7 +705,7 @@ func (a *Agent) gatherCandidatesRelay(ctx context.Context, urls []*stun.URI) {
 			a.log.Errorf("Failed to gather relay candidates: %v", ErrUsernameEmpty)
 
 			return
-		case urls[i].Password == "":
+		case urls[i].Password == "" && urls[i].AccessToken == nil:
 			a.log.Errorf("Failed to gather relay candidates: %v", ErrPasswordEmpty)
 
 			return
@@ -690,6 +721,7 @@ func (a *Agent) gatherCandidatesRelay(ctx context.Context, urls []*stun.URI) {
 				relAddr       string
 				relPort       int
 				relayProtocol string
+				dtlsConfig    *dtls.Config
 			)
 
 			switch {
@@ -741,40 +773,20 @@ func (a *Agent) gatherCandidatesRelay(ctx context.Context, urls []*stun.URI) {
 				relayProtocol = tcp
 				locConn = turn.NewSTUNConn(conn)
 			case url.Proto == stun.ProtoTypeUDP && url.Scheme == stun.SchemeTypeTURNS:
-				udpAddr, connectErr := a.net.ResolveUDPAddr(network, turnServerAddr)
-				if connectErr != nil {
-					a.log.Warnf("Failed to resolve UDP address %s: %v", turnServerAddr, connectErr)
-
-					return
-				}
-
-				udpConn, dialErr := a.net.DialUDP("udp", nil, udpAddr)
-				if dialErr != nil {
-					a.log.Warnf("Failed to dial DTLS address %s: %v", turnServerAddr, dialErr)
+				if locConn, err = a.net.ListenPacket(network, "0.0.0.0:0"); err != nil {
+					a.log.Warnf("Failed to listen %s: %v", network, err)
 
 					return
 				}
 
-				conn, connectErr := dtls.Client(&fakenet.PacketConn{Conn: udpConn}, udpConn.RemoteAddr(), &dtls.Config{
+				// The TURN client runs DTLS on the socket, see RFC 7350.
+				relAddr = locConn.LocalAddr().(*net.UDPAddr).IP.String() //nolint:forcetypeassert
+				relPort = locConn.LocalAddr().(*net.UDPAddr).Port        //nolint:forcetypeassert
+				relayProtocol = relayProtocolDTLS
+				dtlsConfig = &dtls.Config{
 					ServerName:         url.Host,
 					InsecureSkipVerify: a.insecureSkipVerify, //nolint:gosec
-				})
-				if connectErr != nil {
-					a.log.Warnf("Failed to create DTLS client: %v", turnServerAddr, connectErr)
-
-					return
-				}
-
-				if connectErr = conn.HandshakeContext(ctx); connectErr != nil {
-					a.log.Warnf("Failed to create DTLS client: %v", turnServerAddr, connectErr)
-
-					return
 				}
-
-				relAddr = conn.LocalAddr().(*net.UDPAddr).IP.String() //nolint:forcetypeassert
-				relPort = conn.LocalAddr().(*net.UDPAddr).Port        //nolint:forcetypeassert
-				relayProtocol = relayProtocolDTLS
-				locConn = &fakenet.PacketConn{Conn: conn}
 			case url.Proto == stun.ProtoTypeTCP && url.Scheme == stun.SchemeTypeTURNS:
 				tcpAddr, resolvErr := a.net.ResolveTCPAddr(NetworkTypeTCP4.String(), turnServerAddr)
 				if resolvErr != nil {
@@ -819,8 +831,11 @@ func (a *Agent) gatherCandidatesRelay(ctx context.Context, urls []*stun.URI) {
 				Conn:           locConn,
 				Username:       url.Username,
 				Password:       url.Password,
+				AccessToken:    url.AccessToken,
+				MACKey:         url.MACKey,
 				LoggerFactory:  a.loggerFactory,
 				Net:            a.net,
+				DTLSConfig:     dtlsConfig,
 			})
 			if err != nil {
 				closeConnAndLog(locConn, a.log, "failed to create new TURN client %s %s", turnServerAddr, err)
@@ -835,7 +850,18 @@ func (a *Agent) gatherCandidatesRelay(ctx context.Context, urls []*stun.URI) {
 				return
 			}
 
+			// The DTLS handshake and the transactions of Allocate are
+			// aborted by closing the client once the gathering is cancelled.
+			allocated := make(chan struct{})
+			go func() {
+				select {
+				case <-ctx.Done():
+					client.Close()
+				case <-allocated:
+				}
+			}()
 			relayConn, err := client.Allocate()
+			close(allocated)
 			if err != nil {
 				client.Close()
 				closeConnAndLog(locConn, a.log, "failed to allocate on TURN client %s %s", turnServerAddr, err)
@@ -853,7 +879,7 @@ func (a *Agent) gatherCandidatesRelay(ctx context.Context, urls []*stun.URI) {
 
 			relayConfig := CandidateRelayConfig{
 				Network:       network,
-				Component:     ComponentRTP,
+				Component:     a.component,
 				Address:       rAddr.IP.String(),
 				Port:          rAddr.Port,
 				RelAddr:       relAddr,
@@ -889,5 +915,13 @@ func (a *Agent) gatherCandidatesRelay(ctx context.Context, urls []*stun.URI) {
 				a.log.Warnf("Failed to append to localCandidates and run onCandidateHdlr: %v", err)
 			}
 		}(*urls[i])
+
+		if a.gathersTCPRelay(urls[i]) {
+			wg.Add(1)
+			go func(url stun.URI) {
+				defer wg.Done()
+				a.gatherCandidateRelayTCP(ctx, url)
+			}(*urls[i])
+		}
 	}
 }
@@ -122,6 +122,41 @@ func TestGatherConcurrency(t *testing.T) {
 	<-candidateGathered.Done()
 }
 
+func TestGatherComponent(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 30).Stop()
+
+	agent, err := NewAgent(&AgentConfig{
+		NetworkTypes:    []NetworkType{NetworkTypeUDP4},
+		IncludeLoopback: true,
+		Component:       ComponentRTCP,
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, agent.Close())
+	}()
+
+	gatheringDone := make(chan struct{})
+	require.NoError(t, agent.OnCandidate(func(c Candidate) {
+		if c == nil {
+			close(gatheringDone)
+
+			return
+		}
+		require.Equal(t, ComponentRTCP, c.Component())
+	}))
+	require.NoError(t, agent.GatherCandidates())
+	<-gatheringDone
+
+	candidates, err := agent.GetLocalCandidates()
+	require.NoError(t, err)
+	require.NotEmpty(t, candidates)
+	for _, c := range candidates {
+		require.Equal(t, ComponentRTCP, c.Component())
+	}
+}
+
 func TestLoopbackCandidate(t *testing.T) {
 	defer test.CheckRoutines(t)()
 
@@ -438,6 +473,23 @@ func TestTURNConcurrency(t *testing.T) {
 
 		runTest(stun.ProtoTypeUDP, stun.SchemeTypeTURNS, nil, serverListener, serverPort)
 	})
+
+	t.Run("DTLS Relay PacketConn", func(t *testing.T) {
+		certificate, genErr := selfsign.GenerateSelfSigned()
+		require.NoError(t, genErr)
+
+		serverPort := randomPort(t)
+		serverListener, err := dtls.Listen(
+			"udp",
+			&net.UDPAddr{IP: net.ParseIP(localhostIPStr), Port: serverPort},
+			&dtls.Config{
+				Certificates: []tls.Certificate{certificate},
+			},
+		)
+		require.NoError(t, err)
+
+		runTest(stun.ProtoTypeUDP, stun.SchemeTypeTURNS, turn.NewDTLSPacketConn(serverListener), nil, serverPort)
+	})
 }
 
 // Assert that STUN and TURN gathering are done concurrently.
@@ -569,6 +621,67 @@ func TestTURNSrflx(t *testing.T) {
 	<-candidateGathered.Done()
 }
 
+func TestTURNAccessToken(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 30).Stop()
+
+	serverPort := randomPort(t)
+	serverListener, err := net.ListenPacket("udp4", localhostIPStr+":"+strconv.Itoa(serverPort))
+	require.NoError(t, err)
+
+	key := make([]byte, 32)
+	server, err := turn.NewServer(turn.ServerConfig{
+		Realm: "pion.ly",
+		AccessTokenHandler: func(kid string, _ net.Addr) ([]byte, bool) {
+			return key, kid == "kid"
+		},
+		PacketConnConfigs: []turn.PacketConnConfig{
+			{
+				PacketConn:            serverListener,
+				RelayAddressGenerator: &turn.RelayAddressGeneratorNone{Address: localhostIPStr},
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, server.Close())
+	}()
+
+	token := stun.Token{MACKey: []byte("0123456789abcdefghij"), Timestamp: time.Now(), Lifetime: time.Hour}
+	accessToken, err := token.Encrypt(key, "pion.ly")
+	require.NoError(t, err)
+
+	agent, err := NewAgent(&AgentConfig{
+		NetworkTypes: []NetworkType{NetworkTypeUDP4},
+		Urls: []*stun.URI{{
+			Scheme:      stun.SchemeTypeTURN,
+			Proto:       stun.ProtoTypeUDP,
+			Host:        localhostIPStr,
+			Port:        serverPort,
+			Username:    "kid",
+			AccessToken: accessToken,
+			MACKey:      token.MACKey,
+		}},
+		CandidateTypes: []CandidateType{CandidateTypeRelay},
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, agent.Close())
+	}()
+
+	candidateGathered, candidateGatheredFunc := context.WithCancel(context.Background())
+	require.NoError(t, agent.OnCandidate(func(c Candidate) {
+		if c != nil && c.Type() == CandidateTypeRelay {
+			candidateGatheredFunc()
+		}
+	}))
+
+	require.NoError(t, agent.GatherCandidates())
+
+	<-candidateGathered.Done()
+}
+
 func TestCloseConnLog(t *testing.T) {
 	a, err := NewAgent(&AgentConfig{})
 	require.NoError(t, err)
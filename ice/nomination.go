@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package ice
+
+import (
+	"encoding/binary"
+
+	"github.com/pion/stun/v3"
+)
+
+// RenominationOption is the ice-options token peers use to signal support for
+// renomination in SDP.
+const RenominationOption = "renomination"
+
+// AttrNomination is the (goog-)NOMINATION attribute type of
+// https://datatracker.ietf.org/doc/html/draft-thatcher-ice-renomination-01
+const AttrNomination stun.AttrType = 0xC001
+
+// NominationAttr represents the NOMINATION attribute. The controlling agent
+// increases the value with every nomination, the controlled agent selects the
+// pair nominated with the highest value.
+type NominationAttr uint32
+
+const nominationSize = 4 // 32 bit
+
+// AddTo adds NOMINATION attribute to message.
+func (n NominationAttr) AddTo(m *stun.Message) error {
+	v := make([]byte, nominationSize)
+	binary.BigEndian.PutUint32(v, uint32(n))
+	m.Add(AttrNomination, v)
+
+	return nil
+}
+
+// GetFrom decodes NOMINATION attribute from message.
+func (n *NominationAttr) GetFrom(m *stun.Message) error {
+	v, err := m.Get(AttrNomination)
+	if err != nil {
+		return err
+	}
+	if err = stun.CheckSize(AttrNomination, len(v), nominationSize); err != nil {
+		return err
+	}
+	*n = NominationAttr(binary.BigEndian.Uint32(v))
+
+	return nil
+}
@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package ice
+
+import (
+	"testing"
+
+	"github.com/pion/stun/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNominationAttr(t *testing.T) {
+	m := new(stun.Message)
+	var nomination NominationAttr
+	require.Error(t, nomination.GetFrom(m))
+
+	require.NoError(t, m.Build(stun.BindingRequest, NominationAttr(42)))
+	m1 := new(stun.Message)
+	_, err := m1.Write(m.Raw)
+	require.NoError(t, err)
+	require.NoError(t, nomination.GetFrom(m1))
+	require.Equal(t, NominationAttr(42), nomination)
+
+	m2 := new(stun.Message)
+	m2.Add(AttrNomination, []byte{1, 2})
+	require.True(t, stun.IsAttrSizeInvalid(nomination.GetFrom(m2)))
+}
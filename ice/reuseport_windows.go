@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build windows
+
+package ice
+
+import (
+	"syscall"
+)
+
+// reusePortControl lets the listener of a simultaneous-open TCP candidate
+// and the connections it dials bind the same address.
+func reusePortControl(_, _ string, conn syscall.RawConn) error {
+	var opErr error
+	if err := conn.Control(func(fd uintptr) {
+		opErr = syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+	}); err != nil {
+		return err
+	}
+
+	return opErr
+}
@@ -24,11 +24,23 @@ type controllingSelector struct {
 	agent         *Agent
 	nominatedPair *CandidatePair
 	log           logging.LeveledLogger
+
+	// Renomination state, the value of the last NOMINATION sent, when it was
+	// sent and how many requests have been sent for it, and when the valid
+	// pairs were checked last.
+	nominationValue    uint32
+	lastNomination     time.Time
+	nominationRequests uint16
+	lastRecheck        time.Time
 }
 
 func (s *controllingSelector) Start() {
 	s.startTime = time.Now()
 	s.nominatedPair = nil
+	s.nominationValue = 0
+	s.lastNomination = time.Time{}
+	s.nominationRequests = 0
+	s.lastRecheck = time.Time{}
 }
 
 func (s *controllingSelector) isNominatable(c Candidate) bool {
@@ -54,15 +66,17 @@ func (s *controllingSelector) ContactCandidates() {
 		if s.agent.validateSelectedPair() {
 			s.log.Trace("Checking keepalive")
 			s.agent.checkKeepalive()
+			if s.agent.renomination {
+				s.renominate()
+			}
 		}
-	case s.nominatedPair != nil:
+	case s.nominatedPair != nil && s.nominatedPair.state != CandidatePairStateFailed:
 		s.nominatePair(s.nominatedPair)
 	default:
-		p := s.agent.getBestValidCandidatePair()
-		if p != nil && s.isNominatable(p.Local) && s.isNominatable(p.Remote) {
+		if p := s.agent.selectionPolicy.Nominate(nil, s.validPairs()); p != nil {
 			s.log.Tracef("Nominatable pair found, nominating (%s, %s)", p.Local, p.Remote)
 			p.nominated = true
-			s.nominatedPair = p
+			s.setNominatedPair(p)
 			s.nominatePair(p)
 
 			return
@@ -71,19 +85,87 @@ func (s *controllingSelector) ContactCandidates() {
 	}
 }
 
+// validPairs returns the pairs that passed their connectivity checks.
+func (s *controllingSelector) validPairs() []CandidatePairInfo {
+	valid := []CandidatePairInfo{}
+	for _, p := range s.agent.checklist {
+		if p.state == CandidatePairStateSucceeded {
+			valid = append(valid, newCandidatePairInfo(p, s.isNominatable(p.Local) && s.isNominatable(p.Remote)))
+		}
+	}
+
+	return valid
+}
+
+func (s *controllingSelector) setNominatedPair(pair *CandidatePair) {
+	s.nominatedPair = pair
+	s.nominationValue++
+	s.lastNomination = time.Now()
+	s.nominationRequests = 0
+}
+
+// renominate keeps checking the candidate pairs once a pair has been selected,
+// and nominates a better pair when one shows up.
+func (s *controllingSelector) renominate() {
+	selectedPair := s.agent.getSelectedPair()
+
+	if s.nominatedPair != nil && s.nominatedPair != selectedPair {
+		if s.nominatedPair.state != CandidatePairStateFailed && s.nominationRequests < s.agent.maxBindingRequests {
+			s.nominationRequests++
+			s.nominatePair(s.nominatedPair)
+
+			return
+		}
+
+		s.log.Tracef("Renomination of %s failed, keeping %s", s.nominatedPair, selectedPair)
+		s.nominatedPair = selectedPair
+	}
+
+	// Check new pairs, the round trip time of the valid ones is refreshed once
+	// per renomination interval, the keepalives refresh the selected pair.
+	s.agent.pingAllCandidates()
+	if time.Since(s.lastRecheck) >= s.agent.renominationInterval {
+		s.lastRecheck = time.Now()
+		for _, p := range s.agent.checklist {
+			if p.state == CandidatePairStateSucceeded && p != selectedPair {
+				s.PingCandidate(p.Local, p.Remote)
+			}
+		}
+	}
+
+	if time.Since(s.lastNomination) < s.agent.renominationInterval {
+		return
+	}
+
+	selectedInfo := newCandidatePairInfo(selectedPair, true)
+	best := s.agent.selectionPolicy.Nominate(&selectedInfo, s.validPairs())
+	if best == nil || best == selectedPair {
+		return
+	}
+
+	s.log.Debugf("Renominating %s, previously selected %s", best, selectedPair)
+	s.setNominatedPair(best)
+	s.nominatePair(best)
+}
+
 func (s *controllingSelector) nominatePair(pair *CandidatePair) {
 	// The controlling agent MUST include the USE-CANDIDATE attribute in
 	// order to nominate a candidate pair (Section 8.1.1).  The controlled
 	// agent MUST NOT include the USE-CANDIDATE attribute in a Binding
 	// request.
-	msg, err := stun.Build(stun.BindingRequest, stun.TransactionID,
-		stun.NewUsername(s.agent.remoteUfrag+":"+s.agent.localUfrag),
+	setters := []stun.Setter{
+		stun.BindingRequest, stun.TransactionID,
+		stun.NewUsername(s.agent.remoteUfrag + ":" + s.agent.localUfrag),
 		UseCandidate(),
 		AttrControlling(s.agent.tieBreaker),
 		PriorityAttr(pair.Local.Priority()),
-		stun.NewShortTermIntegrity(s.agent.remotePwd),
-		stun.Fingerprint,
-	)
+	}
+	if s.agent.renomination {
+		setters = append(setters, NominationAttr(s.nominationValue))
+	}
+	setters = append(setters, stun.NewShortTermIntegrity(s.agent.remotePwd), stun.Fingerprint)
+
+	msg, err := stun.Build(setters...)
 	if err != nil {
 		s.log.Error(err.Error())
 
@@ -111,13 +193,14 @@ func (s *controllingSelector) HandleBindingRequest(message *stun.Message, local,
 		bestPair := s.agent.getBestAvailableCandidatePair()
 		if bestPair == nil {
 			s.log.Tracef("No best pair available")
-		} else if bestPair.equal(pair) && s.isNominatable(pair.Local) && s.isNominatable(pair.Remote) {
+		} else if bestPair.equal(pair) && s.isNominatable(pair.Local) && s.isNominatable(pair.Remote) &&
+			s.agent.selectionPolicy.Nominate(nil, s.validPairs()) == pair {
 			s.log.Tracef(
 				"The candidate (%s, %s) is the best candidate available, marking it as nominated",
 				pair.Local,
 				pair.Remote,
 			)
-			s.nominatedPair = pair
+			s.setNominatedPair(pair)
 			s.nominatePair(pair)
 		}
 	}
@@ -161,10 +244,14 @@ func (s *controllingSelector) HandleSuccessResponse(m *stun.Message, local, remo
 		return
 	}
 
-	pair.state = CandidatePairStateSucceeded
+	s.agent.setPairState(pair, CandidatePairStateSucceeded)
 	s.log.Tracef("Found valid candidate pair: %s", pair)
-	if pendingRequest.isUseCandidate && s.agent.getSelectedPair() == nil {
-		s.agent.setSelectedPair(pair)
+	if pendingRequest.isUseCandidate {
+		// With renomination the pair nominated last replaces the selected pair.
+		if selectedPair := s.agent.getSelectedPair(); selectedPair == nil ||
+			(s.agent.renomination && pair == s.nominatedPair && pair != selectedPair) {
+			s.agent.setSelectedPair(pair)
+		}
 	}
 
 	pair.UpdateRoundTripTime(rtt)
@@ -190,9 +277,16 @@ func (s *controllingSelector) PingCandidate(local, remote Candidate) {
 type controlledSelector struct {
 	agent *Agent
 	log   logging.LeveledLogger
+
+	// nominationValue is the highest NOMINATION value received, renominatedPair
+	// the pair it nominated.
+	nominationValue uint32
+	renominatedPair *CandidatePair
 }
 
 func (s *controlledSelector) Start() {
+	s.nominationValue = 0
+	s.renominatedPair = nil
 }
 
 func (s *controlledSelector) ContactCandidates() {
@@ -263,12 +357,17 @@ func (s *controlledSelector) HandleSuccessResponse(m *stun.Message, local, remot
 		return
 	}
 
-	pair.state = CandidatePairStateSucceeded
+	s.agent.setPairState(pair, CandidatePairStateSucceeded)
 	s.log.Tracef("Found valid candidate pair: %s", pair)
-	if pair.nominateOnBindingSuccess {
-		if selectedPair := s.agent.getSelectedPair(); selectedPair == nil ||
+	if pair.nominateOnBindingSuccess && pair == s.renominatedPair {
+		if s.agent.getSelectedPair() != pair && s.acceptNomination(pair) {
+			s.agent.setSelectedPair(pair)
+		}
+	} else if pair.nominateOnBindingSuccess {
+		if selectedPair := s.agent.getSelectedPair(); (selectedPair == nil ||
 			(selectedPair != pair &&
-				(!s.agent.needsToCheckPriorityOnNominated() || selectedPair.priority() <= pair.priority())) {
+				(!s.agent.needsToCheckPriorityOnNominated() || selectedPair.priority() <= pair.priority()))) &&
+			s.acceptNomination(pair) {
 			s.agent.setSelectedPair(pair)
 		} else if selectedPair != pair {
 			s.log.Tracef("Ignore nominate new pair %s, already nominated pair %s", pair, selectedPair)
@@ -285,7 +384,7 @@ func (s *controlledSelector) HandleBindingRequest(message *stun.Message, local,
 	}
 	pair.UpdateRequestReceived()
 
-	if message.Contains(stun.AttrUseCandidate) { //nolint:nestif
+	if message.Contains(stun.AttrUseCandidate) && !s.handleRenomination(message, pair) { //nolint:nestif
 		// https://tools.ietf.org/html/rfc8445#section-7.3.1.5
 
 		if pair.state == CandidatePairStateSucceeded {
@@ -294,10 +393,11 @@ func (s *controlledSelector) HandleBindingRequest(message *stun.Message, local,
 			// generated a valid pair (Section 7.2.5.3.2).  The agent sets the
 			// nominated flag value of the valid pair to true.
 			selectedPair := s.agent.getSelectedPair()
-			if selectedPair == nil ||
+			if (selectedPair == nil ||
 				(selectedPair != pair &&
 					(!s.agent.needsToCheckPriorityOnNominated() ||
-						selectedPair.priority() <= pair.priority())) {
+						selectedPair.priority() <= pair.priority()))) &&
+				s.acceptNomination(pair) {
 				s.agent.setSelectedPair(pair)
 			} else if selectedPair != pair {
 				s.log.Tracef("Ignore nominate new pair %s, already nominated pair %s", pair, selectedPair)
@@ -325,6 +425,62 @@ func (s *controlledSelector) HandleBindingRequest(message *stun.Message, local,
 	}
 }
 
+// handleRenomination handles a nomination carrying the NOMINATION attribute,
+// the pair nominated with the highest value is selected regardless of its
+// priority. It returns false if the request has no NOMINATION attribute or
+// renomination was not negotiated, then the request is a regular nomination.
+func (s *controlledSelector) handleRenomination(message *stun.Message, pair *CandidatePair) bool {
+	if !s.agent.renomination {
+		return false
+	}
+
+	var nomination NominationAttr
+	if err := nomination.GetFrom(message); err != nil {
+		return false
+	}
+
+	if uint32(nomination) <= s.nominationValue {
+		s.log.Tracef("Ignore nomination %d of %s, already at %d", nomination, pair, s.nominationValue)
+
+		return true
+	}
+	s.nominationValue = uint32(nomination)
+	s.renominatedPair = pair
+
+	// Nominations of other pairs still waiting for their check are outdated.
+	for _, p := range s.agent.checklist {
+		if p != pair {
+			p.nominateOnBindingSuccess = false
+		}
+	}
+
+	if pair.state == CandidatePairStateSucceeded {
+		if s.agent.getSelectedPair() != pair && s.acceptNomination(pair) {
+			s.agent.setSelectedPair(pair)
+		}
+	} else {
+		pair.nominateOnBindingSuccess = true
+	}
+
+	return true
+}
+
+// acceptNomination asks the selection policy whether to switch to pair.
+func (s *controlledSelector) acceptNomination(pair *CandidatePair) bool {
+	var selected *CandidatePairInfo
+	if selectedPair := s.agent.getSelectedPair(); selectedPair != nil {
+		info := newCandidatePairInfo(selectedPair, true)
+		selected = &info
+	}
+
+	if s.agent.selectionPolicy.AcceptNomination(selected, newCandidatePairInfo(pair, true)) {
+		return true
+	}
+	s.log.Tracef("Nomination of %s rejected by the selection policy", pair)
+
+	return false
+}
+
 type liteSelector struct {
 	pairCandidateSelector
 }
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package ice
+
+import "time"
+
+const (
+	// A pair with a round trip time below renominationRTTRatio times the one of
+	// the selected pair is better. A higher priority pair is better unless its
+	// round trip time is above the one of the selected pair divided by the ratio.
+	renominationRTTRatio = 0.8
+
+	// renominationMaxResponseAge is how recent the round trip time of a pair must
+	// be for it to be considered for renomination.
+	renominationMaxResponseAge = 10 * time.Second
+)
+
+// CandidatePairInfo describes a candidate pair to a CandidatePairSelectionPolicy.
+// The network and candidate types are those of Pair.Local and Pair.Remote.
+type CandidatePairInfo struct {
+	Pair  *CandidatePair
+	Stats CandidatePairStats
+
+	// Priority is the pair priority as defined by RFC 8445 Section 6.1.2.3.
+	Priority uint64
+
+	// Eligible is true once the acceptance minimum wait (for example
+	// AgentConfig.RelayAcceptanceMinWait) of both candidates elapsed.
+	// It is always true on the controlled agent.
+	Eligible bool
+}
+
+// LossRate returns the fraction of the connectivity checks sent on the pair
+// that have not been answered.
+func (i CandidatePairInfo) LossRate() float64 {
+	if i.Stats.RequestsSent == 0 || i.Stats.ResponsesReceived >= i.Stats.RequestsSent {
+		return 0
+	}
+
+	return 1 - float64(i.Stats.ResponsesReceived)/float64(i.Stats.RequestsSent)
+}
+
+// CandidatePairSelectionPolicy decides which candidate pair an Agent uses.
+// Its methods are called from the internal loop of the agent, they must not
+// block nor call methods of the Agent.
+type CandidatePairSelectionPolicy interface {
+	// Nominate is called on the controlling agent with the pairs that passed
+	// their connectivity checks. selected is nil until a pair is selected, after
+	// that Nominate is only called if AgentConfig.Renomination is set. It returns
+	// the pair to nominate, or nil to keep waiting or keep the selected pair.
+	Nominate(selected *CandidatePairInfo, valid []CandidatePairInfo) *CandidatePair
+
+	// AcceptNomination is called on the controlled agent when the controlling
+	// agent nominates a pair; selected is nil until a pair is selected. Returning
+	// false ignores the nomination. Nominations of lower priority pairs made
+	// without renomination are ignored by the agent before asking the policy.
+	AcceptNomination(selected *CandidatePairInfo, nominated CandidatePairInfo) bool
+}
+
+// defaultSelectionPolicy nominates the highest priority pair, and with
+// renomination switches to markedly faster or higher priority pairs.
+type defaultSelectionPolicy struct{}
+
+func (defaultSelectionPolicy) Nominate(selected *CandidatePairInfo, valid []CandidatePairInfo) *CandidatePair {
+	if selected == nil {
+		var best *CandidatePairInfo
+		for i := range valid {
+			if best == nil || best.Priority < valid[i].Priority {
+				best = &valid[i]
+			}
+		}
+		if best == nil || !best.Eligible {
+			return nil
+		}
+
+		return best.Pair
+	}
+
+	var best *CandidatePair
+	for _, info := range valid {
+		if info.Pair == selected.Pair || !info.Eligible ||
+			time.Since(info.Stats.LastResponseTimestamp) > renominationMaxResponseAge {
+			continue
+		}
+		if isBetterPair(info.Pair, selected.Pair) && (best == nil || isBetterPair(info.Pair, best)) {
+			best = info.Pair
+		}
+	}
+
+	return best
+}
+
+func (defaultSelectionPolicy) AcceptNomination(*CandidatePairInfo, CandidatePairInfo) bool {
+	return true
+}
+
+// isBetterPair reports whether pair should replace current. The hysteresis of
+// renominationRTTRatio keeps pairs with similar round trip times from flapping.
+func isBetterPair(pair, current *CandidatePair) bool {
+	rtt, currentRTT := pair.CurrentRoundTripTime(), current.CurrentRoundTripTime()
+	if rtt < currentRTT*renominationRTTRatio {
+		return true
+	}
+
+	return pair.priority() > current.priority() && rtt <= currentRTT/renominationRTTRatio
+}
+
+func newCandidatePairInfo(pair *CandidatePair, eligible bool) CandidatePairInfo {
+	return CandidatePairInfo{
+		Pair:     pair,
+		Stats:    pair.stats(),
+		Priority: pair.priority(),
+		Eligible: eligible,
+	}
+}
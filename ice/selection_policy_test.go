@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package ice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/transport/v3/test"
+	"github.com/pion/transport/v3/vnet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addressPolicy nominates the pair with the given remote address and only
+// accepts nominations of the pair with the given local address.
+type addressPolicy struct {
+	address   string
+	nominated []CandidatePairInfo
+}
+
+func (p *addressPolicy) Nominate(selected *CandidatePairInfo, valid []CandidatePairInfo) *CandidatePair {
+	p.nominated = valid
+	for _, info := range valid {
+		if info.Pair.Remote.Address() == p.address && (selected == nil || selected.Pair != info.Pair) {
+			return info.Pair
+		}
+	}
+
+	return nil
+}
+
+func (p *addressPolicy) AcceptNomination(_ *CandidatePairInfo, nominated CandidatePairInfo) bool {
+	return nominated.Pair.Local.Address() == p.address
+}
+
+func TestCandidatePairSelectionPolicy(t *testing.T) {
+	defer test.CheckRoutines(t)()
+	defer test.TimeOut(time.Second * 30).Stop()
+
+	wan, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "0.0.0.0/0",
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	require.NoError(t, err)
+
+	net0, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"192.168.0.1"}})
+	require.NoError(t, err)
+	require.NoError(t, wan.AddNet(net0))
+
+	net1, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"192.168.0.2", "192.168.0.3", "192.168.0.4"}})
+	require.NoError(t, err)
+	require.NoError(t, wan.AddNet(net1))
+
+	require.NoError(t, wan.Start())
+
+	// Both agents agree on a pair that is not the one with the highest priority.
+	const address = "192.168.0.3"
+	controllingPolicy, controlledPolicy := &addressPolicy{address: address}, &addressPolicy{address: address}
+	checkInterval := 20 * time.Millisecond
+	newAgent := func(n *vnet.Net, policy CandidatePairSelectionPolicy) *Agent {
+		agent, agentErr := NewAgent(&AgentConfig{
+			NetworkTypes:                 []NetworkType{NetworkTypeUDP4},
+			MulticastDNSMode:             MulticastDNSModeDisabled,
+			Net:                          n,
+			CheckInterval:                &checkInterval,
+			CandidatePairSelectionPolicy: policy,
+		})
+		require.NoError(t, agentErr)
+
+		return agent
+	}
+
+	aNotifier, aConnected := onConnected()
+	bNotifier, bConnected := onConnected()
+	controlledAgent, controllingAgent := newAgent(net1, controlledPolicy), newAgent(net0, controllingPolicy)
+	require.NoError(t, controlledAgent.OnConnectionStateChange(aNotifier))
+	require.NoError(t, controllingAgent.OnConnectionStateChange(bNotifier))
+
+	controlledConn, controllingConn := connect(controlledAgent, controllingAgent)
+	<-aConnected
+	<-bConnected
+
+	selectedPair, err := controllingAgent.GetSelectedCandidatePair()
+	require.NoError(t, err)
+	assert.Equal(t, address, selectedPair.Remote.Address())
+	selectedPair, err = controlledAgent.GetSelectedCandidatePair()
+	require.NoError(t, err)
+	assert.Equal(t, address, selectedPair.Local.Address())
+
+	require.NotEmpty(t, controllingPolicy.nominated)
+	for _, info := range controllingPolicy.nominated {
+		assert.Equal(t, CandidatePairStateSucceeded, info.Stats.State)
+		assert.True(t, info.Eligible)
+		assert.NotZero(t, info.Priority)
+		assert.Equal(t, info.Pair.Local.ID(), info.Stats.LocalCandidateID)
+	}
+
+	require.True(t, sendUntilDone(t, controlledConn, controllingConn, 100))
+
+	closePipe(t, controlledConn, controllingConn)
+	require.NoError(t, wan.Stop())
+}
+
+func TestDefaultSelectionPolicy(t *testing.T) {
+	newPairInfo := func(localPriority uint32, eligible bool) CandidatePairInfo {
+		local, err := NewCandidateHost(&CandidateHostConfig{
+			Network: "udp", Address: "192.168.0.1", Port: 1000, Component: 1, Priority: localPriority,
+		})
+		require.NoError(t, err)
+		remote, err := NewCandidateHost(&CandidateHostConfig{
+			Network: "udp", Address: "192.168.0.2", Port: 1000, Component: 1, Priority: 100,
+		})
+		require.NoError(t, err)
+
+		pair := newCandidatePair(local, remote, true)
+		pair.UpdateRoundTripTime(10 * time.Millisecond)
+
+		return newCandidatePairInfo(pair, eligible)
+	}
+
+	policy := defaultSelectionPolicy{}
+	assert.Nil(t, policy.Nominate(nil, nil))
+
+	low, high := newPairInfo(100, true), newPairInfo(200, true)
+	assert.Equal(t, high.Pair, policy.Nominate(nil, []CandidatePairInfo{low, high}))
+
+	// The best pair is not eligible yet, wait for it.
+	waiting := newPairInfo(300, false)
+	assert.Nil(t, policy.Nominate(nil, []CandidatePairInfo{low, high, waiting}))
+
+	// With renomination the higher priority pair replaces the selected one.
+	assert.Equal(t, high.Pair, policy.Nominate(&low, []CandidatePairInfo{low, high}))
+	assert.Nil(t, policy.Nominate(&high, []CandidatePairInfo{low, high}))
+
+	assert.True(t, policy.AcceptNomination(nil, low))
+}
+
+func TestCandidatePairInfoLossRate(t *testing.T) {
+	assert.Equal(t, 0.0, CandidatePairInfo{}.LossRate())
+	assert.Equal(t, 0.25, CandidatePairInfo{Stats: CandidatePairStats{RequestsSent: 4, ResponsesReceived: 3}}.LossRate())
+	assert.Equal(t, 0.0, CandidatePairInfo{Stats: CandidatePairStats{RequestsSent: 4, ResponsesReceived: 5}}.LossRate())
+}
@@ -16,8 +16,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/ice/v4/internal/fakenet"
+	"github.com/pion/logging"
 	"github.com/pion/stun/v3"
 	"github.com/pion/transport/v3/test"
+	"github.com/pion/transport/v3/vnet"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -158,3 +161,173 @@ func TestBindingRequestHandler(t *testing.T) {
 
 	closePipe(t, controllingConn, controlledConn)
 }
+
+func TestRenomination(t *testing.T) {
+	defer test.CheckRoutines(t)()
+	defer test.TimeOut(time.Second * 30).Stop()
+
+	// Without jitter all pairs have the same round trip time.
+	wan, err := vnet.NewRouter(&vnet.RouterConfig{
+		CIDR:          "0.0.0.0/0",
+		MinDelay:      10 * time.Millisecond,
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	require.NoError(t, err)
+
+	net0, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"192.168.0.1"}})
+	require.NoError(t, err)
+	require.NoError(t, wan.AddNet(net0))
+
+	net1, err := vnet.NewNet(&vnet.NetConfig{StaticIPs: []string{"192.168.0.2", "192.168.0.3"}})
+	require.NoError(t, err)
+	require.NoError(t, wan.AddNet(net1))
+
+	require.NoError(t, wan.Start())
+
+	keepaliveInterval := 50 * time.Millisecond
+	newAgent := func(n *vnet.Net) *Agent {
+		agent, agentErr := NewAgent(&AgentConfig{
+			NetworkTypes:      []NetworkType{NetworkTypeUDP4},
+			MulticastDNSMode:  MulticastDNSModeDisabled,
+			Net:               n,
+			KeepaliveInterval: &keepaliveInterval,
+			CheckInterval:     &keepaliveInterval,
+			Renomination:      true,
+		})
+		require.NoError(t, agentErr)
+
+		return agent
+	}
+
+	aNotifier, aConnected := onConnected()
+	bNotifier, bConnected := onConnected()
+	controlledAgent, controllingAgent := newAgent(net1), newAgent(net0)
+	require.NoError(t, controlledAgent.OnConnectionStateChange(aNotifier))
+	require.NoError(t, controllingAgent.OnConnectionStateChange(bNotifier))
+
+	controlledConn, controllingConn := connect(controlledAgent, controllingAgent)
+	<-aConnected
+	<-bConnected
+
+	controlledSelected := make(chan Candidate, 10)
+	require.NoError(t, controlledAgent.OnSelectedCandidatePairChange(func(local, _ Candidate) {
+		controlledSelected <- local
+	}))
+	controllingSelected := make(chan Candidate, 10)
+	require.NoError(t, controllingAgent.OnSelectedCandidatePairChange(func(_, remote Candidate) {
+		controllingSelected <- remote
+	}))
+
+	// A remote candidate of the controlling agent becomes preferred, for example
+	// because its network interface changed.
+	var target Candidate
+	require.NoError(t, controllingAgent.loop.Run(controllingAgent.loop, func(_ context.Context) {
+		controllingAgent.renominationInterval = 0
+		selectedPair := controllingAgent.getSelectedPair()
+		for _, c := range controllingAgent.remoteCandidates[NetworkTypeUDP4] {
+			if c.Address() != selectedPair.Remote.Address() {
+				target = c
+				c.(*CandidateHost).priorityOverride = selectedPair.Remote.Priority() + 1000 //nolint:forcetypeassert
+			}
+		}
+	}))
+	require.NotNil(t, target)
+
+	for remote := range controllingSelected {
+		if remote.Equal(target) {
+			break
+		}
+	}
+	for local := range controlledSelected {
+		if local.Address() == target.Address() {
+			break
+		}
+	}
+
+	selectedPair, err := controlledAgent.GetSelectedCandidatePair()
+	require.NoError(t, err)
+	require.Equal(t, target.Address(), selectedPair.Local.Address())
+	require.True(t, sendUntilDone(t, controlledConn, controllingConn, 100))
+
+	closePipe(t, controlledConn, controllingConn)
+	require.NoError(t, wan.Stop())
+}
+
+func TestRenominationRecheck(t *testing.T) {
+	agent, err := NewAgent(&AgentConfig{Renomination: true})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, agent.Close())
+	}()
+
+	require.NoError(t, agent.loop.Run(agent.loop, func(_ context.Context) {
+		local, err := NewCandidateHost(&CandidateHostConfig{
+			Network: "udp", Address: "192.168.0.1", Port: 1000, Component: 1,
+		})
+		require.NoError(t, err)
+		local.conn = &fakenet.MockPacketConn{}
+		newRemote := func(address string) Candidate {
+			remote, err := NewCandidateHost(&CandidateHostConfig{
+				Network: "udp", Address: address, Port: 1000, Component: 1,
+			})
+			require.NoError(t, err)
+
+			return remote
+		}
+
+		selectedPair := agent.addPair(local, newRemote("192.168.0.2"))
+		validPair := agent.addPair(local, newRemote("192.168.0.3"))
+		selectedPair.state = CandidatePairStateSucceeded
+		validPair.state = CandidatePairStateSucceeded
+		agent.selectedPair.Store(selectedPair)
+
+		selector := &controllingSelector{agent: agent, log: agent.log}
+		selector.Start()
+		selector.nominatedPair = selectedPair
+		selector.lastNomination = time.Now()
+
+		// The valid pairs are checked again once per renomination interval,
+		// not on every tick.
+		for i := 0; i < 5; i++ {
+			selector.renominate()
+		}
+		assert.Equal(t, uint64(1), validPair.RequestsSent())
+		assert.Equal(t, uint64(0), selectedPair.RequestsSent())
+
+		selector.lastRecheck = time.Now().Add(-agent.renominationInterval)
+		selector.renominate()
+		assert.Equal(t, uint64(2), validPair.RequestsSent())
+	}))
+}
+
+func TestIsBetterPair(t *testing.T) {
+	newPair := func(localPriority uint32, rtt time.Duration) *CandidatePair {
+		local, err := NewCandidateHost(&CandidateHostConfig{
+			Network: "udp", Address: "192.168.0.1", Port: 1000, Component: 1, Priority: localPriority,
+		})
+		require.NoError(t, err)
+		remote, err := NewCandidateHost(&CandidateHostConfig{
+			Network: "udp", Address: "192.168.0.2", Port: 1000, Component: 1, Priority: 100,
+		})
+		require.NoError(t, err)
+
+		pair := newCandidatePair(local, remote, true)
+		pair.UpdateRoundTripTime(rtt)
+
+		return pair
+	}
+
+	current := newPair(100, 100*time.Millisecond)
+
+	// Markedly lower round trip time.
+	assert.True(t, isBetterPair(newPair(50, 70*time.Millisecond), current))
+	assert.False(t, isBetterPair(newPair(50, 90*time.Millisecond), current))
+
+	// Higher priority with a comparable round trip time.
+	assert.True(t, isBetterPair(newPair(200, 120*time.Millisecond), current))
+	assert.False(t, isBetterPair(newPair(200, 130*time.Millisecond), current))
+
+	// Switching back is not better.
+	preferred := newPair(200, 120*time.Millisecond)
+	assert.False(t, isBetterPair(current, preferred))
+}
@@ -0,0 +1,318 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package ice
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/stun/v3"
+	"github.com/pion/turn/v4"
+)
+
+// tcpRelayConn is the connection of a relay candidate with a TCP relayed
+// transport, see https://tools.ietf.org/html/rfc6062. Peers connect to the
+// relayed address, the TURN server announces each of their connections with a
+// ConnectionAttempt and the client binds it to a new connection to the server,
+// which then carries the data of the peer.
+type tcpRelayConn struct {
+	*tcpPacketConn
+
+	accept           func() (net.Conn, error)
+	setDeadline      func(time.Time) error
+	createPermission func(...net.Addr) error
+	log              logging.LeveledLogger
+
+	permittedMu sync.Mutex
+	permitted   map[string]struct{}
+}
+
+// tcpRelayPermissionAttempts is how often a permission is requested, the
+// first request may only refresh the nonce of the allocation.
+const tcpRelayPermissionAttempts = 3
+
+func newTCPRelayConn(
+	relayedAddr net.Addr,
+	accept func() (net.Conn, error),
+	setDeadline func(time.Time) error,
+	createPermission func(...net.Addr) error,
+	log logging.LeveledLogger,
+) *tcpRelayConn {
+	conn := &tcpRelayConn{
+		tcpPacketConn: newTCPPacketConn(tcpPacketParams{
+			ReadBuffer: 64,
+			LocalAddr:  relayedAddr,
+			Logger:     log,
+		}),
+		accept:           accept,
+		setDeadline:      setDeadline,
+		createPermission: createPermission,
+		log:              log,
+		permitted:        map[string]struct{}{},
+	}
+	go conn.acceptLoop()
+
+	return conn
+}
+
+func (r *tcpRelayConn) acceptLoop() {
+	for {
+		conn, err := r.accept()
+		if r.isClosed() {
+			if err == nil {
+				closeConnAndLog(conn, r.log, "Failed to close relayed TCP connection: %v", err)
+			}
+
+			return
+		} else if err != nil {
+			r.log.Warnf("Failed to accept relayed TCP connection: %v", err)
+
+			continue
+		}
+
+		if err := r.AddConn(conn, nil); err != nil {
+			closeConnAndLog(conn, r.log, "Failed to add relayed TCP connection: %v", err)
+		}
+	}
+}
+
+// permit lets the peer of a remote candidate connect to the relayed address,
+// the TURN server drops the connections of peers without a permission. It is
+// requested once for each IP address, in the background as it is a transaction
+// with the TURN server.
+func (r *tcpRelayConn) permit(addr net.Addr) {
+	ip, _, _, err := parseAddr(addr)
+	if err != nil {
+		r.log.Warnf("Failed to parse address: %s; error: %s", addr, err)
+
+		return
+	}
+
+	r.permittedMu.Lock()
+	_, ok := r.permitted[ip.String()]
+	r.permitted[ip.String()] = struct{}{}
+	r.permittedMu.Unlock()
+	if ok {
+		return
+	}
+
+	go func() {
+		for i := 0; i < tcpRelayPermissionAttempts; i++ {
+			err = r.createPermission(&net.TCPAddr{IP: ip.AsSlice(), Zone: ip.Zone()})
+			if err == nil || r.isClosed() {
+				return
+			}
+		}
+		r.log.Warnf("Failed to create permission for %s: %v", ip, err)
+	}()
+}
+
+// ReadFrom skips the errors of single connections, the candidate is only
+// done once it is closed.
+func (r *tcpRelayConn) ReadFrom(b []byte) (n int, rAddr net.Addr, err error) {
+	for {
+		n, rAddr, err = r.tcpPacketConn.ReadFrom(b)
+		if err == nil || r.isClosed() {
+			return n, rAddr, err
+		}
+		r.log.Debugf("Relayed TCP connection to %s failed: %v", rAddr, err)
+	}
+}
+
+func (r *tcpRelayConn) Close() error {
+	err := r.tcpPacketConn.Close()
+
+	// Unblock the pending accept
+	if deadlineErr := r.setDeadline(time.Now()); err == nil {
+		err = deadlineErr
+	}
+
+	return err
+}
+
+// gathersTCPRelay returns whether a relay candidate with TCP relayed transport
+// is gathered for a TURN URL. This requires TURN over TCP or TLS, which RFC 6062
+// uses to control the allocation, and the TCP4 network type.
+func (a *Agent) gathersTCPRelay(url *stun.URI) bool {
+	switch {
+	case url.Scheme != stun.SchemeTypeTURN && url.Scheme != stun.SchemeTypeTURNS:
+		return false
+	case url.Proto != stun.ProtoTypeTCP || a.proxyDialer != nil:
+		return false
+	}
+
+	for _, networkType := range a.networkTypes {
+		if networkType == NetworkTypeTCP4 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// gatherCandidateRelayTCP allocates a TCP relayed address and gathers a
+// passive TCP relay candidate for it. The remote agent connects to it with
+// its active TCP candidates, so ICE-TCP traverses TURN without any UDP.
+func (a *Agent) gatherCandidateRelayTCP(ctx context.Context, url stun.URI) { //nolint:cyclop
+	turnServerAddr := fmt.Sprintf("%s:%d", url.Host, url.Port)
+	tcpAddr, err := a.net.ResolveTCPAddr(NetworkTypeTCP4.String(), turnServerAddr)
+	if err != nil {
+		a.log.Warnf("Failed to resolve TCP address %s: %v", turnServerAddr, err)
+
+		return
+	}
+
+	conn, err := a.dialTURNServerTCP(ctx, url, tcpAddr)
+	if err != nil {
+		a.log.Warnf("Failed to dial TCP address %s: %v", turnServerAddr, err)
+
+		return
+	}
+
+	relayProtocol := tcp
+	if url.Scheme == stun.SchemeTypeTURNS {
+		relayProtocol = relayProtocolTLS
+	}
+	relAddr := conn.LocalAddr().(*net.TCPAddr).IP.String() //nolint:forcetypeassert
+	relPort := conn.LocalAddr().(*net.TCPAddr).Port        //nolint:forcetypeassert
+	locConn := turn.NewSTUNConn(conn)
+
+	client, err := turn.NewClient(&turn.ClientConfig{
+		TURNServerAddr: turnServerAddr,
+		Conn:           locConn,
+		Username:       url.Username,
+		Password:       url.Password,
+		AccessToken:    url.AccessToken,
+		MACKey:         url.MACKey,
+		LoggerFactory:  a.loggerFactory,
+		Net:            a.net,
+	})
+	if err != nil {
+		closeConnAndLog(locConn, a.log, "failed to create new TURN client %s %s", turnServerAddr, err)
+
+		return
+	}
+
+	if err = client.Listen(); err != nil {
+		client.Close()
+		closeConnAndLog(locConn, a.log, "failed to listen on TURN client %s %s", turnServerAddr, err)
+
+		return
+	}
+
+	allocation, err := client.AllocateTCP()
+	if err != nil {
+		client.Close()
+		closeConnAndLog(locConn, a.log, "failed to allocate TCP on TURN client %s %s", turnServerAddr, err)
+
+		return
+	}
+
+	closeAllocation := func() error {
+		if err := allocation.Close(); err != nil {
+			a.log.Warnf("Failed to close TCP allocation: %v", err)
+		}
+		client.Close()
+
+		return locConn.Close()
+	}
+
+	rAddr, ok := allocation.Addr().(*net.TCPAddr)
+	if !ok || shouldFilterLocationTracked(rAddr.IP) {
+		a.log.Warnf("TURN address %s is somehow filtered for location tracking reasons", allocation.Addr())
+		if err := closeAllocation(); err != nil {
+			a.log.Warnf("Failed to close TURN connection: %v", err)
+		}
+
+		return
+	}
+
+	candidate, err := NewCandidateRelay(&CandidateRelayConfig{
+		Network:       tcp,
+		Component:     a.component,
+		Address:       rAddr.IP.String(),
+		Port:          rAddr.Port,
+		RelAddr:       relAddr,
+		RelPort:       relPort,
+		RelayProtocol: relayProtocol,
+		TCPType:       TCPTypePassive,
+		OnClose:       closeAllocation,
+	})
+	if err != nil {
+		a.log.Warnf("Failed to create relay candidate: %s %s: %v", tcp, rAddr, err)
+		if err := closeAllocation(); err != nil {
+			a.log.Warnf("Failed to close TURN connection: %v", err)
+		}
+
+		return
+	}
+
+	// The connection of each peer is bound to a new connection to the TURN server.
+	relayConn := newTCPRelayConn(rAddr, func() (net.Conn, error) {
+		dataConn, err := a.dialTURNServerTCP(a.loop, url, tcpAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		peerConn, err := allocation.AcceptTCPWithConn(dataConn)
+		if err != nil {
+			closeConnAndLog(dataConn, a.log, "Failed to bind TURN data connection: %v", err)
+
+			return nil, err
+		}
+
+		return peerConn, nil
+	}, allocation.SetDeadline, client.CreatePermission, a.log)
+
+	if err := a.addCandidate(ctx, candidate, relayConn); err != nil {
+		closeConnAndLog(relayConn, a.log, "Failed to close relayed TCP connection: %v", err)
+		if closeErr := candidate.close(); closeErr != nil {
+			a.log.Warnf("Failed to close candidate: %v", closeErr)
+		}
+		a.log.Warnf("Failed to append to localCandidates and run onCandidateHdlr: %v", err)
+	}
+}
+
+// dialTURNServerTCP connects to the TURN server of a TCP relay candidate, with
+// TLS for turns URLs.
+func (a *Agent) dialTURNServerTCP(ctx context.Context, url stun.URI, addr *net.TCPAddr) (net.Conn, error) {
+	tcpConn, err := a.net.DialTCP(NetworkTypeTCP4.String(), nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	if url.Scheme != stun.SchemeTypeTURNS {
+		return tcpConn, nil
+	}
+
+	conn := tls.Client(tcpConn, &tls.Config{
+		ServerName:         url.Host,
+		InsecureSkipVerify: a.insecureSkipVerify, //nolint:gosec
+	})
+	if err := conn.HandshakeContext(ctx); err != nil {
+		closeConnAndLog(tcpConn, a.log, "Failed TLS handshake with TURN server %s: %v", addr, err)
+
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// permitRemoteCandidate requests the permission of a remote candidate on a
+// TCP relay candidate, so the remote peer can connect to it. Passive remote
+// candidates are permitted as well, their active candidates connect from the
+// same addresses and are only signaled once they are dialing.
+func (a *Agent) permitRemoteCandidate(local, remote Candidate) {
+	relay, ok := local.(*CandidateRelay)
+	if !ok {
+		return
+	}
+	if conn, ok := relay.conn.(*tcpRelayConn); ok {
+		conn.permit(remote.addr())
+	}
+}
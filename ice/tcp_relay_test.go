@@ -0,0 +1,351 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package ice
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v3/pkg/crypto/selfsign"
+	"github.com/pion/logging"
+	"github.com/pion/stun/v3"
+	"github.com/pion/transport/v3/test"
+	"github.com/pion/turn/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPRelayConn(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 10).Stop()
+
+	// The listener stands in for the TURN server, the connections it accepts
+	// are the data connections of the peers.
+	listener, err := net.ListenTCP(tcp, &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	relayedAddr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5000}
+	relayConn := newTCPRelayConn(relayedAddr, func() (net.Conn, error) {
+		return listener.Accept()
+	}, listener.SetDeadline, func(...net.Addr) error {
+		return nil
+	}, logging.NewDefaultLoggerFactory().NewLogger("ice"))
+	require.Equal(t, relayedAddr, relayConn.LocalAddr())
+
+	peerConn, err := net.DialTCP(tcp, nil, listener.Addr().(*net.TCPAddr)) //nolint:forcetypeassert
+	require.NoError(t, err)
+	defer func() {
+		_ = peerConn.Close()
+	}()
+
+	_, err = writeStreamingPacket(peerConn, []byte("foo"))
+	require.NoError(t, err)
+
+	buffer := make([]byte, 1024)
+	n, rAddr, err := relayConn.ReadFrom(buffer)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foo"), buffer[:n])
+	require.Equal(t, peerConn.LocalAddr().String(), rAddr.String())
+
+	_, err = relayConn.WriteTo([]byte("bar"), rAddr)
+	require.NoError(t, err)
+	n, err = readStreamingPacket(peerConn, buffer)
+	require.NoError(t, err)
+	require.Equal(t, []byte("bar"), buffer[:n])
+
+	require.NoError(t, relayConn.Close())
+	_, _, err = relayConn.ReadFrom(buffer)
+	require.Error(t, err)
+	require.False(t, os.IsTimeout(err))
+}
+
+func TestGathersTCPRelay(t *testing.T) {
+	tcpRelayURL := &stun.URI{Scheme: stun.SchemeTypeTURN, Proto: stun.ProtoTypeTCP}
+
+	for _, tc := range []struct {
+		name         string
+		url          *stun.URI
+		networkTypes []NetworkType
+		expected     bool
+	}{
+		{"TURN over TCP", tcpRelayURL, []NetworkType{NetworkTypeUDP4, NetworkTypeTCP4}, true},
+		{"No TCP network type", tcpRelayURL, []NetworkType{NetworkTypeUDP4}, false},
+		{
+			"TURN over UDP",
+			&stun.URI{Scheme: stun.SchemeTypeTURN, Proto: stun.ProtoTypeUDP},
+			[]NetworkType{NetworkTypeTCP4},
+			false,
+		},
+		{
+			"TURN over TLS",
+			&stun.URI{Scheme: stun.SchemeTypeTURNS, Proto: stun.ProtoTypeTCP},
+			[]NetworkType{NetworkTypeTCP4},
+			true,
+		},
+		{
+			"TURN over DTLS",
+			&stun.URI{Scheme: stun.SchemeTypeTURNS, Proto: stun.ProtoTypeUDP},
+			[]NetworkType{NetworkTypeTCP4},
+			false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			agent, err := NewAgent(&AgentConfig{NetworkTypes: tc.networkTypes})
+			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, agent.Close())
+			}()
+
+			require.Equal(t, tc.expected, agent.gathersTCPRelay(tc.url))
+		})
+	}
+}
+
+func TestGatherCandidateRelayTCP(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 30).Stop()
+
+	runTest := func(t *testing.T, scheme stun.SchemeType, listener net.Listener, relayProtocol string) {
+		t.Helper()
+
+		server, err := turn.NewServer(turn.ServerConfig{
+			Realm:       "pion.ly",
+			AuthHandler: optimisticAuthHandler,
+			ListenerConfigs: []turn.ListenerConfig{
+				{
+					Listener: listener,
+					RelayAddressGenerator: &turn.RelayAddressGeneratorStatic{
+						RelayAddress: net.ParseIP(localhostIPStr),
+						Address:      localhostIPStr,
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, server.Close())
+		}()
+
+		agent, err := NewAgent(&AgentConfig{
+			CandidateTypes:     []CandidateType{CandidateTypeRelay},
+			InsecureSkipVerify: true,
+			NetworkTypes:       []NetworkType{NetworkTypeTCP4},
+			Urls: []*stun.URI{{
+				Scheme:   scheme,
+				Host:     localhostIPStr,
+				Port:     listener.Addr().(*net.TCPAddr).Port, //nolint:forcetypeassert
+				Proto:    stun.ProtoTypeTCP,
+				Username: "username",
+				Password: "password",
+			}},
+		})
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, agent.Close())
+		}()
+
+		candidates := make(chan Candidate, 1)
+		require.NoError(t, agent.OnCandidate(func(c Candidate) {
+			if c != nil && c.NetworkType() == NetworkTypeTCP4 {
+				candidates <- c
+			}
+		}))
+		require.NoError(t, agent.GatherCandidates())
+
+		candidate, ok := (<-candidates).(*CandidateRelay)
+		require.True(t, ok)
+		require.Equal(t, TCPTypePassive, candidate.TCPType())
+		require.Equal(t, relayProtocol, candidate.RelayProtocol())
+	}
+
+	t.Run("TURN over TCP", func(t *testing.T) {
+		listener, err := net.Listen(tcp, localhostIPStr+":0")
+		require.NoError(t, err)
+
+		runTest(t, stun.SchemeTypeTURN, listener, tcp)
+	})
+
+	t.Run("TURN over TLS", func(t *testing.T) {
+		certificate, err := selfsign.GenerateSelfSigned()
+		require.NoError(t, err)
+
+		listener, err := tls.Listen(tcp, localhostIPStr+":0", &tls.Config{ //nolint:gosec
+			Certificates: []tls.Certificate{certificate},
+		})
+		require.NoError(t, err)
+
+		runTest(t, stun.SchemeTypeTURNS, listener, relayProtocolTLS)
+	})
+}
+
+func TestTCPRelayConnectivity(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 30).Stop()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	// The TURN server drops the connections of peers without a permission,
+	// the relay agent has to request it for the candidates of the peer.
+	permitted := make(chan struct{})
+	var permittedOnce sync.Once
+	serverListener, err := net.Listen(tcp, localhostIPStr+":0")
+	require.NoError(t, err)
+	server, err := turn.NewServer(turn.ServerConfig{
+		Realm:       "pion.ly",
+		AuthHandler: optimisticAuthHandler,
+		ListenerConfigs: []turn.ListenerConfig{
+			{
+				Listener: serverListener,
+				RelayAddressGenerator: &turn.RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP(localhostIPStr),
+					Address:      localhostIPStr,
+				},
+				PermissionHandler: func(_ net.Addr, peerIP net.IP) bool {
+					if peerIP.IsLoopback() {
+						permittedOnce.Do(func() { close(permitted) })
+					}
+
+					return true
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, server.Close())
+	}()
+
+	relayAgent, err := NewAgent(&AgentConfig{
+		CandidateTypes:   []CandidateType{CandidateTypeRelay},
+		NetworkTypes:     []NetworkType{NetworkTypeTCP4},
+		DisableActiveTCP: true,
+		LoggerFactory:    loggerFactory,
+		Urls: []*stun.URI{{
+			Scheme:   stun.SchemeTypeTURN,
+			Host:     localhostIPStr,
+			Port:     serverListener.Addr().(*net.TCPAddr).Port, //nolint:forcetypeassert
+			Proto:    stun.ProtoTypeTCP,
+			Username: "username",
+			Password: "password",
+		}},
+	})
+	require.NoError(t, err)
+
+	peerListener, err := net.ListenTCP(tcp, &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	tcpMux := NewTCPMuxDefault(TCPMuxParams{
+		Listener:       peerListener,
+		Logger:         loggerFactory.NewLogger("ice-tcp-mux"),
+		ReadBufferSize: 20,
+	})
+	defer func() {
+		_ = tcpMux.Close()
+	}()
+
+	peerAgent, err := NewAgent(&AgentConfig{
+		TCPMux:          tcpMux,
+		CandidateTypes:  []CandidateType{CandidateTypeHost},
+		NetworkTypes:    []NetworkType{NetworkTypeTCP4},
+		IncludeLoopback: true,
+		LoggerFactory:   loggerFactory,
+	})
+	require.NoError(t, err)
+
+	var gathered sync.WaitGroup
+	gathered.Add(2)
+	for _, agent := range []*Agent{relayAgent, peerAgent} {
+		require.NoError(t, agent.OnCandidate(func(c Candidate) {
+			if c == nil {
+				gathered.Done()
+			}
+		}))
+		require.NoError(t, agent.GatherCandidates())
+	}
+	gathered.Wait()
+
+	addRemoteCandidates := func(from, to *Agent) {
+		candidates, err := from.GetLocalCandidates()
+		require.NoError(t, err)
+		require.NotEmpty(t, candidates)
+		for _, c := range candidates {
+			candidateCopy, err := c.copy()
+			require.NoError(t, err)
+			require.NoError(t, to.AddRemoteCandidate(candidateCopy))
+		}
+	}
+
+	// The peer connects to the relayed address with its active candidates once
+	// it has the relay candidate, after the permission of its candidates.
+	addRemoteCandidates(peerAgent, relayAgent)
+	<-permitted
+	addRemoteCandidates(relayAgent, peerAgent)
+
+	accepted := make(chan *Conn)
+	go func() {
+		ufrag, pwd, err := peerAgent.GetLocalUserCredentials()
+		assert.NoError(t, err)
+		conn, err := relayAgent.Accept(context.Background(), ufrag, pwd)
+		assert.NoError(t, err)
+		accepted <- conn
+	}()
+	ufrag, pwd, err := relayAgent.GetLocalUserCredentials()
+	require.NoError(t, err)
+	peerConn, err := peerAgent.Dial(context.Background(), ufrag, pwd)
+	require.NoError(t, err)
+	relayConn := <-accepted
+	require.NotNil(t, relayConn)
+	defer func() {
+		require.NoError(t, peerConn.Close())
+		require.NoError(t, relayConn.Close())
+	}()
+
+	pair, err := relayAgent.GetSelectedCandidatePair()
+	require.NoError(t, err)
+	require.Equal(t, CandidateTypeRelay, pair.Local.Type())
+	require.Equal(t, NetworkTypeTCP4, pair.Local.NetworkType())
+
+	_, err = peerConn.Write([]byte("foo"))
+	require.NoError(t, err)
+	buffer := make([]byte, 1024)
+	n, err := relayConn.Read(buffer)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foo"), buffer[:n])
+}
+
+func TestCandidateRelayTCP(t *testing.T) {
+	candidate, err := NewCandidateRelay(&CandidateRelayConfig{
+		Network:       tcp,
+		Address:       "192.0.2.1",
+		Port:          5000,
+		RelAddr:       "10.0.0.1",
+		RelPort:       6000,
+		RelayProtocol: tcp,
+		TCPType:       TCPTypePassive,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, NetworkTypeTCP4, candidate.NetworkType())
+	require.Equal(t, TCPTypePassive, candidate.TCPType())
+	require.Equal(t, &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1).To4(), Port: 5000}, candidate.addr())
+
+	unmarshaled, err := UnmarshalCandidate(candidate.Marshal())
+	require.NoError(t, err)
+	require.Equal(t, CandidateTypeRelay, unmarshaled.Type())
+	require.Equal(t, TCPTypePassive, unmarshaled.TCPType())
+	require.True(t, candidate.Equal(unmarshaled))
+}
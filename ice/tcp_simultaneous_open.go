@@ -0,0 +1,429 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package ice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	stunx "github.com/pion/ice/v4/internal/stun"
+	"github.com/pion/logging"
+	"github.com/pion/stun/v3"
+	"github.com/pion/transport/v3"
+	"github.com/pion/transport/v3/stdnet"
+	"github.com/pion/turn/v4"
+)
+
+// tcpSimultaneousOpenDialTimeout bounds the connection attempts towards
+// remote simultaneous-open candidates.
+const tcpSimultaneousOpenDialTimeout = 5 * time.Second
+
+// tcpSimultaneousOpenRetryInterval is the pause between the connection
+// attempts towards a remote simultaneous-open candidate, the first ones may be
+// refused until the remote agent dials too.
+const tcpSimultaneousOpenRetryInterval = 200 * time.Millisecond
+
+// tcpSimultaneousOpenConn is the connection of a simultaneous-open TCP
+// candidate, see https://tools.ietf.org/html/rfc6544#section-4.1. The
+// candidate listens on its port and dials the remote simultaneous-open
+// candidates from the same port, so that the SYNs of both peers cross and
+// open their NAT bindings. Packets are exchanged over whichever connections
+// come up.
+type tcpSimultaneousOpenConn struct {
+	*tcpPacketConn
+
+	net      transport.Net
+	listener net.Listener
+	log      logging.LeveledLogger
+}
+
+func newTCPSimultaneousOpenConn(
+	n transport.Net,
+	localAddr *net.TCPAddr,
+	log logging.LeveledLogger,
+) (*tcpSimultaneousOpenConn, error) {
+	listener, err := listenTCPReusePort(n, localAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &tcpSimultaneousOpenConn{
+		tcpPacketConn: newTCPPacketConn(tcpPacketParams{
+			ReadBuffer: 64,
+			LocalAddr:  listener.Addr(),
+			Logger:     log,
+		}),
+		net:      n,
+		listener: listener,
+		log:      log,
+	}
+	go conn.acceptLoop()
+
+	return conn, nil
+}
+
+// listenTCPReusePort listens on localAddr, the port is shared with the
+// connections dialed from it, see reusePortControl.
+func listenTCPReusePort(n transport.Net, localAddr *net.TCPAddr) (net.Listener, error) {
+	if _, ok := n.(*stdnet.Net); !ok {
+		return n.ListenTCP("tcp", localAddr)
+	}
+
+	listenConfig := net.ListenConfig{Control: reusePortControl}
+
+	return listenConfig.Listen(context.Background(), "tcp", localAddr.String())
+}
+
+func (s *tcpSimultaneousOpenConn) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.log.Warnf("Failed to accept simultaneous-open TCP connection: %v", err)
+			}
+
+			return
+		}
+
+		if err := s.AddConn(conn, nil); err != nil {
+			closeConnAndLog(conn, s.log, "Failed to add simultaneous-open TCP connection: %v", err)
+		}
+	}
+}
+
+// dialer returns a dialer that connects from the address of the candidate.
+func (s *tcpSimultaneousOpenConn) dialer(timeout time.Duration) transport.Dialer {
+	return s.net.CreateDialer(&net.Dialer{
+		LocalAddr: s.LocalAddr(),
+		Timeout:   timeout,
+		Control:   reusePortControl,
+	})
+}
+
+// dialContext connects from the address of the candidate to remoteAddr, the
+// dial is aborted once ctx is done.
+func (s *tcpSimultaneousOpenConn) dialContext(
+	ctx context.Context,
+	timeout time.Duration,
+	remoteAddr string,
+) (net.Conn, error) {
+	dialer := &net.Dialer{
+		LocalAddr: s.LocalAddr(),
+		Timeout:   timeout,
+		Control:   reusePortControl,
+	}
+	if _, ok := s.net.(*stdnet.Net); ok {
+		return dialer.DialContext(ctx, "tcp", remoteAddr)
+	}
+
+	return s.net.CreateDialer(dialer).Dial("tcp", remoteAddr)
+}
+
+// dial connects to a remote simultaneous-open candidate. The attempts are
+// repeated until a connection to the remote address is up, either dialed or
+// accepted, they are aborted when the candidate is closed.
+func (s *tcpSimultaneousOpenConn) dial(remoteAddr netip.AddrPort) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-s.closedChan:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	go func() {
+		defer cancel()
+
+		deadline := time.Now().Add(tcpSimultaneousOpenDialTimeout)
+		for {
+			conn, err := s.dialContext(ctx, time.Until(deadline), remoteAddr.String())
+			if err == nil {
+				if err = s.AddConn(conn, nil); err != nil {
+					closeConnAndLog(conn, s.log, "Failed to add simultaneous-open TCP connection: %v", err)
+				}
+
+				return
+			}
+			if s.hasConn(remoteAddr.String()) || time.Until(deadline) < tcpSimultaneousOpenRetryInterval {
+				s.log.Infof("Failed to dial simultaneous-open TCP address %s: %v", remoteAddr, err)
+
+				return
+			}
+
+			select {
+			case <-time.After(tcpSimultaneousOpenRetryInterval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (s *tcpSimultaneousOpenConn) hasConn(remoteAddr string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.conns[remoteAddr]
+
+	return ok
+}
+
+// ReadFrom skips the errors of single connections, the candidate is only
+// done once it is closed.
+func (s *tcpSimultaneousOpenConn) ReadFrom(b []byte) (n int, rAddr net.Addr, err error) {
+	for {
+		n, rAddr, err = s.tcpPacketConn.ReadFrom(b)
+		if err == nil || s.isClosed() {
+			return n, rAddr, err
+		}
+		s.log.Debugf("Simultaneous-open TCP connection to %s failed: %v", rAddr, err)
+	}
+}
+
+func (s *tcpSimultaneousOpenConn) Close() error {
+	err := s.listener.Close()
+	if closeErr := s.tcpPacketConn.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// gatherCandidateSimultaneousOpen gathers a simultaneous-open TCP host
+// candidate for a local address.
+func (a *Agent) gatherCandidateSimultaneousOpen(
+	ctx context.Context,
+	addr netip.Addr,
+	address string,
+	isLocationTracked bool,
+) {
+	conn, err := newTCPSimultaneousOpenConn(a.net, &net.TCPAddr{IP: addr.AsSlice(), Zone: addr.Zone()}, a.log)
+	if err != nil {
+		a.log.Warnf("Failed to listen tcp %s: %v", addr, err)
+
+		return
+	}
+
+	tcpAddr, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		closeConnAndLog(conn, a.log, "Failed to create simultaneous-open TCP candidate: %v", errInvalidAddress)
+
+		return
+	}
+
+	candidateHost, err := NewCandidateHost(&CandidateHostConfig{
+		Network:           tcp,
+		Address:           address,
+		Port:              tcpAddr.Port,
+		Component:         a.component,
+		TCPType:           TCPTypeSimultaneousOpen,
+		IsLocationTracked: isLocationTracked,
+	})
+	if err != nil {
+		closeConnAndLog(conn, a.log, "Failed to create simultaneous-open TCP candidate: %v", err)
+
+		return
+	}
+
+	if a.mDNSMode == MulticastDNSModeQueryAndGather {
+		if err = candidateHost.setIPAddr(addr); err != nil {
+			closeConnAndLog(conn, a.log, "Failed to create simultaneous-open TCP candidate: %v", err)
+
+			return
+		}
+	}
+
+	if err := a.addCandidate(ctx, candidateHost, conn); err != nil {
+		if closeErr := candidateHost.close(); closeErr != nil {
+			a.log.Warnf("Failed to close candidate: %v", closeErr)
+		}
+		a.log.Warnf("Failed to append to localCandidates and run onCandidateHdlr: %v", err)
+
+		return
+	}
+
+	if containsCandidateType(CandidateTypeServerReflexive, a.candidateTypes) {
+		a.gatherCandidatesSimultaneousOpenSrflx(ctx, conn)
+	}
+}
+
+// gatherCandidatesSimultaneousOpenSrflx gathers the server reflexive
+// candidates of a simultaneous-open host candidate. The STUN servers are
+// asked over TCP from the port of the host candidate, which stays the base of
+// the server reflexive ones. See https://tools.ietf.org/html/rfc6544#section-5.2
+func (a *Agent) gatherCandidatesSimultaneousOpenSrflx(ctx context.Context, conn *tcpSimultaneousOpenConn) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for i := range a.urls {
+		if a.urls[i].Scheme != stun.SchemeTypeSTUN {
+			continue
+		}
+
+		wg.Add(1)
+		go func(url stun.URI) {
+			defer wg.Done()
+
+			a.gatherCandidateSimultaneousOpenSrflx(ctx, conn, url)
+		}(*a.urls[i])
+	}
+}
+
+func (a *Agent) gatherCandidateSimultaneousOpenSrflx(ctx context.Context, conn *tcpSimultaneousOpenConn, url stun.URI) {
+	localAddr, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return
+	}
+	network := NetworkTypeTCP4.String()
+	if localAddr.IP.To4() == nil {
+		network = NetworkTypeTCP6.String()
+	}
+
+	hostPort := fmt.Sprintf("%s:%d", url.Host, url.Port)
+	serverAddr, err := a.net.ResolveTCPAddr(network, hostPort)
+	if err != nil {
+		a.log.Debugf("Failed to resolve STUN host: %s %s: %v", network, hostPort, err)
+
+		return
+	}
+
+	stunConn, err := conn.dialer(a.stunGatherTimeout).Dial(network, serverAddr.String())
+	if err != nil {
+		a.log.Debugf("Failed to dial STUN host: %s %s: %v", network, hostPort, err)
+
+		return
+	}
+	defer func() {
+		_ = stunConn.Close()
+	}()
+
+	// If the agent closes midway through the request we end it early.
+	cancelCtx, cancelFunc := context.WithCancel(ctx)
+	defer cancelFunc()
+	go func() {
+		select {
+		case <-cancelCtx.Done():
+		case <-a.loop.Done():
+			_ = stunConn.Close()
+		}
+	}()
+
+	xorAddr, err := stunx.GetXORMappedAddr(turn.NewSTUNConn(stunConn), serverAddr, a.stunGatherTimeout)
+	if err != nil {
+		a.log.Warnf("Failed to get server reflexive address %s %s: %v", network, url, err)
+
+		return
+	}
+
+	// A mapped address equal to the base is redundant.
+	if xorAddr.IP.Equal(localAddr.IP) && xorAddr.Port == localAddr.Port {
+		return
+	}
+
+	candidate, err := NewCandidateServerReflexive(&CandidateServerReflexiveConfig{
+		Network:   tcp,
+		Address:   xorAddr.IP.String(),
+		Port:      xorAddr.Port,
+		Component: a.component,
+		RelAddr:   localAddr.IP.String(),
+		RelPort:   localAddr.Port,
+		TCPType:   TCPTypeSimultaneousOpen,
+	})
+	if err != nil {
+		a.log.Warnf("Failed to create server reflexive candidate: %s %s %d: %v", network, xorAddr.IP, xorAddr.Port, err)
+
+		return
+	}
+
+	if err := a.addCandidate(ctx, candidate, newTCPSimultaneousOpenSrflxConn(conn)); err != nil {
+		if closeErr := candidate.close(); closeErr != nil {
+			a.log.Warnf("Failed to close candidate: %v", closeErr)
+		}
+		a.log.Warnf("Failed to append to localCandidates and run onCandidateHdlr: %v", err)
+	}
+}
+
+// tcpSimultaneousOpenSrflxConn is the connection of a server reflexive
+// simultaneous-open candidate. The socket belongs to the host candidate that
+// is its base, which reads the packets of both and closes the socket.
+type tcpSimultaneousOpenSrflxConn struct {
+	*tcpSimultaneousOpenConn
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newTCPSimultaneousOpenSrflxConn(base *tcpSimultaneousOpenConn) *tcpSimultaneousOpenSrflxConn {
+	return &tcpSimultaneousOpenSrflxConn{
+		tcpSimultaneousOpenConn: base,
+		closed:                  make(chan struct{}),
+	}
+}
+
+// ReadFrom blocks until the connection is closed, the packets are read by the base.
+func (s *tcpSimultaneousOpenSrflxConn) ReadFrom([]byte) (int, net.Addr, error) {
+	<-s.closed
+
+	return 0, nil, net.ErrClosed
+}
+
+// Close leaves the socket to the base.
+func (s *tcpSimultaneousOpenSrflxConn) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+
+	return nil
+}
+
+// canPairTCPTypes returns whether a local and a remote candidate can form a
+// pair, simultaneous-open candidates only pair with each other. See
+// https://tools.ietf.org/html/rfc6544#section-6.2
+func canPairTCPTypes(local, remote Candidate) bool {
+	if local.TCPType() == TCPTypeSimultaneousOpen || remote.TCPType() == TCPTypeSimultaneousOpen {
+		return local.TCPType() == remote.TCPType()
+	}
+
+	return true
+}
+
+// pairCandidates adds a pair to the checklist, a pair of simultaneous-open
+// candidates starts connecting to the remote candidate right away.
+func (a *Agent) pairCandidates(local, remote Candidate) {
+	if !canPairTCPTypes(local, remote) {
+		return
+	}
+	// The pairs of a server reflexive simultaneous-open candidate are the
+	// ones of its base, see https://tools.ietf.org/html/rfc8445#section-6.1.2.4
+	if local.TCPType() == TCPTypeSimultaneousOpen && local.Type() == CandidateTypeServerReflexive {
+		return
+	}
+	a.addPair(local, remote)
+
+	if local.TCPType() != TCPTypeSimultaneousOpen {
+		return
+	}
+	host, ok := local.(*CandidateHost)
+	if !ok {
+		return
+	}
+	conn, ok := host.conn.(*tcpSimultaneousOpenConn)
+	if !ok {
+		return
+	}
+
+	ip, port, _, err := parseAddr(remote.addr())
+	if err != nil {
+		a.log.Warnf("Failed to parse address: %s; error: %s", remote.addr(), err)
+
+		return
+	}
+	conn.dial(netip.AddrPortFrom(ip, uint16(port))) //nolint:gosec // G115, no overflow, a port
+}
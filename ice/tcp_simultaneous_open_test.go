@@ -0,0 +1,365 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package ice
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/logging"
+	"github.com/pion/stun/v3"
+	"github.com/pion/transport/v3"
+	"github.com/pion/transport/v3/stdnet"
+	"github.com/pion/transport/v3/test"
+	"github.com/pion/turn/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPSimultaneousOpen(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 10).Stop()
+
+	hostAcceptanceMinWait := 100 * time.Millisecond
+	newAgent := func() *Agent {
+		agent, err := NewAgent(&AgentConfig{
+			CandidateTypes:        []CandidateType{CandidateTypeHost},
+			NetworkTypes:          []NetworkType{NetworkTypeTCP4},
+			HostAcceptanceMinWait: &hostAcceptanceMinWait,
+			InterfaceFilter:       problematicNetworkInterfaces,
+			TCPSimultaneousOpen:   true,
+		})
+		require.NoError(t, err)
+
+		return agent
+	}
+	aAgent, bAgent := newAgent(), newAgent()
+
+	aConn, bConn := connect(aAgent, bAgent)
+	defer func() {
+		require.NoError(t, aConn.Close())
+		require.NoError(t, bConn.Close())
+	}()
+
+	for _, agent := range []*Agent{aAgent, bAgent} {
+		pair := agent.getSelectedPair()
+		require.NotNil(t, pair)
+		require.Equal(t, TCPTypeSimultaneousOpen, pair.Local.TCPType())
+	}
+
+	foo := []byte("foo")
+	_, err := aConn.Write(foo)
+	require.NoError(t, err)
+
+	buffer := make([]byte, 1024)
+	n, err := bConn.Read(buffer)
+	require.NoError(t, err)
+	require.Equal(t, foo, buffer[:n])
+
+	bar := []byte("bar")
+	_, err = bConn.Write(bar)
+	require.NoError(t, err)
+
+	n, err = aConn.Read(buffer)
+	require.NoError(t, err)
+	require.Equal(t, bar, buffer[:n])
+}
+
+// natNet is a transport.Net whose TCP listeners are unreachable, like behind
+// a NAT that drops unsolicited SYNs. A connection only comes up when both ends
+// dial each other from the addresses they are dialed at, as in a TCP
+// simultaneous open.
+type natNet struct {
+	transport.Net
+
+	mu      sync.Mutex
+	pending map[string]chan net.Conn
+}
+
+func newNATNet(t *testing.T) *natNet {
+	t.Helper()
+
+	n, err := stdnet.NewNet()
+	require.NoError(t, err)
+
+	return &natNet{Net: n, pending: map[string]chan net.Conn{}}
+}
+
+func (n *natNet) ListenTCP(network string, laddr *net.TCPAddr) (transport.TCPListener, error) {
+	listener, err := n.Net.ListenTCP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &unreachableListener{TCPListener: listener, closed: make(chan struct{})}, nil
+}
+
+func (n *natNet) CreateDialer(dialer *net.Dialer) transport.Dialer {
+	return &natDialer{net: n, dialer: dialer}
+}
+
+// dial connects local to remote once remote dials local.
+func (n *natNet) dial(local, remote net.Addr, timeout time.Duration) (net.Conn, error) {
+	n.mu.Lock()
+	if peer, ok := n.pending[remote.String()+" "+local.String()]; ok {
+		delete(n.pending, remote.String()+" "+local.String())
+		localConn, remoteConn := net.Pipe()
+		peer <- &natConn{Conn: remoteConn, local: remote, remote: local}
+		n.mu.Unlock()
+
+		return &natConn{Conn: localConn, local: local, remote: remote}, nil
+	}
+	key := local.String() + " " + remote.String()
+	connCh := make(chan net.Conn, 1)
+	n.pending[key] = connCh
+	n.mu.Unlock()
+
+	select {
+	case conn := <-connCh:
+		return conn, nil
+	case <-time.After(timeout):
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.pending[key] == connCh {
+		delete(n.pending, key)
+	}
+	select {
+	case conn := <-connCh:
+		return conn, nil
+	default:
+		return nil, os.ErrDeadlineExceeded
+	}
+}
+
+type natDialer struct {
+	net    *natNet
+	dialer *net.Dialer
+}
+
+func (d *natDialer) Dial(network, address string) (net.Conn, error) {
+	remote, err := net.ResolveTCPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.net.dial(d.dialer.LocalAddr, remote, d.dialer.Timeout)
+}
+
+type natConn struct {
+	net.Conn
+
+	local, remote net.Addr
+}
+
+func (c *natConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *natConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+type unreachableListener struct {
+	transport.TCPListener
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *unreachableListener) Accept() (net.Conn, error) {
+	<-l.closed
+
+	return nil, net.ErrClosed
+}
+
+func (l *unreachableListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+
+	return l.TCPListener.Close()
+}
+
+func TestTCPSimultaneousOpenBehindNAT(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 20).Stop()
+
+	natNet := newNATNet(t)
+	hostAcceptanceMinWait := 100 * time.Millisecond
+	newAgent := func() *Agent {
+		agent, err := NewAgent(&AgentConfig{
+			CandidateTypes:        []CandidateType{CandidateTypeHost},
+			NetworkTypes:          []NetworkType{NetworkTypeTCP4},
+			HostAcceptanceMinWait: &hostAcceptanceMinWait,
+			InterfaceFilter:       problematicNetworkInterfaces,
+			TCPSimultaneousOpen:   true,
+			Net:                   natNet,
+		})
+		require.NoError(t, err)
+
+		return agent
+	}
+	aAgent, bAgent := newAgent(), newAgent()
+
+	// Neither listener accepts, the agents connect by dialing each other.
+	aConn, bConn := connect(aAgent, bAgent)
+
+	foo := []byte("foo")
+	_, err := aConn.Write(foo)
+	require.NoError(t, err)
+
+	buffer := make([]byte, 1024)
+	n, err := bConn.Read(buffer)
+	require.NoError(t, err)
+	require.Equal(t, foo, buffer[:n])
+
+	require.NoError(t, aConn.Close())
+	require.NoError(t, bConn.Close())
+}
+
+func TestTCPSimultaneousOpenSrflx(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 10).Stop()
+
+	// The STUN server maps the source address of the requests to a public IP.
+	publicIP := net.IPv4(203, 0, 113, 1)
+	listener, err := net.ListenTCP("tcp4", &net.TCPAddr{})
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer func() {
+		require.NoError(t, listener.Close())
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+
+			stunConn := turn.NewSTUNConn(conn)
+			buf := make([]byte, 1500)
+			n, _, readErr := stunConn.ReadFrom(buf)
+			if readErr == nil {
+				req := &stun.Message{Raw: buf[:n]}
+				require.NoError(t, req.Decode())
+				srcAddr := conn.RemoteAddr().(*net.TCPAddr) //nolint:forcetypeassert
+				res, buildErr := stun.Build(req, stun.BindingSuccess,
+					&stun.XORMappedAddress{IP: publicIP, Port: srcAddr.Port})
+				require.NoError(t, buildErr)
+				_, writeErr := conn.Write(res.Raw)
+				require.NoError(t, writeErr)
+			}
+			require.NoError(t, conn.Close())
+		}
+	}()
+
+	serverPort := listener.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert
+	agent, err := NewAgent(&AgentConfig{
+		CandidateTypes:      []CandidateType{CandidateTypeHost, CandidateTypeServerReflexive},
+		NetworkTypes:        []NetworkType{NetworkTypeTCP4},
+		InterfaceFilter:     problematicNetworkInterfaces,
+		TCPSimultaneousOpen: true,
+		Urls:                []*stun.URI{{Scheme: stun.SchemeTypeSTUN, Host: "127.0.0.1", Port: serverPort}},
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, agent.Close())
+	}()
+
+	gathered := make(chan struct{})
+	require.NoError(t, agent.OnCandidate(func(c Candidate) {
+		if c == nil {
+			close(gathered)
+		}
+	}))
+	require.NoError(t, agent.GatherCandidates())
+	<-gathered
+
+	candidates, err := agent.GetLocalCandidates()
+	require.NoError(t, err)
+
+	srflxCount := 0
+	for _, c := range candidates {
+		if c.Type() != CandidateTypeServerReflexive {
+			continue
+		}
+		srflxCount++
+
+		// The STUN server is asked from the port of the host candidate.
+		require.Equal(t, TCPTypeSimultaneousOpen, c.TCPType())
+		require.Equal(t, publicIP.String(), c.Address())
+		require.Equal(t, c.RelatedAddress().Port, c.Port())
+		require.True(t, strings.Contains(c.Marshal(), "tcptype so"))
+	}
+	require.NotZero(t, srflxCount)
+}
+
+func TestTCPSimultaneousOpenDialClose(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 5).Stop()
+
+	stdNet, err := stdnet.NewNet()
+	require.NoError(t, err)
+
+	conn, err := newTCPSimultaneousOpenConn(stdNet, &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		logging.NewDefaultLoggerFactory().NewLogger("ice"))
+	require.NoError(t, err)
+
+	// The dials are aborted by the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = conn.dialContext(ctx, time.Second, "127.0.0.1:9")
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The dial attempts to a remote candidate that does not answer stop
+	// once the candidate is closed, CheckRoutines fails otherwise.
+	conn.dial(netip.MustParseAddrPort("127.0.0.1:9"))
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, conn.Close())
+}
+
+func TestCanPairTCPTypes(t *testing.T) {
+	newCandidate := func(tcpType TCPType) Candidate {
+		candidate, err := NewCandidateHost(&CandidateHostConfig{
+			Network: tcp,
+			Address: "192.0.2.1",
+			Port:    9,
+			TCPType: tcpType,
+		})
+		require.NoError(t, err)
+
+		return candidate
+	}
+
+	for _, tc := range []struct {
+		local, remote TCPType
+		canPair       bool
+	}{
+		{TCPTypeSimultaneousOpen, TCPTypeSimultaneousOpen, true},
+		{TCPTypeSimultaneousOpen, TCPTypePassive, false},
+		{TCPTypeActive, TCPTypeSimultaneousOpen, false},
+		{TCPTypeActive, TCPTypePassive, true},
+	} {
+		require.Equal(t, tc.canPair, canPairTCPTypes(newCandidate(tc.local), newCandidate(tc.remote)),
+			"%s %s", tc.local, tc.remote)
+	}
+}
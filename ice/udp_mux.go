@@ -16,6 +16,9 @@ import (
 	"github.com/pion/stun/v3"
 	"github.com/pion/transport/v3"
 	"github.com/pion/transport/v3/stdnet"
+	udpbatch "github.com/pion/transport/v3/udp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 // UDPMux allows multiple connections to go over a single UDP port.
@@ -36,8 +39,9 @@ type UDPMuxDefault struct {
 	// connsIPv4 and connsIPv6 are maps of all udpMuxedConn indexed by ufrag|network|candidateType
 	connsIPv4, connsIPv6 map[string]*udpMuxedConn
 
-	addressMapMu sync.RWMutex
-	addressMap   map[ipPort]*udpMuxedConn
+	// addressMap maps remote ipPort to *udpMuxedConn. It is read for every
+	// packet and written once per remote address, reads don't take a lock.
+	addressMap sync.Map
 
 	// Buffer pool to recycle buffers for net.UDPAddr encodes/decodes
 	pool *sync.Pool
@@ -58,6 +62,27 @@ type UDPMuxParams struct {
 	// in case a un UDPConn is passed which does not
 	// bind to a specific local address.
 	Net transport.Net
+
+	// ReadBatchSize is the maximum number of packets read from UDPConn at
+	// once. Above 1, packets are read in batches (recvmmsg on Linux) if
+	// UDPConn is a *net.UDPConn or implements the BatchReader of
+	// github.com/pion/transport/v3/udp.
+	ReadBatchSize int
+
+	// Workers is the number of goroutines routing the packets that have been
+	// read to the muxed connections. Packets are sharded across the workers by
+	// remote address, so the packets of a remote address stay in order. By
+	// default packets are routed by the goroutine reading them.
+	Workers int
+}
+
+// udpMuxWorkerQueueSize is the number of packets queued for each worker.
+const udpMuxWorkerQueueSize = 1024
+
+// udpMuxPacket is a packet that has been read and waits to be routed.
+type udpMuxPacket struct {
+	pkt  *bufferHolder
+	addr ipPort
 }
 
 // NewUDPMuxDefault creates an implementation of UDPMux.
@@ -110,7 +135,6 @@ func NewUDPMuxDefault(params UDPMuxParams) *UDPMuxDefault { //nolint:cyclop
 	params.UDPConnString = params.UDPConn.LocalAddr().String()
 
 	mux := &UDPMuxDefault{
-		addressMap: map[ipPort]*udpMuxedConn{},
 		params:     params,
 		connsIPv4:  make(map[string]*udpMuxedConn),
 		connsIPv6:  make(map[string]*udpMuxedConn),
@@ -202,13 +226,10 @@ func (m *UDPMuxDefault) RemoveConnByUfrag(ufrag string) {
 		return
 	}
 
-	m.addressMapMu.Lock()
-	defer m.addressMapMu.Unlock()
-
 	for _, c := range removedConns {
 		addresses := c.getAddresses()
 		for _, addr := range addresses {
-			delete(m.addressMap, addr)
+			m.addressMap.CompareAndDelete(addr, c)
 		}
 	}
 }
@@ -257,14 +278,9 @@ func (m *UDPMuxDefault) registerConnForAddress(conn *udpMuxedConn, addr ipPort)
 		return
 	}
 
-	m.addressMapMu.Lock()
-	defer m.addressMapMu.Unlock()
-
-	existing, ok := m.addressMap[addr]
-	if ok {
-		existing.removeAddress(addr)
+	if existing, ok := m.addressMap.Swap(addr, conn); ok && existing != conn {
+		existing.(*udpMuxedConn).removeAddress(addr) //nolint:forcetypeassert
 	}
-	m.addressMap[addr] = conn
 
 	m.params.Logger.Debugf("Registered %s for %s", addr.addr.String(), conn.params.Key)
 }
@@ -281,85 +297,230 @@ func (m *UDPMuxDefault) createMuxedConn(key string) *udpMuxedConn {
 	return c
 }
 
-func (m *UDPMuxDefault) connWorker() { //nolint:cyclop
-	logger := m.params.Logger
-
+func (m *UDPMuxDefault) connWorker() {
 	defer func() {
 		_ = m.Close()
 	}()
 
-	buf := make([]byte, receiveMTU)
-	for {
-		n, addr, err := m.params.UDPConn.ReadFrom(buf)
-		if m.IsClosed() {
-			return
-		} else if err != nil {
-			if os.IsTimeout(err) {
-				continue
-			} else if !errors.Is(err, io.EOF) {
-				logger.Errorf("Failed to read UDP packet: %v", err)
-			}
-
-			return
+	workers := m.startWorkers()
+	defer func() {
+		for _, worker := range workers {
+			close(worker)
 		}
+	}()
 
-		netUDPAddr, ok := addr.(*net.UDPAddr)
-		if !ok {
-			logger.Errorf("Underlying PacketConn did not return a UDPAddr")
+	dispatch := func(pkt *bufferHolder, addr ipPort) {
+		if len(workers) == 0 {
+			m.routePacket(pkt, addr)
 
 			return
 		}
-		udpAddr, err := newIPPort(netUDPAddr.IP, netUDPAddr.Zone, uint16(netUDPAddr.Port)) //nolint:gosec
-		if err != nil {
-			logger.Errorf("Failed to create a new IP/Port host pair")
+		workers[addr.hash()%uint32(len(workers))] <- udpMuxPacket{pkt, addr} //nolint:gosec // G115
+	}
 
-			return
-		}
+	if batchReader := m.batchReader(); batchReader != nil {
+		m.readBatches(batchReader, dispatch)
+	} else {
+		m.read(dispatch)
+	}
+}
 
-		// If we have already seen this address dispatch to the appropriate destination
-		m.addressMapMu.Lock()
-		destinationConn := m.addressMap[udpAddr]
-		m.addressMapMu.Unlock()
+// startWorkers starts the goroutines routing packets, there are none with a
+// single worker.
+func (m *UDPMuxDefault) startWorkers() []chan udpMuxPacket {
+	if m.params.Workers < 2 {
+		return nil
+	}
 
-		// If we haven't seen this address before but is a STUN packet lookup by ufrag
-		if destinationConn == nil && stun.IsMessage(buf[:n]) {
-			msg := &stun.Message{
-				Raw: append([]byte{}, buf[:n]...),
+	workers := make([]chan udpMuxPacket, m.params.Workers)
+	for i := range workers {
+		workers[i] = make(chan udpMuxPacket, udpMuxWorkerQueueSize)
+		go func(packets <-chan udpMuxPacket) {
+			for p := range packets {
+				m.routePacket(p.pkt, p.addr)
 			}
+		}(workers[i])
+	}
 
-			if err = msg.Decode(); err != nil {
-				m.params.Logger.Warnf("Failed to handle decode ICE from %s: %v", addr.String(), err)
+	return workers
+}
 
-				continue
-			}
+// batchReader returns a reader of packet batches, or nil if UDPConn is read
+// one packet at a time.
+func (m *UDPMuxDefault) batchReader() udpbatch.BatchReader {
+	if m.params.ReadBatchSize < 2 {
+		return nil
+	}
+
+	return newBatchReader(m.params.UDPConn)
+}
+
+// newBatchReader returns a reader of packet batches for conn, or nil if conn
+// can only be read one packet at a time.
+func newBatchReader(conn net.PacketConn) udpbatch.BatchReader {
+	switch conn := conn.(type) {
+	case udpbatch.BatchReader:
+		return conn
+	case *net.UDPConn:
+		if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() == nil {
+			return ipv6.NewPacketConn(conn)
+		}
+
+		return ipv4.NewPacketConn(conn)
+	default:
+		return nil
+	}
+}
+
+// readError returns true if reading has to stop.
+func (m *UDPMuxDefault) readError(err error) bool {
+	if m.IsClosed() {
+		return true
+	} else if err == nil || os.IsTimeout(err) {
+		return false
+	} else if !errors.Is(err, io.EOF) {
+		m.params.Logger.Errorf("Failed to read UDP packet: %v", err)
+	}
 
-			attr, stunAttrErr := msg.Get(stun.AttrUsername)
-			if stunAttrErr != nil {
-				m.params.Logger.Warnf("No Username attribute in STUN message from %s", addr.String())
+	return true
+}
 
-				continue
+func (m *UDPMuxDefault) read(dispatch func(*bufferHolder, ipPort)) {
+	for {
+		pkt := m.pool.Get().(*bufferHolder) //nolint:forcetypeassert
+		n, addr, err := m.params.UDPConn.ReadFrom(pkt.buf[:cap(pkt.buf)])
+		if err != nil || m.IsClosed() {
+			m.pool.Put(pkt)
+			if m.readError(err) {
+				return
 			}
 
-			ufrag := strings.Split(string(attr), ":")[0]
-			isIPv6 := netUDPAddr.IP.To4() == nil
+			continue
+		}
 
-			m.mu.Lock()
-			destinationConn, _ = m.getConn(ufrag, isIPv6)
-			m.mu.Unlock()
+		pkt.buf = pkt.buf[:n]
+		if !m.dispatchPacket(pkt, addr, dispatch) {
+			return
 		}
+	}
+}
 
-		if destinationConn == nil {
-			m.params.Logger.Tracef("Dropping packet from %s, addr: %s", udpAddr.addr, addr)
+func (m *UDPMuxDefault) readBatches(batchReader udpbatch.BatchReader, dispatch func(*bufferHolder, ipPort)) {
+	pkts := make([]*bufferHolder, m.params.ReadBatchSize)
+	msgs := make([]ipv4.Message, m.params.ReadBatchSize)
+	for i := range msgs {
+		pkts[i] = m.pool.Get().(*bufferHolder) //nolint:forcetypeassert
+		msgs[i].Buffers = [][]byte{pkts[i].buf[:cap(pkts[i].buf)]}
+	}
+	defer func() {
+		for _, pkt := range pkts {
+			m.pool.Put(pkt)
+		}
+	}()
+
+	for {
+		n, err := batchReader.ReadBatch(msgs, 0)
+		if err != nil || m.IsClosed() {
+			if m.readError(err) {
+				return
+			}
 
 			continue
 		}
 
-		if err = destinationConn.writePacket(buf[:n], netUDPAddr); err != nil {
-			m.params.Logger.Errorf("Failed to write packet: %v", err)
+		for i := 0; i < n; i++ {
+			pkt := pkts[i]
+			pkt.buf = pkt.buf[:msgs[i].N]
+
+			// The buffer is handed over, the message reads into a new one.
+			pkts[i] = m.pool.Get().(*bufferHolder) //nolint:forcetypeassert
+			msgs[i].Buffers[0] = pkts[i].buf[:cap(pkts[i].buf)]
+
+			if !m.dispatchPacket(pkt, msgs[i].Addr, dispatch) {
+				return
+			}
 		}
 	}
 }
 
+// dispatchPacket passes a packet on to be routed, it returns false if reading
+// has to stop.
+func (m *UDPMuxDefault) dispatchPacket(pkt *bufferHolder, addr net.Addr, dispatch func(*bufferHolder, ipPort)) bool {
+	netUDPAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		m.params.Logger.Errorf("Underlying PacketConn did not return a UDPAddr")
+		m.pool.Put(pkt)
+
+		return false
+	}
+	udpAddr, err := newIPPort(netUDPAddr.IP, netUDPAddr.Zone, uint16(netUDPAddr.Port)) //nolint:gosec
+	if err != nil {
+		m.params.Logger.Errorf("Failed to create a new IP/Port host pair")
+		m.pool.Put(pkt)
+
+		return false
+	}
+
+	pkt.addr = netUDPAddr
+	dispatch(pkt, udpAddr)
+
+	return true
+}
+
+// routePacket hands a packet over to its muxed connection.
+func (m *UDPMuxDefault) routePacket(pkt *bufferHolder, udpAddr ipPort) {
+	// If we have already seen this address dispatch to the appropriate destination
+	var destinationConn *udpMuxedConn
+	if conn, ok := m.addressMap.Load(udpAddr); ok {
+		destinationConn = conn.(*udpMuxedConn) //nolint:forcetypeassert
+	}
+
+	// If we haven't seen this address before but is a STUN packet lookup by ufrag
+	if destinationConn == nil && stun.IsMessage(pkt.buf) {
+		destinationConn = m.getConnForSTUN(pkt)
+	}
+
+	if destinationConn == nil {
+		m.params.Logger.Tracef("Dropping packet from %s, addr: %s", udpAddr.addr, pkt.addr)
+		pkt.reset()
+		m.pool.Put(pkt)
+
+		return
+	}
+
+	if err := destinationConn.enqueuePacket(pkt); err != nil {
+		m.params.Logger.Errorf("Failed to write packet: %v", err)
+	}
+}
+
+func (m *UDPMuxDefault) getConnForSTUN(pkt *bufferHolder) *udpMuxedConn {
+	msg := &stun.Message{
+		Raw: append([]byte{}, pkt.buf...),
+	}
+
+	if err := msg.Decode(); err != nil {
+		m.params.Logger.Warnf("Failed to handle decode ICE from %s: %v", pkt.addr.String(), err)
+
+		return nil
+	}
+
+	attr, stunAttrErr := msg.Get(stun.AttrUsername)
+	if stunAttrErr != nil {
+		m.params.Logger.Warnf("No Username attribute in STUN message from %s", pkt.addr.String())
+
+		return nil
+	}
+
+	ufrag := strings.Split(string(attr), ":")[0]
+	isIPv6 := pkt.addr.IP.To4() == nil
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	destinationConn, _ := m.getConn(ufrag, isIPv6)
+
+	return destinationConn
+}
+
 func (m *UDPMuxDefault) getConn(ufrag string, isIPv6 bool) (val *udpMuxedConn, ok bool) {
 	if isIPv6 {
 		val, ok = m.connsIPv6[ufrag]
@@ -392,6 +553,21 @@ type ipPort struct {
 	port uint16
 }
 
+// hash returns the FNV-1a hash of the address, it shards remote addresses
+// across the workers of the mux.
+func (a ipPort) hash() uint32 {
+	const prime = 16777619
+	hash := uint32(2166136261)
+	for _, b := range a.addr.As16() {
+		hash ^= uint32(b)
+		hash *= prime
+	}
+	hash ^= uint32(a.port)
+	hash *= prime
+
+	return hash
+}
+
 // newIPPort create a custom type of address based on netip.Addr and
 // port. The underlying ip address passed is converted to IPv6 format
 // to simplify ip address handling.
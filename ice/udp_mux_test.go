@@ -10,8 +10,10 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/binary"
+	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -126,6 +128,36 @@ func TestUDPMux(t *testing.T) { //nolint:cyclop
 	}
 }
 
+func TestUDPMux_BatchedWorkers(t *testing.T) {
+	defer test.CheckRoutines(t)()
+
+	defer test.TimeOut(time.Second * 30).Stop()
+
+	conn, err := net.ListenUDP(udp, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+
+	udpMux := NewUDPMuxDefault(UDPMuxParams{
+		UDPConn:       conn,
+		ReadBatchSize: 8,
+		Workers:       4,
+	})
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	wg := sync.WaitGroup{}
+	for _, ufrag := range []string{"ufrag1", "ufrag2", "ufrag3"} {
+		wg.Add(1)
+		go func(ufrag string) {
+			defer wg.Done()
+			testMuxConnection(t, udpMux, ufrag, udp4)
+		}(ufrag)
+	}
+	wg.Wait()
+
+	require.NoError(t, udpMux.Close())
+}
+
 func testMuxConnection(t *testing.T, udpMux *UDPMuxDefault, ufrag string, network string) {
 	t.Helper()
 
@@ -285,3 +317,106 @@ func TestUDPMux_Agent_Restart(t *testing.T) {
 	<-aConnected
 	<-bConnected
 }
+
+func BenchmarkUDPMux(b *testing.B) {
+	for _, readBatchSize := range []int{1, 32} {
+		for _, workers := range []int{1, 2, 4} {
+			b.Run(fmt.Sprintf("ReadBatchSize=%d/Workers=%d", readBatchSize, workers), func(b *testing.B) {
+				benchmarkUDPMux(b, readBatchSize, workers)
+			})
+		}
+	}
+}
+
+func benchmarkUDPMux(b *testing.B, readBatchSize, workers int) {
+	b.Helper()
+
+	const remotes = 16
+
+	conn, err := net.ListenUDP(udp, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(b, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+	require.NoError(b, conn.SetReadBuffer(4*1024*1024))
+
+	udpMux := NewUDPMuxDefault(UDPMuxParams{
+		UDPConn:       conn,
+		ReadBatchSize: readBatchSize,
+		Workers:       workers,
+	})
+	defer func() {
+		_ = udpMux.Close()
+	}()
+
+	var received atomic.Int64
+	readers := sync.WaitGroup{}
+	remoteConns := make([]*net.UDPConn, remotes)
+	for i := range remoteConns {
+		pktConn, err := udpMux.GetConn(fmt.Sprintf("ufrag%d", i), udpMux.LocalAddr())
+		require.NoError(b, err)
+
+		remoteConns[i], err = net.DialUDP(udp4, nil, conn.LocalAddr().(*net.UDPAddr)) //nolint:forcetypeassert
+		require.NoError(b, err)
+		defer func(remoteConn *net.UDPConn) {
+			_ = remoteConn.Close()
+		}(remoteConns[i])
+
+		// Route the remote address to its muxed connection, the address is
+		// registered once the muxed connection writes to it.
+		msg := stun.New()
+		msg.Type = stun.MessageType{Method: stun.MethodBinding, Class: stun.ClassRequest}
+		msg.Add(stun.AttrUsername, []byte(fmt.Sprintf("ufrag%d:otherufrag", i)))
+		msg.Encode()
+		_, err = remoteConns[i].Write(msg.Raw)
+		require.NoError(b, err)
+
+		buf := make([]byte, receiveMTU)
+		_, _, err = pktConn.ReadFrom(buf)
+		require.NoError(b, err)
+		_, err = pktConn.WriteTo(msg.Raw, remoteConns[i].LocalAddr())
+		require.NoError(b, err)
+		_, err = remoteConns[i].Read(buf)
+		require.NoError(b, err)
+
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				if _, _, err := pktConn.ReadFrom(buf); err != nil {
+					return
+				}
+				received.Add(1)
+			}
+		}()
+	}
+
+	payload := make([]byte, 1200)
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+
+	senders := sync.WaitGroup{}
+	for i, remoteConn := range remoteConns {
+		senders.Add(1)
+		go func(remoteConn *net.UDPConn, packets int) {
+			defer senders.Done()
+			for j := 0; j < packets; j++ {
+				_, _ = remoteConn.Write(payload)
+			}
+		}(remoteConn, (b.N+remotes-1-i)/remotes)
+	}
+	senders.Wait()
+
+	// Wait for the packets still in flight
+	for last := int64(-1); last != received.Load(); {
+		last = received.Load()
+		time.Sleep(10 * time.Millisecond)
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(received.Load())/b.Elapsed().Seconds(), "pkts/s")
+	b.ReportMetric(float64(received.Load())/float64(b.N), "delivered")
+
+	require.NoError(b, udpMux.Close())
+	readers.Wait()
+}
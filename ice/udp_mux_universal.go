@@ -11,6 +11,8 @@ import (
 	"github.com/pion/logging"
 	"github.com/pion/stun/v3"
 	"github.com/pion/transport/v3"
+	udpbatch "github.com/pion/transport/v3/udp"
+	"golang.org/x/net/ipv4"
 )
 
 // UniversalUDPMux allows multiple connections to go over a single UDP port for
@@ -41,6 +43,10 @@ type UniversalUDPMuxParams struct {
 	UDPConn               net.PacketConn
 	XORMappedAddrCacheTTL time.Duration
 	Net                   transport.Net
+	// ReadBatchSize is passed on to UDPMuxParams.ReadBatchSize.
+	ReadBatchSize int
+	// Workers is passed on to UDPMuxParams.Workers.
+	Workers int
 }
 
 // NewUniversalUDPMuxDefault creates an implementation of UniversalUDPMux embedding UDPMux.
@@ -59,17 +65,23 @@ func NewUniversalUDPMuxDefault(params UniversalUDPMuxParams) *UniversalUDPMuxDef
 
 	// Wrap UDP connection, process server reflexive messages
 	// before they are passed to the UDPMux connection handler (connWorker)
-	mux.params.UDPConn = &udpConn{
+	conn := &udpConn{
 		PacketConn: params.UDPConn,
 		mux:        mux,
 		logger:     params.Logger,
 	}
+	if params.ReadBatchSize > 1 {
+		conn.batchReader = newBatchReader(params.UDPConn)
+	}
+	mux.params.UDPConn = conn
 
 	// Embed UDPMux
 	udpMuxParams := UDPMuxParams{
-		Logger:  params.Logger,
-		UDPConn: mux.params.UDPConn,
-		Net:     mux.params.Net,
+		Logger:        params.Logger,
+		UDPConn:       mux.params.UDPConn,
+		Net:           mux.params.Net,
+		ReadBatchSize: mux.params.ReadBatchSize,
+		Workers:       mux.params.Workers,
 	}
 	mux.UDPMuxDefault = NewUDPMuxDefault(udpMuxParams)
 
@@ -81,6 +93,10 @@ type udpConn struct {
 	net.PacketConn
 	mux    *UniversalUDPMuxDefault
 	logger logging.LeveledLogger
+
+	// batchReader reads the wrapped conn in batches, it is nil if the
+	// packets are read one at a time.
+	batchReader udpbatch.BatchReader
 }
 
 // GetRelayedAddr creates relayed connection to the given TURN service and returns the relayed addr.
@@ -103,36 +119,64 @@ func (c *udpConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
 	if err != nil {
 		return n, addr, err
 	}
+	c.handlePacket(p[:n], addr)
 
-	if stun.IsMessage(p[:n]) { //nolint:nestif
-		msg := &stun.Message{
-			Raw: append([]byte{}, p[:n]...),
-		}
-
-		if err = msg.Decode(); err != nil {
-			c.logger.Warnf("Failed to handle decode ICE from %s: %v", addr.String(), err)
+	return n, addr, nil
+}
 
-			return n, addr, nil
+// ReadBatch is called by UDPMux if UniversalUDPMuxParams.ReadBatchSize is set, it
+// handles the packets like ReadFrom. Packets are read one at a time if the wrapped
+// conn can not be read in batches.
+func (c *udpConn) ReadBatch(msgs []ipv4.Message, flags int) (int, error) {
+	if c.batchReader == nil {
+		if len(msgs) == 0 {
+			return 0, nil
 		}
 
-		udpAddr, ok := addr.(*net.UDPAddr)
-		if !ok {
-			// Message about this err will be logged in the UDPMux
-			return n, addr, err
+		n, addr, err := c.ReadFrom(msgs[0].Buffers[0])
+		if err != nil {
+			return 0, err
 		}
+		msgs[0].N, msgs[0].Addr = n, addr
 
-		if c.mux.isXORMappedResponse(msg, udpAddr.String()) {
-			err = c.mux.handleXORMappedResponse(udpAddr, msg)
-			if err != nil {
-				c.logger.Debugf("%w: %v", errGetXorMappedAddrResponse, err)
-				err = nil
-			}
+		return 1, nil
+	}
 
-			return n, addr, err
-		}
+	n, err := c.batchReader.ReadBatch(msgs, flags)
+	for i := 0; i < n; i++ {
+		c.handlePacket(msgs[i].Buffers[0][:msgs[i].N], msgs[i].Addr)
+	}
+
+	return n, err
+}
+
+// handlePacket handles the responses of the STUN servers discovering a mapped address.
+func (c *udpConn) handlePacket(p []byte, addr net.Addr) {
+	if !stun.IsMessage(p) {
+		return
 	}
 
-	return n, addr, err
+	msg := &stun.Message{
+		Raw: append([]byte{}, p...),
+	}
+
+	if err := msg.Decode(); err != nil {
+		c.logger.Warnf("Failed to handle decode ICE from %s: %v", addr.String(), err)
+
+		return
+	}
+
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		// Message about this err will be logged in the UDPMux
+		return
+	}
+
+	if c.mux.isXORMappedResponse(msg, udpAddr.String()) {
+		if err := c.mux.handleXORMappedResponse(udpAddr, msg); err != nil {
+			c.logger.Debugf("%w: %v", errGetXorMappedAddrResponse, err)
+		}
+	}
 }
 
 // isXORMappedResponse indicates whether the message is a XORMappedAddress and is coming from the known STUN server.
@@ -42,6 +42,30 @@ func TestUniversalUDPMux(t *testing.T) {
 	wg.Wait()
 }
 
+func TestUniversalUDPMuxReadBatchSize(t *testing.T) {
+	conn, err := net.ListenUDP(udp, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+
+	udpMux := NewUniversalUDPMuxDefault(UniversalUDPMuxParams{
+		UDPConn:       conn,
+		ReadBatchSize: 8,
+	})
+
+	defer func() {
+		_ = udpMux.Close()
+		_ = conn.Close()
+	}()
+
+	// The batch size is passed on to the embedded UDPMux, which reads the
+	// server reflexive packets through the wrapping conn in batches.
+	require.Equal(t, 8, udpMux.UDPMuxDefault.params.ReadBatchSize)
+	wrapped, ok := udpMux.params.UDPConn.(*udpConn)
+	require.True(t, ok)
+	require.NotNil(t, wrapped.batchReader)
+
+	testMuxSrflxConnection(t, udpMux, "ufrag5", udp)
+}
+
 func testMuxSrflxConnection(t *testing.T, udpMux *UniversalUDPMuxDefault, ufrag string, network string) {
 	t.Helper()
 
@@ -207,17 +207,9 @@ func (c *udpMuxedConn) containsAddress(addr ipPort) bool {
 	return false
 }
 
-func (c *udpMuxedConn) writePacket(data []byte, addr *net.UDPAddr) error {
-	pkt := c.params.AddrPool.Get().(*bufferHolder) //nolint:forcetypeassert
-	if cap(pkt.buf) < len(data) {
-		c.params.AddrPool.Put(pkt)
-
-		return io.ErrShortBuffer
-	}
-
-	pkt.buf = append(pkt.buf[:0], data...)
-	pkt.addr = addr
-
+// enqueuePacket queues a packet read by the mux, the conn takes ownership of
+// the buffer.
+func (c *udpMuxedConn) enqueuePacket(pkt *bufferHolder) error {
 	c.mu.Lock()
 	if c.state == udpMuxedConnClosed {
 		c.mu.Unlock()
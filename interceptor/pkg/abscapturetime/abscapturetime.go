@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+// Package abscapturetime provides interceptors that measure end-to-end latency
+// using the abs-capture-time RTP header extension.
+//
+// The SenderInterceptor stamps the capture time on the first packet of each
+// frame. The ReceiverInterceptor maps the sender clock onto the local clock
+// using Sender Reports and reports the latency of every frame.
+package abscapturetime
+
+import (
+	"github.com/pion/interceptor"
+)
+
+// URI is the URI of the abs-capture-time header extension.
+const URI = "http://www.webrtc.org/experiments/rtp-hdrext/abs-capture-time"
+
+func headerExtensionID(info *interceptor.StreamInfo) uint8 {
+	for _, e := range info.RTPHeaderExtensions {
+		if e.URI == URI {
+			return uint8(e.ID) //nolint:gosec // G115
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package abscapturetime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/internal/ntp"
+	"github.com/pion/interceptor/internal/test"
+	"github.com/pion/interceptor/pkg/stats"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+const testExtID = 3
+
+func testStreamInfo() *interceptor.StreamInfo {
+	return &interceptor.StreamInfo{
+		SSRC:                123,
+		ClockRate:           90000,
+		RTPHeaderExtensions: []interceptor.RTPHeaderExtension{{URI: URI, ID: testExtID}},
+	}
+}
+
+func TestSenderInterceptor(t *testing.T) {
+	captureTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	f, err := NewSenderInterceptor(SenderNow(func() time.Time { return captureTime }))
+	assert.NoError(t, err)
+
+	i, err := f.NewInterceptor("")
+	assert.NoError(t, err)
+
+	stream := test.NewMockStream(testStreamInfo(), i)
+	defer func() {
+		assert.NoError(t, stream.Close())
+	}()
+
+	// Two packets of the same frame, only the first one is stamped.
+	for seq := uint16(0); seq < 2; seq++ {
+		assert.NoError(t, stream.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: seq, Timestamp: 1000}}))
+	}
+	pkt := <-stream.WrittenRTP()
+	ext := rtp.AbsCaptureTimeExtension{}
+	assert.NoError(t, ext.Unmarshal(pkt.GetExtension(testExtID)))
+	assert.InDelta(t, 0, ext.CaptureTime().Sub(captureTime), float64(time.Microsecond))
+	pkt = <-stream.WrittenRTP()
+	assert.Nil(t, pkt.GetExtension(testExtID))
+
+	// A forwarded packet keeps its original capture time.
+	forwarded, err := rtp.NewAbsCaptureTimeExtension(captureTime.Add(-time.Second)).Marshal()
+	assert.NoError(t, err)
+	hdr := rtp.Header{SequenceNumber: 2, Timestamp: 4000}
+	assert.NoError(t, hdr.SetExtension(testExtID, forwarded))
+	assert.NoError(t, stream.WriteRTP(&rtp.Packet{Header: hdr}))
+	pkt = <-stream.WrittenRTP()
+	assert.Equal(t, forwarded, pkt.GetExtension(testExtID))
+}
+
+func TestSenderInterceptor_NotNegotiated(t *testing.T) {
+	f, err := NewSenderInterceptor()
+	assert.NoError(t, err)
+
+	i, err := f.NewInterceptor("")
+	assert.NoError(t, err)
+
+	stream := test.NewMockStream(&interceptor.StreamInfo{SSRC: 123, ClockRate: 90000}, i)
+	defer func() {
+		assert.NoError(t, stream.Close())
+	}()
+
+	assert.NoError(t, stream.WriteRTP(&rtp.Packet{Header: rtp.Header{Timestamp: 1000}}))
+	pkt := <-stream.WrittenRTP()
+	assert.False(t, pkt.Extension)
+}
+
+func TestReceiverInterceptor(t *testing.T) {
+	// The remote clock runs 10s behind the local clock, frames take 40ms.
+	remoteNow := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	localNow := remoteNow.Add(10 * time.Second)
+	const networkDelay = 40 * time.Millisecond
+
+	var measured []time.Duration
+	f, err := NewReceiverInterceptor(
+		ReceiverNow(func() time.Time { return localNow }),
+		OnLatency(func(ssrc uint32, latency time.Duration) {
+			assert.Equal(t, uint32(123), ssrc)
+			measured = append(measured, latency)
+		}),
+	)
+	assert.NoError(t, err)
+
+	i, err := f.NewInterceptor("")
+	assert.NoError(t, err)
+
+	stream := test.NewMockStream(testStreamInfo(), i)
+	defer func() {
+		assert.NoError(t, stream.Close())
+	}()
+
+	// Without a Sender Report the clocks can't be related.
+	stream.ReceiveRTP(&rtp.Packet{Header: rtp.Header{SSRC: 123, Timestamp: 0}})
+	<-stream.ReadRTP()
+	assert.Empty(t, measured)
+
+	// The Sender Report is sent now and arrives without delay, to keep the
+	// offset estimate exact in absence of RTT measurements.
+	stream.ReceiveRTCP([]rtcp.Packet{&rtcp.SenderReport{
+		SSRC:    123,
+		NTPTime: ntp.ToNTP(remoteNow),
+		RTPTime: 90000,
+	}})
+	<-stream.ReadRTCP()
+
+	// Frame captured 20ms before the Sender Report, with the extension.
+	ext, err := rtp.NewAbsCaptureTimeExtension(remoteNow.Add(-20 * time.Millisecond)).Marshal()
+	assert.NoError(t, err)
+	hdr := rtp.Header{SSRC: 123, SequenceNumber: 1, Timestamp: 88200}
+	assert.NoError(t, hdr.SetExtension(testExtID, ext))
+	localNow = localNow.Add(networkDelay - 20*time.Millisecond)
+	stream.ReceiveRTP(&rtp.Packet{Header: hdr})
+	<-stream.ReadRTP()
+
+	// Second packet of the same frame is not measured again.
+	stream.ReceiveRTP(&rtp.Packet{Header: rtp.Header{SSRC: 123, SequenceNumber: 2, Timestamp: 88200}})
+	<-stream.ReadRTP()
+
+	// Frame without the extension, captured at the RTP time of the Sender Report.
+	localNow = remoteNow.Add(10*time.Second + networkDelay)
+	stream.ReceiveRTP(&rtp.Packet{Header: rtp.Header{SSRC: 123, SequenceNumber: 3, Timestamp: 90000}})
+	<-stream.ReadRTP()
+
+	assert.Len(t, measured, 2)
+	for _, latency := range measured {
+		assert.InDelta(t, networkDelay, latency, float64(time.Millisecond))
+	}
+
+	offset, ok := i.(*ReceiverInterceptor).ClockOffset(123) //nolint:forcetypeassert
+	assert.True(t, ok)
+	assert.InDelta(t, 10*time.Second, offset, float64(time.Millisecond))
+}
+
+func TestReceiverInterceptor_Stats(t *testing.T) {
+	remoteNow := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	latencyFactory, err := NewReceiverInterceptor(ReceiverNow(func() time.Time {
+		return remoteNow.Add(30 * time.Millisecond)
+	}))
+	assert.NoError(t, err)
+	latencyInterceptor, err := latencyFactory.NewInterceptor("")
+	assert.NoError(t, err)
+
+	statsFactory, err := stats.NewInterceptor()
+	assert.NoError(t, err)
+	var getter stats.Getter
+	statsFactory.OnNewPeerConnection(func(_ string, g stats.Getter) {
+		getter = g
+	})
+	statsInterceptor, err := statsFactory.NewInterceptor("")
+	assert.NoError(t, err)
+
+	stream := test.NewMockStream(testStreamInfo(), interceptor.NewChain(
+		[]interceptor.Interceptor{latencyInterceptor, statsInterceptor},
+	))
+	defer func() {
+		assert.NoError(t, stream.Close())
+	}()
+
+	stream.ReceiveRTCP([]rtcp.Packet{&rtcp.SenderReport{
+		SSRC:    123,
+		NTPTime: ntp.ToNTP(remoteNow.Add(30 * time.Millisecond)),
+		RTPTime: 90000,
+	}})
+	<-stream.ReadRTCP()
+
+	stream.ReceiveRTP(&rtp.Packet{Header: rtp.Header{SSRC: 123, Timestamp: 90000 - 900}})
+	<-stream.ReadRTP()
+
+	s := getter.Get(123)
+	assert.NotNil(t, s)
+	assert.Equal(t, uint64(1), s.InboundRTPStreamStats.Latency.Measurements)
+	assert.InDelta(t, 10*time.Millisecond, s.InboundRTPStreamStats.Latency.P50, float64(time.Millisecond))
+}
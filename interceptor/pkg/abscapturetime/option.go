@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package abscapturetime
+
+import (
+	"time"
+
+	"github.com/pion/logging"
+)
+
+// SenderOption can be used to configure SenderInterceptor.
+type SenderOption func(s *SenderInterceptor) error
+
+// SenderLog sets a logger for the interceptor.
+func SenderLog(log logging.LeveledLogger) SenderOption {
+	return func(s *SenderInterceptor) error {
+		s.log = log
+
+		return nil
+	}
+}
+
+// SenderNow sets an alternative for the time.Now function. It is used as the
+// capture time of each frame.
+func SenderNow(f func() time.Time) SenderOption {
+	return func(s *SenderInterceptor) error {
+		s.now = f
+
+		return nil
+	}
+}
+
+// ReceiverOption can be used to configure ReceiverInterceptor.
+type ReceiverOption func(r *ReceiverInterceptor) error
+
+// ReceiverLog sets a logger for the interceptor.
+func ReceiverLog(log logging.LeveledLogger) ReceiverOption {
+	return func(r *ReceiverInterceptor) error {
+		r.log = log
+
+		return nil
+	}
+}
+
+// ReceiverNow sets an alternative for the time.Now function.
+func ReceiverNow(f func() time.Time) ReceiverOption {
+	return func(r *ReceiverInterceptor) error {
+		r.now = f
+
+		return nil
+	}
+}
+
+// OnLatency sets a callback that is called with the end-to-end latency of
+// every frame for which it can be estimated.
+func OnLatency(f LatencyCallback) ReceiverOption {
+	return func(r *ReceiverInterceptor) error {
+		r.onLatency = f
+
+		return nil
+	}
+}
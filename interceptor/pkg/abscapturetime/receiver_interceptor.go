@@ -0,0 +1,264 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package abscapturetime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/internal/ntp"
+	"github.com/pion/interceptor/pkg/stats"
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// LatencyCallback is called with the end-to-end latency of a frame of the
+// stream with the given SSRC.
+type LatencyCallback func(ssrc uint32, latency time.Duration)
+
+// ReceiverInterceptorFactory is a interceptor.Factory for a ReceiverInterceptor.
+type ReceiverInterceptorFactory struct {
+	opts []ReceiverOption
+}
+
+// NewReceiverInterceptor returns a new ReceiverInterceptorFactory.
+func NewReceiverInterceptor(opts ...ReceiverOption) (*ReceiverInterceptorFactory, error) {
+	return &ReceiverInterceptorFactory{opts}, nil
+}
+
+// NewInterceptor constructs a new ReceiverInterceptor.
+func (r *ReceiverInterceptorFactory) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	receiverInterceptor := &ReceiverInterceptor{
+		now:     time.Now,
+		log:     logging.NewDefaultLoggerFactory().NewLogger("abs_capture_time_receiver"),
+		streams: map[uint32]*receiverStream{},
+	}
+
+	for _, opt := range r.opts {
+		if err := opt(receiverInterceptor); err != nil {
+			return nil, err
+		}
+	}
+
+	return receiverInterceptor, nil
+}
+
+// ReceiverInterceptor estimates the end-to-end latency of every received frame.
+//
+// The capture time of a frame is taken from the abs-capture-time extension if
+// present, otherwise it is derived from the RTP timestamp and the latest Sender
+// Report. The remote clock is mapped onto the local clock using the Sender
+// Report NTP time, corrected by half the round trip time when it is known.
+// The latency is measured up to the arrival of the first packet of the frame.
+//
+// Each measurement is attached to the packet attributes with stats.SetLatency,
+// so a stats interceptor registered after this one publishes percentiles.
+type ReceiverInterceptor struct {
+	interceptor.NoOp
+	now       func() time.Time
+	log       logging.LeveledLogger
+	onLatency LatencyCallback
+
+	mu      sync.Mutex
+	rtt     time.Duration
+	streams map[uint32]*receiverStream
+}
+
+type receiverStream struct {
+	ssrc      uint32
+	clockRate uint32
+	hdrExtID  uint8
+
+	hasSenderReport bool
+	srNTPTime       time.Time
+	srRTPTime       uint32
+
+	hasOffset bool
+	offset    time.Duration // local clock minus remote clock
+
+	started       bool
+	lastTimestamp uint32
+}
+
+// offsetSmoothing is the weight of a new sample in the clock offset estimate.
+const offsetSmoothing = 8
+
+// ClockOffset returns the estimated offset of the local clock to the clock of
+// the sender of the stream with the given SSRC. An SFU can use it to fill the
+// estimated capture clock offset when forwarding the extension.
+func (r *ReceiverInterceptor) ClockOffset(ssrc uint32) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stream, ok := r.streams[ssrc]
+	if !ok || !stream.hasOffset {
+		return 0, false
+	}
+
+	return stream.offset, true
+}
+
+// BindRTCPReader lets you modify any incoming RTCP packets. It is called once per sender/receiver, however this might
+// change in the future. The returned method will be called once per packet batch.
+func (r *ReceiverInterceptor) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	return interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attr, err := reader.Read(b, a)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if attr == nil {
+			attr = make(interceptor.Attributes)
+		}
+		pkts, err := attr.GetRTCPPackets(b[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		r.processRTCP(r.now(), pkts)
+
+		return n, attr, nil
+	})
+}
+
+func (r *ReceiverInterceptor) processRTCP(now time.Time, pkts []rtcp.Packet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pkt := range pkts {
+		var reports []rtcp.ReceptionReport
+		switch pkt := pkt.(type) {
+		case *rtcp.SenderReport:
+			if stream, ok := r.streams[pkt.SSRC]; ok {
+				r.processSenderReport(now, stream, pkt)
+			}
+			reports = pkt.Reports
+		case *rtcp.ReceiverReport:
+			reports = pkt.Reports
+		}
+
+		for _, report := range reports {
+			r.processReceptionReport(now, report)
+		}
+	}
+}
+
+func (r *ReceiverInterceptor) processSenderReport(now time.Time, stream *receiverStream, sr *rtcp.SenderReport) {
+	stream.hasSenderReport = true
+	stream.srNTPTime = ntp.ToTime(sr.NTPTime)
+	stream.srRTPTime = sr.RTPTime
+
+	sample := now.Sub(stream.srNTPTime) - r.rtt/2
+	if !stream.hasOffset {
+		stream.offset = sample
+		stream.hasOffset = true
+	} else {
+		stream.offset += (sample - stream.offset) / offsetSmoothing
+	}
+}
+
+// processReceptionReport derives the round trip time from the reports the
+// remote peer sends about our own streams.
+func (r *ReceiverInterceptor) processReceptionReport(now time.Time, report rtcp.ReceptionReport) {
+	if report.LastSenderReport == 0 {
+		return
+	}
+
+	rtt := ntp.ToNTP32(now) - report.LastSenderReport - report.Delay
+	if int32(rtt) < 0 { //nolint:gosec // G115
+		return
+	}
+
+	r.rtt = time.Duration(rtt) * time.Second / 65536
+}
+
+// BindRemoteStream lets you modify any incoming RTP packets. It is called once for per RemoteStream.
+// The returned method will be called once per rtp packet.
+func (r *ReceiverInterceptor) BindRemoteStream(
+	info *interceptor.StreamInfo, reader interceptor.RTPReader,
+) interceptor.RTPReader {
+	if info.ClockRate == 0 {
+		return reader
+	}
+
+	stream := &receiverStream{
+		ssrc:      info.SSRC,
+		clockRate: info.ClockRate,
+		hdrExtID:  headerExtensionID(info),
+	}
+	r.mu.Lock()
+	r.streams[info.SSRC] = stream
+	r.mu.Unlock()
+
+	return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attr, err := reader.Read(b, a)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if attr == nil {
+			attr = make(interceptor.Attributes)
+		}
+		header, err := attr.GetRTPHeader(b[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if latency, ok := r.processRTP(r.now(), stream, header); ok {
+			stats.SetLatency(attr, latency)
+			if r.onLatency != nil {
+				r.onLatency(stream.ssrc, latency)
+			}
+		}
+
+		return n, attr, nil
+	})
+}
+
+func (r *ReceiverInterceptor) processRTP(
+	now time.Time, stream *receiverStream, header *rtp.Header,
+) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stream.started && header.Timestamp == stream.lastTimestamp {
+		return 0, false
+	}
+	stream.started = true
+	stream.lastTimestamp = header.Timestamp
+
+	// The offset is estimated together with the Sender Report mapping.
+	if !stream.hasOffset {
+		return 0, false
+	}
+
+	var captureTime time.Time
+	if raw := header.GetExtension(stream.hdrExtID); stream.hdrExtID != 0 && raw != nil {
+		ext := rtp.AbsCaptureTimeExtension{}
+		if err := ext.Unmarshal(raw); err != nil {
+			r.log.Debugf("failed to parse abs-capture-time extension: %v", err)
+
+			return 0, false
+		}
+		captureTime = ext.CaptureTime()
+		if offset := ext.EstimatedCaptureClockOffsetDuration(); offset != nil {
+			captureTime = captureTime.Add(*offset)
+		}
+	} else {
+		elapsed := int32(header.Timestamp - stream.srRTPTime) //nolint:gosec // G115
+		captureTime = stream.srNTPTime.Add(time.Duration(elapsed) * time.Second / time.Duration(stream.clockRate))
+	}
+
+	return now.Sub(captureTime.Add(stream.offset)), true
+}
+
+// UnbindRemoteStream is called when the Stream is removed. It can be used to clean up any data related to that track.
+func (r *ReceiverInterceptor) UnbindRemoteStream(info *interceptor.StreamInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.streams, info.SSRC)
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package abscapturetime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/logging"
+	"github.com/pion/rtp"
+)
+
+// SenderInterceptorFactory is a interceptor.Factory for a SenderInterceptor.
+type SenderInterceptorFactory struct {
+	opts []SenderOption
+}
+
+// NewSenderInterceptor returns a new SenderInterceptorFactory.
+func NewSenderInterceptor(opts ...SenderOption) (*SenderInterceptorFactory, error) {
+	return &SenderInterceptorFactory{opts}, nil
+}
+
+// NewInterceptor constructs a new SenderInterceptor.
+func (s *SenderInterceptorFactory) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	senderInterceptor := &SenderInterceptor{
+		now: time.Now,
+		log: logging.NewDefaultLoggerFactory().NewLogger("abs_capture_time_sender"),
+	}
+
+	for _, opt := range s.opts {
+		if err := opt(senderInterceptor); err != nil {
+			return nil, err
+		}
+	}
+
+	return senderInterceptor, nil
+}
+
+// SenderInterceptor adds the abs-capture-time header extension to the first
+// packet of each frame. Packets that already carry the extension, for example
+// because they are forwarded by an SFU, are passed through untouched so the
+// original capture time is preserved across hops.
+type SenderInterceptor struct {
+	interceptor.NoOp
+	now func() time.Time
+	log logging.LeveledLogger
+}
+
+// BindLocalStream lets you modify any outgoing RTP packets. It is called once for per LocalStream.
+// The returned method will be called once per rtp packet.
+func (s *SenderInterceptor) BindLocalStream(
+	info *interceptor.StreamInfo, writer interceptor.RTPWriter,
+) interceptor.RTPWriter {
+	hdrExtID := headerExtensionID(info)
+	if hdrExtID == 0 {
+		return writer
+	}
+
+	var (
+		mu            sync.Mutex
+		started       bool
+		lastTimestamp uint32
+	)
+
+	return interceptor.RTPWriterFunc(
+		func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+			mu.Lock()
+			newFrame := !started || header.Timestamp != lastTimestamp
+			started = true
+			lastTimestamp = header.Timestamp
+			mu.Unlock()
+
+			if newFrame && header.GetExtension(hdrExtID) == nil {
+				ext, err := rtp.NewAbsCaptureTimeExtension(s.now()).Marshal()
+				if err != nil {
+					return 0, err
+				}
+				if err = header.SetExtension(hdrExtID, ext); err != nil {
+					s.log.Warnf("failed to set abs-capture-time extension: %v", err)
+				}
+			}
+
+			return writer.Write(header, payload, attributes)
+		},
+	)
+}
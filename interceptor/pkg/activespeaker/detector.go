@@ -0,0 +1,430 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+// Package activespeaker provides dominant speaker detection based on the
+// audio level RTP header extension (RFC 6464).
+//
+// The Detector implements the dominant speaker identification of Volfin and
+// Cohen, "Dominant Speaker Identification for Multipoint Videoconferencing".
+// Speech activity is scored on three time scales; a speaker only takes over
+// from the current dominant speaker when it is clearly more active on all of
+// them, which keeps short noises and interjections from causing switches.
+package activespeaker
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Number of quantization levels of a frame, and the number of frames in a
+	// medium block and of medium blocks in a long block.
+	n1 = 13
+	n2 = 5
+	n3 = 10
+
+	// An immediate counts as active in the medium block above this value, a
+	// medium block counts as active in the long block above this value.
+	mediumThreshold = 7
+	longThreshold   = 4
+
+	// Log likelihood ratios a challenger needs on the immediate, medium and
+	// long time scale to replace the dominant speaker.
+	c1 = 3.0
+	c2 = 2.0
+	c3 = 0.0
+
+	minActivityScore = 1e-10
+
+	// Audio levels are expressed in -dBov, 127 is digital silence.
+	maxLevel = 127
+
+	// maxNoiseFloor bounds the loudness considered background noise, for
+	// streams that never send silence.
+	maxNoiseFloor = 40
+
+	defaultFrameDuration    = 20 * time.Millisecond
+	defaultDecisionInterval = 300 * time.Millisecond
+)
+
+// Speaker describes an active speaker.
+type Speaker struct {
+	SSRC uint32
+	// AudioLevel is the latest audio level in -dBov, 0 is the loudest.
+	AudioLevel uint8
+	// Score is the sum of the activity scores on the three time scales, used
+	// to rank speakers.
+	Score float64
+}
+
+type speaker struct {
+	ssrc uint32
+
+	// Quantized level of the most recent frames, newest last.
+	immediates []int
+	lastLevel  uint8
+	lastFrame  time.Time
+	seenVoice  bool
+	noiseFloor int
+
+	immediateScore float64
+	mediumScore    float64
+	longScore      float64
+	active         bool
+}
+
+// DetectorOption configures a Detector.
+type DetectorOption func(d *Detector)
+
+// WithDecisionInterval sets how often the dominant speaker is re-evaluated.
+func WithDecisionInterval(interval time.Duration) DetectorOption {
+	return func(d *Detector) {
+		d.decisionInterval = interval
+	}
+}
+
+// WithFrameDuration sets the audio frame duration used to account for frames
+// that are not received, for example during discontinuous transmission.
+func WithFrameDuration(duration time.Duration) DetectorOption {
+	return func(d *Detector) {
+		d.frameDuration = duration
+	}
+}
+
+// Detector identifies the dominant speaker among a set of audio streams.
+// It is safe for concurrent use, so a single Detector can be fed by the
+// streams of many PeerConnections.
+type Detector struct {
+	mu               sync.Mutex
+	decisionInterval time.Duration
+	frameDuration    time.Duration
+
+	speakers     map[uint32]*speaker
+	dominant     *speaker
+	lastDecision time.Time
+	ranked       []Speaker
+
+	onDominantSpeakerChange func(ssrc uint32)
+	onActiveSpeakers        func(speakers []Speaker)
+}
+
+// NewDetector creates a new Detector.
+func NewDetector(opts ...DetectorOption) *Detector {
+	d := &Detector{
+		decisionInterval: defaultDecisionInterval,
+		frameDuration:    defaultFrameDuration,
+		speakers:         map[uint32]*speaker{},
+	}
+	for _, o := range opts {
+		o(d)
+	}
+
+	return d
+}
+
+// OnDominantSpeakerChange sets a handler that is called with the SSRC of the
+// new dominant speaker each time it changes.
+func (d *Detector) OnDominantSpeakerChange(f func(ssrc uint32)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onDominantSpeakerChange = f
+}
+
+// OnActiveSpeakers sets a handler that is called with the ranked active
+// speakers each time the ranking changes. The dominant speaker comes first.
+func (d *Detector) OnActiveSpeakers(f func(speakers []Speaker)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onActiveSpeakers = f
+}
+
+// AddLevel records the audio level of a frame of the stream with the given SSRC.
+// level is in -dBov as carried by the audio level header extension. The
+// dominant speaker is re-evaluated if the decision interval elapsed.
+func (d *Detector) AddLevel(ssrc uint32, level uint8, voice bool, now time.Time) {
+	d.mu.Lock()
+
+	spk, ok := d.speakers[ssrc]
+	if !ok {
+		spk = &speaker{ssrc: ssrc, noiseFloor: maxNoiseFloor}
+		d.speakers[ssrc] = spk
+	}
+	d.padSilence(spk, now)
+	spk.lastFrame = now
+	spk.lastLevel = level
+	if voice {
+		spk.seenVoice = true
+	}
+
+	loudness := maxLevel - int(level&maxLevel)
+	if loudness < spk.noiseFloor {
+		spk.noiseFloor = loudness
+	}
+	quantized := 0
+	// Trust the voice activity flag only if the sender sets it at all.
+	if voice || !spk.seenVoice {
+		quantized = quantize(loudness, spk.noiseFloor)
+	}
+	spk.push(quantized)
+
+	if now.Sub(d.lastDecision) < d.decisionInterval {
+		d.mu.Unlock()
+
+		return
+	}
+	d.evaluateLocked(now)
+}
+
+// RemoveSpeaker forgets the stream with the given SSRC.
+func (d *Detector) RemoveSpeaker(ssrc uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.dominant != nil && d.dominant.ssrc == ssrc {
+		d.dominant = nil
+	}
+	delete(d.speakers, ssrc)
+}
+
+// Evaluate re-evaluates the dominant speaker and the ranking of active speakers
+// now, regardless of the decision interval.
+func (d *Detector) Evaluate(now time.Time) {
+	d.mu.Lock()
+	d.evaluateLocked(now)
+}
+
+// DominantSpeaker returns the SSRC of the current dominant speaker.
+func (d *Detector) DominantSpeaker() (uint32, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.dominant == nil {
+		return 0, false
+	}
+
+	return d.dominant.ssrc, true
+}
+
+// ActiveSpeakers returns the active speakers as of the last evaluation, ranked
+// by activity with the dominant speaker first.
+func (d *Detector) ActiveSpeakers() []Speaker {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Speaker{}, d.ranked...)
+}
+
+// evaluateLocked must be called with d.mu held, it releases it before calling
+// the handlers.
+func (d *Detector) evaluateLocked(now time.Time) {
+	d.lastDecision = now
+
+	for _, spk := range d.speakers {
+		d.padSilence(spk, now)
+		spk.computeScores()
+	}
+
+	dominantChanged := d.electDominant()
+	ranked := d.rank()
+	rankingChanged := !equalRanking(ranked, d.ranked)
+	d.ranked = ranked
+
+	onDominantSpeakerChange := d.onDominantSpeakerChange
+	onActiveSpeakers := d.onActiveSpeakers
+	var dominant uint32
+	if d.dominant != nil {
+		dominant = d.dominant.ssrc
+	}
+	d.mu.Unlock()
+
+	if dominantChanged && onDominantSpeakerChange != nil {
+		onDominantSpeakerChange(dominant)
+	}
+	if rankingChanged && onActiveSpeakers != nil {
+		onActiveSpeakers(append([]Speaker{}, ranked...))
+	}
+}
+
+// electDominant replaces the dominant speaker with the strongest challenger
+// that beats it on all three time scales.
+func (d *Detector) electDominant() bool {
+	if d.dominant == nil {
+		var best *speaker
+		for _, spk := range d.speakers {
+			if spk.active && (best == nil || spk.total() > best.total()) {
+				best = spk
+			}
+		}
+		d.dominant = best
+
+		return best != nil
+	}
+
+	var challenger *speaker
+	bestMargin := 0.0
+	for _, spk := range d.speakers {
+		if spk == d.dominant || !spk.active {
+			continue
+		}
+
+		delta1 := math.Log(spk.immediateScore / d.dominant.immediateScore)
+		delta2 := math.Log(spk.mediumScore / d.dominant.mediumScore)
+		delta3 := math.Log(spk.longScore / d.dominant.longScore)
+		if delta1 > c1 && delta2 > c2 && delta3 > c3 && delta2 > bestMargin {
+			challenger = spk
+			bestMargin = delta2
+		}
+	}
+
+	if challenger == nil {
+		return false
+	}
+	d.dominant = challenger
+
+	return true
+}
+
+func (d *Detector) rank() []Speaker {
+	ranked := []Speaker{}
+	for _, spk := range d.speakers {
+		if !spk.active && spk != d.dominant {
+			continue
+		}
+		ranked = append(ranked, Speaker{SSRC: spk.ssrc, AudioLevel: spk.lastLevel, Score: spk.total()})
+	}
+
+	dominant := uint32(0)
+	hasDominant := d.dominant != nil
+	if hasDominant {
+		dominant = d.dominant.ssrc
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if hasDominant && ranked[i].SSRC == dominant {
+			return true
+		}
+		if hasDominant && ranked[j].SSRC == dominant {
+			return false
+		}
+		if ranked[i].Score == ranked[j].Score {
+			return ranked[i].SSRC < ranked[j].SSRC
+		}
+
+		return ranked[i].Score > ranked[j].Score
+	})
+
+	return ranked
+}
+
+func equalRanking(a, b []Speaker) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].SSRC != b[i].SSRC {
+			return false
+		}
+	}
+
+	return true
+}
+
+// padSilence records silent frames for the time no frame was received.
+func (d *Detector) padSilence(spk *speaker, now time.Time) {
+	if spk.lastFrame.IsZero() || d.frameDuration <= 0 {
+		return
+	}
+
+	missing := int(now.Sub(spk.lastFrame)/d.frameDuration) - 1
+	if missing > n2*n3 {
+		missing = n2 * n3
+	}
+	for i := 0; i < missing; i++ {
+		spk.push(0)
+	}
+	if missing > 0 {
+		spk.lastFrame = spk.lastFrame.Add(time.Duration(missing) * d.frameDuration)
+	}
+}
+
+func quantize(loudness, noiseFloor int) int {
+	if loudness <= noiseFloor {
+		return 0
+	}
+
+	return (loudness - noiseFloor) * n1 / (maxLevel - noiseFloor)
+}
+
+func (s *speaker) push(quantized int) {
+	s.immediates = append(s.immediates, quantized)
+	if len(s.immediates) > n2*n3 {
+		s.immediates = s.immediates[len(s.immediates)-n2*n3:]
+	}
+}
+
+func (s *speaker) total() float64 {
+	return s.immediateScore + s.mediumScore + s.longScore
+}
+
+// computeScores derives the medium and long blocks from the immediates and
+// scores the most recent block of each time scale.
+func (s *speaker) computeScores() {
+	immediate := 0
+	if len(s.immediates) > 0 {
+		immediate = s.immediates[len(s.immediates)-1]
+	}
+
+	mediums := make([]int, 0, n3)
+	for end := len(s.immediates); end > 0 && len(mediums) < n3; end -= n2 {
+		start := end - n2
+		if start < 0 {
+			start = 0
+		}
+		count := 0
+		for _, v := range s.immediates[start:end] {
+			if v > mediumThreshold {
+				count++
+			}
+		}
+		mediums = append(mediums, count)
+	}
+
+	medium, long := 0, 0
+	if len(mediums) > 0 {
+		medium = mediums[0]
+	}
+	for _, v := range mediums {
+		if v > longThreshold {
+			long++
+		}
+	}
+
+	s.immediateScore = activityScore(immediate, n1, 0.5, 0.78)
+	s.mediumScore = activityScore(medium, n2, 0.5, 24)
+	s.longScore = activityScore(long, n3, 0.5, 47)
+	s.active = medium > 0
+}
+
+// activityScore is the log likelihood ratio of speech activity for vL active
+// sub-units out of nR, with the speech and non-speech likelihoods modelled by a
+// binomial and an exponential distribution respectively.
+func activityScore(vL, nR int, p, lambda float64) float64 {
+	score := logBinomial(nR, vL) + float64(vL)*math.Log(p) + float64(nR-vL)*math.Log(1-p) -
+		math.Log(lambda) + lambda*float64(vL)
+	if score < minActivityScore {
+		return minActivityScore
+	}
+
+	return score
+}
+
+func logBinomial(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+
+	return a - b - c
+}
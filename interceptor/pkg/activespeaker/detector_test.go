@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package activespeaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	speech  = 20  // -dBov
+	noise   = 70  // -dBov
+	silence = 127 // -dBov
+)
+
+// feed sends one frame of the given level for every speaker, for the given duration.
+func feed(d *Detector, now time.Time, duration time.Duration, levels map[uint32]uint8) time.Time {
+	for end := now.Add(duration); now.Before(end); now = now.Add(defaultFrameDuration) {
+		for ssrc, level := range levels {
+			d.AddLevel(ssrc, level, false, now)
+		}
+	}
+
+	return now
+}
+
+func TestDetector_Switch(t *testing.T) {
+	d := NewDetector()
+
+	var changes []uint32
+	d.OnDominantSpeakerChange(func(ssrc uint32) {
+		changes = append(changes, ssrc)
+	})
+	var rankings [][]Speaker
+	d.OnActiveSpeakers(func(speakers []Speaker) {
+		rankings = append(rankings, speakers)
+	})
+
+	_, ok := d.DominantSpeaker()
+	assert.False(t, ok)
+
+	now := time.Unix(0, 0)
+	now = feed(d, now, 2*time.Second, map[uint32]uint8{1: speech, 2: silence, 3: noise})
+	dominant, ok := d.DominantSpeaker()
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), dominant)
+	assert.Equal(t, []uint32{1}, changes)
+
+	// A short interjection does not take over.
+	now = feed(d, now, 200*time.Millisecond, map[uint32]uint8{1: speech, 2: speech, 3: noise})
+	now = feed(d, now, 2*time.Second, map[uint32]uint8{1: speech, 2: silence, 3: noise})
+	assert.Equal(t, []uint32{1}, changes)
+
+	// Speaker 1 stops, speaker 2 talks for a while.
+	feed(d, now, 3*time.Second, map[uint32]uint8{1: silence, 2: speech, 3: noise})
+	dominant, _ = d.DominantSpeaker()
+	assert.Equal(t, uint32(2), dominant)
+	assert.Equal(t, []uint32{1, 2}, changes)
+
+	active := d.ActiveSpeakers()
+	assert.Equal(t, uint32(2), active[0].SSRC)
+	assert.Equal(t, uint8(speech), active[0].AudioLevel)
+	for _, spk := range active {
+		assert.NotEqual(t, uint32(3), spk.SSRC, "background noise is not speech")
+	}
+	assert.NotEmpty(t, rankings)
+	assert.Equal(t, active[0].SSRC, rankings[len(rankings)-1][0].SSRC)
+
+	d.RemoveSpeaker(2)
+	_, ok = d.DominantSpeaker()
+	assert.False(t, ok)
+}
+
+func TestDetector_VoiceActivityFlag(t *testing.T) {
+	d := NewDetector()
+
+	now := time.Unix(0, 0)
+	// Both are loud, but according to the VAD of the sender only 2 is voice.
+	// 1 flagged voice once, so its flag is trusted.
+	d.AddLevel(1, silence, true, now)
+	for end := now.Add(2 * time.Second); now.Before(end); now = now.Add(defaultFrameDuration) {
+		d.AddLevel(1, speech, false, now)
+		d.AddLevel(2, speech, true, now)
+	}
+	d.Evaluate(now)
+
+	dominant, ok := d.DominantSpeaker()
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), dominant)
+	assert.Len(t, d.ActiveSpeakers(), 1)
+}
+
+func TestDetector_DiscontinuousTransmission(t *testing.T) {
+	d := NewDetector()
+
+	now := feed(d, time.Unix(0, 0), 2*time.Second, map[uint32]uint8{1: speech})
+	d.Evaluate(now)
+	assert.Len(t, d.ActiveSpeakers(), 1)
+
+	// No packets for a second counts as silence, the dominant speaker is kept
+	// but another speaker takes over quickly.
+	now = now.Add(time.Second)
+	feed(d, now, 2*time.Second, map[uint32]uint8{2: speech})
+	dominant, _ := d.DominantSpeaker()
+	assert.Equal(t, uint32(2), dominant)
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package activespeaker
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/logging"
+	"github.com/pion/rtp"
+)
+
+// AudioLevelURI is the URI of the client-to-mixer audio level header extension.
+const AudioLevelURI = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
+
+// Option can be used to configure the Interceptor.
+type Option func(i *Interceptor) error
+
+// WithDetector sets the Detector the interceptor feeds. By default every
+// interceptor created by the same factory shares the factory's Detector.
+func WithDetector(detector *Detector) Option {
+	return func(i *Interceptor) error {
+		i.detector = detector
+
+		return nil
+	}
+}
+
+// Log sets a logger for the interceptor.
+func Log(log logging.LeveledLogger) Option {
+	return func(i *Interceptor) error {
+		i.log = log
+
+		return nil
+	}
+}
+
+// SetNowFunc sets the function the interceptor uses to get a current timestamp.
+// This is mostly useful for testing.
+func SetNowFunc(now func() time.Time) Option {
+	return func(i *Interceptor) error {
+		i.now = now
+
+		return nil
+	}
+}
+
+// InterceptorFactory is a interceptor.Factory for an active speaker Interceptor.
+type InterceptorFactory struct {
+	opts     []Option
+	detector *Detector
+}
+
+// NewInterceptor returns a new InterceptorFactory. All interceptors it creates
+// feed the same Detector, so the dominant speaker is identified across
+// PeerConnections.
+func NewInterceptor(opts ...Option) (*InterceptorFactory, error) {
+	return &InterceptorFactory{
+		opts:     opts,
+		detector: NewDetector(),
+	}, nil
+}
+
+// Detector returns the Detector shared by the interceptors of the factory.
+func (f *InterceptorFactory) Detector() *Detector {
+	return f.detector
+}
+
+// NewInterceptor constructs a new Interceptor.
+func (f *InterceptorFactory) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	i := &Interceptor{
+		detector: f.detector,
+		now:      time.Now,
+		log:      logging.NewDefaultLoggerFactory().NewLogger("active_speaker"),
+	}
+
+	for _, opt := range f.opts {
+		if err := opt(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return i, nil
+}
+
+// Interceptor reads the audio level extension of every incoming audio stream
+// and feeds it to a Detector.
+type Interceptor struct {
+	interceptor.NoOp
+	detector *Detector
+	now      func() time.Time
+	log      logging.LeveledLogger
+}
+
+// BindRemoteStream lets you modify any incoming RTP packets. It is called once for per RemoteStream.
+// The returned method will be called once per rtp packet.
+func (i *Interceptor) BindRemoteStream(
+	info *interceptor.StreamInfo, reader interceptor.RTPReader,
+) interceptor.RTPReader {
+	if !strings.HasPrefix(strings.ToLower(info.MimeType), "audio/") {
+		return reader
+	}
+
+	var hdrExtID uint8
+	for _, e := range info.RTPHeaderExtensions {
+		if e.URI == AudioLevelURI {
+			hdrExtID = uint8(e.ID) //nolint:gosec // G115
+
+			break
+		}
+	}
+	if hdrExtID == 0 {
+		return reader
+	}
+
+	return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attr, err := reader.Read(b, a)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if attr == nil {
+			attr = make(interceptor.Attributes)
+		}
+		header, err := attr.GetRTPHeader(b[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if raw := header.GetExtension(hdrExtID); raw != nil {
+			ext := rtp.AudioLevelExtension{}
+			if err := ext.Unmarshal(raw); err != nil {
+				i.log.Debugf("failed to parse audio level extension: %v", err)
+			} else {
+				i.detector.AddLevel(header.SSRC, ext.Level, ext.Voice, i.now())
+			}
+		}
+
+		return n, attr, nil
+	})
+}
+
+// UnbindRemoteStream is called when the Stream is removed. It can be used to clean up any data related to that track.
+func (i *Interceptor) UnbindRemoteStream(info *interceptor.StreamInfo) {
+	i.detector.RemoveSpeaker(info.SSRC)
+}
@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package activespeaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/internal/test"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptor(t *testing.T) {
+	now := time.Unix(0, 0)
+	f, err := NewInterceptor(SetNowFunc(func() time.Time { return now }))
+	assert.NoError(t, err)
+
+	i, err := f.NewInterceptor("")
+	assert.NoError(t, err)
+
+	stream := test.NewMockStream(&interceptor.StreamInfo{
+		SSRC:                1,
+		MimeType:            "audio/opus",
+		RTPHeaderExtensions: []interceptor.RTPHeaderExtension{{URI: AudioLevelURI, ID: 1}},
+	}, i)
+	defer func() {
+		assert.NoError(t, stream.Close())
+	}()
+
+	changed := make(chan uint32, 1)
+	f.Detector().OnDominantSpeakerChange(func(ssrc uint32) {
+		changed <- ssrc
+	})
+
+	for seq := uint16(0); seq < 100; seq++ {
+		level, err := rtp.AudioLevelExtension{Level: speech, Voice: true}.Marshal()
+		assert.NoError(t, err)
+		pkt := &rtp.Packet{Header: rtp.Header{SSRC: 1, SequenceNumber: seq}}
+		assert.NoError(t, pkt.SetExtension(1, level))
+
+		stream.ReceiveRTP(pkt)
+		<-stream.ReadRTP()
+		now = now.Add(defaultFrameDuration)
+	}
+
+	select {
+	case ssrc := <-changed:
+		assert.Equal(t, uint32(1), ssrc)
+	default:
+		assert.FailNow(t, "no dominant speaker")
+	}
+
+	i.UnbindRemoteStream(&interceptor.StreamInfo{SSRC: 1})
+	_, ok := f.Detector().DominantSpeaker()
+	assert.False(t, ok)
+}
+
+func TestInterceptor_Video(t *testing.T) {
+	f, err := NewInterceptor()
+	assert.NoError(t, err)
+
+	i, err := f.NewInterceptor("")
+	assert.NoError(t, err)
+
+	reader := interceptor.RTPReaderFunc(func([]byte, interceptor.Attributes) (int, interceptor.Attributes, error) {
+		return 0, nil, nil
+	})
+	bound := i.BindRemoteStream(&interceptor.StreamInfo{
+		SSRC:                1,
+		MimeType:            "video/vp8",
+		RTPHeaderExtensions: []interceptor.RTPHeaderExtension{{URI: AudioLevelURI, ID: 1}},
+	}, reader)
+	_, _, err = bound.Read(nil, nil)
+	assert.NoError(t, err, "video streams are not parsed")
+}
@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+// Package impairment provides an interceptor that emulates network impairment
+// (loss, jitter, reordering, duplication and bandwidth limits) on the RTP/RTCP
+// path. All random decisions are drawn from a seeded source so runs are reproducible.
+package impairment
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// JitterDistribution selects how the random part of the delay is drawn.
+type JitterDistribution int
+
+const (
+	// JitterNone adds no random delay, only Schedule.Delay.
+	JitterNone JitterDistribution = iota
+	// JitterUniform draws the jitter uniformly from [-Jitter, +Jitter].
+	JitterUniform
+	// JitterPareto draws the jitter from a Pareto distribution with mean Jitter.
+	// The long tail makes it a good fit for emulating bufferbloat style spikes.
+	JitterPareto
+)
+
+// defaultParetoShape is used when Schedule.ParetoShape is not set.
+const defaultParetoShape = 2.5
+
+// GilbertElliott is the two state Markov loss model. The channel is either in
+// the Good or in the Bad state; each state has its own loss probability. Setting
+// PGoodToBad to zero disables the model.
+type GilbertElliott struct {
+	// PGoodToBad is the probability to move from Good to Bad per packet.
+	PGoodToBad float64
+	// PBadToGood is the probability to move from Bad to Good per packet.
+	PBadToGood float64
+	// LossGood is the loss probability while in the Good state.
+	LossGood float64
+	// LossBad is the loss probability while in the Bad state.
+	LossBad float64
+}
+
+// UniformLoss returns a GilbertElliott model that drops packets independently
+// with the given probability.
+func UniformLoss(p float64) GilbertElliott {
+	return GilbertElliott{LossGood: p}
+}
+
+// Schedule describes the impairment applied to packets. The zero value does not
+// modify the traffic.
+type Schedule struct {
+	// Loss is the loss model.
+	Loss GilbertElliott
+
+	// Delay is the fixed one way delay added to each packet.
+	Delay time.Duration
+	// Jitter is the amount of random delay; its meaning depends on Distribution.
+	Jitter time.Duration
+	// Distribution selects how the jitter is drawn.
+	Distribution JitterDistribution
+	// ParetoShape is the shape parameter of the Pareto distribution. Must be
+	// greater than one, defaults to 2.5.
+	ParetoShape float64
+
+	// ReorderProbability is the probability a packet skips the delay and
+	// overtakes the ones queued before it.
+	ReorderProbability float64
+	// DuplicateProbability is the probability a packet is delivered twice.
+	DuplicateProbability float64
+
+	// Bandwidth is the link capacity in bits per second. Zero means unlimited.
+	Bandwidth int
+	// QueueLimit is the longest the bandwidth limited queue may grow before new
+	// packets are tail dropped. Zero means unlimited.
+	QueueLimit time.Duration
+}
+
+// delivery is the outcome of impairing one packet. A nil slice means the
+// packet was dropped.
+type delivery []time.Time
+
+// model holds the state needed to impair a single packet flow.
+type model struct {
+	rng      *rand.Rand
+	schedule Schedule
+
+	bad          bool
+	linkFree     time.Time
+	lastDelivery time.Time
+}
+
+func newModel(seed int64, schedule Schedule) *model {
+	return &model{
+		rng:      rand.New(rand.NewSource(seed)), //nolint:gosec
+		schedule: schedule,
+	}
+}
+
+// lost advances the Gilbert-Elliott chain and reports whether the packet is lost.
+func (m *model) lost() bool {
+	loss := m.schedule.Loss
+	if m.bad {
+		if m.rng.Float64() < loss.PBadToGood {
+			m.bad = false
+		}
+	} else if loss.PGoodToBad > 0 && m.rng.Float64() < loss.PGoodToBad {
+		m.bad = true
+	}
+
+	p := loss.LossGood
+	if m.bad {
+		p = loss.LossBad
+	}
+
+	return p > 0 && m.rng.Float64() < p
+}
+
+func (m *model) jitter() time.Duration {
+	schedule := m.schedule
+	if schedule.Jitter <= 0 {
+		return 0
+	}
+
+	switch schedule.Distribution {
+	case JitterUniform:
+		return time.Duration((m.rng.Float64()*2 - 1) * float64(schedule.Jitter))
+	case JitterPareto:
+		shape := schedule.ParetoShape
+		if shape <= 1 {
+			shape = defaultParetoShape
+		}
+		// Scale so that the mean of the extra delay equals Jitter.
+		scale := float64(schedule.Jitter) * (shape - 1)
+		u := 1 - m.rng.Float64() // (0, 1]
+
+		return time.Duration(scale * (math.Pow(u, -1/shape) - 1))
+	default:
+		return 0
+	}
+}
+
+// impair decides the fate of a packet of size bytes sent at now.
+func (m *model) impair(now time.Time, size int) delivery {
+	if m.lost() {
+		return nil
+	}
+
+	schedule := m.schedule
+	departure := now
+	if schedule.Bandwidth > 0 {
+		if m.linkFree.After(departure) {
+			if schedule.QueueLimit > 0 && m.linkFree.Sub(departure) > schedule.QueueLimit {
+				return nil
+			}
+			departure = m.linkFree
+		}
+		departure = departure.Add(time.Duration(int64(size) * 8 * int64(time.Second) / int64(schedule.Bandwidth)))
+		m.linkFree = departure
+	}
+
+	reordered := schedule.ReorderProbability > 0 && m.rng.Float64() < schedule.ReorderProbability
+	arrival := departure
+	if !reordered {
+		delay := schedule.Delay + m.jitter()
+		if delay > 0 {
+			arrival = arrival.Add(delay)
+		}
+		// Jitter alone must not reorder packets, that is ReorderProbability's job.
+		if arrival.Before(m.lastDelivery) {
+			arrival = m.lastDelivery
+		}
+		m.lastDelivery = arrival
+	}
+
+	out := delivery{arrival}
+	if schedule.DuplicateProbability > 0 && m.rng.Float64() < schedule.DuplicateProbability {
+		out = append(out, arrival)
+	}
+
+	return out
+}
+
+// setSchedule replaces the schedule, keeping the channel and queue state.
+func (m *model) setSchedule(schedule Schedule) {
+	m.schedule = schedule
+	if schedule.Loss.PGoodToBad == 0 {
+		m.bad = false
+	}
+}
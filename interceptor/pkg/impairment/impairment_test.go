@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package impairment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModel_ZeroSchedule(t *testing.T) {
+	m := newModel(1, Schedule{})
+	now := time.Unix(0, 0)
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, delivery{now}, m.impair(now, 1200))
+	}
+}
+
+func TestModel_Deterministic(t *testing.T) {
+	schedule := Schedule{
+		Loss: GilbertElliott{
+			PGoodToBad: 0.05,
+			PBadToGood: 0.3,
+			LossGood:   0.01,
+			LossBad:    0.6,
+		},
+		Delay:                20 * time.Millisecond,
+		Jitter:               10 * time.Millisecond,
+		Distribution:         JitterPareto,
+		ReorderProbability:   0.02,
+		DuplicateProbability: 0.02,
+	}
+
+	run := func(seed int64) []delivery {
+		m := newModel(seed, schedule)
+		now := time.Unix(0, 0)
+		out := []delivery{}
+		for i := 0; i < 1000; i++ {
+			out = append(out, m.impair(now, 1000))
+			now = now.Add(time.Millisecond)
+		}
+
+		return out
+	}
+
+	assert.Equal(t, run(42), run(42))
+	assert.NotEqual(t, run(42), run(43))
+}
+
+func TestModel_GilbertElliott(t *testing.T) {
+	m := newModel(7, Schedule{Loss: GilbertElliott{
+		PGoodToBad: 0.01,
+		PBadToGood: 0.1,
+		LossBad:    1,
+	}})
+
+	now := time.Unix(0, 0)
+	lost, bursts, inBurst := 0, 0, false
+	const packets = 100000
+	for i := 0; i < packets; i++ {
+		if m.impair(now, 100) == nil {
+			lost++
+			if !inBurst {
+				bursts++
+			}
+			inBurst = true
+		} else {
+			inBurst = false
+		}
+	}
+
+	// Stationary probability of Bad is p/(p+r) = 0.01/0.11, mean burst is 1/r = 10.
+	assert.InDelta(t, 0.0909, float64(lost)/packets, 0.01)
+	assert.InDelta(t, 10, float64(lost)/float64(bursts), 1.5)
+}
+
+func TestModel_UniformLoss(t *testing.T) {
+	m := newModel(3, Schedule{Loss: UniformLoss(0.2)})
+
+	now := time.Unix(0, 0)
+	lost := 0
+	const packets = 100000
+	for i := 0; i < packets; i++ {
+		if m.impair(now, 100) == nil {
+			lost++
+		}
+	}
+	assert.InDelta(t, 0.2, float64(lost)/packets, 0.01)
+}
+
+func TestModel_Jitter(t *testing.T) {
+	for _, dist := range []JitterDistribution{JitterUniform, JitterPareto} {
+		m := newModel(5, Schedule{
+			Delay:        50 * time.Millisecond,
+			Jitter:       10 * time.Millisecond,
+			Distribution: dist,
+		})
+
+		now := time.Unix(0, 0)
+		last := time.Time{}
+		for i := 0; i < 1000; i++ {
+			d := m.impair(now, 100)
+			assert.Len(t, d, 1)
+			assert.False(t, d[0].Before(last), "jitter must not reorder")
+			assert.False(t, d[0].Before(now.Add(40*time.Millisecond)))
+			last = d[0]
+			now = now.Add(20 * time.Millisecond)
+		}
+	}
+}
+
+func TestModel_Bandwidth(t *testing.T) {
+	m := newModel(1, Schedule{
+		Bandwidth:  800000, // 100 bytes per millisecond
+		QueueLimit: 5 * time.Millisecond,
+	})
+
+	now := time.Unix(0, 0)
+	for i := 1; i <= 6; i++ {
+		assert.Equal(t, delivery{now.Add(time.Duration(i) * time.Millisecond)}, m.impair(now, 100))
+	}
+	// The queue is now 6ms long, over the limit.
+	assert.Nil(t, m.impair(now, 100))
+	assert.NotNil(t, m.impair(now.Add(2*time.Millisecond), 100))
+}
+
+func TestModel_ReorderAndDuplicate(t *testing.T) {
+	m := newModel(1, Schedule{
+		Delay:                time.Second,
+		ReorderProbability:   1,
+		DuplicateProbability: 1,
+	})
+
+	now := time.Unix(0, 0)
+	assert.Equal(t, delivery{now, now}, m.impair(now, 100))
+}
@@ -0,0 +1,328 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package impairment
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// InterceptorFactory is a interceptor.Factory for an impairment Interceptor.
+type InterceptorFactory struct {
+	opts []Option
+
+	mu           sync.Mutex
+	schedule     *Schedule
+	interceptors map[*Interceptor]struct{}
+}
+
+// NewInterceptor returns a new InterceptorFactory.
+func NewInterceptor(opts ...Option) (*InterceptorFactory, error) {
+	return &InterceptorFactory{
+		opts:         opts,
+		interceptors: map[*Interceptor]struct{}{},
+	}, nil
+}
+
+// NewInterceptor constructs a new Interceptor.
+func (f *InterceptorFactory) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	impairment := &Interceptor{
+		impairRTCP: true,
+		now:        time.Now,
+		log:        logging.NewDefaultLoggerFactory().NewLogger("impairment"),
+		close:      make(chan struct{}),
+	}
+
+	for _, opt := range f.opts {
+		if err := opt(impairment); err != nil {
+			return nil, err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.schedule != nil {
+		impairment.schedule = *f.schedule
+	}
+	impairment.model = newModel(impairment.seed, impairment.schedule)
+	impairment.scheduler = newScheduler(impairment.now)
+	impairment.onClose = func() {
+		f.mu.Lock()
+		delete(f.interceptors, impairment)
+		f.mu.Unlock()
+	}
+	f.interceptors[impairment] = struct{}{}
+
+	return impairment, nil
+}
+
+// SetSchedule changes the schedule of every interceptor created by the factory,
+// including the ones created later.
+func (f *InterceptorFactory) SetSchedule(schedule Schedule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.schedule = &schedule
+	for i := range f.interceptors {
+		i.SetSchedule(schedule)
+	}
+}
+
+// Interceptor applies a Schedule of network impairment to the packets that go through it.
+// Packets that are delayed are copied and delivered from a background goroutine.
+type Interceptor struct {
+	interceptor.NoOp
+
+	path       Path
+	impairRTCP bool
+	seed       int64
+	schedule   Schedule
+	now        func() time.Time
+	log        logging.LeveledLogger
+
+	mu        sync.Mutex
+	model     *model
+	scheduler *scheduler
+
+	onClose   func()
+	closeOnce sync.Once
+	close     chan struct{}
+}
+
+// SetSchedule changes the impairment schedule at runtime. Packets that are
+// already queued keep the delivery time they were given.
+func (i *Interceptor) SetSchedule(schedule Schedule) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.model.setSchedule(schedule)
+}
+
+// Schedule returns the current impairment schedule.
+func (i *Interceptor) Schedule() Schedule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.model.schedule
+}
+
+func (i *Interceptor) impair(size int) delivery {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.model.impair(i.now(), size)
+}
+
+// deliver runs fn once per copy of the packet, either immediately or at its
+// arrival time.
+func (i *Interceptor) deliver(arrivals delivery, fn func()) {
+	now := i.now()
+	for _, at := range arrivals {
+		if at.After(now) {
+			i.scheduler.schedule(at, fn)
+		} else {
+			fn()
+		}
+	}
+}
+
+// BindRTCPWriter lets you modify any outgoing RTCP packets. It is called once per PeerConnection. The returned method
+// will be called once per packet batch.
+func (i *Interceptor) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	if i.path != PathWrite || !i.impairRTCP {
+		return writer
+	}
+
+	return interceptor.RTCPWriterFunc(func(pkts []rtcp.Packet, attributes interceptor.Attributes) (int, error) {
+		size := 0
+		for _, pkt := range pkts {
+			size += pkt.MarshalSize()
+		}
+
+		arrivals := i.impair(size)
+		if len(arrivals) == 1 && !arrivals[0].After(i.now()) {
+			return writer.Write(pkts, attributes)
+		}
+
+		i.deliver(arrivals, func() {
+			if _, err := writer.Write(pkts, attributes); err != nil {
+				i.log.Warnf("failed writing delayed RTCP: %v", err)
+			}
+		})
+
+		return size, nil
+	})
+}
+
+// BindLocalStream lets you modify any outgoing RTP packets. It is called once for per LocalStream. The returned method
+// will be called once per rtp packet.
+func (i *Interceptor) BindLocalStream(
+	_ *interceptor.StreamInfo, writer interceptor.RTPWriter,
+) interceptor.RTPWriter {
+	if i.path != PathWrite {
+		return writer
+	}
+
+	return interceptor.RTPWriterFunc(func(
+		header *rtp.Header, payload []byte, attributes interceptor.Attributes,
+	) (int, error) {
+		size := header.MarshalSize() + len(payload)
+
+		arrivals := i.impair(size)
+		if len(arrivals) == 1 && !arrivals[0].After(i.now()) {
+			return writer.Write(header, payload, attributes)
+		}
+
+		// The caller may reuse its buffers once Write returns.
+		hdr := header.Clone()
+		buf := append([]byte{}, payload...)
+		i.deliver(arrivals, func() {
+			if _, err := writer.Write(&hdr, buf, attributes); err != nil {
+				i.log.Warnf("failed writing delayed RTP: %v", err)
+			}
+		})
+
+		return size, nil
+	})
+}
+
+// BindRemoteStream lets you modify any incoming RTP packets.
+// It is called once for per RemoteStream. The returned method
+// will be called once per rtp packet.
+func (i *Interceptor) BindRemoteStream(
+	_ *interceptor.StreamInfo, reader interceptor.RTPReader,
+) interceptor.RTPReader {
+	if i.path != PathRead {
+		return reader
+	}
+
+	in := i.newInbound(reader.Read)
+
+	return interceptor.RTPReaderFunc(in.read)
+}
+
+// BindRTCPReader lets you modify any incoming RTCP packets. It is called once per sender/receiver, however this might
+// change in the future. The returned method will be called once per packet batch.
+func (i *Interceptor) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	if i.path != PathRead || !i.impairRTCP {
+		return reader
+	}
+
+	in := i.newInbound(reader.Read)
+
+	return interceptor.RTCPReaderFunc(in.read)
+}
+
+// Close closes the interceptor. Packets that are still queued are discarded.
+func (i *Interceptor) Close() error {
+	i.closeOnce.Do(func() {
+		close(i.close)
+		i.scheduler.stop()
+		if i.onClose != nil {
+			i.onClose()
+		}
+	})
+
+	return nil
+}
+
+type inboundPacket struct {
+	buf        []byte
+	attributes interceptor.Attributes
+}
+
+// inbound impairs a pull based reader. A goroutine drains the upstream reader
+// as fast as possible and queues the packets that survive until they are due.
+type inbound struct {
+	impairment *Interceptor
+	upstream   func([]byte, interceptor.Attributes) (int, interceptor.Attributes, error)
+
+	startOnce sync.Once
+	mu        sync.Mutex
+	packets   []inboundPacket
+	err       error
+	notify    chan struct{}
+}
+
+func (i *Interceptor) newInbound(
+	upstream func([]byte, interceptor.Attributes) (int, interceptor.Attributes, error),
+) *inbound {
+	return &inbound{
+		impairment: i,
+		upstream:   upstream,
+		notify:     make(chan struct{}, 1),
+	}
+}
+
+func (in *inbound) push(pkt inboundPacket, err error) {
+	in.mu.Lock()
+	if err != nil {
+		in.err = err
+	} else {
+		in.packets = append(in.packets, pkt)
+	}
+	in.mu.Unlock()
+
+	select {
+	case in.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (in *inbound) pump(size int) {
+	buf := make([]byte, size)
+	for {
+		n, attributes, err := in.upstream(buf, interceptor.Attributes{})
+		if err != nil {
+			in.push(inboundPacket{}, err)
+
+			return
+		}
+
+		pkt := inboundPacket{buf: append([]byte{}, buf[:n]...), attributes: attributes}
+		in.impairment.deliver(in.impairment.impair(n), func() {
+			in.push(pkt, nil)
+		})
+	}
+}
+
+func (in *inbound) read(b []byte, _ interceptor.Attributes) (int, interceptor.Attributes, error) {
+	in.startOnce.Do(func() {
+		go in.pump(len(b))
+	})
+
+	for {
+		in.mu.Lock()
+		if len(in.packets) > 0 {
+			pkt := in.packets[0]
+			in.packets = in.packets[1:]
+			in.mu.Unlock()
+
+			if len(pkt.buf) > len(b) {
+				return 0, nil, io.ErrShortBuffer
+			}
+
+			return copy(b, pkt.buf), pkt.attributes, nil
+		}
+		err := in.err
+		in.mu.Unlock()
+
+		if err != nil {
+			return 0, nil, err
+		}
+
+		select {
+		case <-in.notify:
+		case <-in.impairment.close:
+			return 0, nil, io.EOF
+		}
+	}
+}
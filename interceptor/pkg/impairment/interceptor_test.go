@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package impairment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/internal/test"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptor_WritePassThrough(t *testing.T) {
+	f, err := NewInterceptor()
+	assert.NoError(t, err)
+
+	i, err := f.NewInterceptor("")
+	assert.NoError(t, err)
+
+	stream := test.NewMockStream(&interceptor.StreamInfo{SSRC: 1}, i)
+	defer func() {
+		assert.NoError(t, stream.Close())
+	}()
+
+	for seq := uint16(0); seq < 10; seq++ {
+		assert.NoError(t, stream.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: seq}}))
+		select {
+		case p := <-stream.WrittenRTP():
+			assert.Equal(t, seq, p.SequenceNumber)
+		default:
+			assert.FailNow(t, "packet should be written synchronously")
+		}
+	}
+}
+
+func TestInterceptor_WriteDelayAndRuntimeChange(t *testing.T) {
+	f, err := NewInterceptor(WithSchedule(Schedule{Delay: 50 * time.Millisecond}))
+	assert.NoError(t, err)
+
+	i, err := f.NewInterceptor("")
+	assert.NoError(t, err)
+
+	stream := test.NewMockStream(&interceptor.StreamInfo{SSRC: 1}, i)
+	defer func() {
+		assert.NoError(t, stream.Close())
+	}()
+
+	start := time.Now()
+	assert.NoError(t, stream.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1}}))
+	assert.NoError(t, stream.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 1}}))
+
+	select {
+	case p := <-stream.WrittenRTP():
+		assert.Equal(t, uint16(1), p.SequenceNumber)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	case <-time.After(time.Second):
+		assert.FailNow(t, "delayed packet not written")
+	}
+
+	select {
+	case pkts := <-stream.WrittenRTCP():
+		assert.Len(t, pkts, 1)
+	case <-time.After(time.Second):
+		assert.FailNow(t, "delayed RTCP not written")
+	}
+
+	f.SetSchedule(Schedule{Loss: UniformLoss(1)})
+	assert.Equal(t, Schedule{Loss: UniformLoss(1)}, i.(*Interceptor).Schedule()) //nolint:forcetypeassert
+
+	assert.NoError(t, stream.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2}}))
+	select {
+	case <-stream.WrittenRTP():
+		assert.FailNow(t, "packet should be dropped")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestInterceptor_Read(t *testing.T) {
+	f, err := NewInterceptor(
+		OnPath(PathRead),
+		Seed(1),
+		WithSchedule(Schedule{Delay: 10 * time.Millisecond, DuplicateProbability: 1}),
+	)
+	assert.NoError(t, err)
+
+	i, err := f.NewInterceptor("")
+	assert.NoError(t, err)
+
+	stream := test.NewMockStream(&interceptor.StreamInfo{SSRC: 1}, i)
+	defer func() {
+		assert.NoError(t, stream.Close())
+	}()
+
+	stream.ReceiveRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 7}})
+	for n := 0; n < 2; n++ {
+		select {
+		case p := <-stream.ReadRTP():
+			assert.NoError(t, p.Err)
+			assert.Equal(t, uint16(7), p.Packet.SequenceNumber)
+		case <-time.After(time.Second):
+			assert.FailNow(t, "duplicated packet not read")
+		}
+	}
+
+	// Writes are untouched on the read path.
+	assert.NoError(t, stream.WriteRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1}}))
+	select {
+	case <-stream.WrittenRTP():
+	default:
+		assert.FailNow(t, "write should not be impaired")
+	}
+}
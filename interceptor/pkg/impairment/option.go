@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package impairment
+
+import (
+	"time"
+
+	"github.com/pion/logging"
+)
+
+// Path selects the direction the impairment is applied to.
+type Path int
+
+const (
+	// PathWrite impairs outgoing packets (local streams and the RTCP writer).
+	PathWrite Path = iota
+	// PathRead impairs incoming packets (remote streams and RTCP readers).
+	PathRead
+)
+
+// Option can be used to configure the impairment Interceptor.
+type Option func(i *Interceptor) error
+
+// WithSchedule sets the initial impairment schedule.
+func WithSchedule(schedule Schedule) Option {
+	return func(i *Interceptor) error {
+		i.schedule = schedule
+
+		return nil
+	}
+}
+
+// Seed sets the seed of the random source. Two interceptors with the same seed
+// and schedule take the same decisions for the same packet sequence.
+func Seed(seed int64) Option {
+	return func(i *Interceptor) error {
+		i.seed = seed
+
+		return nil
+	}
+}
+
+// OnPath sets the direction the impairment is applied to, PathWrite by default.
+func OnPath(path Path) Option {
+	return func(i *Interceptor) error {
+		i.path = path
+
+		return nil
+	}
+}
+
+// ImpairRTCP sets whether RTCP is impaired in addition to RTP, true by default.
+func ImpairRTCP(enabled bool) Option {
+	return func(i *Interceptor) error {
+		i.impairRTCP = enabled
+
+		return nil
+	}
+}
+
+// Log sets a logger for the interceptor.
+func Log(log logging.LeveledLogger) Option {
+	return func(i *Interceptor) error {
+		i.log = log
+
+		return nil
+	}
+}
+
+// SetNowFunc sets the function the interceptor uses to get a current timestamp.
+// This is mostly useful for testing.
+func SetNowFunc(now func() time.Time) Option {
+	return func(i *Interceptor) error {
+		i.now = now
+
+		return nil
+	}
+}
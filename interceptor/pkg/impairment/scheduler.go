@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package impairment
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+type task struct {
+	at  time.Time
+	seq uint64
+	fn  func()
+}
+
+type taskQueue []task
+
+func (q taskQueue) Len() int { return len(q) }
+
+func (q taskQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+
+	return q[i].at.Before(q[j].at)
+}
+
+func (q taskQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *taskQueue) Push(x interface{}) {
+	t, ok := x.(task)
+	if !ok {
+		return
+	}
+	*q = append(*q, t)
+}
+
+func (q *taskQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	t := old[n-1]
+	*q = old[:n-1]
+
+	return t
+}
+
+// scheduler runs delayed deliveries in order of their due time. Tasks due at
+// the same time run in the order they were scheduled.
+type scheduler struct {
+	mu    sync.Mutex
+	queue taskQueue
+	seq   uint64
+	now   func() time.Time
+
+	wake  chan struct{}
+	close chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newScheduler(now func() time.Time) *scheduler {
+	s := &scheduler{
+		now:   now,
+		wake:  make(chan struct{}, 1),
+		close: make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.loop()
+
+	return s
+}
+
+func (s *scheduler) schedule(at time.Time, fn func()) {
+	s.mu.Lock()
+	s.seq++
+	heap.Push(&s.queue, task{at: at, seq: s.seq, fn: fn})
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// due pops every task that is due and returns the time until the next one.
+func (s *scheduler) due() ([]task, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var tasks []task
+	for len(s.queue) > 0 && !s.queue[0].at.After(now) {
+		t, _ := heap.Pop(&s.queue).(task)
+		tasks = append(tasks, t)
+	}
+	if len(s.queue) == 0 {
+		return tasks, 0, false
+	}
+
+	return tasks, s.queue[0].at.Sub(now), true
+}
+
+func (s *scheduler) loop() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		tasks, wait, pending := s.due()
+		for _, t := range tasks {
+			t.fn()
+		}
+
+		var timerC <-chan time.Time
+		if pending {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(wait)
+			timerC = timer.C
+		}
+
+		select {
+		case <-timerC:
+		case <-s.wake:
+		case <-s.close:
+			return
+		}
+	}
+}
+
+// stop terminates the loop, pending tasks are discarded.
+func (s *scheduler) stop() {
+	select {
+	case <-s.close:
+	default:
+		close(s.close)
+	}
+	s.wg.Wait()
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pion/interceptor"
+)
+
+type latencyKeyType int
+
+const latencyKey latencyKeyType = iota
+
+// latencyWindowSize is the number of recent measurements percentiles are computed over.
+const latencyWindowSize = 256
+
+// SetLatency attaches an end-to-end latency measurement to the attributes of a
+// received RTP packet. The stats interceptor aggregates it into
+// InboundRTPStreamStats.Latency, so the interceptor measuring the latency must be
+// registered before the stats interceptor.
+func SetLatency(attributes interceptor.Attributes, latency time.Duration) {
+	attributes.Set(latencyKey, latency)
+}
+
+func getLatency(attributes interceptor.Attributes) (time.Duration, bool) {
+	if attributes == nil {
+		return 0, false
+	}
+	latency, ok := attributes.Get(latencyKey).(time.Duration)
+
+	return latency, ok
+}
+
+// LatencyStats summarizes the end-to-end (glass-to-glass) latency of the most
+// recent frames of a stream.
+type LatencyStats struct {
+	Measurements uint64
+	Latest       time.Duration
+	Min          time.Duration
+	Max          time.Duration
+	P50          time.Duration
+	P95          time.Duration
+	P99          time.Duration
+}
+
+// String returns a string representation of LatencyStats.
+func (s LatencyStats) String() string {
+	out := fmt.Sprintf("\tLatencyMeasurements: %v\n", s.Measurements)
+	out += fmt.Sprintf("\tLatency: %v\n", s.Latest)
+	out += fmt.Sprintf("\tLatencyMin: %v\n", s.Min)
+	out += fmt.Sprintf("\tLatencyMax: %v\n", s.Max)
+	out += fmt.Sprintf("\tLatencyP50: %v\n", s.P50)
+	out += fmt.Sprintf("\tLatencyP95: %v\n", s.P95)
+	out += fmt.Sprintf("\tLatencyP99: %v\n", s.P99)
+
+	return out
+}
+
+// latencyWindow keeps the last latencyWindowSize measurements.
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+func (w *latencyWindow) add(latency time.Duration) {
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, latency)
+
+		return
+	}
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+func (w *latencyWindow) stats(measurements uint64, latest time.Duration) LatencyStats {
+	sorted := append([]time.Duration{}, w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile := func(p int) time.Duration {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+
+	return LatencyStats{
+		Measurements: measurements,
+		Latest:       latest,
+		Min:          sorted[0],
+		Max:          sorted[len(sorted)-1],
+		P50:          percentile(50),
+		P95:          percentile(95),
+		P99:          percentile(99),
+	}
+}
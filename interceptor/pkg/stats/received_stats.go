@@ -34,6 +34,7 @@ type InboundRTPStreamStats struct {
 	FIRCount                    uint32
 	PLICount                    uint32
 	NACKCount                   uint32
+	Latency                     LatencyStats
 }
 
 // String returns a string representation of InboundRTPStreamStats.
@@ -46,6 +47,7 @@ func (s InboundRTPStreamStats) String() string {
 	out += fmt.Sprintf("\tFIRCount: %v\n", s.FIRCount)
 	out += fmt.Sprintf("\tPLICount: %v\n", s.PLICount)
 	out += fmt.Sprintf("\tNACKCount: %v\n", s.NACKCount)
+	out += s.Latency.String()
 
 	return out
 }
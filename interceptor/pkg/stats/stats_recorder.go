@@ -34,6 +34,8 @@ type internalStats struct {
 	inboundLastArrival            time.Time
 	inboundLastTransit            int
 
+	inboundLatency latencyWindow
+
 	remoteInboundFirstSequenceNumberInitialized bool
 	remoteInboundFirstSequenceNumber            int64
 
@@ -154,6 +156,13 @@ func (r *recorder) recordIncomingRTP(latestStats internalStats, incoming *incomi
 	latestStats.HeaderBytesReceived += uint64(incoming.header.MarshalSize())                 //nolint:gosec // G115
 	latestStats.BytesReceived += uint64(incoming.header.MarshalSize() + incoming.payloadLen) //nolint:gosec // G115
 
+	if latency, ok := getLatency(incoming.attr); ok {
+		latestStats.inboundLatency.add(latency)
+		latestStats.InboundRTPStreamStats.Latency = latestStats.inboundLatency.stats(
+			latestStats.InboundRTPStreamStats.Latency.Measurements+1, latency,
+		)
+	}
+
 	return latestStats
 }
 
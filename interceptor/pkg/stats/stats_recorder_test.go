@@ -849,6 +849,33 @@ func TestStatsRecorder_DLRR_Precision(t *testing.T) {
 	assert.Equal(t, int64(s.RemoteOutboundRTPStreamStats.RoundTripTime), int64(-9223372036854775808))
 }
 
+func TestStatsRecorder_Latency(t *testing.T) {
+	recorder := newRecorder(1234, 90_000)
+	recorder.Start()
+
+	for i := 1; i <= 100; i++ {
+		attributes := interceptor.Attributes{}
+		SetLatency(attributes, time.Duration(i)*time.Millisecond)
+		recorder.QueueIncomingRTP(time.Now(), mustMarshalRTP(t, rtp.Packet{
+			Header: rtp.Header{SSRC: 1234, SequenceNumber: uint16(i)},
+		}), attributes)
+	}
+	// Packets without a measurement don't change the latency stats.
+	recorder.QueueIncomingRTP(time.Now(), mustMarshalRTP(t, rtp.Packet{
+		Header: rtp.Header{SSRC: 1234, SequenceNumber: 101},
+	}), interceptor.Attributes{})
+
+	assert.Equal(t, LatencyStats{
+		Measurements: 100,
+		Latest:       100 * time.Millisecond,
+		Min:          time.Millisecond,
+		Max:          100 * time.Millisecond,
+		P50:          50 * time.Millisecond,
+		P95:          95 * time.Millisecond,
+		P99:          99 * time.Millisecond,
+	}, recorder.GetStats().InboundRTPStreamStats.Latency)
+}
+
 func TestGetStatsNotBlocking(t *testing.T) {
 	r := newRecorder(0, 90_000)
 
@@ -106,6 +106,17 @@ const (
 	AttrRequestedAddressFamily AttrType = 0x0017 // REQUESTED-ADDRESS-FAMILY
 )
 
+// Attributes from RFC 7635 STUN Extension for Third-Party Authorization.
+const (
+	AttrAccessToken             AttrType = 0x001B // ACCESS-TOKEN
+	AttrThirdPartyAuthorization AttrType = 0x802E // THIRD-PARTY-AUTHORIZATION
+)
+
+// Attributes from RFC 8016 Mobility with TURN.
+const (
+	AttrMobilityTicket AttrType = 0x8030 // MOBILITY-TICKET
+)
+
 // Attributes from An Origin Attribute for the STUN Protocol.
 const (
 	AttrOrigin AttrType = 0x802F
@@ -120,6 +131,12 @@ const (
 	AttrAlternateDomain        AttrType = 0x8003 // ALTERNATE-DOMAIN
 )
 
+// Attributes from RFC 8656 TURN.
+const (
+	AttrAdditionalAddressFamily AttrType = 0x8000 // ADDITIONAL-ADDRESS-FAMILY
+	AttrAddressErrorCode        AttrType = 0x8001 // ADDRESS-ERROR-CODE
+)
+
 // Value returns uint16 representation of attribute type.
 func (t AttrType) Value() uint16 {
 	return uint16(t)
@@ -127,37 +144,42 @@ func (t AttrType) Value() uint16 {
 
 func attrNames() map[AttrType]string {
 	return map[AttrType]string{
-		AttrMappedAddress:          "MAPPED-ADDRESS",
-		AttrUsername:               "USERNAME",
-		AttrErrorCode:              "ERROR-CODE",
-		AttrMessageIntegrity:       "MESSAGE-INTEGRITY",
-		AttrUnknownAttributes:      "UNKNOWN-ATTRIBUTES",
-		AttrRealm:                  "REALM",
-		AttrNonce:                  "NONCE",
-		AttrXORMappedAddress:       "XOR-MAPPED-ADDRESS",
-		AttrSoftware:               "SOFTWARE",
-		AttrAlternateServer:        "ALTERNATE-SERVER",
-		AttrFingerprint:            "FINGERPRINT",
-		AttrPriority:               "PRIORITY",
-		AttrUseCandidate:           "USE-CANDIDATE",
-		AttrICEControlled:          "ICE-CONTROLLED",
-		AttrICEControlling:         "ICE-CONTROLLING",
-		AttrChannelNumber:          "CHANNEL-NUMBER",
-		AttrLifetime:               "LIFETIME",
-		AttrXORPeerAddress:         "XOR-PEER-ADDRESS",
-		AttrData:                   "DATA",
-		AttrXORRelayedAddress:      "XOR-RELAYED-ADDRESS",
-		AttrEvenPort:               "EVEN-PORT",
-		AttrRequestedTransport:     "REQUESTED-TRANSPORT",
-		AttrDontFragment:           "DONT-FRAGMENT",
-		AttrReservationToken:       "RESERVATION-TOKEN",
-		AttrConnectionID:           "CONNECTION-ID",
-		AttrRequestedAddressFamily: "REQUESTED-ADDRESS-FAMILY",
-		AttrMessageIntegritySHA256: "MESSAGE-INTEGRITY-SHA256",
-		AttrPasswordAlgorithm:      "PASSWORD-ALGORITHM",
-		AttrUserhash:               "USERHASH",
-		AttrPasswordAlgorithms:     "PASSWORD-ALGORITHMS",
-		AttrAlternateDomain:        "ALTERNATE-DOMAIN",
+		AttrMappedAddress:           "MAPPED-ADDRESS",
+		AttrUsername:                "USERNAME",
+		AttrErrorCode:               "ERROR-CODE",
+		AttrMessageIntegrity:        "MESSAGE-INTEGRITY",
+		AttrUnknownAttributes:       "UNKNOWN-ATTRIBUTES",
+		AttrRealm:                   "REALM",
+		AttrNonce:                   "NONCE",
+		AttrXORMappedAddress:        "XOR-MAPPED-ADDRESS",
+		AttrSoftware:                "SOFTWARE",
+		AttrAlternateServer:         "ALTERNATE-SERVER",
+		AttrFingerprint:             "FINGERPRINT",
+		AttrPriority:                "PRIORITY",
+		AttrUseCandidate:            "USE-CANDIDATE",
+		AttrICEControlled:           "ICE-CONTROLLED",
+		AttrICEControlling:          "ICE-CONTROLLING",
+		AttrChannelNumber:           "CHANNEL-NUMBER",
+		AttrLifetime:                "LIFETIME",
+		AttrXORPeerAddress:          "XOR-PEER-ADDRESS",
+		AttrData:                    "DATA",
+		AttrXORRelayedAddress:       "XOR-RELAYED-ADDRESS",
+		AttrEvenPort:                "EVEN-PORT",
+		AttrRequestedTransport:      "REQUESTED-TRANSPORT",
+		AttrDontFragment:            "DONT-FRAGMENT",
+		AttrReservationToken:        "RESERVATION-TOKEN",
+		AttrConnectionID:            "CONNECTION-ID",
+		AttrRequestedAddressFamily:  "REQUESTED-ADDRESS-FAMILY",
+		AttrMessageIntegritySHA256:  "MESSAGE-INTEGRITY-SHA256",
+		AttrPasswordAlgorithm:       "PASSWORD-ALGORITHM",
+		AttrUserhash:                "USERHASH",
+		AttrPasswordAlgorithms:      "PASSWORD-ALGORITHMS",
+		AttrAlternateDomain:         "ALTERNATE-DOMAIN",
+		AttrAccessToken:             "ACCESS-TOKEN",
+		AttrThirdPartyAuthorization: "THIRD-PARTY-AUTHORIZATION",
+		AttrAdditionalAddressFamily: "ADDITIONAL-ADDRESS-FAMILY",
+		AttrAddressErrorCode:        "ADDRESS-ERROR-CODE",
+		AttrMobilityTicket:          "MOBILITY-TICKET",
 	}
 }
 
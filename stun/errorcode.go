@@ -137,6 +137,13 @@ const (
 	CodePeerAddrFamilyMismatch ErrorCode = 443 // Peer Address Family Mismatch
 )
 
+// Error codes from RFC 8016.
+//
+// RFC 8016 Section 5.
+const (
+	CodeMobilityForbidden ErrorCode = 405 // Mobility Forbidden
+)
+
 //nolint:gochecknoglobals
 var errorReasons = map[ErrorCode][]byte{
 	CodeTryAlternate:     []byte("Try Alternate"),
@@ -162,4 +169,7 @@ var errorReasons = map[ErrorCode][]byte{
 	// RFC 6156.
 	CodeAddrFamilyNotSupported: []byte("Address Family not Supported"),
 	CodePeerAddrFamilyMismatch: []byte("Peer Address Family Mismatch"),
+
+	// RFC 8016.
+	CodeMobilityForbidden: []byte("Mobility Forbidden"),
 }
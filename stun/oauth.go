@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package stun
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"time"
+)
+
+// AccessToken represents ACCESS-TOKEN attribute, which carries an OAuth
+// access token in place of a password. USERNAME is then the key ID of the
+// token and MESSAGE-INTEGRITY is computed with its mac_key.
+//
+// RFC 7635 Section 6.2.
+type AccessToken []byte
+
+// AddTo adds ACCESS-TOKEN to message.
+func (t AccessToken) AddTo(m *Message) error {
+	m.Add(AttrAccessToken, t)
+
+	return nil
+}
+
+// GetFrom decodes ACCESS-TOKEN from message.
+func (t *AccessToken) GetFrom(m *Message) error {
+	v, err := m.Get(AttrAccessToken)
+	if err != nil {
+		return err
+	}
+	*t = v
+
+	return nil
+}
+
+// ThirdPartyAuthorization represents THIRD-PARTY-AUTHORIZATION attribute,
+// which a server supporting access tokens adds to its 401 responses. It holds
+// the server name of the authorization server issuing the tokens.
+//
+// RFC 7635 Section 6.1.
+type ThirdPartyAuthorization []byte
+
+// NewThirdPartyAuthorization returns ThirdPartyAuthorization from the
+// server name of the authorization server.
+func NewThirdPartyAuthorization(server string) ThirdPartyAuthorization {
+	return ThirdPartyAuthorization(server)
+}
+
+func (a ThirdPartyAuthorization) String() string {
+	return string(a)
+}
+
+// AddTo adds THIRD-PARTY-AUTHORIZATION to message.
+func (a ThirdPartyAuthorization) AddTo(m *Message) error {
+	m.Add(AttrThirdPartyAuthorization, a)
+
+	return nil
+}
+
+// GetFrom decodes THIRD-PARTY-AUTHORIZATION from message.
+func (a *ThirdPartyAuthorization) GetFrom(m *Message) error {
+	v, err := m.Get(AttrThirdPartyAuthorization)
+	if err != nil {
+		return err
+	}
+	*a = v
+
+	return nil
+}
+
+// ErrAccessTokenInvalid means that a self-contained access token could not
+// be decoded or decrypted.
+var ErrAccessTokenInvalid = errors.New("invalid access token")
+
+// Token is the content of a self-contained access token, which the
+// authorization server encrypts with a key shared with the STUN server.
+//
+// RFC 7635 Section 6.2.
+type Token struct {
+	// MACKey is the key of MESSAGE-INTEGRITY in requests with the token
+	MACKey []byte
+
+	// Timestamp is when the token was issued, it is valid for Lifetime
+	Timestamp time.Time
+	Lifetime  time.Duration
+}
+
+const (
+	tokenLengthSize    = 2
+	tokenTimestampSize = 8
+	tokenLifetimeSize  = 4
+
+	// The timestamp has 48 bits of seconds and 16 bits of 1/64000 seconds.
+	tokenTimestampFraction = 64000
+)
+
+// newTokenAEAD returns AES-GCM with the key, which is AEAD_AES_128_GCM or
+// AEAD_AES_256_GCM for 16 and 32 byte keys.
+func newTokenAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals the token with key into a self-contained ACCESS-TOKEN. The
+// STUN server name is the associated data of the encryption.
+func (t Token) Encrypt(key []byte, serverName string) (AccessToken, error) {
+	aead, err := newTokenAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, tokenLengthSize+len(t.MACKey)+tokenTimestampSize+tokenLifetimeSize)
+	bin.PutUint16(plaintext, uint16(len(t.MACKey))) //nolint:gosec // G115
+	offset := tokenLengthSize + copy(plaintext[tokenLengthSize:], t.MACKey)
+	fraction := t.Timestamp.Nanosecond() / (int(time.Second) / tokenTimestampFraction)
+	bin.PutUint64(plaintext[offset:], uint64(t.Timestamp.Unix())<<16|uint64(fraction)) //nolint:gosec // G115
+	offset += tokenTimestampSize
+	bin.PutUint32(plaintext[offset:], uint32(t.Lifetime/time.Second)) //nolint:gosec // G115
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, tokenLengthSize, tokenLengthSize+len(nonce)+len(plaintext)+aead.Overhead())
+	bin.PutUint16(token, uint16(len(nonce))) //nolint:gosec // G115
+	token = append(token, nonce...)
+
+	return aead.Seal(token, nonce, plaintext, []byte(serverName)), nil
+}
+
+// Decrypt opens the self-contained ACCESS-TOKEN with key, serverName must be
+// the one it was encrypted for.
+func (t AccessToken) Decrypt(key []byte, serverName string) (Token, error) {
+	aead, err := newTokenAEAD(key)
+	if err != nil {
+		return Token{}, err
+	}
+
+	if len(t) < tokenLengthSize {
+		return Token{}, ErrAccessTokenInvalid
+	}
+	nonceLength := int(bin.Uint16(t))
+	if nonceLength != aead.NonceSize() || len(t) < tokenLengthSize+nonceLength {
+		return Token{}, ErrAccessTokenInvalid
+	}
+	nonce := t[tokenLengthSize : tokenLengthSize+nonceLength]
+
+	plaintext, err := aead.Open(nil, nonce, t[tokenLengthSize+nonceLength:], []byte(serverName))
+	if err != nil || len(plaintext) < tokenLengthSize {
+		return Token{}, ErrAccessTokenInvalid
+	}
+	keyLength := int(bin.Uint16(plaintext))
+	if len(plaintext) != tokenLengthSize+keyLength+tokenTimestampSize+tokenLifetimeSize {
+		return Token{}, ErrAccessTokenInvalid
+	}
+	offset := tokenLengthSize + keyLength
+	timestamp := bin.Uint64(plaintext[offset:])
+	offset += tokenTimestampSize
+	lifetime := bin.Uint32(plaintext[offset:])
+
+	fraction := time.Duration(timestamp&0xFFFF) * time.Second / tokenTimestampFraction
+
+	return Token{
+		MACKey:    plaintext[tokenLengthSize : offset-tokenTimestampSize],
+		Timestamp: time.Unix(int64(timestamp>>16), int64(fraction)), //nolint:gosec // G115
+		Lifetime:  time.Duration(lifetime) * time.Second,
+	}, nil
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package stun
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAccessToken(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	token := Token{
+		MACKey:    []byte("mac-key-mac-key-mac-"),
+		Timestamp: time.Unix(1700000000, int64(500*time.Millisecond)),
+		Lifetime:  time.Hour,
+	}
+
+	accessToken, err := token.Encrypt(key, "example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("AddTo", func(t *testing.T) {
+		msg := New()
+		if err := msg.Build(BindingRequest, accessToken); err != nil {
+			t.Fatal(err)
+		}
+		var got AccessToken
+		if err := got.GetFrom(msg); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, accessToken) {
+			t.Error("ACCESS-TOKEN mismatch")
+		}
+		if err := got.GetFrom(New()); !errors.Is(err, ErrAttributeNotFound) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+	t.Run("Decrypt", func(t *testing.T) {
+		got, err := accessToken.Decrypt(key, "example.org")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.MACKey, token.MACKey) {
+			t.Error("mac_key mismatch")
+		}
+		if !got.Timestamp.Equal(token.Timestamp) {
+			t.Errorf("Timestamp %v != %v", got.Timestamp, token.Timestamp)
+		}
+		if got.Lifetime != token.Lifetime {
+			t.Errorf("Lifetime %v != %v", got.Lifetime, token.Lifetime)
+		}
+	})
+	t.Run("WrongServerName", func(t *testing.T) {
+		if _, err := accessToken.Decrypt(key, "example.com"); !errors.Is(err, ErrAccessTokenInvalid) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+	t.Run("WrongKey", func(t *testing.T) {
+		if _, err := accessToken.Decrypt(bytes.Repeat([]byte{2}, 32), "example.org"); !errors.Is(err, ErrAccessTokenInvalid) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+	t.Run("Truncated", func(t *testing.T) {
+		for _, v := range []AccessToken{nil, {0}, accessToken[:10], accessToken[:len(accessToken)-1]} {
+			if _, err := v.Decrypt(key, "example.org"); !errors.Is(err, ErrAccessTokenInvalid) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}
+	})
+}
+
+func TestThirdPartyAuthorization(t *testing.T) {
+	msg := New()
+	if err := msg.Build(BindingError, NewThirdPartyAuthorization("auth.example.org")); err != nil {
+		t.Fatal(err)
+	}
+	var got ThirdPartyAuthorization
+	if err := got.GetFrom(msg); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "auth.example.org" {
+		t.Errorf("Unexpected THIRD-PARTY-AUTHORIZATION %q", got)
+	}
+	if err := got.GetFrom(New()); !errors.Is(err, ErrAttributeNotFound) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -235,6 +235,23 @@ func TestNonce_AddTo(t *testing.T) {
 	}
 }
 
+func TestAlternateDomain(t *testing.T) {
+	m := New()
+	if err := NewAlternateDomain("turn.example.org").AddTo(m); err != nil {
+		t.Fatal(err)
+	}
+	var d AlternateDomain
+	if err := d.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if d.String() != "turn.example.org" {
+		t.Errorf("bad domain %q", d)
+	}
+	if err := make(AlternateDomain, 256).AddTo(New()); !IsAttrSizeOverflow(err) {
+		t.Errorf("AddTo should return *AttrOverflowErr, got: %v", err)
+	}
+}
+
 func BenchmarkNonce_AddTo(b *testing.B) {
 	b.ReportAllocs()
 	m := New()
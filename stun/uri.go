@@ -132,6 +132,11 @@ type URI struct {
 	Username string
 	Password string
 	Proto    ProtoType
+
+	// AccessToken and MACKey authenticate to a TURN server with a third-party
+	// ACCESS-TOKEN (RFC 7635) instead of Password, Username is then the key ID.
+	AccessToken []byte
+	MACKey      []byte
 }
 
 // ParseURI parses a STUN or TURN urls following the ABNF syntax described in
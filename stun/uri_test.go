@@ -45,6 +45,11 @@ func TestParseURL(t *testing.T) {
 				"turns:google.de:5349?transport=tcp",
 				SchemeTypeTURNS, true, "google.de", 5349, ProtoTypeTCP,
 			},
+			{
+				"turns:google.de?transport=udp",
+				"turns:google.de:5349?transport=udp",
+				SchemeTypeTURNS, true, "google.de", 5349, ProtoTypeUDP,
+			},
 		}
 
 		for i, testCase := range testCases {
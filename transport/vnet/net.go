@@ -47,7 +47,7 @@ func newMACAddress() net.HardwareAddr {
 // Net represents a local network stack equivalent to a set of layers from NIC
 // up to the transport (UDP / TCP) layer.
 type Net struct {
-	interfaces []*transport.Interface // read-only
+	interfaces []*transport.Interface // requires mutex, replaced on change
 	staticIPs  []net.IP               // read-only
 	router     *Router                // read-only
 	udpConns   *udpConnMap            // read-only
@@ -101,6 +101,9 @@ func (v *Net) getInterface(ifName string) (*transport.Interface, error) {
 // sharing the logical data link; for more precision use
 // InterfaceByName.
 func (v *Net) InterfaceByIndex(index int) (*transport.Interface, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
 	for _, ifc := range v.interfaces {
 		if ifc.Index == index {
 			return ifc, nil
@@ -146,6 +149,64 @@ func (v *Net) getAllIPAddrs(ipv6 bool) []net.IP {
 	return ips
 }
 
+// addInterface adds a new interface with the given name and addresses.
+func (v *Net) addInterface(ifName string, ips []net.IP, mask net.IPMask) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if _, err := v._getInterface(ifName); err == nil {
+		return fmt.Errorf("%w: %s", errInterfaceExists, ifName)
+	}
+
+	index := 0
+	for _, ifc := range v.interfaces {
+		if ifc.Index > index {
+			index = ifc.Index
+		}
+	}
+
+	ifc := transport.NewInterface(net.Interface{
+		Index:        index + 1,
+		MTU:          1500,
+		Name:         ifName,
+		HardwareAddr: newMACAddress(),
+		Flags:        net.FlagUp | net.FlagMulticast,
+	})
+	for _, ip := range ips {
+		ifc.AddAddress(&net.IPNet{
+			IP:   ip,
+			Mask: mask,
+		})
+	}
+
+	// Copy on write, the slice returned by Interfaces must not change.
+	interfaces := make([]*transport.Interface, 0, len(v.interfaces)+1)
+	v.interfaces = append(append(interfaces, v.interfaces...), ifc)
+
+	return nil
+}
+
+// removeInterface removes the interface with the given name and returns it.
+func (v *Net) removeInterface(ifName string) (*transport.Interface, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	removed, err := v._getInterface(ifName)
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces := make([]*transport.Interface, 0, len(v.interfaces)-1)
+	for _, ifc := range v.interfaces {
+		if ifc != removed {
+			interfaces = append(interfaces, ifc)
+		}
+	}
+	v.interfaces = interfaces
+
+	return removed, nil
+}
+
 func (v *Net) setRouter(r *Router) error {
 	v.mutex.Lock()
 	defer v.mutex.Unlock()
@@ -30,6 +30,8 @@ var (
 	errStaticIPisBeyondSubnet        = errors.New("static IP is beyond subnet")
 	errAddressSpaceExhausted         = errors.New("address space exhausted")
 	errNoIPAddrEth0                  = errors.New("no IP address is assigned for eth0")
+	errInterfaceExists               = errors.New("interface already exists")
+	errNetNotAttached                = errors.New("net is not attached to this router")
 )
 
 // Generate a unique router name.
@@ -379,6 +381,80 @@ func (r *Router) AddNet(nic NIC) error {
 	return r.addNIC(nic)
 }
 
+// AddNetInterface adds an interface named ifName to a Net previously added
+// with AddNet, as when a host joins another network. The interface gets the
+// given static IPs, or an IP address assigned by the router if there are none.
+func (r *Router) AddNetInterface(nic *Net, ifName string, staticIPs ...string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.hasNIC(nic) {
+		return errNetNotAttached
+	}
+
+	var ips []net.IP
+	for _, ipStr := range staticIPs {
+		ip := net.ParseIP(ipStr)
+		if ip == nil || !r.ipv4Net.Contains(ip) {
+			return fmt.Errorf("%w: %s", errStaticIPisBeyondSubnet, r.ipv4Net.String())
+		}
+		ips = append(ips, ip)
+	}
+	if len(ips) == 0 {
+		ip, err := r.assignIPAddress()
+		if err != nil {
+			return err
+		}
+		ips = append(ips, ip)
+	}
+
+	if err := nic.addInterface(ifName, ips, r.ipv4Net.Mask); err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		r.nics[ip.String()] = nic
+	}
+
+	return nil
+}
+
+// RemoveNetInterface removes the interface named ifName from a Net previously
+// added with AddNet, as when a host leaves a network. Packets sent to the
+// addresses of the interface are dropped from then on.
+func (r *Router) RemoveNetInterface(nic *Net, ifName string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.hasNIC(nic) {
+		return errNetNotAttached
+	}
+
+	ifc, err := nic.removeInterface(ifName)
+	if err != nil {
+		return err
+	}
+
+	addrs, _ := ifc.Addrs()
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && r.nics[ipNet.IP.String()] == nic {
+			delete(r.nics, ipNet.IP.String())
+		}
+	}
+
+	return nil
+}
+
+// caller must hold the mutex.
+func (r *Router) hasNIC(nic NIC) bool {
+	for _, n := range r.nics {
+		if n == nic {
+			return true
+		}
+	}
+
+	return false
+}
+
 // AddHost adds a mapping of hostname and an IP address to the local resolver.
 func (r *Router) AddHost(hostName string, ipAddr string) error {
 	return r.resolver.addHost(hostName, ipAddr)
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/pion/logging"
+	"github.com/pion/transport/v3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -737,3 +738,71 @@ func TestRouterFailures(t *testing.T) {
 		assert.Error(t, err, "should fail")
 	})
 }
+
+func TestRouterNetInterface(t *testing.T) {
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	wan, err := NewRouter(&RouterConfig{
+		CIDR:          "1.2.3.0/24",
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+
+	nic, err := NewNet(&NetConfig{})
+	assert.NoError(t, err)
+
+	other, err := NewNet(&NetConfig{})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, wan.AddNetInterface(other, "eth1"), errNetNotAttached)
+
+	assert.NoError(t, wan.AddNet(nic))
+	assert.NoError(t, wan.AddNet(other))
+	assert.NoError(t, wan.AddNetInterface(nic, "eth1", "1.2.3.100"))
+	assert.ErrorIs(t, wan.AddNetInterface(nic, "eth1"), errInterfaceExists)
+	assert.ErrorIs(t, wan.AddNetInterface(nic, "eth2", "5.6.7.8"), errStaticIPisBeyondSubnet)
+
+	ifs, err := nic.Interfaces()
+	assert.NoError(t, err)
+	assert.Len(t, ifs, 3)
+
+	eth1, err := nic.InterfaceByName("eth1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, eth1.Index)
+	addrs, err := eth1.Addrs()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3.100/24", addrs[0].String())
+
+	assert.NoError(t, wan.Start())
+	defer func() {
+		assert.NoError(t, wan.Stop())
+	}()
+
+	conn, err := nic.ListenPacket(udp, "1.2.3.100:1234")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, conn.Close())
+	}()
+
+	sender, err := other.ListenPacket(udp, "0.0.0.0:0")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, sender.Close())
+	}()
+
+	_, err = sender.WriteTo([]byte("hello"), conn.LocalAddr())
+	assert.NoError(t, err)
+
+	buf := make([]byte, 16)
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(buf[:n]))
+
+	assert.NoError(t, wan.RemoveNetInterface(nic, "eth1"))
+	assert.ErrorIs(t, wan.RemoveNetInterface(nic, "eth1"), transport.ErrInterfaceNotFound)
+
+	ifs, err = nic.Interfaces()
+	assert.NoError(t, err)
+	assert.Len(t, ifs, 2)
+	_, ok := wan.nics["1.2.3.100"]
+	assert.False(t, ok, "packets to the removed address are no longer routed")
+}
@@ -5,12 +5,14 @@ package turn
 
 import (
 	b64 "encoding/base64"
+	"errors"
 	"fmt"
 	"math"
 	"net"
 	"sync"
 	"time"
 
+	"github.com/pion/dtls/v3"
 	"github.com/pion/logging"
 	"github.com/pion/stun/v3"
 	"github.com/pion/transport/v3"
@@ -23,6 +25,8 @@ const (
 	defaultRTO        = 200 * time.Millisecond
 	maxRtxCount       = 7              // Total 7 requests (Rc)
 	maxDataBufferSize = math.MaxUint16 // Message size limit for Chromium
+
+	maxAlternateServerRedirects = 3
 )
 
 //              interval [msec]
@@ -36,6 +40,11 @@ const (
 // -: 63500 ms  failed
 
 // ClientConfig is a bag of config parameters for Client.
+//
+// Allocate follows the redirects of the server to alternate servers only if Conn is a
+// datagram socket. A STUNConn (TCP or TLS) or a DTLS session is tied to its server, the
+// redirect is then returned as a *TryAlternateError: the caller dials the alternate server
+// and verifies its certificate against the Domain of the error (ALTERNATE-DOMAIN).
 type ClientConfig struct {
 	STUNServerAddr string // STUN server address (e.g. "stun.abc.com:3478")
 	TURNServerAddr string // TURN server address (e.g. "turn.abc.com:3478")
@@ -47,6 +56,19 @@ type ClientConfig struct {
 	Conn           net.PacketConn // Listening socket (net.PacketConn)
 	Net            transport.Net
 	LoggerFactory  logging.LoggerFactory
+
+	// AccessToken and MACKey authorize the allocation with a third-party ACCESS-TOKEN of RFC 7635
+	// instead of Password. Username is then the key ID of the token.
+	AccessToken []byte
+	MACKey      []byte
+
+	// Mobility requests a MOBILITY-TICKET for UDP allocations, see RFC 8016. When the address of
+	// the client changes, the allocation is moved to the new address instead of being lost.
+	Mobility bool
+
+	// DTLSConfig dials TURN over DTLS (RFC 7350) to TURNServerAddr on Conn. Close closes
+	// the DTLS connection, Conn is closed by the caller as without DTLS.
+	DTLSConfig *dtls.Config
 }
 
 // Client is a STUN server client.
@@ -54,10 +76,13 @@ type Client struct {
 	conn           net.PacketConn // Read-only
 	net            transport.Net  // Read-only
 	stunServerAddr net.Addr       // Read-only
-	turnServerAddr net.Addr       // Read-only
+	turnServerAddr net.Addr       // Protected by mutex ***
 
 	username      stun.Username          // Read-only
 	password      string                 // Read-only
+	accessToken   stun.AccessToken       // Read-only
+	macKey        []byte                 // Read-only
+	mobility      bool                   // Read-only
 	realm         stun.Realm             // Read-only
 	integrity     stun.MessageIntegrity  // Read-only
 	software      stun.Software          // Read-only
@@ -120,12 +145,28 @@ func NewClient(config *ClientConfig) (*Client, error) {
 		log.Debugf("Resolved TURN server %s to %s", config.TURNServerAddr, turnServ)
 	}
 
+	conn := config.Conn
+	if config.DTLSConfig != nil {
+		if turnServ == nil {
+			return nil, errDTLSTURNServerAddrUnset
+		}
+
+		dtlsConn, err := dtls.Client(&dtlsNextConn{PacketConn: config.Conn}, turnServ, config.DTLSConfig)
+		if err != nil {
+			return nil, err
+		}
+		conn = newDTLSClientConn(dtlsConn)
+	}
+
 	client := &Client{
-		conn:           config.Conn,
+		conn:           conn,
 		stunServerAddr: stunServ,
 		turnServerAddr: turnServ,
 		username:       stun.NewUsername(config.Username),
 		password:       config.Password,
+		accessToken:    config.AccessToken,
+		macKey:         config.MACKey,
+		mobility:       config.Mobility,
 		realm:          stun.NewRealm(config.Realm),
 		software:       stun.NewSoftware(config.Software),
 		trMap:          client.NewTransactionMap(),
@@ -139,6 +180,9 @@ func NewClient(config *ClientConfig) (*Client, error) {
 
 // TURNServerAddr return the TURN server address.
 func (c *Client) TURNServerAddr() net.Addr {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
 	return c.turnServerAddr
 }
 
@@ -200,6 +244,12 @@ func (c *Client) Close() {
 	defer c.mutexTrMap.Unlock()
 
 	c.trMap.CloseAndDeleteAll()
+
+	if dtlsConn, ok := c.conn.(*dtlsClientConn); ok {
+		if err := dtlsConn.Close(); err != nil {
+			c.log.Debugf("Failed to close DTLS connection: %s", err)
+		}
+	}
 }
 
 // TransactionID & Base64: https://play.golang.org/p/EEgmJDI971P
@@ -240,85 +290,196 @@ func (c *Client) SendBindingRequest() (net.Addr, error) {
 	return c.SendBindingRequestTo(c.stunServerAddr)
 }
 
-func (c *Client) sendAllocateRequest(protocol proto.Protocol) ( //nolint:cyclop
-	proto.RelayedAddress,
+func (c *Client) sendAllocateRequest(protocol proto.Protocol, family []stun.Setter) ( //nolint:cyclop
+	[]proto.RelayedAddress,
 	proto.Lifetime,
 	stun.Nonce,
+	proto.MobilityTicket,
 	error,
 ) {
-	var relayed proto.RelayedAddress
+	var relayed []proto.RelayedAddress
 	var lifetime proto.Lifetime
 	var nonce stun.Nonce
+	var ticket proto.MobilityTicket
 
-	msg, err := stun.Build(
+	// A MOBILITY-TICKET attribute with zero length requests mobility for
+	// the allocation, see https://tools.ietf.org/html/rfc8016#section-3.1.
+	if c.mobility && protocol == proto.ProtoUDP {
+		family = append(family, proto.MobilityTicket{})
+	}
+
+	msg, err := stun.Build(append([]stun.Setter{
 		stun.TransactionID,
 		stun.NewType(stun.MethodAllocate, stun.ClassRequest),
 		proto.RequestedTransport{Protocol: protocol},
-		stun.Fingerprint,
-	)
+	}, append(family, stun.Fingerprint)...)...)
 	if err != nil {
-		return relayed, lifetime, nonce, err
+		return relayed, lifetime, nonce, ticket, err
 	}
 
-	trRes, err := c.PerformTransaction(msg, c.turnServerAddr, false)
+	trRes, err := c.PerformTransaction(msg, c.TURNServerAddr(), false)
 	if err != nil {
-		return relayed, lifetime, nonce, err
+		return relayed, lifetime, nonce, ticket, err
 	}
 
 	res := trRes.Msg
+	if tryAlternate := tryAlternateServer(res); tryAlternate != nil {
+		return relayed, lifetime, nonce, ticket, tryAlternate
+	}
 
 	// Anonymous allocate failed, trying to authenticate.
 	if err = nonce.GetFrom(res); err != nil {
-		return relayed, lifetime, nonce, err
+		return relayed, lifetime, nonce, ticket, err
 	}
 	if err = c.realm.GetFrom(res); err != nil {
-		return relayed, lifetime, nonce, err
+		return relayed, lifetime, nonce, ticket, err
 	}
 	c.realm = append([]byte(nil), c.realm...)
-	c.integrity = stun.NewLongTermIntegrity(
-		c.username.String(), c.realm.String(), c.password,
-	)
-	// Trying to authorize.
-	msg, err = stun.Build(
+	setters := []stun.Setter{
 		stun.TransactionID,
 		stun.NewType(stun.MethodAllocate, stun.ClassRequest),
 		proto.RequestedTransport{Protocol: protocol},
-		&c.username,
-		&c.realm,
-		&nonce,
-		&c.integrity,
-		stun.Fingerprint,
-	)
+	}
+	setters = append(setters, family...)
+	setters = append(setters, &c.username, &c.realm, &nonce)
+	if len(c.accessToken) > 0 {
+		c.integrity = stun.MessageIntegrity(c.macKey)
+		setters = append(setters, c.accessToken)
+	} else {
+		c.integrity = stun.NewLongTermIntegrity(
+			c.username.String(), c.realm.String(), c.password,
+		)
+	}
+	// Trying to authorize.
+	msg, err = stun.Build(append(setters, &c.integrity, stun.Fingerprint)...)
 	if err != nil {
-		return relayed, lifetime, nonce, err
+		return relayed, lifetime, nonce, ticket, err
 	}
 
-	trRes, err = c.PerformTransaction(msg, c.turnServerAddr, false)
+	trRes, err = c.PerformTransaction(msg, c.TURNServerAddr(), false)
 	if err != nil {
-		return relayed, lifetime, nonce, err
+		return relayed, lifetime, nonce, ticket, err
 	}
 	res = trRes.Msg
+	if tryAlternate := tryAlternateServer(res); tryAlternate != nil {
+		return relayed, lifetime, nonce, ticket, tryAlternate
+	}
 
 	if res.Type.Class == stun.ClassErrorResponse {
 		var code stun.ErrorCodeAttribute
 		if err = code.GetFrom(res); err == nil {
-			return relayed, lifetime, nonce, fmt.Errorf("%s (error %s)", res.Type, code) //nolint:goerr113
+			return relayed, lifetime, nonce, ticket, fmt.Errorf("%s (error %s)", res.Type, code) //nolint:goerr113
 		}
 
-		return relayed, lifetime, nonce, fmt.Errorf("%s", res.Type) //nolint:goerr113
+		return relayed, lifetime, nonce, ticket, fmt.Errorf("%s", res.Type) //nolint:goerr113
 	}
 
 	// Getting relayed addresses from response.
-	if err := relayed.GetFrom(res); err != nil {
-		return relayed, lifetime, nonce, err
+	if relayed, err = relayedAddresses(res); err != nil {
+		return relayed, lifetime, nonce, ticket, err
+	}
+	if err := res.ForEach(stun.AttrAddressErrorCode, func(m *stun.Message) error {
+		var addressErr proto.AddressErrorCode
+		if err := addressErr.GetFrom(m); err != nil {
+			return err
+		}
+		c.log.Debugf("No %s relayed transport address allocated: %d %s",
+			addressErr.Family, int(addressErr.Code), addressErr.Reason)
+
+		return nil
+	}); err != nil {
+		return relayed, lifetime, nonce, ticket, err
 	}
 
 	// Getting lifetime from response
 	if err := lifetime.GetFrom(res); err != nil {
-		return relayed, lifetime, nonce, err
+		return relayed, lifetime, nonce, ticket, err
+	}
+
+	// The server may not support mobility, the allocation then has no ticket.
+	_ = ticket.GetFrom(res)
+
+	return relayed, lifetime, nonce, ticket, nil
+}
+
+// relayedAddresses returns the XOR-RELAYED-ADDRESS attributes of an Allocate
+// response, a dual-stack allocation has one for each address family.
+func relayedAddresses(res *stun.Message) ([]proto.RelayedAddress, error) {
+	var relayed []proto.RelayedAddress
+	if err := res.ForEach(stun.AttrXORRelayedAddress, func(m *stun.Message) error {
+		var addr proto.RelayedAddress
+		if err := addr.GetFrom(m); err != nil {
+			return err
+		}
+		relayed = append(relayed, addr)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if len(relayed) == 0 {
+		return nil, stun.ErrAttributeNotFound
 	}
 
-	return relayed, lifetime, nonce, nil
+	return relayed, nil
+}
+
+// allocate sends Allocate requests until a server accepts one, it follows the
+// redirects to alternate servers, see https://tools.ietf.org/html/rfc8489#section-10.
+func (c *Client) allocate(protocol proto.Protocol, family ...stun.Setter) (
+	[]proto.RelayedAddress,
+	proto.Lifetime,
+	stun.Nonce,
+	proto.MobilityTicket,
+	error,
+) {
+	tried := map[string]bool{}
+	for {
+		relayed, lifetime, nonce, ticket, err := c.sendAllocateRequest(protocol, family)
+
+		var tryAlternate *TryAlternateError
+		if !errors.As(err, &tryAlternate) {
+			return relayed, lifetime, nonce, ticket, err
+		}
+
+		// The connection of a stream or a DTLS session is tied to the server
+		switch c.conn.(type) {
+		case *STUNConn, *dtlsClientConn:
+			return relayed, lifetime, nonce, ticket, err
+		}
+
+		tried[c.TURNServerAddr().String()] = true
+		switch {
+		case tried[tryAlternate.Server.String()]:
+			return relayed, lifetime, nonce, ticket, fmt.Errorf("%w: %s", errRedirectLoop, tryAlternate.Server)
+		case len(tried) > maxAlternateServerRedirects:
+			return relayed, lifetime, nonce, ticket, errTooManyRedirects
+		}
+
+		c.log.Debugf("Redirected from %s to alternate server %s", c.TURNServerAddr(), tryAlternate.Server)
+		c.mutex.Lock()
+		c.turnServerAddr = tryAlternate.Server
+		c.mutex.Unlock()
+	}
+}
+
+// tryAlternateServer returns the alternate server of a 300 (Try Alternate) error response.
+func tryAlternateServer(res *stun.Message) *TryAlternateError {
+	var code stun.ErrorCodeAttribute
+	var server stun.AlternateServer
+	if res.Type.Class != stun.ClassErrorResponse ||
+		code.GetFrom(res) != nil || code.Code != stun.CodeTryAlternate ||
+		server.GetFrom(res) != nil {
+		return nil
+	}
+
+	var domain stun.AlternateDomain
+	_ = domain.GetFrom(res)
+
+	return &TryAlternateError{
+		Server: &net.UDPAddr{IP: server.IP, Port: server.Port},
+		Domain: domain.String(),
+	}
 }
 
 // Allocate sends a TURN allocation request to the given transport address.
@@ -333,33 +494,88 @@ func (c *Client) Allocate() (net.PacketConn, error) {
 		return nil, fmt.Errorf("%w: %s", errAlreadyAllocated, relayedConn.LocalAddr().String())
 	}
 
-	relayed, lifetime, nonce, err := c.sendAllocateRequest(proto.ProtoUDP)
+	relayed, lifetime, nonce, ticket, err := c.allocate(proto.ProtoUDP)
 	if err != nil {
 		return nil, err
 	}
 
 	relayedAddr := &net.UDPAddr{
-		IP:   relayed.IP,
-		Port: relayed.Port,
+		IP:   relayed[0].IP,
+		Port: relayed[0].Port,
 	}
 
 	relayedConn = client.NewUDPConn(&client.AllocationConfig{
-		Client:      c,
-		RelayedAddr: relayedAddr,
-		ServerAddr:  c.turnServerAddr,
-		Realm:       c.realm,
-		Username:    c.username,
-		Integrity:   c.integrity,
-		Nonce:       nonce,
-		Lifetime:    lifetime.Duration,
-		Net:         c.net,
-		Log:         c.log,
+		Client:         c,
+		RelayedAddr:    relayedAddr,
+		ServerAddr:     c.TURNServerAddr(),
+		Realm:          c.realm,
+		Username:       c.username,
+		Integrity:      c.integrity,
+		AccessToken:    c.accessToken,
+		Nonce:          nonce,
+		Lifetime:       lifetime.Duration,
+		MobilityTicket: ticket,
+		Net:            c.net,
+		Log:            c.log,
 	})
 	c.setRelayedUDPConn(relayedConn)
 
 	return relayedConn, nil
 }
 
+// AllocateDualStack sends a TURN allocation request for both an IPv4 and an IPv6
+// relayed transport address, see https://tools.ietf.org/html/rfc8656#section-7.2.
+// Either of the returned conns is nil if the server could not allocate a relayed
+// transport address of its address family. Closing either of them deletes the
+// allocation and closes both.
+func (c *Client) AllocateDualStack() (ipv4, ipv6 net.PacketConn, err error) {
+	if err = c.allocTryLock.Lock(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", errOneAllocateOnly, err.Error())
+	}
+	defer c.allocTryLock.Unlock()
+
+	relayedConn := c.relayedUDPConn()
+	if relayedConn != nil {
+		return nil, nil, fmt.Errorf("%w: %s", errAlreadyAllocated, relayedConn.LocalAddr().String())
+	}
+
+	relayed, lifetime, nonce, ticket, err := c.allocate(proto.ProtoUDP,
+		proto.AdditionalAddressFamily(proto.RequestedFamilyIPv6))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relayedConn = client.NewUDPConn(&client.AllocationConfig{
+		Client:         c,
+		RelayedAddr:    &net.UDPAddr{IP: relayed[0].IP, Port: relayed[0].Port},
+		ServerAddr:     c.TURNServerAddr(),
+		Realm:          c.realm,
+		Username:       c.username,
+		Integrity:      c.integrity,
+		AccessToken:    c.accessToken,
+		Nonce:          nonce,
+		Lifetime:       lifetime.Duration,
+		MobilityTicket: ticket,
+		Net:            c.net,
+		Log:            c.log,
+	})
+	conns := []net.PacketConn{relayedConn}
+	if len(relayed) > 1 {
+		conns = append(conns, relayedConn.NewSiblingConn(&net.UDPAddr{IP: relayed[1].IP, Port: relayed[1].Port}))
+	}
+	c.setRelayedUDPConn(relayedConn)
+
+	for i, conn := range conns {
+		if relayed[i].IP.To4() != nil {
+			ipv4 = conn
+		} else {
+			ipv6 = conn
+		}
+	}
+
+	return ipv4, ipv6, nil
+}
+
 // AllocateTCP creates a new TCP allocation at the TURN server.
 func (c *Client) AllocateTCP() (*client.TCPAllocation, error) {
 	if err := c.allocTryLock.Lock(); err != nil {
@@ -372,23 +588,24 @@ func (c *Client) AllocateTCP() (*client.TCPAllocation, error) {
 		return nil, fmt.Errorf("%w: %s", errAlreadyAllocated, allocation.Addr())
 	}
 
-	relayed, lifetime, nonce, err := c.sendAllocateRequest(proto.ProtoTCP)
+	relayed, lifetime, nonce, _, err := c.allocate(proto.ProtoTCP)
 	if err != nil {
 		return nil, err
 	}
 
 	relayedAddr := &net.TCPAddr{
-		IP:   relayed.IP,
-		Port: relayed.Port,
+		IP:   relayed[0].IP,
+		Port: relayed[0].Port,
 	}
 
 	allocation = client.NewTCPAllocation(&client.AllocationConfig{
 		Client:      c,
 		RelayedAddr: relayedAddr,
-		ServerAddr:  c.turnServerAddr,
+		ServerAddr:  c.TURNServerAddr(),
 		Realm:       c.realm,
 		Username:    c.username,
 		Integrity:   c.integrity,
+		AccessToken: c.accessToken,
 		Nonce:       nonce,
 		Lifetime:    lifetime.Duration,
 		Net:         c.net,
@@ -404,8 +621,17 @@ func (c *Client) AllocateTCP() (*client.TCPAllocation, error) {
 // as described in https://datatracker.ietf.org/doc/html/rfc5766#section-9
 func (c *Client) CreatePermission(addrs ...net.Addr) error {
 	if conn := c.relayedUDPConn(); conn != nil {
-		if err := conn.CreatePermissions(addrs...); err != nil {
-			return err
+		// The permissions of a dual-stack allocation are created on the
+		// relayed transport address of the address family of the peer.
+		addrsByConn := map[*client.UDPConn][]net.Addr{}
+		for _, addr := range addrs {
+			relayedConn := conn.ConnFor(addr)
+			addrsByConn[relayedConn] = append(addrsByConn[relayedConn], addr)
+		}
+		for relayedConn, relayedAddrs := range addrsByConn {
+			if err := relayedConn.CreatePermissions(relayedAddrs...); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -418,6 +644,18 @@ func (c *Client) CreatePermission(addrs ...net.Addr) error {
 	return nil
 }
 
+// MoveAllocation moves the UDP allocation to the current address of the client with its
+// MOBILITY-TICKET, see ClientConfig.Mobility. Call it when the network of the client changes,
+// otherwise the allocation is moved by the next request the server answers with 437 (Allocation Mismatch).
+func (c *Client) MoveAllocation() error {
+	relayedConn := c.relayedUDPConn()
+	if relayedConn == nil {
+		return errNoAllocation
+	}
+
+	return relayedConn.MoveAllocation()
+}
+
 // PerformTransaction performs STUN transaction.
 func (c *Client) PerformTransaction(msg *stun.Message, to net.Addr, ignoreResult bool) (client.TransactionResult,
 	error,
@@ -545,7 +783,7 @@ func (c *Client) handleSTUNMessage(data []byte, from net.Addr) error { //nolint:
 
 				return nil // Silently discard
 			}
-			relayedConn.HandleInbound(data, from)
+			relayedConn.ConnFor(from).HandleInbound(data, from)
 		case stun.MethodConnectionAttempt:
 			var peerAddr proto.PeerAddress
 			if err := peerAddr.GetFrom(msg); err != nil {
@@ -613,10 +851,8 @@ func (c *Client) handleSTUNMessage(data []byte, from net.Addr) error { //nolint:
 }
 
 func (c *Client) handleChannelData(data []byte) error {
-	chData := &proto.ChannelData{
-		Raw: make([]byte, len(data)),
-	}
-	copy(chData.Raw, data)
+	// The data is decoded in place, the relayed conn copies the payload.
+	chData := &proto.ChannelData{Raw: data}
 	if err := chData.Decode(); err != nil {
 		return err
 	}
@@ -633,9 +869,7 @@ func (c *Client) handleChannelData(data []byte) error {
 		return fmt.Errorf("%w: %d", errChannelBindNotFound, int(chData.Number))
 	}
 
-	c.log.Tracef("Channel data received from %s (ch=%d)", addr.String(), int(chData.Number))
-
-	relayedConn.HandleInbound(chData.Data, addr)
+	relayedConn.ConnFor(addr).HandleInbound(chData.Data, addr)
 
 	return nil
 }
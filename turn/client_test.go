@@ -156,6 +156,7 @@ func TestClientNonceExpiration(t *testing.T) {
 		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
 			return GenerateAuthKey(username, realm, "pass"), true
 		},
+		PeerACL: PeerACL{AllowInternalPeers: true},
 		PacketConnConfigs: []PacketConnConfig{
 			{
 				PacketConn: udpListener,
@@ -207,6 +208,7 @@ func TestTCPClient(t *testing.T) {
 		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
 			return GenerateAuthKey(username, realm, "pass"), true
 		},
+		PeerACL: PeerACL{AllowInternalPeers: true},
 		ListenerConfigs: []ListenerConfig{
 			{
 				Listener: tcpListener,
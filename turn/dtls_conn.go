@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pion/dtls/v3"
+	"github.com/pion/transport/v3/deadline"
+)
+
+// maxDTLSRecordSize is the maximum size of the application data of a DTLS record.
+const maxDTLSRecordSize = 1 << 14
+
+type dtlsDatagram struct {
+	data []byte
+	addr net.Addr
+}
+
+// DTLSPacketConn serves the connections of a DTLS listener as a net.PacketConn, so that
+// a TURN server serves TURN over DTLS (RFC 7350) like TURN over UDP. Each client has a DTLS
+// connection, the packets of its 5-tuple are read from and written to it.
+type DTLSPacketConn struct {
+	listener     net.Listener
+	conns        map[string]net.Conn // Protected by mutex
+	mutex        sync.Mutex
+	readCh       chan dtlsDatagram
+	closeCh      chan struct{}
+	closeOnce    sync.Once
+	readDeadline *deadline.Deadline
+}
+
+// NewDTLSPacketConn accepts the connections of listener, as returned by dtls.Listen, and
+// serves them as one net.PacketConn. Use it as the PacketConn of a PacketConnConfig.
+func NewDTLSPacketConn(listener net.Listener) *DTLSPacketConn {
+	conn := &DTLSPacketConn{
+		listener:     listener,
+		conns:        map[string]net.Conn{},
+		readCh:       make(chan dtlsDatagram),
+		closeCh:      make(chan struct{}),
+		readDeadline: deadline.New(),
+	}
+	go conn.acceptLoop()
+
+	return conn
+}
+
+func (c *DTLSPacketConn) acceptLoop() {
+	defer c.closeConns()
+
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		c.mutex.Lock()
+		if previous, ok := c.conns[conn.RemoteAddr().String()]; ok {
+			_ = previous.Close()
+		}
+		c.conns[conn.RemoteAddr().String()] = conn
+		c.mutex.Unlock()
+
+		go c.readLoop(conn)
+	}
+}
+
+// readLoop passes the packets of a connection to ReadFrom, the DTLS handshake
+// is done by the first Read.
+func (c *DTLSPacketConn) readLoop(conn net.Conn) {
+	defer func() {
+		c.mutex.Lock()
+		if c.conns[conn.RemoteAddr().String()] == conn {
+			delete(c.conns, conn.RemoteAddr().String())
+		}
+		c.mutex.Unlock()
+
+		_ = conn.Close()
+	}()
+
+	buf := make([]byte, maxDTLSRecordSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+
+		select {
+		case c.readCh <- dtlsDatagram{data: append([]byte(nil), buf[:n]...), addr: conn.RemoteAddr()}:
+		case <-c.closeCh:
+			return
+		}
+	}
+}
+
+func (c *DTLSPacketConn) closeConns() {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+	})
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, conn := range c.conns {
+		_ = conn.Close()
+	}
+}
+
+// ReadFrom reads a packet of any of the DTLS connections.
+func (c *DTLSPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case datagram := <-c.readCh:
+		return copy(p, datagram.data), datagram.addr, nil
+	case <-c.readDeadline.Done():
+		return 0, nil, os.ErrDeadlineExceeded
+	case <-c.closeCh:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+// WriteTo writes a packet to the DTLS connection of addr.
+func (c *DTLSPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	c.mutex.Lock()
+	conn, ok := c.conns[addr.String()]
+	c.mutex.Unlock()
+	if !ok {
+		return 0, errNoDTLSConn
+	}
+
+	return conn.Write(p)
+}
+
+// Close closes the listener and all DTLS connections.
+func (c *DTLSPacketConn) Close() error {
+	err := c.listener.Close()
+	c.closeConns()
+
+	return err
+}
+
+// LocalAddr returns the address of the listener.
+func (c *DTLSPacketConn) LocalAddr() net.Addr {
+	return c.listener.Addr()
+}
+
+// SetDeadline sets the read deadline, writes to DTLS connections do not block.
+func (c *DTLSPacketConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline sets the deadline of ReadFrom.
+func (c *DTLSPacketConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Set(t)
+
+	return nil
+}
+
+// SetWriteDeadline is a no-op, writes to DTLS connections do not block.
+func (c *DTLSPacketConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// dtlsHandshakeTimeout bounds the DTLS handshake of a Client, which runs with
+// the first request to the TURN server.
+const dtlsHandshakeTimeout = 30 * time.Second
+
+// dtlsClientConn is the DTLS connection of a Client to its TURN server. The
+// DTLS connection starts a new handshake on each Read or Write until one
+// succeeds. ctx stops it from starting them once the client is closed.
+type dtlsClientConn struct {
+	*dtls.Conn
+	ctx    context.Context //nolint:containedctx
+	cancel context.CancelFunc
+}
+
+func newDTLSClientConn(conn *dtls.Conn) *dtlsClientConn {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &dtlsClientConn{Conn: conn, ctx: ctx, cancel: cancel}
+}
+
+// dtlsNextConn is the socket of a dtlsClientConn, it is closed by the owner
+// of the ClientConfig.Conn and not by the DTLS connection.
+type dtlsNextConn struct {
+	net.PacketConn
+}
+
+func (c *dtlsNextConn) Close() error {
+	return nil
+}
+
+func (c *dtlsClientConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	if err := c.HandshakeContext(c.ctx); err != nil {
+		return 0, nil, err
+	}
+
+	n, err := c.Read(p)
+
+	return n, c.RemoteAddr(), err
+}
+
+func (c *dtlsClientConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if addr.String() != c.RemoteAddr().String() {
+		return 0, errDTLSRemoteAddr
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, dtlsHandshakeTimeout)
+	defer cancel()
+	if err := c.HandshakeContext(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.Write(p)
+}
+
+func (c *dtlsClientConn) Close() error {
+	c.cancel()
+
+	return c.Conn.Close()
+}
@@ -3,7 +3,18 @@
 
 package turn
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/pion/turn/v4/internal/allocation"
+)
+
+// ErrAddressFamilyNotSupported is returned by RelayAddressGenerator.AllocatePacketConn if it
+// cannot allocate a relayed transport address of the address family of the network. The
+// server then rejects the Allocate request with 440 (Address Family not Supported).
+var ErrAddressFamilyNotSupported = allocation.ErrAddressFamilyNotSupported
 
 var (
 	errRelayAddressInvalid           = errors.New("turn: RelayAddress must be valid IP to use RelayAddressGeneratorStatic")
@@ -29,4 +40,26 @@ var (
 	errFailedToDecodeSTUN            = errors.New("failed to decode STUN message")
 	errUnexpectedSTUNRequestMessage  = errors.New("unexpected STUN request message")
 	errRelayAddressGeneratorNil      = errors.New("RelayAddressGenerator is nil")
+	errRedirectLoop                  = errors.New("turn: redirected to a server that was already tried")
+	errTooManyRedirects              = errors.New("turn: too many redirects to alternate servers")
+	errNoAllocation                  = errors.New("turn: no UDP allocation")
+	errNoDTLSConn                    = errors.New("turn: no DTLS connection to the address")
+	errDTLSRemoteAddr                = errors.New("turn: DTLS connection only sends to the TURN server")
+	errDTLSTURNServerAddrUnset       = errors.New("turn: TURNServerAddr must be set to dial DTLS")
+	errRelayAddressInUse             = errors.New("turn: relay address is in use")
 )
+
+// TryAlternateError is returned by Allocate when the server redirects the client with a 300
+// (Try Alternate) error that the client cannot follow, because its connection to the server is
+// a TCP or TLS stream or a DTLS session. The caller can connect to the alternate server and
+// allocate there.
+type TryAlternateError struct {
+	Server net.Addr
+
+	// Domain is the name to verify the certificate of the server against, if the server sent one
+	Domain string
+}
+
+func (e *TryAlternateError) Error() string {
+	return fmt.Sprintf("turn: try alternate server %s", e.Server)
+}
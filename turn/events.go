@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"net"
+	"time"
+
+	"github.com/pion/turn/v4/internal/allocation"
+	"github.com/pion/turn/v4/internal/proto"
+)
+
+// AllocationStats are the cumulative traffic counters of an allocation.
+type AllocationStats struct {
+	// BytesSent and PacketsSent count the traffic relayed from the client to its peers
+	BytesSent   uint64
+	PacketsSent uint64
+
+	// BytesReceived and PacketsReceived count the traffic relayed from the peers to the client
+	BytesReceived   uint64
+	PacketsReceived uint64
+
+	// PeersDenied counts the CreatePermission and ChannelBind requests and the packets
+	// to peers denied by the PeerACL
+	PeersDenied uint64
+}
+
+// AllocationInfo describes an allocation of the server.
+type AllocationInfo struct {
+	// ClientAddr, ServerAddr and Protocol are the five-tuple of the allocation
+	ClientAddr net.Addr
+	ServerAddr net.Addr
+	Protocol   string
+
+	// RelayedAddr is the relayed transport address of the allocation, and
+	// AdditionalRelayedAddr the IPv6 one of a dual-stack allocation
+	RelayedAddr           net.Addr
+	AdditionalRelayedAddr net.Addr
+
+	// Username is the user that created the allocation
+	Username string
+
+	CreatedAt time.Time
+	Stats     AllocationStats
+}
+
+// EventHandlers are callbacks for the lifecycle of allocations, their permissions and channels.
+// All of them are optional and are called synchronously, so they must not block.
+type EventHandlers struct {
+	// OnAllocationCreated is called once an Allocate request succeeded
+	OnAllocationCreated func(info AllocationInfo)
+
+	// OnAllocationRefreshed is called when a Refresh request extended the lifetime of an allocation
+	OnAllocationRefreshed func(info AllocationInfo, lifetime time.Duration)
+
+	// OnAllocationDeleted is called when an allocation is deleted. expired is true if its
+	// lifetime elapsed, and false if it was deleted by the client or the server was closed.
+	// info.Stats holds the final traffic counters of the allocation.
+	OnAllocationDeleted func(info AllocationInfo, expired bool)
+
+	// OnPermissionCreated, OnPermissionRefreshed and OnPermissionExpired follow the
+	// permissions installed by CreatePermission and ChannelBind requests
+	OnPermissionCreated   func(info AllocationInfo, peerIP net.IP)
+	OnPermissionRefreshed func(info AllocationInfo, peerIP net.IP)
+	OnPermissionExpired   func(info AllocationInfo, peerIP net.IP)
+
+	// OnChannelCreated, OnChannelRefreshed and OnChannelExpired follow the channels
+	// bound by ChannelBind requests
+	OnChannelCreated   func(info AllocationInfo, number uint16, peer net.Addr)
+	OnChannelRefreshed func(info AllocationInfo, number uint16, peer net.Addr)
+	OnChannelExpired   func(info AllocationInfo, number uint16, peer net.Addr)
+}
+
+// Allocations returns the active allocations of the server.
+func (s *Server) Allocations() []AllocationInfo {
+	var infos []AllocationInfo
+	for _, am := range s.allocationManagers {
+		for _, a := range am.Allocations() {
+			infos = append(infos, newAllocationInfo(a))
+		}
+	}
+
+	return infos
+}
+
+func newAllocationInfo(alloc *allocation.Allocation) AllocationInfo {
+	fiveTuple := alloc.FiveTuple()
+	stats := alloc.Stats()
+
+	info := AllocationInfo{
+		ClientAddr:            fiveTuple.SrcAddr,
+		ServerAddr:            fiveTuple.DstAddr,
+		Protocol:              "udp",
+		RelayedAddr:           alloc.RelayAddr,
+		AdditionalRelayedAddr: alloc.AdditionalRelayAddr,
+		Username:              alloc.Username(),
+		CreatedAt:             alloc.CreatedAt(),
+		Stats: AllocationStats{
+			BytesSent:       stats.BytesSent,
+			PacketsSent:     stats.PacketsSent,
+			BytesReceived:   stats.BytesReceived,
+			PacketsReceived: stats.PacketsReceived,
+			PeersDenied:     stats.PeersDenied,
+		},
+	}
+	if _, ok := fiveTuple.DstAddr.(*net.TCPAddr); ok {
+		info.Protocol = "tcp"
+	}
+
+	return info
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	default:
+		return nil
+	}
+}
+
+// eventHandler adapts EventHandlers to allocation.EventHandler.
+type eventHandler struct {
+	handlers EventHandlers
+}
+
+func (h *eventHandler) OnAllocationCreated(alloc *allocation.Allocation) {
+	if h.handlers.OnAllocationCreated != nil {
+		h.handlers.OnAllocationCreated(newAllocationInfo(alloc))
+	}
+}
+
+func (h *eventHandler) OnAllocationRefreshed(alloc *allocation.Allocation, lifetime time.Duration) {
+	if h.handlers.OnAllocationRefreshed != nil {
+		h.handlers.OnAllocationRefreshed(newAllocationInfo(alloc), lifetime)
+	}
+}
+
+func (h *eventHandler) OnAllocationDeleted(alloc *allocation.Allocation, expired bool) {
+	if h.handlers.OnAllocationDeleted != nil {
+		h.handlers.OnAllocationDeleted(newAllocationInfo(alloc), expired)
+	}
+}
+
+func (h *eventHandler) OnPermissionCreated(alloc *allocation.Allocation, peer net.Addr) {
+	if h.handlers.OnPermissionCreated != nil {
+		h.handlers.OnPermissionCreated(newAllocationInfo(alloc), addrIP(peer))
+	}
+}
+
+func (h *eventHandler) OnPermissionRefreshed(alloc *allocation.Allocation, peer net.Addr) {
+	if h.handlers.OnPermissionRefreshed != nil {
+		h.handlers.OnPermissionRefreshed(newAllocationInfo(alloc), addrIP(peer))
+	}
+}
+
+func (h *eventHandler) OnPermissionExpired(alloc *allocation.Allocation, peer net.Addr) {
+	if h.handlers.OnPermissionExpired != nil {
+		h.handlers.OnPermissionExpired(newAllocationInfo(alloc), addrIP(peer))
+	}
+}
+
+func (h *eventHandler) OnChannelCreated(alloc *allocation.Allocation, number proto.ChannelNumber, peer net.Addr) {
+	if h.handlers.OnChannelCreated != nil {
+		h.handlers.OnChannelCreated(newAllocationInfo(alloc), uint16(number), peer)
+	}
+}
+
+func (h *eventHandler) OnChannelRefreshed(alloc *allocation.Allocation, number proto.ChannelNumber, peer net.Addr) {
+	if h.handlers.OnChannelRefreshed != nil {
+		h.handlers.OnChannelRefreshed(newAllocationInfo(alloc), uint16(number), peer)
+	}
+}
+
+func (h *eventHandler) OnChannelExpired(alloc *allocation.Allocation, number proto.ChannelNumber, peer net.Addr) {
+	if h.handlers.OnChannelExpired != nil {
+		h.handlers.OnChannelExpired(newAllocationInfo(alloc), uint16(number), peer)
+	}
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+// Package main implements a TURN server with DTLS support
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+
+	"github.com/pion/dtls/v3"
+	"github.com/pion/turn/v4"
+)
+
+func main() {
+	publicIP := flag.String("public-ip", "", "IP Address that TURN can be contacted by.")
+	port := flag.Int("port", 5349, "Listening port.")
+	users := flag.String("users", "", "List of username and password (e.g. \"user=pass,user=pass\")")
+	realm := flag.String("realm", "pion.ly", "Realm (defaults to \"pion.ly\")")
+	certFile := flag.String("cert", "server.crt", "Certificate (defaults to \"server.crt\")")
+	keyFile := flag.String("key", "server.key", "Key (defaults to \"server.key\")")
+	flag.Parse()
+
+	if len(*publicIP) == 0 {
+		log.Fatalf("'public-ip' is required")
+	} else if len(*users) == 0 {
+		log.Fatalf("'users' is required")
+	}
+
+	cer, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+	if err != nil {
+		log.Println(err)
+
+		return
+	}
+
+	// Create a DTLS listener to pass into pion/turn
+	// pion/turn itself doesn't allocate any DTLS listeners, but lets the user pass them in
+	// this allows us to add logging, storage or modify inbound/outbound traffic
+	dtlsListener, err := dtls.Listen("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: *port}, &dtls.Config{
+		Certificates: []tls.Certificate{cer},
+	})
+	if err != nil {
+		log.Println(err)
+
+		return
+	}
+
+	// Cache -users flag for easy lookup later
+	// If passwords are stored they should be saved to your DB hashed using turn.GenerateAuthKey
+	usersMap := map[string][]byte{}
+	for _, kv := range regexp.MustCompile(`(\w+)=(\w+)`).FindAllStringSubmatch(*users, -1) {
+		usersMap[kv[1]] = turn.GenerateAuthKey(kv[1], *realm, kv[2])
+	}
+
+	server, err := turn.NewServer(turn.ServerConfig{
+		Realm: *realm,
+		// Set AuthHandler callback
+		// This is called every time a user tries to authenticate with the TURN server
+		// Return the key for that user, or false when no user is found
+		AuthHandler: func(username string, realm string, srcAddr net.Addr) ([]byte, bool) { // nolint: revive
+			if key, ok := usersMap[username]; ok {
+				return key, true
+			}
+
+			return nil, false
+		},
+		// PacketConnConfigs is a list of UDP Listeners and the configuration around them
+		// NewDTLSPacketConn serves a DTLS connection per client like a UDP socket
+		PacketConnConfigs: []turn.PacketConnConfig{
+			{
+				PacketConn: turn.NewDTLSPacketConn(dtlsListener),
+				RelayAddressGenerator: &turn.RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP(*publicIP),
+					Address:      "0.0.0.0",
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Block until user sends SIGINT or SIGTERM
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	<-sigs
+
+	if err = server.Close(); err != nil {
+		log.Panic(err)
+	}
+}
@@ -6,12 +6,14 @@ package allocation
 
 import (
 	"net"
+	"net/netip"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/pion/logging"
 	"github.com/pion/stun/v3"
+	"github.com/pion/transport/v3/udp"
 	"github.com/pion/turn/v4/internal/ipnet"
 	"github.com/pion/turn/v4/internal/proto"
 )
@@ -28,11 +30,29 @@ type Allocation struct {
 	Protocol            Protocol
 	TurnSocket          net.PacketConn
 	RelaySocket         net.PacketConn
-	fiveTuple           *FiveTuple
+	RelayListener       net.Listener
+	fiveTuple           atomic.Pointer[FiveTuple]
 	permissionsLock     sync.RWMutex
-	permissions         map[string]*Permission
+	permissions         map[netip.Addr]*Permission
 	channelBindingsLock sync.RWMutex
 	channelBindings     []*ChannelBind
+	tcpConnsLock        sync.Mutex
+	tcpConns            map[proto.ConnectionID]*TCPConnection
+	quotaLock           sync.Mutex
+	quotas              *Quotas
+	username            string
+	srcIP               net.IP
+	quota               Quota
+	bandwidth           *tokenBucket
+	userBandwidth       *tokenBucket
+	accessTokenLock     sync.Mutex
+	accessTokenKey      []byte
+	accessTokenExpires  time.Time
+	mobilityTicket      string // Protected by the lock of the Manager
+	mobility            atomic.Bool
+	events              EventHandler
+	stats               stats
+	createdAt           time.Time
 	lifetimeTimer       *time.Timer
 	closed              chan interface{}
 	log                 logging.LeveledLogger
@@ -42,17 +62,34 @@ type Allocation struct {
 	// cache for response lost and client retry to implement 'stateless stack approach'
 	// See: https://datatracker.ietf.org/doc/html/rfc5766#section-6.2
 	responseCache atomic.Value // *allocationResponse
+
+	// AdditionalRelayAddr and AdditionalRelaySocket are the IPv6 relayed transport
+	// address of a dual-stack allocation, RelayAddr is then the IPv4 one.
+	// See: https://tools.ietf.org/html/rfc8656#section-7.2
+	AdditionalRelayAddr   net.Addr
+	AdditionalRelaySocket net.PacketConn
+
+	// relayBatchWriter and additionalRelayBatchWriter write the packets of a
+	// RelayWriteBatch from the relay sockets, nil if they are written one at a time.
+	relayBatchWriter           udp.BatchWriter
+	additionalRelayBatchWriter udp.BatchWriter
 }
 
 // NewAllocation creates a new instance of NewAllocation.
 func NewAllocation(turnSocket net.PacketConn, fiveTuple *FiveTuple, log logging.LeveledLogger) *Allocation {
-	return &Allocation{
+	alloc := &Allocation{
 		TurnSocket:  turnSocket,
-		fiveTuple:   fiveTuple,
-		permissions: make(map[string]*Permission, 64),
+		permissions: make(map[netip.Addr]*Permission, 64),
+		tcpConns:    make(map[proto.ConnectionID]*TCPConnection),
+		bandwidth:   newTokenBucket(0),
+		events:      nopEventHandler{},
+		createdAt:   time.Now(),
 		closed:      make(chan interface{}),
 		log:         log,
 	}
+	alloc.fiveTuple.Store(fiveTuple)
+
+	return alloc
 }
 
 // GetPermission gets the Permission from the allocation.
@@ -67,22 +104,24 @@ func (a *Allocation) GetPermission(addr net.Addr) *Permission {
 func (a *Allocation) AddPermission(perms *Permission) {
 	fingerprint := ipnet.FingerprintAddr(perms.Addr)
 
-	a.permissionsLock.RLock()
+	// The permission is started before it is published, Close stops its timer.
+	a.permissionsLock.Lock()
 	existedPermission, ok := a.permissions[fingerprint]
-	a.permissionsLock.RUnlock()
+	if !ok {
+		perms.allocation = a
+		perms.start(permissionTimeout)
+		a.permissions[fingerprint] = perms
+	}
+	a.permissionsLock.Unlock()
 
 	if ok {
 		existedPermission.refresh(permissionTimeout)
+		a.events.OnPermissionRefreshed(a, existedPermission.Addr)
 
 		return
 	}
 
-	perms.allocation = a
-	a.permissionsLock.Lock()
-	a.permissions[fingerprint] = perms
-	a.permissionsLock.Unlock()
-
-	perms.start(permissionTimeout)
+	a.events.OnPermissionCreated(a, perms.Addr)
 }
 
 // RemovePermission removes the net.Addr's fingerprint from the allocation's permissions.
@@ -106,19 +145,20 @@ func (a *Allocation) AddChannelBind(chanBind *ChannelBind, lifetime time.Duratio
 	// Add or refresh this channel.
 	if channelByNumber == nil {
 		a.channelBindingsLock.Lock()
-		defer a.channelBindingsLock.Unlock()
-
 		chanBind.allocation = a
 		a.channelBindings = append(a.channelBindings, chanBind)
 		chanBind.start(lifetime)
+		a.channelBindingsLock.Unlock()
 
 		// Channel binds also refresh permissions.
 		a.AddPermission(NewPermission(chanBind.Peer, a.log))
+		a.events.OnChannelCreated(a, chanBind.Number, chanBind.Peer)
 	} else {
 		channelByNumber.refresh(lifetime)
 
 		// Channel binds also refresh permissions.
 		a.AddPermission(NewPermission(channelByNumber.Peer, a.log))
+		a.events.OnChannelRefreshed(a, channelByNumber.Number, channelByNumber.Peer)
 	}
 
 	return nil
@@ -169,8 +209,9 @@ func (a *Allocation) GetChannelByAddr(addr net.Addr) *ChannelBind {
 // Refresh updates the allocations lifetime.
 func (a *Allocation) Refresh(lifetime time.Duration) {
 	if !a.lifetimeTimer.Reset(lifetime) {
-		a.log.Errorf("Failed to reset allocation timer for %v", a.fiveTuple)
+		a.log.Errorf("Failed to reset allocation timer for %v", a.FiveTuple())
 	}
+	a.events.OnAllocationRefreshed(a, lifetime)
 }
 
 // SetResponseCache cache allocation response for retransmit allocation request.
@@ -200,6 +241,7 @@ func (a *Allocation) Close() error {
 	close(a.closed)
 
 	a.lifetimeTimer.Stop()
+	a.releaseQuota()
 
 	a.permissionsLock.RLock()
 	for _, p := range a.permissions {
@@ -213,9 +255,62 @@ func (a *Allocation) Close() error {
 	}
 	a.channelBindingsLock.RUnlock()
 
+	a.tcpConnsLock.Lock()
+	tcpConns := make([]*TCPConnection, 0, len(a.tcpConns))
+	for _, c := range a.tcpConns {
+		tcpConns = append(tcpConns, c)
+	}
+	a.tcpConnsLock.Unlock()
+
+	for _, c := range tcpConns {
+		if err := c.Close(); err != nil {
+			a.log.Debugf("Failed to close connection to peer %v: %v", c.PeerAddr, err)
+		}
+	}
+
+	if a.RelayListener != nil {
+		return a.RelayListener.Close()
+	}
+
+	if a.AdditionalRelaySocket != nil {
+		if err := a.AdditionalRelaySocket.Close(); err != nil {
+			a.log.Debugf("Failed to close relay socket %v: %v", a.AdditionalRelayAddr, err)
+		}
+	}
+
 	return a.RelaySocket.Close()
 }
 
+// RelaySocketFor returns the relay socket of the address family of the peer, or
+// nil if the allocation has no relayed transport address of that family.
+func (a *Allocation) RelaySocketFor(peer net.Addr) net.PacketConn {
+	switch {
+	case sameAddressFamily(a.RelayAddr, peer):
+		return a.RelaySocket
+	case a.AdditionalRelaySocket != nil && sameAddressFamily(a.AdditionalRelayAddr, peer):
+		return a.AdditionalRelaySocket
+	default:
+		return nil
+	}
+}
+
+// HasAddressFamilyOf returns whether the allocation has a relayed transport
+// address of the address family of the peer.
+func (a *Allocation) HasAddressFamilyOf(peer net.Addr) bool {
+	return sameAddressFamily(a.RelayAddr, peer) ||
+		a.AdditionalRelayAddr != nil && sameAddressFamily(a.AdditionalRelayAddr, peer)
+}
+
+func sameAddressFamily(a, b net.Addr) bool {
+	aIP, _, aErr := ipnet.AddrIPPort(a)
+	bIP, _, bErr := ipnet.AddrIPPort(b)
+	if aErr != nil || bErr != nil {
+		return false
+	}
+
+	return (aIP.To4() == nil) == (bIP.To4() == nil)
+}
+
 //  https://tools.ietf.org/html/rfc5766#section-10.3
 //  When the server receives a UDP datagram at a currently allocated
 //  relayed transport address, the server looks up the allocation
@@ -238,62 +333,110 @@ func (a *Allocation) Close() error {
 
 const rtpMTU = 1600
 
-func (a *Allocation) packetHandler(manager *Manager) {
-	buffer := make([]byte, rtpMTU)
+func (a *Allocation) packetHandler(manager *Manager, relaySocket net.PacketConn) {
+	var bufs relayBuffers
+	if batchReader := manager.relayBatchReader(relaySocket); batchReader != nil {
+		a.readRelayBatches(manager, batchReader, &bufs)
 
+		return
+	}
+
+	buffer := make([]byte, rtpMTU)
 	for {
-		n, srcAddr, err := a.RelaySocket.ReadFrom(buffer)
+		n, srcAddr, err := relaySocket.ReadFrom(buffer)
 		if err != nil {
-			manager.DeleteAllocation(a.fiveTuple)
+			manager.DeleteAllocation(a.FiveTuple())
 
 			return
 		}
 
-		a.log.Debugf("Relay socket %s received %d bytes from %s",
-			a.RelaySocket.LocalAddr(),
-			n,
-			srcAddr)
-
-		if channel := a.GetChannelByAddr(srcAddr); channel != nil { // nolint:nestif
-			channelData := &proto.ChannelData{
-				Data:   buffer[:n],
-				Number: channel.Number,
-			}
-			channelData.Encode()
-
-			if _, err = a.TurnSocket.WriteTo(channelData.Raw, a.fiveTuple.SrcAddr); err != nil {
-				a.log.Errorf("Failed to send ChannelData from allocation %v %v", srcAddr, err)
-			}
-		} else if p := a.GetPermission(srcAddr); p != nil {
-			udpAddr, ok := srcAddr.(*net.UDPAddr)
-			if !ok {
-				a.log.Errorf("Failed to send DataIndication from allocation %v %v", srcAddr, err)
-
-				return
-			}
-
-			peerAddressAttr := proto.PeerAddress{IP: udpAddr.IP, Port: udpAddr.Port}
-			dataAttr := proto.Data(buffer[:n])
-
-			msg, err := stun.Build(
-				stun.TransactionID,
-				stun.NewType(stun.MethodData, stun.ClassIndication),
-				peerAddressAttr,
-				dataAttr,
-			)
-			if err != nil {
-				a.log.Errorf("Failed to send DataIndication from allocation %v %v", srcAddr, err)
-
-				return
-			}
-			a.log.Debugf("Relaying message from %s to client at %s",
-				srcAddr,
-				a.fiveTuple.SrcAddr)
-			if _, err = a.TurnSocket.WriteTo(msg.Raw, a.fiveTuple.SrcAddr); err != nil {
-				a.log.Errorf("Failed to send DataIndication from allocation %v %v", srcAddr, err)
-			}
+		if !a.relayToClient(buffer[:n], srcAddr, &bufs) {
+			return
+		}
+	}
+}
+
+// relayBuffers are reused to encode the packets a relay socket relays to the
+// client, so that relaying does not allocate per packet.
+type relayBuffers struct {
+	channelData proto.ChannelData
+	msg         stun.Message
+}
+
+// relayToClient relays a packet received from a peer on the relay socket to the
+// client, it returns false if the relay socket must not be read anymore.
+func (a *Allocation) relayToClient(data []byte, srcAddr net.Addr, bufs *relayBuffers) bool {
+	if !a.AllowRelay(len(data)) {
+		a.log.Debugf("Bandwidth exceeded, dropping %d bytes from %v on allocation %v", len(data), srcAddr, a.RelayAddr)
+
+		return true
+	}
+
+	if channel := a.GetChannelByAddr(srcAddr); channel != nil { // nolint:nestif
+		bufs.channelData.Data = data
+		bufs.channelData.Number = channel.Number
+		bufs.channelData.Encode()
+
+		if _, err := a.TurnSocket.WriteTo(bufs.channelData.Raw, a.FiveTuple().SrcAddr); err != nil {
+			a.log.Errorf("Failed to send ChannelData from allocation %v %v", srcAddr, err)
+		} else {
+			a.addReceived(len(data))
+		}
+	} else if p := a.GetPermission(srcAddr); p != nil {
+		udpAddr, ok := srcAddr.(*net.UDPAddr)
+		if !ok {
+			a.log.Errorf("Failed to send DataIndication from allocation %v: not a UDP address", srcAddr)
+
+			return false
+		}
+
+		peerAddressAttr := proto.PeerAddress{IP: udpAddr.IP, Port: udpAddr.Port}
+		dataAttr := proto.Data(data)
+
+		if err := bufs.msg.Build(
+			stun.TransactionID,
+			stun.NewType(stun.MethodData, stun.ClassIndication),
+			peerAddressAttr,
+			dataAttr,
+		); err != nil {
+			a.log.Errorf("Failed to send DataIndication from allocation %v %v", srcAddr, err)
+
+			return false
+		}
+		a.log.Debugf("Relaying message from %s to client at %s",
+			srcAddr,
+			a.FiveTuple().SrcAddr)
+		if _, err := a.TurnSocket.WriteTo(bufs.msg.Raw, a.FiveTuple().SrcAddr); err != nil {
+			a.log.Errorf("Failed to send DataIndication from allocation %v %v", srcAddr, err)
 		} else {
-			a.log.Infof("No Permission or Channel exists for %v on allocation %v", srcAddr, a.RelayAddr)
+			a.addReceived(len(data))
 		}
+	} else {
+		a.log.Infof("No Permission or Channel exists for %v on allocation %v", srcAddr, a.RelayAddr)
 	}
+
+	return true
+}
+
+// SetAccessTokenKey caches the mac_key of the ACCESS-TOKEN that authorized the
+// allocation until the token expires, see https://tools.ietf.org/html/rfc7635.
+func (a *Allocation) SetAccessTokenKey(key []byte, expires time.Time) {
+	a.accessTokenLock.Lock()
+	defer a.accessTokenLock.Unlock()
+
+	a.accessTokenKey = key
+	a.accessTokenExpires = expires
+}
+
+// AccessTokenKey returns the mac_key of the ACCESS-TOKEN that authorized the
+// allocation, or nil if there is none or it has expired.
+func (a *Allocation) AccessTokenKey() []byte {
+	a.accessTokenLock.Lock()
+	defer a.accessTokenLock.Unlock()
+
+	if a.accessTokenKey == nil || time.Now().After(a.accessTokenExpires) {
+		return nil
+	}
+
+	return a.accessTokenKey
 }
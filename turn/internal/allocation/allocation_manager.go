@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/pion/logging"
+	"github.com/pion/turn/v4/internal/proto"
 )
 
 // ManagerConfig a bag of config params for Manager.
@@ -18,6 +19,20 @@ type ManagerConfig struct {
 	AllocatePacketConn func(network string, requestedPort int) (net.PacketConn, net.Addr, error)
 	AllocateConn       func(network string, requestedPort int) (net.Conn, net.Addr, error)
 	PermissionHandler  func(sourceAddr net.Addr, peerIP net.IP) bool
+
+	// AllocateListener and DialTCP are optional, TCP allocations are only
+	// supported if both are set.
+	AllocateListener func(network string, requestedPort int) (net.Listener, net.Addr, error)
+	DialTCP          func(network string, relayAddr, rAddr *net.TCPAddr) (net.Conn, error)
+
+	// EventHandler is optional, it is notified of the lifecycle of allocations
+	EventHandler EventHandler
+
+	// BatchSize is the maximum number of packets read from a relay socket at
+	// once. Above 1, relay sockets are read and written in batches (recvmmsg and
+	// sendmmsg on Linux) if they are a *net.UDPConn or implement the BatchReader
+	// and BatchWriter of github.com/pion/transport/v3/udp.
+	BatchSize int
 }
 
 type reservation struct {
@@ -33,9 +48,23 @@ type Manager struct {
 	allocations  map[FiveTupleFingerprint]*Allocation
 	reservations []*reservation
 
+	// mobilityTickets holds the allocations that move to the five-tuple of
+	// the Refresh request carrying their MOBILITY-TICKET.
+	mobilityTickets map[string]*Allocation
+
+	// tcpConnections holds the peer connections of TCP allocations until
+	// the client binds them with a ConnectionBind request.
+	tcpConnections map[proto.ConnectionID]*TCPConnection
+
 	allocatePacketConn func(network string, requestedPort int) (net.PacketConn, net.Addr, error)
 	allocateConn       func(network string, requestedPort int) (net.Conn, net.Addr, error)
+	allocateListener   func(network string, requestedPort int) (net.Listener, net.Addr, error)
+	dialTCP            func(network string, relayAddr, rAddr *net.TCPAddr) (net.Conn, error)
 	permissionHandler  func(sourceAddr net.Addr, peerIP net.IP) bool
+	events             EventHandler
+
+	batchSize    int
+	relayBatches sync.Pool
 }
 
 // NewManager creates a new instance of Manager.
@@ -49,13 +78,29 @@ func NewManager(config ManagerConfig) (*Manager, error) {
 		return nil, errLeveledLoggerMustBeSet
 	}
 
-	return &Manager{
+	events := config.EventHandler
+	if events == nil {
+		events = nopEventHandler{}
+	}
+
+	manager := &Manager{
 		log:                config.LeveledLogger,
 		allocations:        make(map[FiveTupleFingerprint]*Allocation, 64),
+		mobilityTickets:    make(map[string]*Allocation),
+		tcpConnections:     make(map[proto.ConnectionID]*TCPConnection),
 		allocatePacketConn: config.AllocatePacketConn,
 		allocateConn:       config.AllocateConn,
+		allocateListener:   config.AllocateListener,
+		dialTCP:            config.DialTCP,
 		permissionHandler:  config.PermissionHandler,
-	}, nil
+		events:             events,
+		batchSize:          config.BatchSize,
+	}
+	manager.relayBatches.New = func() any {
+		return newRelayBatch(manager.batchSize)
+	}
+
+	return manager, nil
 }
 
 // GetAllocation fetches the allocation matching the passed FiveTuple.
@@ -76,24 +121,152 @@ func (m *Manager) AllocationCount() int {
 
 // Close closes the manager and closes all allocations it manages.
 func (m *Manager) Close() error {
+	// Closing TCP allocations takes the lock to remove their connections
 	m.lock.Lock()
-	defer m.lock.Unlock()
-
+	allocations := make([]*Allocation, 0, len(m.allocations))
 	for _, a := range m.allocations {
+		allocations = append(allocations, a)
+	}
+	m.allocations = make(map[FiveTupleFingerprint]*Allocation)
+	m.mobilityTickets = make(map[string]*Allocation)
+	m.lock.Unlock()
+
+	for _, a := range allocations {
 		if err := a.Close(); err != nil {
 			return err
 		}
+		m.events.OnAllocationDeleted(a, false)
 	}
 
 	return nil
 }
 
-// CreateAllocation creates a new allocation and starts relaying.
+// CreateAllocation creates a new allocation with an IPv4 relayed transport
+// address and starts relaying.
 func (m *Manager) CreateAllocation(
 	fiveTuple *FiveTuple,
 	turnSocket net.PacketConn,
 	requestedPort int,
 	lifetime time.Duration,
+	username string,
+) (*Allocation, error) {
+	return m.createUDPAllocation("udp4", fiveTuple, turnSocket, requestedPort, lifetime, username)
+}
+
+// CreateIPv6Allocation creates a new allocation with an IPv6 relayed transport
+// address and starts relaying, see https://tools.ietf.org/html/rfc6156#section-4.2.
+func (m *Manager) CreateIPv6Allocation(
+	fiveTuple *FiveTuple,
+	turnSocket net.PacketConn,
+	requestedPort int,
+	lifetime time.Duration,
+	username string,
+) (*Allocation, error) {
+	return m.createUDPAllocation("udp6", fiveTuple, turnSocket, requestedPort, lifetime, username)
+}
+
+func (m *Manager) createUDPAllocation(
+	network string,
+	fiveTuple *FiveTuple,
+	turnSocket net.PacketConn,
+	requestedPort int,
+	lifetime time.Duration,
+	username string,
+) (*Allocation, error) {
+	return m.createAllocation(fiveTuple, turnSocket, lifetime, username, func(alloc *Allocation) error {
+		conn, relayAddr, err := m.allocatePacketConn(network, requestedPort)
+		if err != nil {
+			return err
+		}
+
+		alloc.RelaySocket = conn
+		alloc.RelayAddr = relayAddr
+
+		return nil
+	})
+}
+
+// CreateDualStackAllocation creates a new allocation with both an IPv4 and an
+// IPv6 relayed transport address and starts relaying, see
+// https://tools.ietf.org/html/rfc8656#section-7.2. The allocation is created
+// as long as one of them can be allocated, ipv4Err and ipv6Err are the errors
+// of the ones that could not. If the allocation is not created, both are set.
+func (m *Manager) CreateDualStackAllocation(
+	fiveTuple *FiveTuple,
+	turnSocket net.PacketConn,
+	requestedPort int,
+	lifetime time.Duration,
+	username string,
+) (alloc *Allocation, ipv4Err, ipv6Err error) {
+	alloc, err := m.createAllocation(fiveTuple, turnSocket, lifetime, username, func(alloc *Allocation) error {
+		conn4, relayAddr4, err4 := m.allocatePacketConn("udp4", requestedPort)
+		conn6, relayAddr6, err6 := m.allocatePacketConn("udp6", 0)
+		ipv4Err, ipv6Err = err4, err6
+
+		switch {
+		case err4 == nil && err6 == nil:
+			alloc.RelaySocket, alloc.RelayAddr = conn4, relayAddr4
+			alloc.AdditionalRelaySocket, alloc.AdditionalRelayAddr = conn6, relayAddr6
+		case err4 == nil:
+			alloc.RelaySocket, alloc.RelayAddr = conn4, relayAddr4
+		case err6 == nil:
+			alloc.RelaySocket, alloc.RelayAddr = conn6, relayAddr6
+		default:
+			return err4
+		}
+
+		return nil
+	})
+	if err != nil {
+		if ipv4Err == nil || ipv6Err == nil {
+			ipv4Err, ipv6Err = err, err
+		}
+
+		return nil, ipv4Err, ipv6Err
+	}
+
+	return alloc, ipv4Err, ipv6Err
+}
+
+// CreateTCPAllocation creates a new allocation with a TCP relayed transport
+// address and starts accepting connections from peers, see
+// https://tools.ietf.org/html/rfc6062#section-5.1.
+func (m *Manager) CreateTCPAllocation(
+	fiveTuple *FiveTuple,
+	turnSocket net.PacketConn,
+	requestedPort int,
+	lifetime time.Duration,
+	username string,
+) (*Allocation, error) {
+	if !m.SupportsTCP() {
+		return nil, errTCPAllocationUnsupported
+	}
+
+	return m.createAllocation(fiveTuple, turnSocket, lifetime, username, func(alloc *Allocation) error {
+		listener, relayAddr, err := m.allocateListener("tcp4", requestedPort)
+		if err != nil {
+			return err
+		}
+
+		alloc.Protocol = TCP
+		alloc.RelayListener = listener
+		alloc.RelayAddr = relayAddr
+
+		return nil
+	})
+}
+
+// SupportsTCP returns whether TCP allocations can be created.
+func (m *Manager) SupportsTCP() bool {
+	return m.allocateListener != nil && m.dialTCP != nil
+}
+
+func (m *Manager) createAllocation(
+	fiveTuple *FiveTuple,
+	turnSocket net.PacketConn,
+	lifetime time.Duration,
+	username string,
+	allocateRelay func(alloc *Allocation) error,
 ) (*Allocation, error) {
 	switch {
 	case fiveTuple == nil:
@@ -112,37 +285,53 @@ func (m *Manager) CreateAllocation(
 		return nil, fmt.Errorf("%w: %v", errDupeFiveTuple, fiveTuple)
 	}
 	alloc := NewAllocation(turnSocket, fiveTuple, m.log)
+	alloc.username = username
+	alloc.events = m.events
 
-	conn, relayAddr, err := m.allocatePacketConn("udp4", requestedPort)
-	if err != nil {
+	if err := allocateRelay(alloc); err != nil {
 		return nil, err
 	}
 
-	alloc.RelaySocket = conn
-	alloc.RelayAddr = relayAddr
-
 	m.log.Debugf("Listening on relay address: %s", alloc.RelayAddr)
 
+	alloc.relayBatchWriter = m.relayBatchWriter(alloc.RelaySocket)
+	alloc.additionalRelayBatchWriter = m.relayBatchWriter(alloc.AdditionalRelaySocket)
+
 	alloc.lifetimeTimer = time.AfterFunc(lifetime, func() {
-		m.DeleteAllocation(alloc.fiveTuple)
+		m.deleteAllocation(alloc.FiveTuple(), true)
 	})
 
 	m.lock.Lock()
 	m.allocations[fiveTuple.Fingerprint()] = alloc
 	m.lock.Unlock()
 
-	go alloc.packetHandler(m)
+	if alloc.RelayListener != nil {
+		go alloc.acceptHandler(m)
+	} else {
+		go alloc.packetHandler(m, alloc.RelaySocket)
+	}
+	if alloc.AdditionalRelaySocket != nil {
+		go alloc.packetHandler(m, alloc.AdditionalRelaySocket)
+	}
+	m.events.OnAllocationCreated(alloc)
 
 	return alloc, nil
 }
 
 // DeleteAllocation removes an allocation.
 func (m *Manager) DeleteAllocation(fiveTuple *FiveTuple) {
+	m.deleteAllocation(fiveTuple, false)
+}
+
+func (m *Manager) deleteAllocation(fiveTuple *FiveTuple, expired bool) {
 	fingerprint := fiveTuple.Fingerprint()
 
 	m.lock.Lock()
 	allocation := m.allocations[fingerprint]
 	delete(m.allocations, fingerprint)
+	if allocation != nil && allocation.mobilityTicket != "" {
+		delete(m.mobilityTickets, allocation.mobilityTicket)
+	}
 	m.lock.Unlock()
 
 	if allocation == nil {
@@ -152,6 +341,7 @@ func (m *Manager) DeleteAllocation(fiveTuple *FiveTuple) {
 	if err := allocation.Close(); err != nil {
 		m.log.Errorf("Failed to close allocation: %v", err)
 	}
+	m.events.OnAllocationDeleted(allocation, expired)
 }
 
 // CreateReservation stores the reservation for the token+port.
@@ -7,10 +7,13 @@
 package allocation
 
 import (
+	"errors"
 	"io"
 	"math/rand"
 	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -31,6 +34,11 @@ func TestManager(t *testing.T) {
 		{"AllocationTimeout", subTestAllocationTimeout},
 		{"Close", subTestManagerClose},
 		{"GetRandomEvenPort", subTestGetRandomEvenPort},
+		{"CreateTCPAllocation", subTestCreateTCPAllocation},
+		{"TCPAllocationAcceptError", subTestTCPAllocationAcceptError},
+		{"EventHandler", subTestEventHandler},
+		{"MoveAllocation", subTestMoveAllocation},
+		{"RelayWriteBatch", subTestRelayWriteBatch},
 	}
 
 	network := "udp4"
@@ -54,13 +62,13 @@ func subTestCreateInvalidAllocation(t *testing.T, turnSocket net.PacketConn) {
 	m, err := newTestManager()
 	assert.NoError(t, err)
 
-	if a, err := m.CreateAllocation(nil, turnSocket, 0, proto.DefaultLifetime); a != nil || err == nil {
+	if a, err := m.CreateAllocation(nil, turnSocket, 0, proto.DefaultLifetime, ""); a != nil || err == nil {
 		t.Errorf("Illegally created allocation with nil FiveTuple")
 	}
-	if a, err := m.CreateAllocation(randomFiveTuple(), nil, 0, proto.DefaultLifetime); a != nil || err == nil {
+	if a, err := m.CreateAllocation(randomFiveTuple(), nil, 0, proto.DefaultLifetime, ""); a != nil || err == nil {
 		t.Errorf("Illegally created allocation with nil turnSocket")
 	}
-	if a, err := m.CreateAllocation(randomFiveTuple(), turnSocket, 0, 0); a != nil || err == nil {
+	if a, err := m.CreateAllocation(randomFiveTuple(), turnSocket, 0, 0, ""); a != nil || err == nil {
 		t.Errorf("Illegally created allocation with 0 lifetime")
 	}
 }
@@ -73,7 +81,7 @@ func subTestCreateAllocation(t *testing.T, turnSocket net.PacketConn) {
 	assert.NoError(t, err)
 
 	fiveTuple := randomFiveTuple()
-	if a, err := m.CreateAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime); a == nil || err != nil {
+	if a, err := m.CreateAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime, ""); a == nil || err != nil {
 		t.Errorf("Failed to create allocation %v %v", a, err)
 	}
 
@@ -90,11 +98,11 @@ func subTestCreateAllocationDuplicateFiveTuple(t *testing.T, turnSocket net.Pack
 	assert.NoError(t, err)
 
 	fiveTuple := randomFiveTuple()
-	if a, err := m.CreateAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime); a == nil || err != nil {
+	if a, err := m.CreateAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime, ""); a == nil || err != nil {
 		t.Errorf("Failed to create allocation %v %v", a, err)
 	}
 
-	if a, err := m.CreateAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime); a != nil || err == nil {
+	if a, err := m.CreateAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime, ""); a != nil || err == nil {
 		t.Errorf("Was able to create allocation with same FiveTuple twice")
 	}
 }
@@ -106,7 +114,7 @@ func subTestDeleteAllocation(t *testing.T, turnSocket net.PacketConn) {
 	assert.NoError(t, err)
 
 	fiveTuple := randomFiveTuple()
-	if a, err := manager.CreateAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime); a == nil || err != nil {
+	if a, err := manager.CreateAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime, ""); a == nil || err != nil {
 		t.Errorf("Failed to create allocation %v %v", a, err)
 	}
 
@@ -133,7 +141,7 @@ func subTestAllocationTimeout(t *testing.T, turnSocket net.PacketConn) {
 	for index := range allocations {
 		fiveTuple := randomFiveTuple()
 
-		a, err := m.CreateAllocation(fiveTuple, turnSocket, 0, lifetime)
+		a, err := m.CreateAllocation(fiveTuple, turnSocket, 0, lifetime, "")
 		if err != nil {
 			t.Errorf("Failed to create allocation with %v", fiveTuple)
 		}
@@ -159,9 +167,9 @@ func subTestManagerClose(t *testing.T, turnSocket net.PacketConn) {
 
 	allocations := make([]*Allocation, 2)
 
-	a1, _ := manager.CreateAllocation(randomFiveTuple(), turnSocket, 0, time.Second)
+	a1, _ := manager.CreateAllocation(randomFiveTuple(), turnSocket, 0, time.Second, "")
 	allocations[0] = a1
-	a2, _ := manager.CreateAllocation(randomFiveTuple(), turnSocket, 0, time.Minute)
+	a2, _ := manager.CreateAllocation(randomFiveTuple(), turnSocket, 0, time.Minute, "")
 	allocations[1] = a2
 
 	// Make a1 timeout
@@ -178,6 +186,256 @@ func subTestManagerClose(t *testing.T, turnSocket net.PacketConn) {
 	}
 }
 
+// Test creating an allocation with a TCP relayed transport address.
+func subTestCreateTCPAllocation(t *testing.T, turnSocket net.PacketConn) {
+	t.Helper()
+
+	manager, err := newTestManager()
+	assert.NoError(t, err)
+
+	_, err = manager.CreateTCPAllocation(randomFiveTuple(), turnSocket, 0, proto.DefaultLifetime, "")
+	assert.ErrorIs(t, err, errTCPAllocationUnsupported)
+
+	manager.allocateListener = func(network string, _ int) (net.Listener, net.Addr, error) {
+		listener, err := net.Listen(network, "127.0.0.1:0")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return listener, listener.Addr(), nil
+	}
+	manager.dialTCP = func(network string, _, rAddr *net.TCPAddr) (net.Conn, error) {
+		return net.DialTCP(network, nil, rAddr)
+	}
+
+	alloc, err := manager.CreateTCPAllocation(randomFiveTuple(), turnSocket, 0, proto.DefaultLifetime, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, TCP, alloc.Protocol)
+	assert.Nil(t, alloc.RelaySocket)
+
+	udpAlloc, err := manager.CreateAllocation(randomFiveTuple(), turnSocket, 0, proto.DefaultLifetime, "")
+	assert.NoError(t, err)
+
+	peerAddr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+	_, err = manager.Connect(udpAlloc, peerAddr)
+	assert.ErrorIs(t, err, errNotTCPAllocation)
+
+	_, err = manager.BindTCPConnection(1, "alice")
+	assert.ErrorIs(t, err, errNoSuchTCPConnection)
+
+	// Only the user of the allocation binds its connections to peers
+	peerListener, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	tcpConn, err := manager.Connect(alloc, peerListener.Addr().(*net.TCPAddr)) //nolint:forcetypeassert
+	assert.NoError(t, err)
+	_, err = manager.BindTCPConnection(tcpConn.ID, "bob")
+	assert.ErrorIs(t, err, errTCPConnectionUsername)
+	bound, err := manager.BindTCPConnection(tcpConn.ID, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, tcpConn, bound)
+	assert.NoError(t, bound.Close())
+	assert.NoError(t, peerListener.Close())
+
+	assert.NoError(t, manager.Close())
+	_, err = alloc.RelayListener.Accept()
+	assert.Error(t, err)
+}
+
+var errTooManyOpenFiles = errors.New("too many open files")
+
+// flakyListener fails its first Accept with an error other than net.ErrClosed.
+type flakyListener struct {
+	net.Listener
+
+	failed atomic.Bool
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	if !l.failed.Swap(true) {
+		return nil, errTooManyOpenFiles
+	}
+
+	return l.Listener.Accept()
+}
+
+// Test that a failed Accept only deletes the TCP allocation once the listener is closed.
+func subTestTCPAllocationAcceptError(t *testing.T, turnSocket net.PacketConn) {
+	t.Helper()
+
+	manager, err := newTestManager()
+	assert.NoError(t, err)
+
+	listener := &flakyListener{}
+	manager.allocateListener = func(network string, _ int) (net.Listener, net.Addr, error) {
+		l, err := net.Listen(network, "127.0.0.1:0")
+		if err != nil {
+			return nil, nil, err
+		}
+		listener.Listener = l
+
+		return listener, l.Addr(), nil
+	}
+	manager.dialTCP = func(network string, _, rAddr *net.TCPAddr) (net.Conn, error) {
+		return net.DialTCP(network, nil, rAddr)
+	}
+
+	fiveTuple := randomFiveTuple()
+	_, err = manager.CreateTCPAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime, "")
+	assert.NoError(t, err)
+
+	assert.Eventually(t, listener.failed.Load, time.Second, 10*time.Millisecond)
+	time.Sleep(10 * minAcceptDelay)
+	assert.NotNil(t, manager.GetAllocation(fiveTuple))
+
+	assert.NoError(t, listener.Close())
+	assert.Eventually(t, func() bool {
+		return manager.GetAllocation(fiveTuple) == nil
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, manager.Close())
+}
+
+type recordingEventHandler struct {
+	nopEventHandler
+
+	lock   sync.Mutex
+	events []string
+}
+
+func (h *recordingEventHandler) record(event string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.events = append(h.events, event)
+}
+
+func (h *recordingEventHandler) recorded() []string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return append([]string{}, h.events...)
+}
+
+func (h *recordingEventHandler) OnAllocationCreated(*Allocation) {
+	h.record("allocation created")
+}
+
+func (h *recordingEventHandler) OnAllocationDeleted(_ *Allocation, expired bool) {
+	if expired {
+		h.record("allocation expired")
+	} else {
+		h.record("allocation deleted")
+	}
+}
+
+func (h *recordingEventHandler) OnPermissionCreated(*Allocation, net.Addr) {
+	h.record("permission created")
+}
+
+func (h *recordingEventHandler) OnPermissionRefreshed(*Allocation, net.Addr) {
+	h.record("permission refreshed")
+}
+
+func (h *recordingEventHandler) OnChannelCreated(*Allocation, proto.ChannelNumber, net.Addr) {
+	h.record("channel created")
+}
+
+func (h *recordingEventHandler) OnChannelRefreshed(*Allocation, proto.ChannelNumber, net.Addr) {
+	h.record("channel refreshed")
+}
+
+// Test the lifecycle events of allocations, permissions and channels.
+func subTestEventHandler(t *testing.T, turnSocket net.PacketConn) {
+	t.Helper()
+
+	manager, err := newTestManager()
+	assert.NoError(t, err)
+
+	handler := &recordingEventHandler{}
+	manager.events = handler
+
+	alloc, err := manager.CreateAllocation(randomFiveTuple(), turnSocket, 0, time.Second, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, "user", alloc.Username())
+	assert.Len(t, manager.Allocations(), 1)
+
+	peer := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}
+	alloc.AddPermission(NewPermission(peer, manager.log))
+	alloc.AddPermission(NewPermission(peer, manager.log))
+	assert.NoError(t, alloc.AddChannelBind(NewChannelBind(proto.MinChannelNumber, peer, manager.log), time.Minute))
+	assert.NoError(t, alloc.AddChannelBind(NewChannelBind(proto.MinChannelNumber, peer, manager.log), time.Minute))
+
+	alloc.AddSent(100)
+	alloc.addReceived(50)
+	assert.Equal(t, Stats{BytesSent: 100, PacketsSent: 1, BytesReceived: 50, PacketsReceived: 1}, alloc.Stats())
+
+	// Wait for the allocation to expire
+	time.Sleep(2 * time.Second)
+	assert.Empty(t, manager.Allocations())
+	assert.Equal(t, []string{
+		"allocation created",
+		"permission created",
+		"permission refreshed",
+		"permission refreshed",
+		"channel created",
+		"permission refreshed",
+		"channel refreshed",
+		"allocation expired",
+	}, handler.recorded())
+
+	assert.NoError(t, manager.Close())
+}
+
+// Test that a MOBILITY-TICKET moves an allocation to a new FiveTuple.
+func subTestMoveAllocation(t *testing.T, turnSocket net.PacketConn) {
+	t.Helper()
+
+	manager, err := newTestManager()
+	assert.NoError(t, err)
+
+	fiveTuple := randomFiveTuple()
+	alloc, err := manager.CreateAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime, "user")
+	assert.NoError(t, err)
+	assert.False(t, alloc.Mobility())
+
+	ticket, err := manager.NewMobilityTicket(alloc)
+	assert.NoError(t, err)
+	assert.True(t, alloc.Mobility())
+
+	movedFiveTuple := randomFiveTuple()
+	movedFiveTuple.DstAddr = fiveTuple.DstAddr
+	_, err = manager.MoveAllocation([]byte("unknown"), movedFiveTuple, "user")
+	assert.ErrorIs(t, err, errNoSuchMobilityTicket)
+	_, err = manager.MoveAllocation(ticket, movedFiveTuple, "other")
+	assert.ErrorIs(t, err, errMobilityTicketUsername)
+
+	// The data of the allocation is relayed through the socket of its server
+	// address, a client that comes back on another one is rejected.
+	_, err = manager.MoveAllocation(ticket, randomFiveTuple(), "user")
+	assert.ErrorIs(t, err, errMobilityTicketServerAddr)
+	assert.Equal(t, alloc, manager.GetAllocation(fiveTuple))
+
+	moved, err := manager.MoveAllocation(ticket, movedFiveTuple, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, alloc, moved)
+	assert.Nil(t, manager.GetAllocation(fiveTuple))
+	assert.Equal(t, alloc, manager.GetAllocation(movedFiveTuple))
+	assert.True(t, alloc.FiveTuple().Equal(movedFiveTuple))
+
+	// A new ticket replaces the previous one.
+	newTicket, err := manager.NewMobilityTicket(alloc)
+	assert.NoError(t, err)
+	_, err = manager.MoveAllocation(ticket, randomFiveTuple(), "user")
+	assert.ErrorIs(t, err, errNoSuchMobilityTicket)
+
+	// The ticket of a deleted allocation is no longer valid.
+	manager.DeleteAllocation(movedFiveTuple)
+	_, err = manager.MoveAllocation(newTicket, randomFiveTuple(), "user")
+	assert.ErrorIs(t, err, errNoSuchMobilityTicket)
+
+	assert.NoError(t, manager.Close())
+}
+
 func randomFiveTuple() *FiveTuple {
 	// nolint
 	return &FiveTuple{
@@ -222,3 +480,42 @@ func subTestGetRandomEvenPort(t *testing.T, _ net.PacketConn) {
 	assert.True(t, port > 0)
 	assert.True(t, port%2 == 0)
 }
+
+func subTestRelayWriteBatch(t *testing.T, turnSocket net.PacketConn) {
+	t.Helper()
+
+	m, err := newTestManager()
+	assert.NoError(t, err)
+	m.batchSize = 4
+
+	fiveTuple := randomFiveTuple()
+	alloc, err := m.CreateAllocation(fiveTuple, turnSocket, 0, proto.DefaultLifetime, "")
+	assert.NoError(t, err)
+
+	peer, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	batch := NewRelayWriteBatch(4)
+	assert.Equal(t, alloc, batch.GetAllocation(m, fiveTuple))
+	assert.Nil(t, batch.GetAllocation(m, randomFiveTuple()))
+
+	// The packets are written by Flush, the batch takes up to 4 of them
+	for i := 0; i < 4; i++ {
+		assert.True(t, batch.WriteTo(alloc, alloc.RelaySocket, []byte{byte(i)}, peer.LocalAddr()))
+	}
+	assert.False(t, batch.WriteTo(alloc, alloc.RelaySocket, []byte{4}, peer.LocalAddr()))
+	assert.NoError(t, batch.Flush())
+
+	buf := make([]byte, 16)
+	for i := 0; i < 4; i++ {
+		n, _, err := peer.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{byte(i)}, buf[:n])
+	}
+
+	// Sockets that do not write batches are written directly
+	assert.False(t, batch.WriteTo(alloc, peer, []byte{0}, peer.LocalAddr()))
+
+	assert.NoError(t, peer.Close())
+	assert.NoError(t, m.Close())
+}
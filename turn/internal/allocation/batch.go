@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package allocation
+
+import (
+	"net"
+
+	"github.com/pion/transport/v3/udp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// relayBatch holds the messages a relay socket is read into, the batches are
+// pooled by the Manager and reused by the allocations.
+type relayBatch struct {
+	msgs []ipv4.Message
+}
+
+func newRelayBatch(size int) *relayBatch {
+	batch := &relayBatch{msgs: make([]ipv4.Message, size)}
+	for i := range batch.msgs {
+		batch.msgs[i].Buffers = [][]byte{make([]byte, rtpMTU)}
+	}
+
+	return batch
+}
+
+// relayBatchReader returns a reader of packet batches for the relay socket, or
+// nil if it is read one packet at a time.
+func (m *Manager) relayBatchReader(relaySocket net.PacketConn) udp.BatchReader {
+	if m.batchSize < 2 {
+		return nil
+	}
+
+	switch conn := relaySocket.(type) {
+	case udp.BatchReader:
+		return conn
+	case *net.UDPConn:
+		return newBatchPacketConn(conn)
+	default:
+		return nil
+	}
+}
+
+// relayBatchWriter returns a writer of packet batches for the relay socket, or
+// nil if it is written one packet at a time.
+func (m *Manager) relayBatchWriter(relaySocket net.PacketConn) udp.BatchWriter {
+	if m.batchSize < 2 {
+		return nil
+	}
+
+	switch conn := relaySocket.(type) {
+	case udp.BatchWriter:
+		return conn
+	case *net.UDPConn:
+		return newBatchPacketConn(conn)
+	default:
+		return nil
+	}
+}
+
+func newBatchPacketConn(conn *net.UDPConn) udp.BatchPacketConn {
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() == nil {
+		return ipv6.NewPacketConn(conn)
+	}
+
+	return ipv4.NewPacketConn(conn)
+}
+
+func (a *Allocation) readRelayBatches(manager *Manager, batchReader udp.BatchReader, bufs *relayBuffers) {
+	batch := manager.relayBatches.Get().(*relayBatch) //nolint:forcetypeassert
+	defer manager.relayBatches.Put(batch)
+
+	for {
+		n, err := batchReader.ReadBatch(batch.msgs, 0)
+		if err != nil {
+			manager.DeleteAllocation(a.FiveTuple())
+
+			return
+		}
+
+		for i := 0; i < n; i++ {
+			msg := &batch.msgs[i]
+			if !a.relayToClient(msg.Buffers[0][:msg.N], msg.Addr, bufs) {
+				return
+			}
+		}
+	}
+}
+
+// RelayWriteBatch queues the packets relayed from the clients to the peers while
+// a batch of packets read from the clients is handled, Flush writes them with one
+// call per relay socket (sendmmsg on Linux). The packets are not copied, they must
+// not be modified until Flush returns.
+type RelayWriteBatch struct {
+	msgs    []ipv4.Message
+	writers []udp.BatchWriter
+	n       int
+
+	// alloc is the allocation of the last packet of the batch, looked up by
+	// the fingerprint of its five-tuple.
+	alloc            *Allocation
+	allocFingerprint FiveTupleFingerprint
+}
+
+// NewRelayWriteBatch creates a RelayWriteBatch of up to size packets.
+func NewRelayWriteBatch(size int) *RelayWriteBatch {
+	batch := &RelayWriteBatch{
+		msgs:    make([]ipv4.Message, size),
+		writers: make([]udp.BatchWriter, size),
+	}
+	for i := range batch.msgs {
+		batch.msgs[i].Buffers = make([][]byte, 1)
+	}
+
+	return batch
+}
+
+// GetAllocation fetches the allocation matching the passed FiveTuple from the
+// Manager, the allocation of the previous packet of the batch is reused for the
+// following packets of the same client.
+func (b *RelayWriteBatch) GetAllocation(manager *Manager, fiveTuple *FiveTuple) *Allocation {
+	fingerprint := fiveTuple.Fingerprint()
+	if b.alloc == nil || fingerprint != b.allocFingerprint {
+		manager.lock.RLock()
+		b.alloc = manager.allocations[fingerprint]
+		manager.lock.RUnlock()
+		b.allocFingerprint = fingerprint
+	}
+
+	return b.alloc
+}
+
+// WriteTo queues p to be written to addr from the relay socket of the allocation.
+// It returns false if the relay socket does not write batches or the batch is
+// full, p must then be written to the relay socket directly.
+func (b *RelayWriteBatch) WriteTo(alloc *Allocation, relaySocket net.PacketConn, p []byte, addr net.Addr) bool {
+	var writer udp.BatchWriter
+	switch relaySocket {
+	case alloc.RelaySocket:
+		writer = alloc.relayBatchWriter
+	case alloc.AdditionalRelaySocket:
+		writer = alloc.additionalRelayBatchWriter
+	}
+	if writer == nil || b.n == len(b.msgs) {
+		return false
+	}
+
+	b.msgs[b.n].Buffers[0] = p
+	b.msgs[b.n].Addr = addr
+	b.writers[b.n] = writer
+	b.n++
+
+	return true
+}
+
+// Flush writes the queued packets, the consecutive packets of a relay socket
+// are written at once. It returns the first error of a write.
+func (b *RelayWriteBatch) Flush() error {
+	var firstErr error
+	for start := 0; start < b.n; {
+		end := start + 1
+		for end < b.n && b.writers[end] == b.writers[start] {
+			end++
+		}
+
+		for start < end {
+			n, err := b.writers[start].WriteBatch(b.msgs[start:end], 0)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+
+				break
+			}
+			start += n
+		}
+		start = end
+	}
+
+	for i := 0; i < b.n; i++ {
+		b.msgs[i].Buffers[0] = nil
+		b.msgs[i].Addr = nil
+		b.writers[i] = nil
+	}
+	b.n = 0
+	b.alloc = nil
+
+	return firstErr
+}
@@ -5,6 +5,10 @@ package allocation
 
 import "errors"
 
+// ErrAddressFamilyNotSupported is returned by AllocatePacketConn when it cannot
+// allocate a relayed transport address of the address family of the network.
+var ErrAddressFamilyNotSupported = errors.New("address family not supported")
+
 var (
 	errAllocatePacketConnMustBeSet = errors.New("AllocatePacketConn must be set")
 	errAllocateConnMustBeSet       = errors.New("AllocateConn must be set")
@@ -19,4 +23,15 @@ var (
 	errFailedToCastUDPAddr         = errors.New("failed to cast net.Addr to *net.UDPAddr")
 	errFailedToAllocateEvenPort    = errors.New("failed to allocate an even port")
 	errAdminProhibited             = errors.New("permission request administratively prohibited")
+	errTCPAllocationUnsupported    = errors.New("TCP allocations are not supported by the relay address generator")
+	errNotTCPAllocation            = errors.New("allocation does not have a TCP relayed transport address")
+	errTCPConnectionExists         = errors.New("allocation already has a connection to the peer")
+	errTCPConnectTimeout           = errors.New("connection to the peer timed out")
+	errNoSuchTCPConnection         = errors.New("no such connection waiting to be bound")
+	errTCPConnectionUsername       = errors.New("connection to the peer belongs to another user")
+	errAllocationQuotaReached      = errors.New("allocation quota reached")
+	errAllocationClosed            = errors.New("allocation is closed")
+	errNoSuchMobilityTicket        = errors.New("no allocation for the mobility ticket")
+	errMobilityTicketUsername      = errors.New("mobility ticket was issued to another user")
+	errMobilityTicketServerAddr    = errors.New("mobility ticket was issued on another server address")
 )
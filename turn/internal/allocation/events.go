@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package allocation
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/turn/v4/internal/proto"
+)
+
+// EventHandler is notified of the lifecycle of allocations, their
+// permissions and channels. Its methods are called synchronously.
+type EventHandler interface {
+	OnAllocationCreated(alloc *Allocation)
+	OnAllocationRefreshed(alloc *Allocation, lifetime time.Duration)
+	OnAllocationDeleted(alloc *Allocation, expired bool)
+	OnPermissionCreated(alloc *Allocation, peer net.Addr)
+	OnPermissionRefreshed(alloc *Allocation, peer net.Addr)
+	OnPermissionExpired(alloc *Allocation, peer net.Addr)
+	OnChannelCreated(alloc *Allocation, number proto.ChannelNumber, peer net.Addr)
+	OnChannelRefreshed(alloc *Allocation, number proto.ChannelNumber, peer net.Addr)
+	OnChannelExpired(alloc *Allocation, number proto.ChannelNumber, peer net.Addr)
+}
+
+type nopEventHandler struct{}
+
+func (nopEventHandler) OnAllocationCreated(*Allocation)                               {}
+func (nopEventHandler) OnAllocationRefreshed(*Allocation, time.Duration)              {}
+func (nopEventHandler) OnAllocationDeleted(*Allocation, bool)                         {}
+func (nopEventHandler) OnPermissionCreated(*Allocation, net.Addr)                     {}
+func (nopEventHandler) OnPermissionRefreshed(*Allocation, net.Addr)                   {}
+func (nopEventHandler) OnPermissionExpired(*Allocation, net.Addr)                     {}
+func (nopEventHandler) OnChannelCreated(*Allocation, proto.ChannelNumber, net.Addr)   {}
+func (nopEventHandler) OnChannelRefreshed(*Allocation, proto.ChannelNumber, net.Addr) {}
+func (nopEventHandler) OnChannelExpired(*Allocation, proto.ChannelNumber, net.Addr)   {}
+
+// Stats are the cumulative traffic counters of an allocation.
+type Stats struct {
+	// BytesSent and PacketsSent count the traffic relayed from the client to peers
+	BytesSent   uint64
+	PacketsSent uint64
+
+	// BytesReceived and PacketsReceived count the traffic relayed from peers to the client
+	BytesReceived   uint64
+	PacketsReceived uint64
+
+	// PeersDenied counts the requests and packets to peers denied by the peer ACL
+	PeersDenied uint64
+}
+
+type stats struct {
+	bytesSent       atomic.Uint64
+	packetsSent     atomic.Uint64
+	bytesReceived   atomic.Uint64
+	packetsReceived atomic.Uint64
+	peersDenied     atomic.Uint64
+}
+
+// Stats returns the traffic relayed by the allocation so far.
+func (a *Allocation) Stats() Stats {
+	return Stats{
+		BytesSent:       a.stats.bytesSent.Load(),
+		PacketsSent:     a.stats.packetsSent.Load(),
+		BytesReceived:   a.stats.bytesReceived.Load(),
+		PacketsReceived: a.stats.packetsReceived.Load(),
+		PeersDenied:     a.stats.peersDenied.Load(),
+	}
+}
+
+// AddSent counts a packet of n bytes relayed from the client to a peer.
+func (a *Allocation) AddSent(n int) {
+	a.stats.bytesSent.Add(uint64(n)) //nolint:gosec // G115
+	a.stats.packetsSent.Add(1)
+}
+
+func (a *Allocation) addReceived(n int) {
+	a.stats.bytesReceived.Add(uint64(n)) //nolint:gosec // G115
+	a.stats.packetsReceived.Add(1)
+}
+
+// AddPeerDenied counts a request or packet to a peer denied by the peer ACL.
+func (a *Allocation) AddPeerDenied() {
+	a.stats.peersDenied.Add(1)
+}
+
+// FiveTuple returns the FiveTuple the allocation is tied to.
+func (a *Allocation) FiveTuple() *FiveTuple {
+	return a.fiveTuple.Load()
+}
+
+// CreatedAt returns when the allocation was created.
+func (a *Allocation) CreatedAt() time.Time {
+	return a.createdAt
+}
+
+// Allocations returns the active allocations.
+func (m *Manager) Allocations() []*Allocation {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	allocations := make([]*Allocation, 0, len(m.allocations))
+	for _, a := range m.allocations {
+		allocations = append(allocations, a)
+	}
+
+	return allocations
+}
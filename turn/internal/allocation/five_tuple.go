@@ -41,24 +41,34 @@ type FiveTupleFingerprint struct {
 
 // Fingerprint is the identity of a FiveTuple.
 func (f *FiveTuple) Fingerprint() (fp FiveTupleFingerprint) {
-	srcIP, srcPort := netAddrIPAndPort(f.SrcAddr)
-	copy(fp.srcIP[:], srcIP)
-	fp.srcPort = srcPort
-	dstIP, dstPort := netAddrIPAndPort(f.DstAddr)
-	copy(fp.dstIP[:], dstIP)
-	fp.dstPort = dstPort
+	fp.srcPort = putNetAddrIP(&fp.srcIP, f.SrcAddr)
+	fp.dstPort = putNetAddrIP(&fp.dstIP, f.DstAddr)
 	fp.protocol = f.Protocol
 
 	return
 }
 
-func netAddrIPAndPort(addr net.Addr) (net.IP, uint16) {
+// putNetAddrIP puts the IP of addr in its 16-byte form into ip and returns the
+// port, without allocating like net.IP.To16 does for IPv4 addresses.
+func putNetAddrIP(ip *[16]byte, addr net.Addr) uint16 {
+	var netIP net.IP
+	var port int
 	switch a := addr.(type) {
 	case *net.UDPAddr:
-		return a.IP.To16(), uint16(a.Port) // nolint:gosec // G115
+		netIP, port = a.IP, a.Port
 	case *net.TCPAddr:
-		return a.IP.To16(), uint16(a.Port) // nolint:gosec // G115
+		netIP, port = a.IP, a.Port
 	default:
-		return nil, 0
+		return 0
 	}
+
+	switch len(netIP) {
+	case net.IPv4len:
+		ip[10], ip[11] = 0xff, 0xff
+		copy(ip[12:], netIP)
+	case net.IPv6len:
+		copy(ip[:], netIP)
+	}
+
+	return uint16(port) // nolint:gosec // G115
 }
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package allocation
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// mobilityTicketSize is the size of the random MOBILITY-TICKETs, which are
+// opaque to the client and only looked up by the server that issued them.
+const mobilityTicketSize = 32
+
+// NewMobilityTicket issues a MOBILITY-TICKET that moves the allocation to the
+// new five-tuple of a client whose address changed, it replaces the previous
+// ticket of the allocation, see https://tools.ietf.org/html/rfc8016#section-3.
+func (m *Manager) NewMobilityTicket(alloc *Allocation) ([]byte, error) {
+	ticket := make([]byte, mobilityTicketSize)
+	if _, err := rand.Read(ticket); err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.allocations[alloc.FiveTuple().Fingerprint()]; !ok {
+		return nil, errAllocationClosed
+	}
+	if alloc.mobilityTicket != "" {
+		delete(m.mobilityTickets, alloc.mobilityTicket)
+	}
+	alloc.mobilityTicket = string(ticket)
+	m.mobilityTickets[alloc.mobilityTicket] = alloc
+	alloc.mobility.Store(true)
+
+	return ticket, nil
+}
+
+// MoveAllocation moves the allocation of the MOBILITY-TICKET, with its
+// permissions and channels, to the five-tuple. The ticket is valid until
+// the next one is issued, and only for the user that created the allocation.
+// The client has to reach the same server address, whose socket relays the
+// data of the allocation.
+func (m *Manager) MoveAllocation(ticket []byte, fiveTuple *FiveTuple, username string) (*Allocation, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	alloc, ok := m.mobilityTickets[string(ticket)]
+	if !ok {
+		return nil, errNoSuchMobilityTicket
+	}
+	current, moved := alloc.FiveTuple().Fingerprint(), fiveTuple.Fingerprint()
+	switch {
+	case alloc.Username() != username:
+		return nil, errMobilityTicketUsername
+	case current.dstIP != moved.dstIP || current.dstPort != moved.dstPort || current.protocol != moved.protocol:
+		return nil, errMobilityTicketServerAddr
+	}
+	if _, ok := m.allocations[fiveTuple.Fingerprint()]; ok {
+		return nil, fmt.Errorf("%w: %v", errDupeFiveTuple, fiveTuple)
+	}
+
+	m.log.Debugf("Moving allocation %v to %v", alloc.FiveTuple(), fiveTuple)
+	delete(m.allocations, alloc.FiveTuple().Fingerprint())
+	alloc.fiveTuple.Store(fiveTuple)
+	m.allocations[fiveTuple.Fingerprint()] = alloc
+
+	return alloc, nil
+}
+
+// Mobility returns whether a MOBILITY-TICKET was issued for the allocation.
+func (a *Allocation) Mobility() bool {
+	return a.mobility.Load()
+}
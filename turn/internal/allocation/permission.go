@@ -33,11 +33,12 @@ func NewPermission(addr net.Addr, log logging.LeveledLogger) *Permission {
 func (p *Permission) start(lifetime time.Duration) {
 	p.lifetimeTimer = time.AfterFunc(lifetime, func() {
 		p.allocation.RemovePermission(p.Addr)
+		p.allocation.events.OnPermissionExpired(p.allocation, p.Addr)
 	})
 }
 
 func (p *Permission) refresh(lifetime time.Duration) {
 	if !p.lifetimeTimer.Reset(lifetime) {
-		p.log.Errorf("Failed to reset permission timer for %v %v", p.Addr, p.allocation.fiveTuple)
+		p.log.Errorf("Failed to reset permission timer for %v %v", p.Addr, p.allocation.FiveTuple())
 	}
 }
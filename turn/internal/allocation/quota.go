@@ -0,0 +1,312 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package allocation
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/pion/turn/v4/internal/ipnet"
+)
+
+// Quota are the limits applied to an allocation and its user, zero values
+// are unlimited.
+type Quota struct {
+	MaxAllocationsPerUser int
+	MaxAllocationsPerIP   int
+	MaxPermissions        int
+	MaxChannels           int
+
+	// AllocationBandwidth and UserBandwidth are in bytes per second
+	AllocationBandwidth int
+	UserBandwidth       int
+}
+
+// Quotas tracks the allocations and the relayed traffic of users and source
+// IPs across all the Managers of a server. A nil Quotas enforces nothing.
+type Quotas struct {
+	lock  sync.Mutex
+	users map[string]*userQuota
+	ips   map[string]int
+}
+
+type userQuota struct {
+	allocations int
+	bandwidth   *tokenBucket
+}
+
+// NewQuotas creates a new instance of Quotas.
+func NewQuotas() *Quotas {
+	return &Quotas{
+		users: map[string]*userQuota{},
+		ips:   map[string]int{},
+	}
+}
+
+// Reserve counts a new allocation of the user from srcIP, it fails if the
+// quota does not allow it.
+func (q *Quotas) Reserve(username string, srcIP net.IP, quota Quota) error {
+	if q == nil {
+		return nil
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	user := q.users[username]
+	ip := srcIP.String()
+	switch {
+	case quota.MaxAllocationsPerUser > 0 && user != nil && user.allocations >= quota.MaxAllocationsPerUser:
+		return errAllocationQuotaReached
+	case quota.MaxAllocationsPerIP > 0 && q.ips[ip] >= quota.MaxAllocationsPerIP:
+		return errAllocationQuotaReached
+	}
+
+	if user == nil {
+		user = &userQuota{bandwidth: newTokenBucket(0)}
+		q.users[username] = user
+	}
+	user.allocations++
+	user.bandwidth.setRate(quota.UserBandwidth)
+	q.ips[ip]++
+
+	return nil
+}
+
+// Release undoes Reserve.
+func (q *Quotas) Release(username string, srcIP net.IP) {
+	if q == nil {
+		return
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if user := q.users[username]; user != nil {
+		if user.allocations--; user.allocations <= 0 {
+			delete(q.users, username)
+		}
+	}
+
+	ip := srcIP.String()
+	if q.ips[ip]--; q.ips[ip] <= 0 {
+		delete(q.ips, ip)
+	}
+}
+
+func (q *Quotas) userBandwidth(username string) *tokenBucket {
+	if q == nil {
+		return nil
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if user := q.users[username]; user != nil {
+		return user.bandwidth
+	}
+
+	return nil
+}
+
+// SetQuota applies the quota to the allocation, which has been reserved for
+// its user with Quotas.Reserve. Closing the allocation releases it.
+func (a *Allocation) SetQuota(quotas *Quotas, srcIP net.IP, quota Quota) {
+	a.quotaLock.Lock()
+	defer a.quotaLock.Unlock()
+
+	select {
+	case <-a.closed:
+		quotas.Release(a.username, srcIP)
+
+		return
+	default:
+	}
+
+	a.quotas = quotas
+	a.srcIP = srcIP
+	a.userBandwidth = quotas.userBandwidth(a.username)
+	a.setQuotaLocked(quota)
+}
+
+// UpdateQuota applies changed limits to the allocation.
+func (a *Allocation) UpdateQuota(quota Quota) {
+	a.quotaLock.Lock()
+	defer a.quotaLock.Unlock()
+
+	a.setQuotaLocked(quota)
+}
+
+func (a *Allocation) setQuotaLocked(quota Quota) {
+	a.quota = quota
+	a.bandwidth.setRate(quota.AllocationBandwidth)
+	a.userBandwidth.setRate(quota.UserBandwidth)
+}
+
+func (a *Allocation) releaseQuota() {
+	a.quotaLock.Lock()
+	defer a.quotaLock.Unlock()
+
+	a.quotas.Release(a.username, a.srcIP)
+	a.quotas = nil
+}
+
+// Username returns the user that created the allocation.
+func (a *Allocation) Username() string {
+	return a.username
+}
+
+// PermissionQuotaReached returns whether adding permissions for addrs would
+// exceed the maximum number of permissions of the allocation.
+func (a *Allocation) PermissionQuotaReached(addrs ...net.Addr) bool {
+	a.quotaLock.Lock()
+	maxPermissions := a.quota.MaxPermissions
+	a.quotaLock.Unlock()
+
+	if maxPermissions <= 0 {
+		return false
+	}
+
+	a.permissionsLock.RLock()
+	defer a.permissionsLock.RUnlock()
+
+	added := map[netip.Addr]struct{}{}
+	for _, addr := range addrs {
+		fingerprint := ipnet.FingerprintAddr(addr)
+		if _, ok := a.permissions[fingerprint]; !ok {
+			added[fingerprint] = struct{}{}
+		}
+	}
+
+	return len(added) > 0 && len(a.permissions)+len(added) > maxPermissions
+}
+
+// ChannelQuotaReached returns whether binding a new channel to addr would
+// exceed the maximum number of channels of the allocation.
+func (a *Allocation) ChannelQuotaReached(addr net.Addr) bool {
+	a.quotaLock.Lock()
+	maxChannels := a.quota.MaxChannels
+	a.quotaLock.Unlock()
+
+	if maxChannels <= 0 || a.GetChannelByAddr(addr) != nil {
+		return false
+	}
+
+	a.channelBindingsLock.RLock()
+	defer a.channelBindingsLock.RUnlock()
+
+	return len(a.channelBindings) >= maxChannels
+}
+
+// AllowRelay returns whether n bytes can be relayed within the bandwidth of
+// the allocation and its user. Datagrams that are not allowed are dropped.
+func (a *Allocation) AllowRelay(n int) bool {
+	return a.bandwidth.allow(n) && a.userBandwidth.allow(n)
+}
+
+// waitRelay blocks until n bytes of a stream can be relayed within the
+// bandwidth of the allocation and its user.
+func (a *Allocation) waitRelay(n int) {
+	delay := a.bandwidth.reserve(n)
+	if userDelay := a.userBandwidth.reserve(n); userDelay > delay {
+		delay = userDelay
+	}
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-a.closed:
+		}
+	}
+}
+
+// tokenBucket limits a rate in bytes per second with bursts of up to one
+// second. A rate of zero is unlimited, as is a nil tokenBucket.
+type tokenBucket struct {
+	lock   sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int) *tokenBucket {
+	b := &tokenBucket{}
+	b.setRate(rate)
+
+	return b
+}
+
+func (b *tokenBucket) setRate(rate int) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.rate == 0 {
+		b.tokens = float64(rate)
+		b.last = time.Now()
+	}
+	b.rate = float64(rate)
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+}
+
+func (b *tokenBucket) refillLocked() {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+}
+
+// allow takes n tokens if there are enough.
+func (b *tokenBucket) allow(n int) bool {
+	if b == nil {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.rate == 0 {
+		return true
+	}
+
+	b.refillLocked()
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+
+	return true
+}
+
+// reserve takes n tokens and returns how long to wait until they are
+// available.
+func (b *tokenBucket) reserve(n int) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.rate == 0 {
+		return 0
+	}
+
+	b.refillLocked()
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package allocation
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/turn/v4/internal/ipnet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotas(t *testing.T) {
+	quotas := NewQuotas()
+	quota := Quota{MaxAllocationsPerUser: 2, MaxAllocationsPerIP: 1}
+	ip1, ip2 := net.IPv4(127, 0, 0, 1), net.IPv4(127, 0, 0, 2)
+
+	assert.NoError(t, quotas.Reserve("user", ip1, quota))
+	assert.ErrorIs(t, quotas.Reserve("other", ip1, quota), errAllocationQuotaReached)
+	assert.NoError(t, quotas.Reserve("user", ip2, quota))
+	assert.ErrorIs(t, quotas.Reserve("user", net.IPv4(127, 0, 0, 3), quota), errAllocationQuotaReached)
+
+	// Limits can change at runtime
+	assert.NoError(t, quotas.Reserve("other", ip1, Quota{}))
+
+	quotas.Release("user", ip1)
+	quotas.Release("other", ip1)
+	assert.NoError(t, quotas.Reserve("other", ip1, quota))
+
+	var nilQuotas *Quotas
+	assert.NoError(t, nilQuotas.Reserve("user", ip1, quota))
+}
+
+func TestTokenBucket(t *testing.T) {
+	var unlimited *tokenBucket
+	assert.True(t, unlimited.allow(1<<20))
+	assert.True(t, newTokenBucket(0).allow(1<<20))
+
+	bucket := newTokenBucket(1000)
+	assert.True(t, bucket.allow(600))
+	assert.False(t, bucket.allow(600))
+	assert.True(t, bucket.allow(400))
+
+	assert.Zero(t, newTokenBucket(1000).reserve(1000))
+	delay := bucket.reserve(500)
+	assert.Greater(t, delay, 400*time.Millisecond)
+	assert.LessOrEqual(t, delay, 500*time.Millisecond)
+}
+
+func TestAllocationQuota(t *testing.T) {
+	alloc := NewAllocation(nil, nil, nil)
+	quotas := NewQuotas()
+	ip := net.IPv4(127, 0, 0, 1)
+
+	quota := Quota{MaxPermissions: 1, MaxChannels: 1, UserBandwidth: 1000}
+	assert.NoError(t, quotas.Reserve("user", ip, quota))
+	alloc.username = "user"
+	alloc.SetQuota(quotas, ip, quota)
+	assert.Equal(t, "user", alloc.Username())
+
+	peer1 := &net.UDPAddr{IP: ip, Port: 5000}
+	peer2 := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 5000}
+	assert.False(t, alloc.PermissionQuotaReached(peer1))
+	alloc.permissions[ipnet.FingerprintAddr(peer1)] = &Permission{Addr: peer1, lifetimeTimer: time.NewTimer(time.Hour)}
+	assert.False(t, alloc.PermissionQuotaReached(peer1))
+	assert.True(t, alloc.PermissionQuotaReached(peer2))
+	assert.True(t, alloc.PermissionQuotaReached(peer1, peer2))
+	assert.False(t, alloc.ChannelQuotaReached(peer1))
+	alloc.channelBindings = append(alloc.channelBindings, &ChannelBind{
+		Peer:          peer1,
+		lifetimeTimer: time.NewTimer(time.Hour),
+	})
+	assert.True(t, alloc.ChannelQuotaReached(peer2))
+
+	// The user bandwidth is shared by all allocations of the user
+	assert.True(t, alloc.AllowRelay(1000))
+	assert.False(t, alloc.AllowRelay(1))
+
+	alloc.UpdateQuota(Quota{})
+	assert.False(t, alloc.PermissionQuotaReached(peer2))
+	assert.True(t, alloc.AllowRelay(1<<20))
+
+	alloc.lifetimeTimer = time.NewTimer(time.Hour)
+	alloc.RelaySocket, _ = net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, alloc.Close())
+	assert.Empty(t, quotas.users)
+	assert.Empty(t, quotas.ips)
+}
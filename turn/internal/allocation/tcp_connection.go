@@ -0,0 +1,386 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package allocation
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/stun/v3"
+	"github.com/pion/turn/v4/internal/ipnet"
+	"github.com/pion/turn/v4/internal/proto"
+)
+
+const (
+	// tcpConnectTimeout bounds the connection attempt to the peer of a
+	// Connect request, see https://tools.ietf.org/html/rfc6062#section-5.2.
+	tcpConnectTimeout = 30 * time.Second
+
+	// tcpConnectionBindTimeout is how long a connection to a peer waits for
+	// the ConnectionBind request of the client before it is closed.
+	tcpConnectionBindTimeout = 30 * time.Second
+
+	tcpRelayBufferSize = 16 * 1024
+
+	// minAcceptDelay and maxAcceptDelay bound the backoff after a failed
+	// Accept on the relayed transport address.
+	minAcceptDelay = 5 * time.Millisecond
+	maxAcceptDelay = time.Second
+)
+
+// TCPConnection is a connection between the relayed transport address of a
+// TCP allocation and a peer. The client binds it to a new connection to the
+// server with the CONNECTION-ID, which then carries the data of the peer, see
+// https://tools.ietf.org/html/rfc6062.
+type TCPConnection struct {
+	ID       proto.ConnectionID
+	PeerAddr *net.TCPAddr
+
+	allocation *Allocation
+	manager    *Manager
+	peerConn   net.Conn
+	dataConn   net.Conn
+	bindTimer  *time.Timer
+	closeOnce  sync.Once
+}
+
+// Relay relays the data between the peer and the data connection of the
+// client until either of them is closed.
+func (c *TCPConnection) Relay(dataConn net.Conn) {
+	if !c.allocation.setTCPDataConn(c, dataConn) {
+		if err := dataConn.Close(); err != nil {
+			c.allocation.log.Debugf("Failed to close data connection: %v", err)
+		}
+		if err := c.Close(); err != nil {
+			c.allocation.log.Debugf("Failed to close connection to peer %v: %v", c.PeerAddr, err)
+		}
+
+		return
+	}
+
+	go c.copy(c.peerConn, dataConn, c.allocation.AddSent)
+	go c.copy(dataConn, c.peerConn, c.allocation.addReceived)
+}
+
+func (c *TCPConnection) copy(dst, src net.Conn, count func(n int)) {
+	buf := make([]byte, tcpRelayBufferSize)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			c.allocation.waitRelay(n)
+			if _, writeErr := dst.Write(buf[:n]); writeErr != nil {
+				err = writeErr
+			} else {
+				count(n)
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				c.allocation.log.Debugf("Failed to relay data of peer %v: %v", c.PeerAddr, err)
+			}
+
+			break
+		}
+	}
+
+	if err := c.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		c.allocation.log.Debugf("Failed to close connection to peer %v: %v", c.PeerAddr, err)
+	}
+}
+
+// Close closes the connection to the peer and the data connection of the
+// client if it has been bound.
+func (c *TCPConnection) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.bindTimer.Stop()
+		c.manager.removeTCPConnection(c.ID)
+
+		dataConn := c.allocation.removeTCPConnection(c)
+		err = c.peerConn.Close()
+		if dataConn != nil {
+			if closeErr := dataConn.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	})
+
+	return err
+}
+
+// HasTCPConnection returns whether the allocation has a connection to the peer.
+func (a *Allocation) HasTCPConnection(peerAddr net.Addr) bool {
+	a.tcpConnsLock.Lock()
+	defer a.tcpConnsLock.Unlock()
+
+	for _, c := range a.tcpConns {
+		if ipnet.AddrEqual(c.PeerAddr, peerAddr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *Allocation) addTCPConnection(tcpConn *TCPConnection) error {
+	a.tcpConnsLock.Lock()
+	defer a.tcpConnsLock.Unlock()
+
+	select {
+	case <-a.closed:
+		return errAllocationClosed
+	default:
+	}
+
+	for _, c := range a.tcpConns {
+		if ipnet.AddrEqual(c.PeerAddr, tcpConn.PeerAddr) {
+			return errTCPConnectionExists
+		}
+	}
+	a.tcpConns[tcpConn.ID] = tcpConn
+
+	return nil
+}
+
+func (a *Allocation) setTCPDataConn(tcpConn *TCPConnection, dataConn net.Conn) bool {
+	a.tcpConnsLock.Lock()
+	defer a.tcpConnsLock.Unlock()
+
+	if a.tcpConns[tcpConn.ID] != tcpConn {
+		return false
+	}
+	tcpConn.dataConn = dataConn
+
+	return true
+}
+
+func (a *Allocation) removeTCPConnection(tcpConn *TCPConnection) (dataConn net.Conn) {
+	a.tcpConnsLock.Lock()
+	defer a.tcpConnsLock.Unlock()
+
+	if a.tcpConns[tcpConn.ID] == tcpConn {
+		delete(a.tcpConns, tcpConn.ID)
+	}
+
+	return tcpConn.dataConn
+}
+
+// acceptHandler accepts the connections of peers to the relayed transport
+// address and announces each to the client with a ConnectionAttempt
+// indication, see https://tools.ietf.org/html/rfc6062#section-5.3.
+func (a *Allocation) acceptHandler(manager *Manager) {
+	var acceptDelay time.Duration
+	for {
+		conn, err := a.RelayListener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			manager.DeleteAllocation(a.FiveTuple())
+
+			return
+		} else if err != nil {
+			// Errors such as running out of file descriptors are retried
+			// with a backoff, like net/http.Server does.
+			acceptDelay *= 2
+			if acceptDelay < minAcceptDelay {
+				acceptDelay = minAcceptDelay
+			} else if acceptDelay > maxAcceptDelay {
+				acceptDelay = maxAcceptDelay
+			}
+			a.log.Warnf("Failed to accept connection on allocation %v, retrying in %v: %v", a.RelayAddr, acceptDelay, err)
+
+			select {
+			case <-time.After(acceptDelay):
+				continue
+			case <-a.closed:
+				return
+			}
+		}
+		acceptDelay = 0
+
+		peerAddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+		if !ok || a.GetPermission(peerAddr) == nil {
+			a.log.Infof("No Permission exists for %v on allocation %v", conn.RemoteAddr(), a.RelayAddr)
+			if err := conn.Close(); err != nil {
+				a.log.Debugf("Failed to close connection of peer %v: %v", conn.RemoteAddr(), err)
+			}
+
+			continue
+		}
+
+		tcpConn, err := manager.addTCPConnection(a, conn, peerAddr)
+		if err != nil {
+			a.log.Infof("Failed to accept connection of peer %v on allocation %v: %v", peerAddr, a.RelayAddr, err)
+
+			continue
+		}
+
+		msg, err := stun.Build(
+			stun.TransactionID,
+			stun.NewType(stun.MethodConnectionAttempt, stun.ClassIndication),
+			tcpConn.ID,
+			&proto.PeerAddress{IP: peerAddr.IP, Port: peerAddr.Port},
+		)
+		if err == nil {
+			_, err = a.TurnSocket.WriteTo(msg.Raw, a.FiveTuple().SrcAddr)
+		}
+		if err != nil {
+			a.log.Errorf("Failed to send ConnectionAttempt for peer %v: %v", peerAddr, err)
+			if err := tcpConn.Close(); err != nil {
+				a.log.Debugf("Failed to close connection to peer %v: %v", peerAddr, err)
+			}
+		}
+	}
+}
+
+// Connect opens a connection from the TCP allocation to the peer, which waits
+// for the ConnectionBind request of the client, see
+// https://tools.ietf.org/html/rfc6062#section-5.2.
+func (m *Manager) Connect(alloc *Allocation, peerAddr *net.TCPAddr) (*TCPConnection, error) {
+	switch {
+	case alloc.Protocol != TCP:
+		return nil, errNotTCPAllocation
+	case alloc.HasTCPConnection(peerAddr):
+		return nil, errTCPConnectionExists
+	}
+
+	relayAddr, ok := alloc.RelayAddr.(*net.TCPAddr)
+	if !ok {
+		return nil, errNotTCPAllocation
+	}
+	network := "tcp4"
+	if relayAddr.IP.To4() == nil {
+		network = "tcp6"
+	}
+
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	result := make(chan dialResult, 1)
+	go func() {
+		conn, err := m.dialTCP(network, relayAddr, peerAddr)
+		result <- dialResult{conn, err}
+	}()
+
+	timer := time.NewTimer(tcpConnectTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case res := <-result:
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		return m.addTCPConnection(alloc, res.conn, peerAddr)
+	case <-timer.C:
+		err = errTCPConnectTimeout
+	case <-alloc.closed:
+		err = errAllocationClosed
+	}
+
+	// Close the connection once the abandoned attempt completes
+	go func() {
+		if res := <-result; res.err == nil {
+			_ = res.conn.Close()
+		}
+	}()
+
+	return nil, err
+}
+
+// BindTCPConnection takes the connection to a peer with the CONNECTION-ID
+// for a ConnectionBind request of the user that owns the allocation. The
+// caller responds to the request before the data is relayed with
+// TCPConnection.Relay.
+func (m *Manager) BindTCPConnection(id proto.ConnectionID, username string) (*TCPConnection, error) {
+	m.lock.Lock()
+	tcpConn, ok := m.tcpConnections[id]
+	switch {
+	case !ok:
+		m.lock.Unlock()
+
+		return nil, errNoSuchTCPConnection
+	case tcpConn.allocation.Username() != username:
+		m.lock.Unlock()
+
+		return nil, errTCPConnectionUsername
+	}
+	delete(m.tcpConnections, id)
+	m.lock.Unlock()
+
+	tcpConn.bindTimer.Stop()
+
+	return tcpConn, nil
+}
+
+func (m *Manager) addTCPConnection(
+	alloc *Allocation,
+	conn net.Conn,
+	peerAddr *net.TCPAddr,
+) (*TCPConnection, error) {
+	tcpConn := &TCPConnection{
+		PeerAddr:   peerAddr,
+		allocation: alloc,
+		manager:    m,
+		peerConn:   conn,
+	}
+
+	m.lock.Lock()
+	for tcpConn.ID == 0 || m.tcpConnections[tcpConn.ID] != nil {
+		id, err := randomConnectionID()
+		if err != nil {
+			m.lock.Unlock()
+			if closeErr := conn.Close(); closeErr != nil {
+				m.log.Debugf("Failed to close connection to peer %v: %v", peerAddr, closeErr)
+			}
+
+			return nil, err
+		}
+		tcpConn.ID = id
+	}
+	m.tcpConnections[tcpConn.ID] = tcpConn
+	tcpConn.bindTimer = time.AfterFunc(tcpConnectionBindTimeout, func() {
+		m.log.Debugf("Connection to peer %v was not bound in time", peerAddr)
+		if err := tcpConn.Close(); err != nil {
+			m.log.Debugf("Failed to close connection to peer %v: %v", peerAddr, err)
+		}
+	})
+	m.lock.Unlock()
+
+	if err := alloc.addTCPConnection(tcpConn); err != nil {
+		if closeErr := tcpConn.Close(); closeErr != nil {
+			m.log.Debugf("Failed to close connection to peer %v: %v", peerAddr, closeErr)
+		}
+
+		return nil, err
+	}
+
+	return tcpConn, nil
+}
+
+// randomConnectionID returns a CONNECTION-ID that cannot be guessed, since it
+// is all a ConnectionBind request needs besides the credentials of the user.
+func randomConnectionID() (proto.ConnectionID, error) {
+	var id [4]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return 0, err
+	}
+
+	return proto.ConnectionID(binary.BigEndian.Uint32(id[:])), nil
+}
+
+func (m *Manager) removeTCPConnection(id proto.ConnectionID) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, ok := m.tcpConnections[id]
+	delete(m.tcpConnections, id)
+
+	return ok
+}
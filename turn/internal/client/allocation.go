@@ -18,16 +18,18 @@ import (
 
 // AllocationConfig is a set of configuration params use by NewUDPConn and NewTCPAllocation.
 type AllocationConfig struct {
-	Client      Client
-	RelayedAddr net.Addr
-	ServerAddr  net.Addr
-	Integrity   stun.MessageIntegrity
-	Nonce       stun.Nonce
-	Username    stun.Username
-	Realm       stun.Realm
-	Lifetime    time.Duration
-	Net         transport.Net
-	Log         logging.LeveledLogger
+	Client         Client
+	RelayedAddr    net.Addr
+	ServerAddr     net.Addr
+	Integrity      stun.MessageIntegrity
+	AccessToken    stun.AccessToken
+	Nonce          stun.Nonce
+	Username       stun.Username
+	Realm          stun.Realm
+	Lifetime       time.Duration
+	MobilityTicket proto.MobilityTicket
+	Net            transport.Net
+	Log            logging.LeveledLogger
 }
 
 type allocation struct {
@@ -36,10 +38,12 @@ type allocation struct {
 	serverAddr        net.Addr              // Read-only
 	permMap           *permissionMap        // Thread-safe
 	integrity         stun.MessageIntegrity // Read-only
+	accessToken       stun.AccessToken      // Read-only
 	username          stun.Username         // Read-only
 	realm             stun.Realm            // Read-only
 	_nonce            stun.Nonce            // Needs mutex x
 	_lifetime         time.Duration         // Needs mutex x
+	mobility          *mobility             // Thread-safe, nil without a MOBILITY-TICKET
 	net               transport.Net         // Thread-safe
 	refreshAllocTimer *PeriodicTimer        // Thread-safe
 	refreshPermsTimer *PeriodicTimer        // Thread-safe
@@ -59,17 +63,33 @@ func (a *allocation) setNonceFromMsg(msg *stun.Message) {
 	}
 }
 
-func (a *allocation) refreshAllocation(lifetime time.Duration, dontWait bool) error {
-	msg, err := stun.Build(
+// authAttributes returns the attributes that authenticate a request. The server
+// keeps the ACCESS-TOKEN of the allocation, only Refresh requests and requests on
+// other connections carry it.
+func (a *allocation) authAttributes(withAccessToken bool) []stun.Setter {
+	setters := []stun.Setter{a.username, a.realm, a.nonce()}
+	if withAccessToken && len(a.accessToken) > 0 {
+		setters = append(setters, a.accessToken)
+	}
+
+	return append(setters, a.integrity, stun.Fingerprint)
+}
+
+func (a *allocation) refreshAllocation(lifetime time.Duration, dontWait bool) error { //nolint:cyclop
+	setters := []stun.Setter{
 		stun.TransactionID,
 		stun.NewType(stun.MethodRefresh, stun.ClassRequest),
 		proto.Lifetime{Duration: lifetime},
-		a.username,
-		a.realm,
-		a.nonce(),
-		a.integrity,
-		stun.Fingerprint,
-	)
+	}
+	// The server issues a new ticket with every response, the Refresh
+	// requests are serialized so that the latest one is kept.
+	if a.mobility != nil {
+		a.mobility.mutex.Lock()
+		defer a.mobility.mutex.Unlock()
+
+		setters = append(setters, a.mobility.ticket)
+	}
+	msg, err := stun.Build(append(setters, a.authAttributes(true)...)...)
 	if err != nil {
 		return fmt.Errorf("%w: %s", errFailedToBuildRefreshRequest, err.Error())
 	}
@@ -98,7 +118,7 @@ func (a *allocation) refreshAllocation(lifetime time.Duration, dontWait bool) er
 				return errTryAgain
 			}
 
-			return err
+			return fmt.Errorf("%s (error %s)", res.Type, code) //nolint:goerr113
 		}
 
 		return fmt.Errorf("%s", res.Type) //nolint:goerr113
@@ -113,6 +133,13 @@ func (a *allocation) refreshAllocation(lifetime time.Duration, dontWait bool) er
 	a.setLifetime(updatedLifetime.Duration)
 	a.log.Debugf("Updated lifetime: %d seconds", int(a.lifetime().Seconds()))
 
+	if a.mobility != nil {
+		var ticket proto.MobilityTicket
+		if err := ticket.GetFrom(res); err == nil {
+			a.mobility.ticket = ticket
+		}
+	}
+
 	return nil
 }
 
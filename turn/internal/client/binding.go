@@ -5,6 +5,7 @@ package client
 
 import (
 	"net"
+	"net/netip"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -61,10 +62,32 @@ func (b *binding) refreshedAt() time.Time {
 	return b._refreshedAt
 }
 
+// bindingKey indexes the bindings by peer address, the UDP addresses written
+// to on every packet are indexed without the allocations of their String.
+type bindingKey struct {
+	addrPort netip.AddrPort
+	addr     string
+}
+
+func keyOf(addr net.Addr) bindingKey {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		if ip, ok := netip.AddrFromSlice(udpAddr.IP); ok {
+			ip = ip.Unmap()
+			if udpAddr.Zone != "" {
+				ip = ip.WithZone(udpAddr.Zone)
+			}
+
+			return bindingKey{addrPort: netip.AddrPortFrom(ip, uint16(udpAddr.Port))} //nolint:gosec // G115
+		}
+	}
+
+	return bindingKey{addr: addr.String()}
+}
+
 // Thread-safe binding map.
 type bindingManager struct {
 	chanMap map[uint16]*binding
-	addrMap map[string]*binding
+	addrMap map[bindingKey]*binding
 	next    uint16
 	mutex   sync.RWMutex
 }
@@ -72,7 +95,7 @@ type bindingManager struct {
 func newBindingManager() *bindingManager {
 	return &bindingManager{
 		chanMap: map[uint16]*binding{},
-		addrMap: map[string]*binding{},
+		addrMap: map[bindingKey]*binding{},
 		next:    minChannelNumber,
 	}
 }
@@ -100,7 +123,7 @@ func (mgr *bindingManager) create(addr net.Addr) *binding {
 	}
 
 	mgr.chanMap[b.number] = b
-	mgr.addrMap[b.addr.String()] = b
+	mgr.addrMap[keyOf(b.addr)] = b
 
 	return b
 }
@@ -109,7 +132,7 @@ func (mgr *bindingManager) findByAddr(addr net.Addr) (*binding, bool) {
 	mgr.mutex.RLock()
 	defer mgr.mutex.RUnlock()
 
-	b, ok := mgr.addrMap[addr.String()]
+	b, ok := mgr.addrMap[keyOf(addr)]
 
 	return b, ok
 }
@@ -127,12 +150,12 @@ func (mgr *bindingManager) deleteByAddr(addr net.Addr) bool {
 	mgr.mutex.Lock()
 	defer mgr.mutex.Unlock()
 
-	b, ok := mgr.addrMap[addr.String()]
+	b, ok := mgr.addrMap[keyOf(addr)]
 	if !ok {
 		return false
 	}
 
-	delete(mgr.addrMap, addr.String())
+	delete(mgr.addrMap, keyOf(addr))
 	delete(mgr.chanMap, b.number)
 
 	return true
@@ -147,7 +170,7 @@ func (mgr *bindingManager) deleteByNumber(number uint16) bool {
 		return false
 	}
 
-	delete(mgr.addrMap, b.addr.String())
+	delete(mgr.addrMap, keyOf(b.addr))
 	delete(mgr.chanMap, number)
 
 	return true
@@ -11,7 +11,7 @@ var (
 	errFake                                = errors.New("fake error")
 	errTryAgain                            = errors.New("try again")
 	errClosed                              = errors.New("use of closed network connection")
-	errTCPAddrCast                         = errors.New("addr is not a TCP address")
+	errNotTCPConn                          = errors.New("not supported by a connection that is not a TCP connection")
 	errUDPAddrCast                         = errors.New("addr is not a UDP address")
 	errAlreadyClosed                       = errors.New("already closed")
 	errDoubleLock                          = errors.New("try-lock is already locked")
@@ -22,6 +22,7 @@ var (
 	errFailedToGetLifetime                 = errors.New("failed to get lifetime from refresh response")
 	errInvalidTURNAddress                  = errors.New("invalid TURN server address")
 	errUnexpectedSTUNRequestMessage        = errors.New("unexpected STUN request message")
+	errNoMobilityTicket                    = errors.New("allocation has no mobility ticket")
 )
 
 type timeoutError struct {
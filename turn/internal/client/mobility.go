@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/pion/stun/v3"
+	"github.com/pion/turn/v4/internal/proto"
+)
+
+// mobility holds the MOBILITY-TICKET of an allocation, which is shared by the
+// conns of a dual-stack allocation, see https://tools.ietf.org/html/rfc8016.
+type mobility struct {
+	ticket proto.MobilityTicket // Needs mutex x
+	mutex  sync.Mutex           // Thread-safe
+}
+
+func newMobility(ticket proto.MobilityTicket) *mobility {
+	if len(ticket) == 0 {
+		return nil
+	}
+
+	return &mobility{ticket: ticket}
+}
+
+// MoveAllocation sends a Refresh request with the MOBILITY-TICKET, which moves the
+// allocation to the current 5-tuple of the client after its address changed.
+func (a *allocation) MoveAllocation() error {
+	if a.mobility == nil {
+		return errNoMobilityTicket
+	}
+
+	var err error
+	for i := 0; i < maxRetryAttempts; i++ {
+		if err = a.refreshAllocation(a.lifetime(), false); !errors.Is(err, errTryAgain) {
+			break
+		}
+	}
+
+	return err
+}
+
+// moveOnMismatch moves the allocation when the server answered a request with
+// 437 (Allocation Mismatch), it returns whether the request can be tried again.
+func (a *allocation) moveOnMismatch(code stun.ErrorCode) bool {
+	if a.mobility == nil || code != stun.CodeAllocMismatch {
+		return false
+	}
+	if err := a.MoveAllocation(); err != nil {
+		a.log.Warnf("Failed to move allocation: %s", err)
+
+		return false
+	}
+	a.log.Debugf("Moved allocation %s to the new address of the client", a.relayedAddr)
+
+	return true
+}
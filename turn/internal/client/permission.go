@@ -5,6 +5,7 @@ package client
 
 import (
 	"net"
+	"net/netip"
 	"sync"
 	"sync/atomic"
 
@@ -34,7 +35,7 @@ func (p *permission) state() permState {
 
 // Thread-safe permission map.
 type permissionMap struct {
-	permMap map[string]*permission
+	permMap map[netip.Addr]*permission
 	mutex   sync.RWMutex
 }
 
@@ -75,6 +76,6 @@ func (m *permissionMap) addrs() []net.Addr {
 
 func newPermissionMap() *permissionMap {
 	return &permissionMap{
-		permMap: map[string]*permission{},
+		permMap: map[netip.Addr]*permission{},
 	}
 }
@@ -47,6 +47,7 @@ func NewTCPAllocation(config *AllocationConfig) *TCPAllocation {
 			realm:       config.Realm,
 			permMap:     newPermissionMap(),
 			integrity:   config.Integrity,
+			accessToken: config.AccessToken,
 			_nonce:      config.Nonce,
 			_lifetime:   config.Lifetime,
 			net:         config.Net,
@@ -200,13 +201,8 @@ func (a *TCPAllocation) DialTCPWithConn(conn net.Conn, _ string, rAddr *net.TCPA
 		return nil, err
 	}
 
-	tcpConn, ok := conn.(transport.TCPConn)
-	if !ok {
-		return nil, errTCPAddrCast
-	}
-
 	dataConn := &TCPConn{
-		TCPConn:       tcpConn,
+		TCPConn:       toTCPConn(conn),
 		ConnectionID:  cid,
 		remoteAddress: rAddr,
 		allocation:    a,
@@ -221,16 +217,11 @@ func (a *TCPAllocation) DialTCPWithConn(conn net.Conn, _ string, rAddr *net.TCPA
 
 // BindConnection associates the provided connection.
 func (a *TCPAllocation) BindConnection(dataConn *TCPConn, cid proto.ConnectionID) error { //nolint:cyclop
-	msg, err := stun.Build(
+	msg, err := stun.Build(append([]stun.Setter{
 		stun.TransactionID,
 		stun.NewType(stun.MethodConnectionBind, stun.ClassRequest),
 		cid,
-		a.username,
-		a.realm,
-		a.nonce(),
-		a.integrity,
-		stun.Fingerprint,
-	)
+	}, a.authAttributes(true)...)...)
 	if err != nil {
 		return err
 	}
@@ -313,14 +304,8 @@ func (a *TCPAllocation) AcceptTCP() (transport.TCPConn, error) {
 func (a *TCPAllocation) AcceptTCPWithConn(conn net.Conn) (*TCPConn, error) {
 	select {
 	case attempt := <-a.connAttemptCh:
-
-		tcpConn, ok := conn.(transport.TCPConn)
-		if !ok {
-			return nil, errTCPAddrCast
-		}
-
 		dataConn := &TCPConn{
-			TCPConn:       tcpConn,
+			TCPConn:       toTCPConn(conn),
 			ConnectionID:  attempt.cid,
 			remoteAddress: attempt.from,
 			allocation:    a,
@@ -5,7 +5,9 @@ package client
 
 import (
 	"errors"
+	"io"
 	"net"
+	"time"
 
 	"github.com/pion/transport/v3"
 	"github.com/pion/turn/v4/internal/proto"
@@ -47,3 +49,59 @@ func (c *TCPConn) LocalAddr() net.Addr {
 func (c *TCPConn) RemoteAddr() net.Addr {
 	return c.remoteAddress
 }
+
+// streamConn adapts a connection to the TURN server that is not a TCP
+// connection, for example a TLS connection, to transport.TCPConn. The TCP
+// specific options are not supported.
+type streamConn struct {
+	net.Conn
+}
+
+// toTCPConn returns conn as a transport.TCPConn.
+func toTCPConn(conn net.Conn) transport.TCPConn {
+	if tcpConn, ok := conn.(transport.TCPConn); ok {
+		return tcpConn
+	}
+
+	return &streamConn{Conn: conn}
+}
+
+func (c *streamConn) CloseRead() error {
+	return errNotTCPConn
+}
+
+func (c *streamConn) CloseWrite() error {
+	if closeWriter, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return closeWriter.CloseWrite()
+	}
+
+	return errNotTCPConn
+}
+
+func (c *streamConn) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(c.Conn, r)
+}
+
+func (c *streamConn) SetLinger(int) error {
+	return errNotTCPConn
+}
+
+func (c *streamConn) SetKeepAlive(bool) error {
+	return errNotTCPConn
+}
+
+func (c *streamConn) SetKeepAlivePeriod(time.Duration) error {
+	return errNotTCPConn
+}
+
+func (c *streamConn) SetNoDelay(bool) error {
+	return errNotTCPConn
+}
+
+func (c *streamConn) SetWriteBuffer(int) error {
+	return errNotTCPConn
+}
+
+func (c *streamConn) SetReadBuffer(int) error {
+	return errNotTCPConn
+}
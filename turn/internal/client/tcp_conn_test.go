@@ -161,6 +161,30 @@ func TestTCPConn(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("AcceptTCPWithConn() with a stream connection", func(t *testing.T) {
+		relayedAddr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:13478")
+		assert.NoError(t, err)
+
+		loggerFactory := logging.NewDefaultLoggerFactory()
+		alloc := NewTCPAllocation(&AllocationConfig{
+			Client:      &mockClient{},
+			Lifetime:    time.Second,
+			Log:         loggerFactory.NewLogger("test"),
+			RelayedAddr: relayedAddr,
+		})
+
+		from, err := net.ResolveTCPAddr("tcp", "127.0.0.1:11111")
+		assert.NoError(t, err)
+		alloc.HandleConnectionAttempt(from, 5)
+
+		// A TLS connection to the TURN server is not a TCP connection
+		conn := struct{ net.Conn }{dummyTCPConn{}}
+		dataConn, err := alloc.AcceptTCPWithConn(conn)
+		assert.NoError(t, err)
+		assert.Equal(t, proto.ConnectionID(5), dataConn.ConnectionID)
+		assert.ErrorIs(t, dataConn.SetNoDelay(true), errNotTCPConn)
+	})
+
 	t.Run("DialWithConn()", func(t *testing.T) {
 		relayedAddr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:13478")
 		assert.NoError(t, err)
@@ -10,6 +10,7 @@ import (
 	"io"
 	"math"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/pion/stun/v3"
@@ -32,12 +33,20 @@ type inboundData struct {
 	from net.Addr
 }
 
+// inboundDataPool and channelDataPool hold the buffers of the packets relayed
+// to and from the peers, so that they are not allocated on every packet.
+var (
+	inboundDataPool = sync.Pool{New: func() any { return &inboundData{} }}
+	channelDataPool = sync.Pool{New: func() any { return &proto.ChannelData{} }}
+)
+
 // UDPConn is the implementation of the Conn and PacketConn interfaces for UDP network connections.
 // compatible with net.PacketConn and net.Conn.
 type UDPConn struct {
 	bindingMgr *bindingManager   // Thread-safe
 	readCh     chan *inboundData // Thread-safe
 	closeCh    chan struct{}     // Thread-safe
+	sibling    *UDPConn          // Read-only
 	allocation
 }
 
@@ -56,8 +65,10 @@ func NewUDPConn(config *AllocationConfig) *UDPConn {
 			username:    config.Username,
 			realm:       config.Realm,
 			integrity:   config.Integrity,
+			accessToken: config.AccessToken,
 			_nonce:      config.Nonce,
 			_lifetime:   config.Lifetime,
+			mobility:    newMobility(config.MobilityTicket),
 			net:         config.Net,
 			log:         config.Log,
 		},
@@ -87,6 +98,72 @@ func NewUDPConn(config *AllocationConfig) *UDPConn {
 	return conn
 }
 
+// NewSiblingConn creates the UDPConn of the other relayed transport address of
+// a dual-stack allocation, see https://tools.ietf.org/html/rfc8656#section-7.2.
+// The conns share the channel numbers of the allocation and have separate
+// permissions, the allocation is refreshed by c. Closing either of them
+// deletes the allocation and closes both.
+func (c *UDPConn) NewSiblingConn(relayedAddr net.Addr) *UDPConn {
+	conn := &UDPConn{
+		bindingMgr: c.bindingMgr,
+		readCh:     make(chan *inboundData, maxReadQueueSize),
+		closeCh:    make(chan struct{}),
+		sibling:    c,
+		allocation: allocation{
+			client:      c.client,
+			relayedAddr: relayedAddr,
+			serverAddr:  c.serverAddr,
+			readTimer:   time.NewTimer(time.Duration(math.MaxInt64)),
+			permMap:     newPermissionMap(),
+			username:    c.username,
+			realm:       c.realm,
+			integrity:   c.integrity,
+			accessToken: c.accessToken,
+			_nonce:      c.nonce(),
+			_lifetime:   c.lifetime(),
+			mobility:    c.mobility,
+			net:         c.net,
+			log:         c.log,
+		},
+	}
+	c.sibling = conn
+
+	// Only the refresh allocation timer of c runs.
+	conn.refreshAllocTimer = NewPeriodicTimer(
+		timerIDRefreshAlloc,
+		conn.onRefreshTimers,
+		conn.lifetime()/2,
+	)
+
+	conn.refreshPermsTimer = NewPeriodicTimer(
+		timerIDRefreshPerms,
+		conn.onRefreshTimers,
+		permRefreshInterval,
+	)
+
+	if conn.refreshPermsTimer.Start() {
+		conn.log.Debugf("Started refresh permission timer")
+	}
+
+	return conn
+}
+
+// ConnFor returns the conn of a dual-stack allocation whose relayed transport
+// address is of the address family of the peer, which is c otherwise.
+func (c *UDPConn) ConnFor(peer net.Addr) *UDPConn {
+	if c.sibling != nil && isIPv4(c.relayedAddr) != isIPv4(peer) {
+		return c.sibling
+	}
+
+	return c
+}
+
+func isIPv4(addr net.Addr) bool {
+	udpAddr, ok := addr.(*net.UDPAddr)
+
+	return ok && udpAddr.IP.To4() != nil
+}
+
 // ReadFrom reads a packet from the connection,
 // copying the payload into p. It returns the number of
 // bytes copied into p and the return address that
@@ -101,12 +178,15 @@ func (c *UDPConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
 	for {
 		select {
 		case ibData := <-c.readCh:
-			n := copy(p, ibData.data)
-			if n < len(ibData.data) {
+			n, from := copy(p, ibData.data), ibData.from
+			short := n < len(ibData.data)
+			ibData.from = nil
+			inboundDataPool.Put(ibData)
+			if short {
 				return 0, nil, io.ErrShortBuffer
 			}
 
-			return n, ibData.from, nil
+			return n, from, nil
 
 		case <-c.readTimer.C:
 			return 0, nil, &net.OpError{
@@ -264,19 +344,35 @@ func (c *UDPConn) WriteTo(payload []byte, addr net.Addr) (int, error) { //nolint
 // Close closes the connection.
 // Any blocked ReadFrom or WriteTo operations will be unblocked and return errors.
 func (c *UDPConn) Close() error {
+	if !c.closeConn() {
+		return errAlreadyClosed
+	}
+	if c.sibling != nil {
+		c.sibling.closeConn()
+	}
+
+	c.client.OnDeallocated(c.relayedAddr)
+
+	return c.refreshAllocation(0, true /* dontWait=true */)
+}
+
+// closeConn stops the timers and unblocks ReadFrom, it returns false if the
+// conn was already closed.
+func (c *UDPConn) closeConn() bool {
 	c.refreshAllocTimer.Stop()
 	c.refreshPermsTimer.Stop()
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	select {
 	case <-c.closeCh:
-		return errAlreadyClosed
+		return false
 	default:
 		close(c.closeCh)
-	}
 
-	c.client.OnDeallocated(c.relayedAddr)
-
-	return c.refreshAllocation(0, true /* dontWait=true */)
+		return true
+	}
 }
 
 // LocalAddr returns the local network address.
@@ -381,6 +477,9 @@ func (a *allocation) CreatePermissions(addrs ...net.Addr) error {
 
 				return errTryAgain
 			}
+			if a.moveOnMismatch(code.Code) {
+				return errTryAgain
+			}
 
 			return fmt.Errorf("%s (error %s)", res.Type, code) //nolint:goerr113
 		}
@@ -394,13 +493,16 @@ func (a *allocation) CreatePermissions(addrs ...net.Addr) error {
 // HandleInbound passes inbound data in UDPConn.
 func (c *UDPConn) HandleInbound(data []byte, from net.Addr) {
 	// Copy data
-	copied := make([]byte, len(data))
-	copy(copied, data)
+	ibData := inboundDataPool.Get().(*inboundData) //nolint:forcetypeassert
+	ibData.data = append(ibData.data[:0], data...)
+	ibData.from = from
 
 	select {
-	case c.readCh <- &inboundData{data: copied, from: from}:
+	case c.readCh <- ibData:
 	default:
 		c.log.Warnf("Receive buffer full")
+		ibData.from = nil
+		inboundDataPool.Put(ibData)
 	}
 }
 
@@ -443,6 +545,11 @@ func (c *UDPConn) bind(bound *binding) error {
 	res := trRes.Msg
 
 	if res.Type != stun.NewType(stun.MethodChannelBind, stun.ClassSuccessResponse) {
+		var code stun.ErrorCodeAttribute
+		if code.GetFrom(res) == nil && c.moveOnMismatch(code.Code) {
+			return c.bind(bound)
+		}
+
 		return fmt.Errorf("unexpected response type %s", res.Type) //nolint:goerr113
 	}
 
@@ -453,11 +560,13 @@ func (c *UDPConn) bind(bound *binding) error {
 }
 
 func (c *UDPConn) sendChannelData(data []byte, chNum uint16) (int, error) {
-	chData := &proto.ChannelData{
-		Data:   data,
-		Number: proto.ChannelNumber(chNum),
-	}
+	chData := channelDataPool.Get().(*proto.ChannelData) //nolint:forcetypeassert
+	defer channelDataPool.Put(chData)
+
+	chData.Data = data
+	chData.Number = proto.ChannelNumber(chNum)
 	chData.Encode()
+	chData.Data = nil
 	_, err := c.client.WriteTo(chData.Raw, c.serverAddr)
 	if err != nil {
 		return 0, err
@@ -7,6 +7,7 @@ package ipnet
 import (
 	"errors"
 	"net"
+	"net/netip"
 )
 
 var errFailedToCastAddr = errors.New("failed to cast net.Addr to *net.UDPAddr or *net.TCPAddr")
@@ -27,30 +28,34 @@ func AddrIPPort(a net.Addr) (net.IP, int, error) {
 }
 
 // AddrEqual asserts that two net.Addrs are equal
-// Currently only supports UDP but will be extended in the future to support others.
+// Currently only supports UDP and TCP but will be extended in the future to support others.
 func AddrEqual(a, b net.Addr) bool {
-	aUDP, ok := a.(*net.UDPAddr)
-	if !ok {
-		return false
-	}
+	switch aAddr := a.(type) {
+	case *net.UDPAddr:
+		bUDP, ok := b.(*net.UDPAddr)
 
-	bUDP, ok := b.(*net.UDPAddr)
-	if !ok {
-		return false
+		return ok && aAddr.IP.Equal(bUDP.IP) && aAddr.Port == bUDP.Port
+	case *net.TCPAddr:
+		bTCP, ok := b.(*net.TCPAddr)
+
+		return ok && aAddr.IP.Equal(bTCP.IP) && aAddr.Port == bTCP.Port
 	}
 
-	return aUDP.IP.Equal(bUDP.IP) && aUDP.Port == bUDP.Port
+	return false
 }
 
 // FingerprintAddr generates a fingerprint from net.UDPAddr or net.TCPAddr's
-// which can be used for indexing maps.
-func FingerprintAddr(addr net.Addr) string {
+// which can be used for indexing maps without allocating.
+func FingerprintAddr(addr net.Addr) netip.Addr {
+	var ip net.IP
 	switch a := addr.(type) {
 	case *net.UDPAddr:
-		return a.IP.String()
+		ip = a.IP
 	case *net.TCPAddr: // Do we really need this case?
-		return a.IP.String()
+		ip = a.IP
 	}
 
-	return "" // Should never happen
+	fingerprint, _ := netip.AddrFromSlice(ip)
+
+	return fingerprint.Unmap()
 }
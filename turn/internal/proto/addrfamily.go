@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package proto
+
+import (
+	"errors"
+
+	"github.com/pion/stun/v3"
+)
+
+// AdditionalAddressFamily represents the ADDITIONAL-ADDRESS-FAMILY Attribute
+// as defined in RFC 8656 Section 18.11. A client requests an IPv6 relayed
+// transport address in addition to the IPv4 one with it.
+type AdditionalAddressFamily RequestedAddressFamily
+
+var errInvalidAdditionalFamilyValue = errors.New("invalid value for additional family attribute")
+
+// GetFrom decodes ADDITIONAL-ADDRESS-FAMILY from message.
+func (f *AdditionalAddressFamily) GetFrom(m *stun.Message) error {
+	v, err := m.Get(stun.AttrAdditionalAddressFamily)
+	if err != nil {
+		return err
+	}
+	if err = stun.CheckSize(stun.AttrAdditionalAddressFamily, len(v), requestedFamilySize); err != nil {
+		return err
+	}
+	// The only valid value is IPv6.
+	if v[0] != byte(RequestedFamilyIPv6) {
+		return errInvalidAdditionalFamilyValue
+	}
+	*f = AdditionalAddressFamily(v[0])
+
+	return nil
+}
+
+func (f AdditionalAddressFamily) String() string {
+	return RequestedAddressFamily(f).String()
+}
+
+// AddTo adds ADDITIONAL-ADDRESS-FAMILY to message.
+func (f AdditionalAddressFamily) AddTo(m *stun.Message) error {
+	v := make([]byte, requestedFamilySize)
+	v[0] = byte(f)
+	// b[1:4] is RFFU = 0.
+	m.Add(stun.AttrAdditionalAddressFamily, v)
+
+	return nil
+}
+
+// AddressErrorCode represents the ADDRESS-ERROR-CODE Attribute as defined in
+// RFC 8656 Section 18.12. It tells why the relayed transport address of an
+// address family could not be allocated.
+type AddressErrorCode struct {
+	Family RequestedAddressFamily
+	Code   stun.ErrorCode
+	Reason []byte
+}
+
+const (
+	addressErrorCodeHeaderSize = 4
+	addressErrorCodeModulo     = 100
+)
+
+var errInvalidAddressErrorCode = errors.New("invalid value for address error code attribute")
+
+// GetFrom decodes ADDRESS-ERROR-CODE from message.
+func (c *AddressErrorCode) GetFrom(m *stun.Message) error {
+	v, err := m.Get(stun.AttrAddressErrorCode)
+	if err != nil {
+		return err
+	}
+	if len(v) < addressErrorCodeHeaderSize {
+		return errInvalidAddressErrorCode
+	}
+	switch v[0] {
+	case byte(RequestedFamilyIPv4), byte(RequestedFamilyIPv6):
+		c.Family = RequestedAddressFamily(v[0])
+	default:
+		return errInvalidAddressErrorCode
+	}
+	class := int(v[2] & 0x07)
+	number := int(v[3])
+	c.Code = stun.ErrorCode(class*addressErrorCodeModulo + number)
+	c.Reason = v[addressErrorCodeHeaderSize:]
+
+	return nil
+}
+
+// AddTo adds ADDRESS-ERROR-CODE to message.
+func (c AddressErrorCode) AddTo(m *stun.Message) error {
+	v := make([]byte, addressErrorCodeHeaderSize, addressErrorCodeHeaderSize+len(c.Reason))
+	v[0] = byte(c.Family)
+	v[2] = byte(int(c.Code) / addressErrorCodeModulo) //nolint:gosec // G115
+	v[3] = byte(int(c.Code) % addressErrorCodeModulo) //nolint:gosec // G115
+	m.Add(stun.AttrAddressErrorCode, append(v, c.Reason...))
+
+	return nil
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package proto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pion/stun/v3"
+)
+
+func TestAdditionalAddressFamily(t *testing.T) {
+	t.Run("AddTo", func(t *testing.T) {
+		stunMsg := new(stun.Message)
+		family := AdditionalAddressFamily(RequestedFamilyIPv6)
+		if err := family.AddTo(stunMsg); err != nil {
+			t.Error(err)
+		}
+		stunMsg.WriteHeader()
+
+		decoded := new(stun.Message)
+		if _, err := decoded.Write(stunMsg.Raw); err != nil {
+			t.Fatal("failed to decode message:", err)
+		}
+		var got AdditionalAddressFamily
+		if err := got.GetFrom(decoded); err != nil {
+			t.Fatal(err)
+		}
+		if got != family {
+			t.Errorf("Decoded %q, expected %q", got, family)
+		}
+	})
+	t.Run("HandleErr", func(t *testing.T) {
+		m := new(stun.Message)
+		var handle AdditionalAddressFamily
+		if err := handle.GetFrom(m); !errors.Is(err, stun.ErrAttributeNotFound) {
+			t.Errorf("%v should be not found", err)
+		}
+		m.Add(stun.AttrAdditionalAddressFamily, []byte{2, 0, 0})
+		if err := handle.GetFrom(m); !stun.IsAttrSizeInvalid(err) {
+			t.Error("IsAttrSizeInvalid should be true")
+		}
+		m.Reset()
+		m.Add(stun.AttrAdditionalAddressFamily, []byte{byte(RequestedFamilyIPv4), 0, 0, 0})
+		if err := handle.GetFrom(m); !errors.Is(err, errInvalidAdditionalFamilyValue) {
+			t.Errorf("IPv4 should be invalid: %v", err)
+		}
+	})
+}
+
+func TestAddressErrorCode(t *testing.T) {
+	t.Run("AddTo", func(t *testing.T) {
+		stunMsg := new(stun.Message)
+		code := AddressErrorCode{
+			Family: RequestedFamilyIPv6,
+			Code:   stun.CodeAddrFamilyNotSupported,
+			Reason: []byte("Address Family not Supported"),
+		}
+		if err := code.AddTo(stunMsg); err != nil {
+			t.Error(err)
+		}
+		stunMsg.WriteHeader()
+
+		decoded := new(stun.Message)
+		if _, err := decoded.Write(stunMsg.Raw); err != nil {
+			t.Fatal("failed to decode message:", err)
+		}
+		var got AddressErrorCode
+		if err := got.GetFrom(decoded); err != nil {
+			t.Fatal(err)
+		}
+		if got.Family != code.Family || got.Code != code.Code || string(got.Reason) != string(code.Reason) {
+			t.Errorf("Decoded %v, expected %v", got, code)
+		}
+	})
+	t.Run("HandleErr", func(t *testing.T) {
+		m := new(stun.Message)
+		var handle AddressErrorCode
+		if err := handle.GetFrom(m); !errors.Is(err, stun.ErrAttributeNotFound) {
+			t.Errorf("%v should be not found", err)
+		}
+		m.Add(stun.AttrAddressErrorCode, []byte{2, 0, 4})
+		if err := handle.GetFrom(m); !errors.Is(err, errInvalidAddressErrorCode) {
+			t.Errorf("short value should be invalid: %v", err)
+		}
+		m.Reset()
+		m.Add(stun.AttrAddressErrorCode, []byte{3, 0, 4, 40})
+		if err := handle.GetFrom(m); !errors.Is(err, errInvalidAddressErrorCode) {
+			t.Errorf("unknown family should be invalid: %v", err)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package proto
+
+import "github.com/pion/stun/v3"
+
+// MobilityTicket represents MOBILITY-TICKET attribute.
+//
+// The MOBILITY-TICKET attribute is used to retain an allocation on the
+// TURN server when the client's IP address changes. A client requests
+// mobility with an empty MOBILITY-TICKET attribute in the Allocate
+// request, and the server issues the opaque ticket in the success
+// responses to Allocate and Refresh requests. A Refresh request with
+// the ticket moves the allocation to its new 5-tuple.
+//
+// RFC 8016 Section 5.1.
+type MobilityTicket []byte
+
+// AddTo adds MOBILITY-TICKET to message.
+func (t MobilityTicket) AddTo(m *stun.Message) error {
+	m.Add(stun.AttrMobilityTicket, t)
+
+	return nil
+}
+
+// GetFrom decodes MOBILITY-TICKET from message.
+func (t *MobilityTicket) GetFrom(m *stun.Message) error {
+	v, err := m.Get(stun.AttrMobilityTicket)
+	if err != nil {
+		return err
+	}
+	*t = v
+
+	return nil
+}
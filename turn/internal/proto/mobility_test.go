@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/pion/stun/v3"
+)
+
+func TestMobilityTicket(t *testing.T) {
+	for _, ticket := range []MobilityTicket{{}, []byte("ticket")} {
+		stunMsg := new(stun.Message)
+		if err := ticket.AddTo(stunMsg); err != nil {
+			t.Error(err)
+		}
+		stunMsg.WriteHeader()
+
+		decoded := new(stun.Message)
+		if _, err := decoded.Write(stunMsg.Raw); err != nil {
+			t.Fatal("failed to decode message:", err)
+		}
+		var got MobilityTicket
+		if err := got.GetFrom(decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, ticket) {
+			t.Errorf("Decoded %x, expected %x", got, ticket)
+		}
+	}
+
+	var handle MobilityTicket
+	if err := handle.GetFrom(new(stun.Message)); !errors.Is(err, stun.ErrAttributeNotFound) {
+		t.Errorf("%v should be not found", err)
+	}
+}
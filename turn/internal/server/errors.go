@@ -26,4 +26,22 @@ var (
 	errShortWrite                             = errors.New("packet write smaller than packet")
 	errNoSuchChannelBind                      = errors.New("no such channel bind")
 	errFailedWriteSocket                      = errors.New("failed writing to socket")
+	errTCPAllocationOverUDP                   = errors.New("TCP allocations require a TCP or TLS connection")
+	errTCPAllocationWithReservation           = errors.New("EVEN-PORT and RESERVATION-TOKEN not allowed for TCP")
+	errNotAllowedOnTCPAllocation              = errors.New("method not allowed on TCP allocations")
+	errNotTCPAllocation                       = errors.New("allocation does not have a TCP relayed transport address")
+	errTCPConnectionExists                    = errors.New("allocation already has a connection to the peer")
+	errPermissionQuotaReached                 = errors.New("permission quota reached")
+	errChannelQuotaReached                    = errors.New("channel quota reached")
+	errConnectionBindOverUDP                  = errors.New("ConnectionBind requires a TCP or TLS connection")
+	errNoAccessTokenHandler                   = errors.New("ACCESS-TOKEN is not supported")
+	errNoSuchKey                              = errors.New("no such access token key")
+	errAccessTokenNotYetValid                 = errors.New("ACCESS-TOKEN is not valid yet")
+	errAccessTokenExpired                     = errors.New("ACCESS-TOKEN has expired")
+	errRequestedAndAdditionalAddressFamily    = errors.New("Request must not contain two address family attributes")
+	errAddressFamilyWithReservationToken      = errors.New("RESERVATION-TOKEN not allowed with address families")
+	errTCPAllocationAddressFamily             = errors.New("TCP allocations are only supported for IPv4")
+	errPeerAddressFamilyMismatch              = errors.New("no relayed transport address of the peer address family")
+	errMobilityForbidden                      = errors.New("mobility is not allowed for the user")
+	errPeerDenied                             = errors.New("peer is denied")
 )
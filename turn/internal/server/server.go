@@ -22,30 +22,51 @@ type Request struct {
 	SrcAddr net.Addr
 	Buff    []byte
 
+	// StreamConn is the TCP or TLS connection the request was received on,
+	// it is nil for requests received on a net.PacketConn.
+	StreamConn net.Conn
+
+	// OnStreamConnBound is called once a ConnectionBind request made
+	// StreamConn the data connection of a peer, no more requests must be
+	// read from it, see https://tools.ietf.org/html/rfc6062#section-5.4.
+	OnStreamConnBound func()
+
+	// RelayBatch queues the packets relayed to peers while a batch of packets
+	// read from Conn is handled, it is nil if Conn is read one packet at a time.
+	RelayBatch *allocation.RelayWriteBatch
+
 	// Server State
 	AllocationManager *allocation.Manager
 	NonceHash         *NonceHash
+	Quotas            *allocation.Quotas
 
 	// User Configuration
 	AuthHandler        func(username string, realm string, srcAddr net.Addr) (key []byte, ok bool)
 	Log                logging.LeveledLogger
 	Realm              string
 	ChannelBindTimeout time.Duration
+	QuotaHandler       func(username, realm string, srcAddr net.Addr) allocation.Quota
+	AccessTokenHandler func(kid string, srcAddr net.Addr) (key []byte, ok bool)
+	AuthServer         string
+	RedirectHandler    func(username, realm string, srcAddr net.Addr) (alternate *stun.AlternateServer, domain string)
+	MobilityHandler    func(username, realm string, srcAddr net.Addr) bool
+	PeerACLHandler     func(username, realm string, peerIP net.IP) bool
 }
 
 // HandleRequest processes the give Request.
 func HandleRequest(r Request) error {
-	r.Log.Debugf("Received %d bytes of udp from %s on %s", len(r.Buff), r.SrcAddr, r.Conn.LocalAddr())
-
+	// ChannelData is not logged per packet, the variadic arguments of the
+	// logger would allocate on the data path.
 	if proto.IsChannelData(r.Buff) {
 		return handleDataPacket(r)
 	}
 
+	r.Log.Debugf("Received %d bytes of udp from %s on %s", len(r.Buff), r.SrcAddr, r.Conn.LocalAddr())
+
 	return handleTURNPacket(r)
 }
 
 func handleDataPacket(req Request) error {
-	req.Log.Debugf("Received DataPacket from %s", req.SrcAddr.String())
 	c := proto.ChannelData{Raw: req.Buff}
 	if err := c.Decode(); err != nil {
 		return fmt.Errorf("%w: %v", errFailedToCreateChannelData, err) //nolint:errorlint
@@ -121,6 +142,10 @@ func getMessageHandler(class stun.MessageClass, method stun.Method) ( // nolint:
 			return handleChannelBindRequest, nil
 		case stun.MethodBinding:
 			return handleBindingRequest, nil
+		case stun.MethodConnect:
+			return handleConnectRequest, nil
+		case stun.MethodConnectionBind:
+			return handleConnectionBindRequest, nil
 		default:
 			return nil, fmt.Errorf("%w: %s", errUnexpectedMethod, method)
 		}
@@ -4,6 +4,7 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net"
 
@@ -49,6 +50,11 @@ func handleAllocateRequest(req Request, stunMsg *stun.Message) error { //nolint:
 		stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
 		&stun.ErrorCodeAttribute{Code: stun.CodeInsufficientCapacity},
 	)
+	addressFamilyNotSupportedMsg := buildMsg(
+		stunMsg.TransactionID,
+		stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
+		&stun.ErrorCodeAttribute{Code: stun.CodeAddrFamilyNotSupported},
+	)
 
 	// 2. The server checks if the 5-tuple is currently in use by an
 	//    existing allocation.  If yes, the server rejects the request with
@@ -93,6 +99,26 @@ func handleAllocateRequest(req Request, stunMsg *stun.Message) error { //nolint:
 		return buildAndSendErr(req.Conn, req.SrcAddr, errUnsupportedTransportProtocol, msg...)
 	}
 
+	// RFC 6062: TCP allocations are controlled over TCP or TLS, are supported
+	// by the relay address generator and do not reserve ports.
+	//   https://tools.ietf.org/html/rfc6062#section-5.1
+	if requestedTransport.Protocol == proto.ProtoTCP {
+		switch {
+		case req.StreamConn == nil:
+			return buildAndSendErr(req.Conn, req.SrcAddr, errTCPAllocationOverUDP, badRequestMsg...)
+		case !req.AllocationManager.SupportsTCP():
+			msg := buildMsg(
+				stunMsg.TransactionID,
+				stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
+				&stun.ErrorCodeAttribute{Code: stun.CodeUnsupportedTransProto},
+			)
+
+			return buildAndSendErr(req.Conn, req.SrcAddr, errUnsupportedTransportProtocol, msg...)
+		case stunMsg.Contains(stun.AttrEvenPort) || stunMsg.Contains(stun.AttrReservationToken):
+			return buildAndSendErr(req.Conn, req.SrcAddr, errTCPAllocationWithReservation, badRequestMsg...)
+		}
+	}
+
 	// 4. The request may contain a DONT-FRAGMENT attribute.  If it does,
 	//    but the server does not support sending UDP datagrams with the DF
 	//    bit set to 1 (see Section 12), then the server treats the DONT-
@@ -109,6 +135,35 @@ func handleAllocateRequest(req Request, stunMsg *stun.Message) error { //nolint:
 		return buildAndSendErr(req.Conn, req.SrcAddr, errNoDontFragmentSupport, msg...)
 	}
 
+	// RFC 8656: the request may contain a REQUESTED-ADDRESS-FAMILY attribute
+	// for an IPv6 relayed transport address, or an ADDITIONAL-ADDRESS-FAMILY
+	// attribute for an IPv6 one in addition to the IPv4 one.  A request with
+	// both, or with either of them and a RESERVATION-TOKEN attribute, is
+	// rejected with a 400 (Bad Request) error.
+	//   https://tools.ietf.org/html/rfc8656#section-7.2
+	var requestedFamily proto.RequestedAddressFamily
+	var additionalFamily proto.AdditionalAddressFamily
+	hasRequestedFamily := stunMsg.Contains(stun.AttrRequestedAddressFamily)
+	dualStack := stunMsg.Contains(stun.AttrAdditionalAddressFamily)
+	if hasRequestedFamily {
+		if err = requestedFamily.GetFrom(stunMsg); err != nil {
+			return buildAndSendErr(req.Conn, req.SrcAddr, err, addressFamilyNotSupportedMsg...)
+		}
+	}
+	if dualStack {
+		if err = additionalFamily.GetFrom(stunMsg); err != nil {
+			return buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
+		}
+	}
+	switch {
+	case dualStack && hasRequestedFamily:
+		return buildAndSendErr(req.Conn, req.SrcAddr, errRequestedAndAdditionalAddressFamily, badRequestMsg...)
+	case (dualStack || hasRequestedFamily) && stunMsg.Contains(stun.AttrReservationToken):
+		return buildAndSendErr(req.Conn, req.SrcAddr, errAddressFamilyWithReservationToken, badRequestMsg...)
+	case requestedTransport.Protocol == proto.ProtoTCP && (dualStack || requestedFamily == proto.RequestedFamilyIPv6):
+		return buildAndSendErr(req.Conn, req.SrcAddr, errTCPAllocationAddressFamily, addressFamilyNotSupportedMsg...)
+	}
+
 	// 5.  The server checks if the request contains a RESERVATION-TOKEN
 	//     attribute.  If yes, and the request also contains an EVEN-PORT
 	//     attribute, then the server rejects the request with a 400 (Bad
@@ -151,20 +206,98 @@ func handleAllocateRequest(req Request, stunMsg *stun.Message) error { //nolint:
 	//    server is free to define this allocation quota any way it wishes,
 	//    but SHOULD define it based on the username used to authenticate
 	//    the request, and not on the client's transport address.
+	srcIP, srcPort, err := ipnet.AddrIPPort(req.SrcAddr)
+	if err != nil {
+		return buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
+	}
+
+	username, quota := requestQuota(req, stunMsg)
+	if err = req.Quotas.Reserve(username, srcIP, quota); err != nil {
+		msg := buildMsg(
+			stunMsg.TransactionID,
+			stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: stun.CodeAllocQuotaReached},
+		)
+
+		return buildAndSendErr(req.Conn, req.SrcAddr, err, msg...)
+	}
 
 	// 8. Also at any point, the server MAY choose to reject the request
 	//    with a 300 (Try Alternate) error if it wishes to redirect the
 	//    client to a different server.  The use of this error code and
 	//    attribute follow the specification in [RFC5389].
+	if alternate, domain := requestRedirect(req, stunMsg); alternate != nil {
+		req.Quotas.Release(username, srcIP)
+
+		attrs := []stun.Setter{&stun.ErrorCodeAttribute{Code: stun.CodeTryAlternate}, alternate}
+		if domain != "" {
+			attrs = append(attrs, stun.NewAlternateDomain(domain))
+		}
+		attrs = append(attrs, messageIntegrity)
+
+		return buildAndSend(req.Conn, req.SrcAddr, buildMsg(
+			stunMsg.TransactionID,
+			stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
+			attrs...,
+		)...)
+	}
+
+	// RFC 8016: a client asks for mobility with an empty MOBILITY-TICKET
+	// attribute.  If the user is not allowed mobility, the server rejects
+	// the request with a 405 (Mobility Forbidden) error.
+	//   https://tools.ietf.org/html/rfc8016#section-3.1
+	mobility, mobilityAllowed := requestMobility(req, stunMsg)
+	if mobility && !mobilityAllowed {
+		req.Quotas.Release(username, srcIP)
+
+		return buildAndSendErr(req.Conn, req.SrcAddr, errMobilityForbidden, buildMsg(
+			stunMsg.TransactionID,
+			stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: stun.CodeMobilityForbidden},
+		)...)
+	}
+
 	lifetimeDuration := allocationLifeTime(stunMsg)
-	alloc, err := req.AllocationManager.CreateAllocation(
-		fiveTuple,
-		req.Conn,
-		requestedPort,
-		lifetimeDuration)
+	var alloc *allocation.Allocation
+	var addressErrors []stun.Setter
+	switch {
+	case requestedTransport.Protocol == proto.ProtoTCP:
+		alloc, err = req.AllocationManager.CreateTCPAllocation(
+			fiveTuple, req.Conn, requestedPort, lifetimeDuration, username)
+	case dualStack:
+		// A dual-stack allocation succeeds with either relayed transport address,
+		// an ADDRESS-ERROR-CODE attribute tells why the other one failed.
+		var ipv4Err, ipv6Err error
+		alloc, ipv4Err, ipv6Err = req.AllocationManager.CreateDualStackAllocation(
+			fiveTuple, req.Conn, requestedPort, lifetimeDuration, username)
+		if alloc == nil {
+			err = ipv4Err
+
+			break
+		}
+		if ipv4Err != nil {
+			addressErrors = append(addressErrors, newAddressErrorCode(proto.RequestedFamilyIPv4, ipv4Err))
+		}
+		if ipv6Err != nil {
+			addressErrors = append(addressErrors, newAddressErrorCode(proto.RequestedFamilyIPv6, ipv6Err))
+		}
+	case requestedFamily == proto.RequestedFamilyIPv6:
+		alloc, err = req.AllocationManager.CreateIPv6Allocation(
+			fiveTuple, req.Conn, requestedPort, lifetimeDuration, username)
+	default:
+		alloc, err = req.AllocationManager.CreateAllocation(
+			fiveTuple, req.Conn, requestedPort, lifetimeDuration, username)
+	}
 	if err != nil {
+		req.Quotas.Release(username, srcIP)
+		if errors.Is(err, allocation.ErrAddressFamilyNotSupported) {
+			return buildAndSendErr(req.Conn, req.SrcAddr, err, addressFamilyNotSupportedMsg...)
+		}
+
 		return buildAndSendErr(req.Conn, req.SrcAddr, err, insufficientCapacityMsg...)
 	}
+	alloc.SetQuota(req.Quotas, srcIP, quota)
+	cacheAccessToken(req, stunMsg, alloc)
 
 	// Once the allocation is created, the server replies with a success
 	// response.
@@ -178,11 +311,6 @@ func handleAllocateRequest(req Request, stunMsg *stun.Message) error { //nolint:
 	//   * An XOR-MAPPED-ADDRESS attribute containing the client's IP address
 	//     and port (from the 5-tuple).
 
-	srcIP, srcPort, err := ipnet.AddrIPPort(req.SrcAddr)
-	if err != nil {
-		return buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
-	}
-
 	relayIP, relayPort, err := ipnet.AddrIPPort(alloc.RelayAddr)
 	if err != nil {
 		return buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
@@ -193,6 +321,19 @@ func handleAllocateRequest(req Request, stunMsg *stun.Message) error { //nolint:
 			IP:   relayIP,
 			Port: relayPort,
 		},
+	}
+	if alloc.AdditionalRelayAddr != nil {
+		additionalIP, additionalPort, err := ipnet.AddrIPPort(alloc.AdditionalRelayAddr)
+		if err != nil {
+			return buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
+		}
+		responseAttrs = append(responseAttrs, &proto.RelayedAddress{
+			IP:   additionalIP,
+			Port: additionalPort,
+		})
+	}
+	responseAttrs = append(responseAttrs, addressErrors...)
+	responseAttrs = append(responseAttrs,
 		&proto.Lifetime{
 			Duration: lifetimeDuration,
 		},
@@ -200,13 +341,28 @@ func handleAllocateRequest(req Request, stunMsg *stun.Message) error { //nolint:
 			IP:   srcIP,
 			Port: srcPort,
 		},
-	}
+	)
 
 	if reservationToken != "" {
 		req.AllocationManager.CreateReservation(reservationToken, relayPort)
 		responseAttrs = append(responseAttrs, proto.ReservationToken([]byte(reservationToken)))
 	}
 
+	if mobility {
+		ticket, err := req.AllocationManager.NewMobilityTicket(alloc)
+		if err != nil {
+			// Deleting the allocation releases its quota
+			req.AllocationManager.DeleteAllocation(fiveTuple)
+
+			return buildAndSendErr(req.Conn, req.SrcAddr, err, buildMsg(
+				stunMsg.TransactionID,
+				stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse),
+				&stun.ErrorCodeAttribute{Code: stun.CodeServerError},
+			)...)
+		}
+		responseAttrs = append(responseAttrs, proto.MobilityTicket(ticket))
+	}
+
 	msg := buildMsg(
 		stunMsg.TransactionID,
 		stun.NewType(stun.MethodAllocate, stun.ClassSuccessResponse),
@@ -232,13 +388,48 @@ func handleRefreshRequest(req Request, stunMsg *stun.Message) error {
 		Protocol: allocation.UDP,
 	}
 
-	if lifetimeDuration != 0 {
-		a := req.AllocationManager.GetAllocation(fiveTuple)
+	// RFC 8016: a Refresh request with the MOBILITY-TICKET of an allocation
+	// from a new 5-tuple moves the allocation to it.
+	//   https://tools.ietf.org/html/rfc8016#section-3.2
+	a := req.AllocationManager.GetAllocation(fiveTuple)
+	var ticket proto.MobilityTicket
+	if a == nil && req.MobilityHandler != nil && req.StreamConn == nil && ticket.GetFrom(stunMsg) == nil {
+		username, _ := requestQuota(req, stunMsg)
+		if a, err = req.AllocationManager.MoveAllocation(ticket, fiveTuple, username); err != nil {
+			return buildAndSendErr(req.Conn, req.SrcAddr, err, allocMismatchMsg(stunMsg, stun.MethodRefresh)...)
+		}
+	}
 
+	responseAttrs := []stun.Setter{
+		&proto.Lifetime{
+			Duration: lifetimeDuration,
+		},
+	}
+
+	if lifetimeDuration != 0 {
 		if a == nil {
-			return fmt.Errorf("%w %v:%v", errNoAllocationFound, req.SrcAddr, req.Conn.LocalAddr())
+			return buildAndSendErr(req.Conn, req.SrcAddr,
+				fmt.Errorf("%w %v:%v", errNoAllocationFound, req.SrcAddr, req.Conn.LocalAddr()),
+				allocMismatchMsg(stunMsg, stun.MethodRefresh)...)
 		}
 		a.Refresh(lifetimeDuration)
+		cacheAccessToken(req, stunMsg, a)
+
+		_, quota := requestQuota(req, stunMsg)
+		a.UpdateQuota(quota)
+
+		// Every Refresh of a mobile allocation issues a new ticket
+		if a.Mobility() {
+			newTicket, err := req.AllocationManager.NewMobilityTicket(a)
+			if err != nil {
+				return buildAndSendErr(req.Conn, req.SrcAddr, err, buildMsg(
+					stunMsg.TransactionID,
+					stun.NewType(stun.MethodRefresh, stun.ClassErrorResponse),
+					&stun.ErrorCodeAttribute{Code: stun.CodeServerError},
+				)...)
+			}
+			responseAttrs = append(responseAttrs, proto.MobilityTicket(newTicket))
+		}
 	} else {
 		req.AllocationManager.DeleteAllocation(fiveTuple)
 	}
@@ -249,12 +440,7 @@ func handleRefreshRequest(req Request, stunMsg *stun.Message) error {
 		buildMsg(
 			stunMsg.TransactionID,
 			stun.NewType(stun.MethodRefresh, stun.ClassSuccessResponse),
-			[]stun.Setter{
-				&proto.Lifetime{
-					Duration: lifetimeDuration,
-				},
-				messageIntegrity,
-			}...,
+			append(responseAttrs, messageIntegrity)...,
 		)...,
 	)
 }
@@ -268,7 +454,9 @@ func handleCreatePermissionRequest(req Request, stunMsg *stun.Message) error {
 		Protocol: allocation.UDP,
 	})
 	if alloc == nil {
-		return fmt.Errorf("%w %v:%v", errNoAllocationFound, req.SrcAddr, req.Conn.LocalAddr())
+		return buildAndSendErr(req.Conn, req.SrcAddr,
+			fmt.Errorf("%w %v:%v", errNoAllocationFound, req.SrcAddr, req.Conn.LocalAddr()),
+			allocMismatchMsg(stunMsg, stun.MethodCreatePermission)...)
 	}
 
 	messageIntegrity, hasAuth, err := authenticateRequest(req, stunMsg, stun.MethodCreatePermission)
@@ -276,39 +464,69 @@ func handleCreatePermissionRequest(req Request, stunMsg *stun.Message) error {
 		return err
 	}
 
-	addCount := 0
-
-	if err := stunMsg.ForEach(stun.AttrXORPeerAddress, func(m *stun.Message) error {
+	// All peers are validated before any permission is installed, a request
+	// either installs all its permissions or none (RFC 8656 Section 9.2).
+	var peers []net.Addr
+	err = stunMsg.ForEach(stun.AttrXORPeerAddress, func(m *stun.Message) error {
 		var peerAddress proto.PeerAddress
 		if err := peerAddress.GetFrom(m); err != nil {
 			return err
 		}
 
+		peer := &net.UDPAddr{
+			IP:   peerAddress.IP,
+			Port: peerAddress.Port,
+		}
+		if !alloc.HasAddressFamilyOf(peer) {
+			return errPeerAddressFamilyMismatch
+		}
+
 		if err := req.AllocationManager.GrantPermission(req.SrcAddr, peerAddress.IP); err != nil {
 			req.Log.Infof("permission denied for client %s to peer %s", req.SrcAddr, peerAddress.IP)
 
-			return err
+			return fmt.Errorf("%w: %v", errPeerDenied, err) //nolint:errorlint
 		}
 
-		req.Log.Debugf("Adding permission for %s", fmt.Sprintf("%s:%d",
-			peerAddress.IP, peerAddress.Port))
+		if !peerAllowed(req, alloc, peer) {
+			req.Log.Warnf("peer ACL denied client %s of user %q to peer %s", req.SrcAddr, alloc.Username(), peer.IP)
+
+			return errPeerDenied
+		}
 
-		alloc.AddPermission(allocation.NewPermission(
-			&net.UDPAddr{
-				IP:   peerAddress.IP,
-				Port: peerAddress.Port,
-			},
-			req.Log,
-		))
-		addCount++
+		peers = append(peers, peer)
 
 		return nil
-	}); err != nil {
-		addCount = 0
+	})
+	if err == nil && alloc.PermissionQuotaReached(peers...) {
+		req.Log.Infof("permission quota reached for client %s", req.SrcAddr)
+		err = errPermissionQuotaReached
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, errPermissionQuotaReached):
+			return buildAndSendErr(req.Conn, req.SrcAddr, err, buildMsg(stunMsg.TransactionID,
+				stun.NewType(stun.MethodCreatePermission, stun.ClassErrorResponse),
+				&stun.ErrorCodeAttribute{Code: stun.CodeInsufficientCapacity})...)
+		case errors.Is(err, errPeerAddressFamilyMismatch):
+			return buildAndSendErr(req.Conn, req.SrcAddr, err, buildMsg(stunMsg.TransactionID,
+				stun.NewType(stun.MethodCreatePermission, stun.ClassErrorResponse),
+				&stun.ErrorCodeAttribute{Code: stun.CodePeerAddrFamilyMismatch})...)
+		case errors.Is(err, errPeerDenied):
+			return buildAndSendErr(req.Conn, req.SrcAddr, err, buildMsg(stunMsg.TransactionID,
+				stun.NewType(stun.MethodCreatePermission, stun.ClassErrorResponse),
+				&stun.ErrorCodeAttribute{Code: stun.CodeForbidden})...)
+		}
+		peers = nil
+	}
+
+	for _, peer := range peers {
+		req.Log.Debugf("Adding permission for %s", peer)
+		alloc.AddPermission(allocation.NewPermission(peer, req.Log))
 	}
 
 	respClass := stun.ClassSuccessResponse
-	if addCount == 0 {
+	if len(peers) == 0 {
 		respClass = stun.ClassErrorResponse
 	}
 
@@ -329,6 +547,8 @@ func handleSendIndication(req Request, stunMsg *stun.Message) error {
 	})
 	if alloc == nil {
 		return fmt.Errorf("%w %v:%v", errNoAllocationFound, req.SrcAddr, req.Conn.LocalAddr())
+	} else if alloc.Protocol == allocation.TCP {
+		return fmt.Errorf("%w: %v", errNotAllowedOnTCPAllocation, stunMsg.Type.Method)
 	}
 
 	dataAttr := proto.Data{}
@@ -346,10 +566,28 @@ func handleSendIndication(req Request, stunMsg *stun.Message) error {
 		return fmt.Errorf("%w: %v", errNoPermission, msgDst)
 	}
 
-	l, err := alloc.RelaySocket.WriteTo(dataAttr, msgDst)
+	if !peerAllowed(req, alloc, msgDst) {
+		req.Log.Debugf("Peer ACL denied, dropping %d bytes to %v", len(dataAttr), msgDst)
+
+		return nil
+	}
+
+	if !alloc.AllowRelay(len(dataAttr)) {
+		req.Log.Debugf("Bandwidth exceeded, dropping %d bytes to %v", len(dataAttr), msgDst)
+
+		return nil
+	}
+
+	relaySocket := alloc.RelaySocketFor(msgDst)
+	if relaySocket == nil {
+		return fmt.Errorf("%w: %v", errPeerAddressFamilyMismatch, msgDst)
+	}
+
+	l, err := writeToPeer(req, alloc, relaySocket, dataAttr, msgDst)
 	if l != len(dataAttr) {
 		return fmt.Errorf("%w %d != %d (expected) err: %v", errShortWrite, l, len(dataAttr), err) //nolint:errorlint
 	}
+	alloc.AddSent(l)
 
 	return err
 }
@@ -363,7 +601,9 @@ func handleChannelBindRequest(req Request, stunMsg *stun.Message) error {
 		Protocol: allocation.UDP,
 	})
 	if alloc == nil {
-		return fmt.Errorf("%w %v:%v", errNoAllocationFound, req.SrcAddr, req.Conn.LocalAddr())
+		return buildAndSendErr(req.Conn, req.SrcAddr,
+			fmt.Errorf("%w %v:%v", errNoAllocationFound, req.SrcAddr, req.Conn.LocalAddr()),
+			allocMismatchMsg(stunMsg, stun.MethodChannelBind)...)
 	}
 
 	badRequestMsg := buildMsg(
@@ -377,6 +617,12 @@ func handleChannelBindRequest(req Request, stunMsg *stun.Message) error {
 		return err
 	}
 
+	// Channels are not supported for TCP allocations.
+	//   https://tools.ietf.org/html/rfc6062#section-6.1
+	if alloc.Protocol == allocation.TCP {
+		return buildAndSendErr(req.Conn, req.SrcAddr, errNotAllowedOnTCPAllocation, badRequestMsg...)
+	}
+
 	var channel proto.ChannelNumber
 	if err = channel.GetFrom(stunMsg); err != nil {
 		return buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
@@ -387,22 +633,41 @@ func handleChannelBindRequest(req Request, stunMsg *stun.Message) error {
 		return buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
 	}
 
+	peer := &net.UDPAddr{IP: peerAddr.IP, Port: peerAddr.Port}
+	if !alloc.HasAddressFamilyOf(peer) {
+		peerAddressFamilyMismatchMsg := buildMsg(stunMsg.TransactionID,
+			stun.NewType(stun.MethodChannelBind, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: stun.CodePeerAddrFamilyMismatch})
+
+		return buildAndSendErr(req.Conn, req.SrcAddr, errPeerAddressFamilyMismatch, peerAddressFamilyMismatchMsg...)
+	}
+
+	forbiddenMsg := buildMsg(stunMsg.TransactionID,
+		stun.NewType(stun.MethodChannelBind, stun.ClassErrorResponse),
+		&stun.ErrorCodeAttribute{Code: stun.CodeForbidden})
+
 	if err = req.AllocationManager.GrantPermission(req.SrcAddr, peerAddr.IP); err != nil {
 		req.Log.Infof("permission denied for client %s to peer %s", req.SrcAddr, peerAddr.IP)
 
-		unauthorizedRequestMsg := buildMsg(stunMsg.TransactionID,
+		return buildAndSendErr(req.Conn, req.SrcAddr, err, forbiddenMsg...)
+	}
+
+	if !peerAllowed(req, alloc, peer) {
+		req.Log.Warnf("peer ACL denied client %s of user %q to peer %s", req.SrcAddr, alloc.Username(), peer.IP)
+
+		return buildAndSendErr(req.Conn, req.SrcAddr, errPeerDenied, forbiddenMsg...)
+	}
+
+	if alloc.ChannelQuotaReached(peer) || alloc.PermissionQuotaReached(peer) {
+		insufficientCapacityMsg := buildMsg(stunMsg.TransactionID,
 			stun.NewType(stun.MethodChannelBind, stun.ClassErrorResponse),
-			&stun.ErrorCodeAttribute{Code: stun.CodeUnauthorized})
+			&stun.ErrorCodeAttribute{Code: stun.CodeInsufficientCapacity})
 
-		return buildAndSendErr(req.Conn, req.SrcAddr, err, unauthorizedRequestMsg...)
+		return buildAndSendErr(req.Conn, req.SrcAddr, errChannelQuotaReached, insufficientCapacityMsg...)
 	}
 
 	req.Log.Debugf("Binding channel %d to %s", channel, peerAddr)
-	err = alloc.AddChannelBind(allocation.NewChannelBind(
-		channel,
-		&net.UDPAddr{IP: peerAddr.IP, Port: peerAddr.Port},
-		req.Log,
-	), req.ChannelBindTimeout)
+	err = alloc.AddChannelBind(allocation.NewChannelBind(channel, peer, req.Log), req.ChannelBindTimeout)
 	if err != nil {
 		return buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
 	}
@@ -416,28 +681,213 @@ func handleChannelBindRequest(req Request, stunMsg *stun.Message) error {
 }
 
 func handleChannelData(req Request, channelData *proto.ChannelData) error {
-	req.Log.Debugf("Received ChannelData from %s", req.SrcAddr)
-
-	alloc := req.AllocationManager.GetAllocation(&allocation.FiveTuple{
+	fiveTuple := &allocation.FiveTuple{
 		SrcAddr:  req.SrcAddr,
 		DstAddr:  req.Conn.LocalAddr(),
 		Protocol: allocation.UDP,
-	})
+	}
+	var alloc *allocation.Allocation
+	if req.RelayBatch != nil {
+		alloc = req.RelayBatch.GetAllocation(req.AllocationManager, fiveTuple)
+	} else {
+		alloc = req.AllocationManager.GetAllocation(fiveTuple)
+	}
 	if alloc == nil {
 		return fmt.Errorf("%w %v:%v", errNoAllocationFound, req.SrcAddr, req.Conn.LocalAddr())
 	}
 
+	if alloc.Protocol == allocation.TCP {
+		return errNotAllowedOnTCPAllocation
+	}
+
 	channel := alloc.GetChannelByNumber(channelData.Number)
 	if channel == nil {
 		return fmt.Errorf("%w %x", errNoSuchChannelBind, uint16(channelData.Number))
 	}
 
-	l, err := alloc.RelaySocket.WriteTo(channelData.Data, channel.Peer)
+	if !peerAllowed(req, alloc, channel.Peer) {
+		req.Log.Debugf("Peer ACL denied, dropping %d bytes to %v", len(channelData.Data), channel.Peer)
+
+		return nil
+	}
+
+	if !alloc.AllowRelay(len(channelData.Data)) {
+		req.Log.Debugf("Bandwidth exceeded, dropping %d bytes to %v", len(channelData.Data), channel.Peer)
+
+		return nil
+	}
+
+	relaySocket := alloc.RelaySocketFor(channel.Peer)
+	if relaySocket == nil {
+		return fmt.Errorf("%w: %v", errPeerAddressFamilyMismatch, channel.Peer)
+	}
+
+	l, err := writeToPeer(req, alloc, relaySocket, channelData.Data, channel.Peer)
 	if err != nil {
 		return fmt.Errorf("%w: %s", errFailedWriteSocket, err.Error())
 	} else if l != len(channelData.Data) {
 		return fmt.Errorf("%w %d != %d (expected)", errShortWrite, l, len(channelData.Data))
 	}
+	alloc.AddSent(l)
+
+	return nil
+}
+
+// writeToPeer writes p to the peer from the relay socket, or queues it in the
+// RelayBatch of the request to be written with the other packets of the batch.
+func writeToPeer(
+	req Request,
+	alloc *allocation.Allocation,
+	relaySocket net.PacketConn,
+	p []byte,
+	peer net.Addr,
+) (int, error) {
+	if req.RelayBatch != nil && req.RelayBatch.WriteTo(alloc, relaySocket, p, peer) {
+		return len(p), nil
+	}
+
+	return relaySocket.WriteTo(p, peer)
+}
+
+// See: https://tools.ietf.org/html/rfc6062#section-5.2
+// .
+func handleConnectRequest(req Request, stunMsg *stun.Message) error {
+	req.Log.Debugf("Received ConnectRequest from %s", req.SrcAddr)
+
+	alloc := req.AllocationManager.GetAllocation(&allocation.FiveTuple{
+		SrcAddr:  req.SrcAddr,
+		DstAddr:  req.Conn.LocalAddr(),
+		Protocol: allocation.UDP,
+	})
+	if alloc == nil {
+		return fmt.Errorf("%w %v:%v", errNoAllocationFound, req.SrcAddr, req.Conn.LocalAddr())
+	}
+
+	messageIntegrity, hasAuth, err := authenticateRequest(req, stunMsg, stun.MethodConnect)
+	if !hasAuth {
+		return err
+	}
+
+	errorMsg := func(code stun.ErrorCode) []stun.Setter {
+		return buildMsg(
+			stunMsg.TransactionID,
+			stun.NewType(stun.MethodConnect, stun.ClassErrorResponse),
+			&stun.ErrorCodeAttribute{Code: code},
+		)
+	}
+
+	if alloc.Protocol != allocation.TCP {
+		return buildAndSendErr(req.Conn, req.SrcAddr, errNotTCPAllocation, errorMsg(stun.CodeBadRequest)...)
+	}
+
+	peerAddr := proto.PeerAddress{}
+	if err = peerAddr.GetFrom(stunMsg); err != nil {
+		return buildAndSendErr(req.Conn, req.SrcAddr, err, errorMsg(stun.CodeBadRequest)...)
+	}
+	peer := &net.TCPAddr{IP: peerAddr.IP, Port: peerAddr.Port}
+
+	// If the server already has a connection to the peer, it rejects the
+	// request with a 446 (Connection Already Exists) error.
+	if alloc.HasTCPConnection(peer) {
+		return buildAndSendErr(req.Conn, req.SrcAddr, errTCPConnectionExists, errorMsg(stun.CodeConnAlreadyExists)...)
+	}
+
+	// The connection to the peer requires a permission, see
+	// https://tools.ietf.org/html/rfc6062#section-5.2.
+	if alloc.GetPermission(peer) == nil {
+		return buildAndSendErr(
+			req.Conn,
+			req.SrcAddr,
+			fmt.Errorf("%w: %v", errNoPermission, peer),
+			errorMsg(stun.CodeForbidden)...,
+		)
+	}
+
+	// Connecting to the peer takes up to 30 seconds, the response is sent
+	// once it completes so the control connection keeps being served.
+	go func() {
+		tcpConn, err := req.AllocationManager.Connect(alloc, peer)
+		if err != nil {
+			req.Log.Infof("Failed to connect to peer %s for %s: %v", peer, req.SrcAddr, err)
+			if err = buildAndSend(req.Conn, req.SrcAddr, errorMsg(stun.CodeConnTimeoutOrFailure)...); err != nil {
+				req.Log.Errorf("Failed to send Connect error response to %s: %v", req.SrcAddr, err)
+			}
+
+			return
+		}
+
+		if err = buildAndSend(
+			req.Conn,
+			req.SrcAddr,
+			buildMsg(stunMsg.TransactionID, stun.NewType(stun.MethodConnect, stun.ClassSuccessResponse),
+				[]stun.Setter{tcpConn.ID, messageIntegrity}...)...,
+		); err != nil {
+			req.Log.Errorf("Failed to send Connect response to %s: %v", req.SrcAddr, err)
+			if err = tcpConn.Close(); err != nil {
+				req.Log.Debugf("Failed to close connection to peer %s: %v", peer, err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// See: https://tools.ietf.org/html/rfc6062#section-5.4
+// .
+func handleConnectionBindRequest(req Request, stunMsg *stun.Message) error {
+	req.Log.Debugf("Received ConnectionBindRequest from %s", req.SrcAddr)
+
+	badRequestMsg := buildMsg(
+		stunMsg.TransactionID,
+		stun.NewType(stun.MethodConnectionBind, stun.ClassErrorResponse),
+		&stun.ErrorCodeAttribute{Code: stun.CodeBadRequest},
+	)
+
+	// The request is sent on a new TCP or TLS connection, which must not be
+	// used for an allocation.
+	if req.StreamConn == nil {
+		return buildAndSendErr(req.Conn, req.SrcAddr, errConnectionBindOverUDP, badRequestMsg...)
+	} else if alloc := req.AllocationManager.GetAllocation(&allocation.FiveTuple{
+		SrcAddr:  req.SrcAddr,
+		DstAddr:  req.Conn.LocalAddr(),
+		Protocol: allocation.UDP,
+	}); alloc != nil {
+		return buildAndSendErr(req.Conn, req.SrcAddr, errRelayAlreadyAllocatedForFiveTuple, badRequestMsg...)
+	}
+
+	messageIntegrity, hasAuth, err := authenticateRequest(req, stunMsg, stun.MethodConnectionBind)
+	if !hasAuth {
+		return err
+	}
+
+	var connectionID proto.ConnectionID
+	if err = connectionID.GetFrom(stunMsg); err != nil {
+		return buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
+	}
+
+	tcpConn, err := req.AllocationManager.BindTCPConnection(connectionID, requestUsername(stunMsg))
+	if err != nil {
+		return buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
+	}
+
+	if err = buildAndSend(
+		req.Conn,
+		req.SrcAddr,
+		buildMsg(stunMsg.TransactionID, stun.NewType(stun.MethodConnectionBind, stun.ClassSuccessResponse),
+			[]stun.Setter{messageIntegrity}...)...,
+	); err != nil {
+		if closeErr := tcpConn.Close(); closeErr != nil {
+			req.Log.Debugf("Failed to close connection to peer %s: %v", tcpConn.PeerAddr, closeErr)
+		}
+
+		return err
+	}
+
+	// From now on the connection carries the data of the peer.
+	if req.OnStreamConnBound != nil {
+		req.OnStreamConnBound()
+	}
+	tcpConn.Relay(req.StreamConn)
 
 	return nil
 }
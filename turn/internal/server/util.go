@@ -10,12 +10,18 @@ import (
 	"time"
 
 	"github.com/pion/stun/v3"
+	"github.com/pion/turn/v4/internal/allocation"
+	"github.com/pion/turn/v4/internal/ipnet"
 	"github.com/pion/turn/v4/internal/proto"
 )
 
 const (
 	// See: https://tools.ietf.org/html/rfc5766#section-6.2 defines 3600 seconds recommendation.
 	maximumAllocationLifetime = time.Hour
+
+	// maxAccessTokenClockSkew tolerates the clock difference between the
+	// server and the authorization server that issued an ACCESS-TOKEN.
+	maxAccessTokenClockSkew = 5 * time.Second
 )
 
 func buildAndSend(conn net.PacketConn, dst net.Addr, attrs ...stun.Setter) error {
@@ -59,12 +65,19 @@ func authenticateRequest(req Request, stunMsg *stun.Message, callingMethod stun.
 			return nil, false, err
 		}
 
-		return nil, false, buildAndSend(req.Conn, req.SrcAddr, buildMsg(stunMsg.TransactionID,
+		attrs := buildMsg(stunMsg.TransactionID,
 			stun.NewType(callingMethod, stun.ClassErrorResponse),
 			&stun.ErrorCodeAttribute{Code: responseCode},
 			stun.NewNonce(nonce),
 			stun.NewRealm(req.Realm),
-		)...)
+		)
+		// Clients learn that the server accepts access tokens, see
+		// https://tools.ietf.org/html/rfc7635#section-6.1.
+		if responseCode == stun.CodeUnauthorized && req.AccessTokenHandler != nil {
+			attrs = append(attrs, stun.NewThirdPartyAuthorization(req.AuthServer))
+		}
+
+		return nil, false, buildAndSend(req.Conn, req.SrcAddr, attrs...)
 	}
 
 	if !stunMsg.Contains(stun.AttrMessageIntegrity) {
@@ -82,7 +95,7 @@ func authenticateRequest(req Request, stunMsg *stun.Message, callingMethod stun.
 
 	// No Auth handler is set, server is running in STUN only mode
 	// Respond with 400 so clients don't retry.
-	if req.AuthHandler == nil {
+	if req.AuthHandler == nil && req.AccessTokenHandler == nil {
 		sendErr := buildAndSend(req.Conn, req.SrcAddr, badRequestMsg...)
 
 		return nil, false, sendErr
@@ -103,7 +116,32 @@ func authenticateRequest(req Request, stunMsg *stun.Message, callingMethod stun.
 		return nil, false, buildAndSendErr(req.Conn, req.SrcAddr, err, badRequestMsg...)
 	}
 
-	ourKey, ok := req.AuthHandler(usernameAttr.String(), realmAttr.String(), req.SrcAddr)
+	var ourKey []byte
+	ok := false
+	switch {
+	case stunMsg.Contains(stun.AttrAccessToken):
+		token, err := requestAccessToken(req, stunMsg)
+		if err != nil {
+			req.Log.Debugf("Rejecting ACCESS-TOKEN of %v: %v", req.SrcAddr, err)
+
+			return respondWithNonce(stun.CodeUnauthorized)
+		}
+		ourKey, ok = token.MACKey, true
+	case req.AllocationManager != nil:
+		// Requests after Allocate are authenticated with the mac_key of its ACCESS-TOKEN
+		alloc := req.AllocationManager.GetAllocation(&allocation.FiveTuple{
+			SrcAddr:  req.SrcAddr,
+			DstAddr:  req.Conn.LocalAddr(),
+			Protocol: allocation.UDP,
+		})
+		if alloc != nil && alloc.Username() == usernameAttr.String() {
+			ourKey = alloc.AccessTokenKey()
+			ok = ourKey != nil
+		}
+	}
+	if !ok && req.AuthHandler != nil {
+		ourKey, ok = req.AuthHandler(usernameAttr.String(), realmAttr.String(), req.SrcAddr)
+	}
 	if !ok {
 		return nil, false, buildAndSendErr(
 			req.Conn,
@@ -120,6 +158,152 @@ func authenticateRequest(req Request, stunMsg *stun.Message, callingMethod stun.
 	return stun.MessageIntegrity(ourKey), true, nil
 }
 
+// requestAccessToken decrypts the ACCESS-TOKEN of a request with the key of
+// the kid in USERNAME, and checks that the token is valid now, see
+// https://tools.ietf.org/html/rfc7635#section-6.2.
+func requestAccessToken(req Request, stunMsg *stun.Message) (stun.Token, error) {
+	var accessToken stun.AccessToken
+	usernameAttr := &stun.Username{}
+	if err := accessToken.GetFrom(stunMsg); err != nil {
+		return stun.Token{}, err
+	} else if err := usernameAttr.GetFrom(stunMsg); err != nil {
+		return stun.Token{}, err
+	}
+
+	if req.AccessTokenHandler == nil {
+		return stun.Token{}, errNoAccessTokenHandler
+	}
+	key, ok := req.AccessTokenHandler(usernameAttr.String(), req.SrcAddr)
+	if !ok {
+		return stun.Token{}, fmt.Errorf("%w %s", errNoSuchKey, usernameAttr.String())
+	}
+
+	token, err := accessToken.Decrypt(key, req.Realm)
+	if err != nil {
+		return stun.Token{}, err
+	}
+
+	now := time.Now()
+	switch {
+	case token.Timestamp.After(now.Add(maxAccessTokenClockSkew)):
+		return stun.Token{}, errAccessTokenNotYetValid
+	case now.After(token.Timestamp.Add(token.Lifetime + maxAccessTokenClockSkew)):
+		return stun.Token{}, errAccessTokenExpired
+	}
+
+	return token, nil
+}
+
+// cacheAccessToken keeps the mac_key of the ACCESS-TOKEN of an Allocate or
+// Refresh request, which authenticates the requests of the allocation that
+// do not carry the token.
+func cacheAccessToken(req Request, stunMsg *stun.Message, alloc *allocation.Allocation) {
+	if !stunMsg.Contains(stun.AttrAccessToken) {
+		return
+	}
+
+	if token, err := requestAccessToken(req, stunMsg); err == nil {
+		alloc.SetAccessTokenKey(token.MACKey, token.Timestamp.Add(token.Lifetime))
+	}
+}
+
+// requestQuota returns the user of an authenticated request and its quota.
+func requestQuota(req Request, stunMsg *stun.Message) (string, allocation.Quota) {
+	username := requestUsername(stunMsg)
+	realmAttr := &stun.Realm{}
+	_ = realmAttr.GetFrom(stunMsg)
+
+	if req.QuotaHandler == nil {
+		return username, allocation.Quota{}
+	}
+
+	return username, req.QuotaHandler(username, realmAttr.String(), req.SrcAddr)
+}
+
+// requestUsername returns the USERNAME of an authenticated request.
+func requestUsername(stunMsg *stun.Message) string {
+	usernameAttr := &stun.Username{}
+	_ = usernameAttr.GetFrom(stunMsg)
+
+	return usernameAttr.String()
+}
+
+// requestRedirect returns the alternate server an authenticated Allocate
+// request is redirected to, or nil to serve it.
+func requestRedirect(req Request, stunMsg *stun.Message) (*stun.AlternateServer, string) {
+	if req.RedirectHandler == nil {
+		return nil, ""
+	}
+
+	usernameAttr := &stun.Username{}
+	realmAttr := &stun.Realm{}
+	_ = usernameAttr.GetFrom(stunMsg)
+	_ = realmAttr.GetFrom(stunMsg)
+
+	return req.RedirectHandler(usernameAttr.String(), realmAttr.String(), req.SrcAddr)
+}
+
+// allocMismatchMsg is the 437 (Allocation Mismatch) error response to a request
+// that needs an allocation when the 5-tuple has none, a mobile client then moves
+// its allocation with a Refresh request.
+func allocMismatchMsg(stunMsg *stun.Message, method stun.Method) []stun.Setter {
+	return buildMsg(
+		stunMsg.TransactionID,
+		stun.NewType(method, stun.ClassErrorResponse),
+		&stun.ErrorCodeAttribute{Code: stun.CodeAllocMismatch},
+	)
+}
+
+// peerAllowed checks the peer against the peer ACL of the user of the allocation,
+// a denied peer is counted in the stats of the allocation.
+func peerAllowed(req Request, alloc *allocation.Allocation, peer net.Addr) bool {
+	if req.PeerACLHandler == nil {
+		return true
+	}
+
+	peerIP, _, err := ipnet.AddrIPPort(peer)
+	if err == nil && req.PeerACLHandler(alloc.Username(), req.Realm, peerIP) {
+		return true
+	}
+	alloc.AddPeerDenied()
+
+	return false
+}
+
+// requestMobility returns whether an authenticated Allocate request asks for a
+// MOBILITY-TICKET, and whether the user is allowed one. Mobility is supported for
+// allocations over UDP, see https://tools.ietf.org/html/rfc8016#section-3.1.
+func requestMobility(req Request, stunMsg *stun.Message) (requested, allowed bool) {
+	if req.MobilityHandler == nil || req.StreamConn != nil || !stunMsg.Contains(stun.AttrMobilityTicket) {
+		return false, false
+	}
+
+	usernameAttr := &stun.Username{}
+	realmAttr := &stun.Realm{}
+	_ = usernameAttr.GetFrom(stunMsg)
+	_ = realmAttr.GetFrom(stunMsg)
+
+	return true, req.MobilityHandler(usernameAttr.String(), realmAttr.String(), req.SrcAddr)
+}
+
+// newAddressErrorCode returns the ADDRESS-ERROR-CODE of a relayed transport address
+// that could not be allocated for a dual-stack allocation.
+func newAddressErrorCode(family proto.RequestedAddressFamily, err error) *proto.AddressErrorCode {
+	if errors.Is(err, allocation.ErrAddressFamilyNotSupported) {
+		return &proto.AddressErrorCode{
+			Family: family,
+			Code:   stun.CodeAddrFamilyNotSupported,
+			Reason: []byte("Address Family not Supported"),
+		}
+	}
+
+	return &proto.AddressErrorCode{
+		Family: family,
+		Code:   stun.CodeInsufficientCapacity,
+		Reason: []byte("Insufficient Capacity"),
+	}
+}
+
 func allocationLifeTime(m *stun.Message) time.Duration {
 	lifetimeDuration := proto.DefaultLifetime
 
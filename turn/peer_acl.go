@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"net"
+)
+
+// PeerACLRule allows and denies ranges of peer addresses. Allow takes precedence over Deny,
+// so that a part of a denied range can be allowed.
+type PeerACLRule struct {
+	Allow []*net.IPNet
+	Deny  []*net.IPNet
+}
+
+// decide returns whether the rule allows the peer, decided is false if neither list contains it.
+func (r PeerACLRule) decide(peerIP net.IP) (allowed, decided bool) {
+	switch {
+	case containsIP(r.Allow, peerIP):
+		return true, true
+	case containsIP(r.Deny, peerIP):
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// PeerACL controls the peer addresses clients may relay to. The rule of the user decides first,
+// then the rule of the realm and the server-wide rule. A peer none of them decides is allowed,
+// unless it is in DefaultDeniedPeers.
+type PeerACL struct {
+	// PeerACLRule applies to all users of the server
+	PeerACLRule
+
+	// Realms and Users are the rules of a realm and of a username
+	Realms map[string]PeerACLRule
+	Users  map[string]PeerACLRule
+
+	// AllowInternalPeers allows the peers in DefaultDeniedPeers that no rule denies
+	AllowInternalPeers bool
+}
+
+// Allowed returns whether the user may relay to the peer.
+func (acl *PeerACL) Allowed(username, realm string, peerIP net.IP) bool {
+	for _, rule := range []PeerACLRule{acl.Users[username], acl.Realms[realm], acl.PeerACLRule} {
+		if allowed, decided := rule.decide(peerIP); decided {
+			return allowed
+		}
+	}
+
+	return acl.AllowInternalPeers || !containsIP(defaultDeniedPeers, peerIP)
+}
+
+var defaultDeniedPeers = DefaultDeniedPeers() //nolint:gochecknoglobals
+
+// DefaultDeniedPeers returns the ranges a PeerACL denies unless they are allowed: the loopback,
+// unspecified and link-local addresses, and the metadata services of cloud providers that are
+// not link-local. A relay to them reaches services of the TURN server host or its network.
+func DefaultDeniedPeers() []*net.IPNet {
+	return []*net.IPNet{
+		mustParseCIDR("0.0.0.0/8"),
+		mustParseCIDR("127.0.0.0/8"),
+		mustParseCIDR("169.254.0.0/16"),
+		mustParseCIDR("::/128"),
+		mustParseCIDR("::1/128"),
+		mustParseCIDR("fe80::/10"),
+		// Alibaba Cloud and AWS IPv6 metadata services
+		mustParseCIDR("100.100.100.200/32"),
+		mustParseCIDR("fd00:ec2::254/128"),
+	}
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return ipNet
+}
+
+func containsIP(ipNets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerACL(t *testing.T) {
+	t.Run("DefaultDeniedPeers", func(t *testing.T) {
+		acl := PeerACL{}
+		for _, peer := range []string{
+			"127.0.0.1", "127.1.2.3", "0.0.0.0", "169.254.169.254", "100.100.100.200",
+			"::1", "::", "fe80::1", "fd00:ec2::254", "::ffff:127.0.0.1",
+		} {
+			assert.False(t, acl.Allowed("user", "realm", net.ParseIP(peer)), peer)
+		}
+		for _, peer := range []string{"10.0.0.1", "192.168.1.1", "8.8.8.8", "2001:db8::1"} {
+			assert.True(t, acl.Allowed("user", "realm", net.ParseIP(peer)), peer)
+		}
+
+		acl.AllowInternalPeers = true
+		assert.True(t, acl.Allowed("user", "realm", net.ParseIP("127.0.0.1")))
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		acl := PeerACL{
+			PeerACLRule: PeerACLRule{
+				Allow: []*net.IPNet{mustParseCIDR("10.1.0.0/16")},
+				Deny:  []*net.IPNet{mustParseCIDR("10.0.0.0/8")},
+			},
+			Realms: map[string]PeerACLRule{
+				"internal": {Allow: []*net.IPNet{mustParseCIDR("10.0.0.0/8")}},
+			},
+			Users: map[string]PeerACLRule{
+				"admin":   {Allow: []*net.IPNet{mustParseCIDR("127.0.0.1/32")}},
+				"blocked": {Deny: []*net.IPNet{mustParseCIDR("0.0.0.0/0")}},
+			},
+		}
+
+		// Allow takes precedence over Deny
+		assert.False(t, acl.Allowed("user", "realm", net.ParseIP("10.2.0.1")))
+		assert.True(t, acl.Allowed("user", "realm", net.ParseIP("10.1.0.1")))
+
+		// The rule of the realm decides before the server-wide rule
+		assert.True(t, acl.Allowed("user", "internal", net.ParseIP("10.2.0.1")))
+
+		// The rule of the user decides first
+		assert.True(t, acl.Allowed("admin", "realm", net.ParseIP("127.0.0.1")))
+		assert.False(t, acl.Allowed("admin", "realm", net.ParseIP("127.0.0.2")))
+		assert.False(t, acl.Allowed("blocked", "internal", net.ParseIP("10.1.0.1")))
+		assert.True(t, acl.Allowed("blocked", "internal", net.ParseIP("2001:db8::1")))
+	})
+}
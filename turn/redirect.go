@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"hash/fnv"
+	"net"
+	"sync/atomic"
+)
+
+// RedirectByUsernameHash returns a RedirectHandler that spreads users across servers by a hash
+// of their username, so that all allocations of a user are on the same server. Users that hash
+// to servers[local] are served, the others are redirected.
+func RedirectByUsernameHash(servers []AlternateServer, local int) RedirectHandler {
+	return func(username, _ string, _ net.Addr) (AlternateServer, bool) {
+		if len(servers) == 0 {
+			return AlternateServer{}, false
+		}
+
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(username))
+		i := int(h.Sum32() % uint32(len(servers))) //nolint:gosec // G115
+		if i == local {
+			return AlternateServer{}, false
+		}
+
+		return servers[i], true
+	}
+}
+
+// RedirectOverAllocations returns a RedirectHandler that redirects Allocate requests to the
+// alternates in turn once the server has maxAllocations, which can be Server.AllocationCount.
+func RedirectOverAllocations(
+	allocationCount func() int,
+	maxAllocations int,
+	alternates ...AlternateServer,
+) RedirectHandler {
+	var next atomic.Uint32
+
+	return func(string, string, net.Addr) (AlternateServer, bool) {
+		if len(alternates) == 0 || allocationCount() < maxAllocations {
+			return AlternateServer{}, false
+		}
+
+		return alternates[int(next.Add(1)-1)%len(alternates)], true
+	}
+}
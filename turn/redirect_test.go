@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package turn
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectByUsernameHash(t *testing.T) {
+	servers := []AlternateServer{
+		{IP: net.IPv4(10, 0, 0, 1), Port: 3478},
+		{IP: net.IPv4(10, 0, 0, 2), Port: 3478},
+		{IP: net.IPv4(10, 0, 0, 3), Port: 3478},
+	}
+
+	served := map[int]int{}
+	for local := range servers {
+		redirect := RedirectByUsernameHash(servers, local)
+		for i := 0; i < 30; i++ {
+			username := fmt.Sprintf("user%d", i)
+			alternate, ok := redirect(username, "pion.ly", nil)
+			if !ok {
+				served[local]++
+
+				continue
+			}
+
+			// Users are always sent to the same server, which serves them
+			again, _ := redirect(username, "pion.ly", nil)
+			assert.Equal(t, alternate, again)
+			_, redirected := RedirectByUsernameHash(servers, indexOf(servers, alternate))(username, "pion.ly", nil)
+			assert.False(t, redirected)
+		}
+	}
+
+	assert.Equal(t, 30, served[0]+served[1]+served[2])
+	assert.Len(t, served, len(servers))
+
+	_, ok := RedirectByUsernameHash(nil, 0)("user", "pion.ly", nil)
+	assert.False(t, ok)
+}
+
+func indexOf(servers []AlternateServer, server AlternateServer) int {
+	for i := range servers {
+		if servers[i].IP.Equal(server.IP) && servers[i].Port == server.Port {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func TestRedirectOverAllocations(t *testing.T) {
+	alternates := []AlternateServer{
+		{IP: net.IPv4(10, 0, 0, 1), Port: 3478},
+		{IP: net.IPv4(10, 0, 0, 2), Port: 3478},
+	}
+
+	allocations := 1
+	redirect := RedirectOverAllocations(func() int { return allocations }, 2, alternates...)
+
+	_, ok := redirect("user", "pion.ly", nil)
+	assert.False(t, ok)
+
+	// Once full, requests go to the alternates in turn
+	allocations = 2
+	for i := 0; i < 4; i++ {
+		alternate, ok := redirect("user", "pion.ly", nil)
+		assert.True(t, ok)
+		assert.Equal(t, alternates[i%2], alternate)
+	}
+
+	_, ok = RedirectOverAllocations(func() int { return allocations }, 2)("user", "pion.ly", nil)
+	assert.False(t, ok)
+}
@@ -17,6 +17,10 @@ type RelayAddressGeneratorNone struct {
 	// Address is passed to Listen/ListenPacket when creating the Relay
 	Address string
 
+	// AddressIPv6 is passed to ListenPacket when creating IPv6 relays, which
+	// are not supported if it is empty
+	AddressIPv6 string
+
 	Net transport.Net
 }
 
@@ -45,7 +49,12 @@ func (r *RelayAddressGeneratorNone) AllocatePacketConn(network string, requested
 	net.Addr,
 	error,
 ) {
-	conn, err := r.Net.ListenPacket(network, r.Address+":"+strconv.Itoa(requestedPort))
+	address, _, err := relayAddressForNetwork(network, r.Address, nil, r.AddressIPv6, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := r.Net.ListenPacket(network, net.JoinHostPort(address, strconv.Itoa(requestedPort)))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -58,3 +67,23 @@ func (r *RelayAddressGeneratorNone) AllocatePacketConn(network string, requested
 func (r *RelayAddressGeneratorNone) AllocateConn(string, int) (net.Conn, net.Addr, error) {
 	return nil, nil, errTODO
 }
+
+// AllocateListener generates a new Listener to accept TCP connections from peers on
+// and the IP/Port to populate the allocation response with.
+func (r *RelayAddressGeneratorNone) AllocateListener(network string, requestedPort int) (
+	net.Listener,
+	net.Addr,
+	error,
+) {
+	listener, err := listenTCP(r.Net, network, r.Address, requestedPort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return listener, listener.Addr(), nil
+}
+
+// DialTCP connects to a peer from the listening address and the port of relayAddr.
+func (r *RelayAddressGeneratorNone) DialTCP(network string, relayAddr, rAddr *net.TCPAddr) (net.Conn, error) {
+	return dialTCP(r.Net, network, r.Address, relayAddr.Port, rAddr)
+}
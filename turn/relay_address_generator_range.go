@@ -4,8 +4,10 @@
 package turn
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
 
 	"github.com/pion/randutil"
 	"github.com/pion/transport/v3"
@@ -32,6 +34,11 @@ type RelayAddressGeneratorPortRange struct {
 	// Address is passed to Listen/ListenPacket when creating the Relay
 	Address string
 
+	// RelayAddressIPv6 and AddressIPv6 are used for IPv6 relays, which
+	// are not supported if AddressIPv6 is empty
+	RelayAddressIPv6 net.IP
+	AddressIPv6      string
+
 	Net transport.Net
 }
 
@@ -60,6 +67,8 @@ func (r *RelayAddressGeneratorPortRange) Validate() error {
 		return errMaxPortNotZero
 	case r.RelayAddress == nil:
 		return errRelayAddressInvalid
+	case r.AddressIPv6 != "" && r.RelayAddressIPv6 == nil:
+		return errRelayAddressInvalid
 	case r.Address == "":
 		return errListeningAddressInvalid
 	default:
@@ -73,8 +82,13 @@ func (r *RelayAddressGeneratorPortRange) AllocatePacketConn(
 	network string,
 	requestedPort int,
 ) (net.PacketConn, net.Addr, error) {
+	address, relayIP, err := relayAddressForNetwork(network, r.Address, r.RelayAddress, r.AddressIPv6, r.RelayAddressIPv6)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if requestedPort != 0 {
-		conn, err := r.Net.ListenPacket(network, fmt.Sprintf("%s:%d", r.Address, requestedPort))
+		conn, err := r.Net.ListenPacket(network, net.JoinHostPort(address, strconv.Itoa(requestedPort)))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -84,14 +98,14 @@ func (r *RelayAddressGeneratorPortRange) AllocatePacketConn(
 			return nil, nil, errNilConn
 		}
 
-		relayAddr.IP = r.RelayAddress
+		relayAddr.IP = relayIP
 
 		return conn, relayAddr, nil
 	}
 
 	for try := 0; try < r.MaxRetries; try++ {
 		port := r.MinPort + uint16(r.Rand.Intn(int((r.MaxPort+1)-r.MinPort))) // nolint:gosec // G115 false positive
-		conn, err := r.Net.ListenPacket(network, fmt.Sprintf("%s:%d", r.Address, port))
+		conn, err := r.Net.ListenPacket(network, net.JoinHostPort(address, strconv.Itoa(int(port))))
 		if err != nil {
 			continue
 		}
@@ -101,7 +115,7 @@ func (r *RelayAddressGeneratorPortRange) AllocatePacketConn(
 			return nil, nil, errNilConn
 		}
 
-		relayAddr.IP = r.RelayAddress
+		relayAddr.IP = relayIP
 
 		return conn, relayAddr, nil
 	}
@@ -114,3 +128,45 @@ func (r *RelayAddressGeneratorPortRange) AllocatePacketConn(
 func (r *RelayAddressGeneratorPortRange) AllocateConn(string, int) (net.Conn, net.Addr, error) {
 	return nil, nil, errTODO
 }
+
+// AllocateListener generates a new Listener to accept TCP connections from peers on
+// and the IP/Port to populate the allocation response with.
+func (r *RelayAddressGeneratorPortRange) AllocateListener(
+	network string,
+	requestedPort int,
+) (net.Listener, net.Addr, error) {
+	relayListener := func(port int) (net.Listener, net.Addr, error) {
+		listener, err := listenTCP(r.Net, network, r.Address, port)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tcpAddr, ok := listener.Addr().(*net.TCPAddr)
+		if !ok {
+			_ = listener.Close()
+
+			return nil, nil, errNilConn
+		}
+
+		return listener, &net.TCPAddr{IP: r.RelayAddress, Port: tcpAddr.Port}, nil
+	}
+
+	if requestedPort != 0 {
+		return relayListener(requestedPort)
+	}
+
+	for try := 0; try < r.MaxRetries; try++ {
+		port := r.MinPort + uint16(r.Rand.Intn(int((r.MaxPort+1)-r.MinPort))) // nolint:gosec // G115 false positive
+		listener, relayAddr, err := relayListener(int(port))
+		if err == nil || errors.Is(err, errNilConn) {
+			return listener, relayAddr, err
+		}
+	}
+
+	return nil, nil, errMaxRetriesExceeded
+}
+
+// DialTCP connects to a peer from the listening address and the port of relayAddr.
+func (r *RelayAddressGeneratorPortRange) DialTCP(network string, relayAddr, rAddr *net.TCPAddr) (net.Conn, error) {
+	return dialTCP(r.Net, network, r.Address, relayAddr.Port, rAddr)
+}
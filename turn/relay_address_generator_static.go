@@ -4,9 +4,11 @@
 package turn
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"strconv"
+	"sync"
 
 	"github.com/pion/transport/v3"
 	"github.com/pion/transport/v3/stdnet"
@@ -21,6 +23,11 @@ type RelayAddressGeneratorStatic struct {
 	// Address is passed to Listen/ListenPacket when creating the Relay
 	Address string
 
+	// RelayAddressIPv6 and AddressIPv6 are used for IPv6 relays, which
+	// are not supported if AddressIPv6 is empty
+	RelayAddressIPv6 net.IP
+	AddressIPv6      string
+
 	Net transport.Net
 }
 
@@ -37,6 +44,8 @@ func (r *RelayAddressGeneratorStatic) Validate() error {
 	switch {
 	case r.RelayAddress == nil:
 		return errRelayAddressInvalid
+	case r.AddressIPv6 != "" && r.RelayAddressIPv6 == nil:
+		return errRelayAddressInvalid
 	case r.Address == "":
 		return errListeningAddressInvalid
 	default:
@@ -50,7 +59,12 @@ func (r *RelayAddressGeneratorStatic) AllocatePacketConn(
 	network string,
 	requestedPort int,
 ) (net.PacketConn, net.Addr, error) {
-	conn, err := r.Net.ListenPacket(network, r.Address+":"+strconv.Itoa(requestedPort))
+	address, relayIP, err := relayAddressForNetwork(network, r.Address, r.RelayAddress, r.AddressIPv6, r.RelayAddressIPv6)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := r.Net.ListenPacket(network, net.JoinHostPort(address, strconv.Itoa(requestedPort)))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -61,7 +75,7 @@ func (r *RelayAddressGeneratorStatic) AllocatePacketConn(
 		return nil, nil, errNilConn
 	}
 
-	relayAddr.IP = r.RelayAddress
+	relayAddr.IP = relayIP
 
 	return conn, relayAddr, nil
 }
@@ -71,3 +85,126 @@ func (r *RelayAddressGeneratorStatic) AllocatePacketConn(
 func (r *RelayAddressGeneratorStatic) AllocateConn(string, int) (net.Conn, net.Addr, error) {
 	return nil, nil, errTODO
 }
+
+// AllocateListener generates a new Listener to accept TCP connections from peers on
+// and the IP/Port to populate the allocation response with.
+func (r *RelayAddressGeneratorStatic) AllocateListener(
+	network string,
+	requestedPort int,
+) (net.Listener, net.Addr, error) {
+	listener, err := listenTCP(r.Net, network, r.Address, requestedPort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Replace actual listening IP with the user requested one of RelayAddressGeneratorStatic
+	tcpAddr, ok := listener.Addr().(*net.TCPAddr)
+	if !ok {
+		_ = listener.Close()
+
+		return nil, nil, errNilConn
+	}
+
+	return listener, &net.TCPAddr{IP: r.RelayAddress, Port: tcpAddr.Port}, nil
+}
+
+// DialTCP connects to a peer from the listening address and the port of relayAddr.
+func (r *RelayAddressGeneratorStatic) DialTCP(network string, relayAddr, rAddr *net.TCPAddr) (net.Conn, error) {
+	return dialTCP(r.Net, network, r.Address, relayAddr.Port, rAddr)
+}
+
+// maxRelayListenTries bounds the ephemeral ports tried for a relay listener.
+const maxRelayListenTries = 10
+
+// relayListenerAddrs are the addresses of the relay listeners of the process.
+// SO_REUSEPORT would let two relay listeners share an address, so an address
+// is only listened on again once its listener is closed.
+var (
+	relayListenerLock  sync.Mutex              //nolint:gochecknoglobals
+	relayListenerAddrs = map[string]struct{}{} //nolint:gochecknoglobals
+)
+
+// relayListener releases the address of a relay listener when it is closed.
+type relayListener struct {
+	net.Listener
+
+	closeOnce sync.Once
+}
+
+func (l *relayListener) Close() error {
+	l.closeOnce.Do(func() {
+		relayListenerLock.Lock()
+		delete(relayListenerAddrs, l.Addr().String())
+		relayListenerLock.Unlock()
+	})
+
+	return l.Listener.Close()
+}
+
+// listenTCP listens for the connections of peers, the connections to peers are
+// dialed from the same port, see dialTCP.
+func listenTCP(n transport.Net, network, address string, port int) (net.Listener, error) {
+	lAddr, err := n.ResolveTCPAddr(network, net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := n.(*stdnet.Net); !ok {
+		return n.ListenTCP(network, lAddr)
+	}
+
+	relayListenerLock.Lock()
+	defer relayListenerLock.Unlock()
+
+	if _, ok := relayListenerAddrs[lAddr.String()]; ok && port != 0 {
+		return nil, fmt.Errorf("%w: %s", errRelayAddressInUse, lAddr)
+	}
+
+	// The socket is bound once with SO_REUSEPORT set. An ephemeral port that
+	// another relay listener has is skipped, and kept until a free one is found
+	// so that it is not handed out again.
+	listenConfig := net.ListenConfig{Control: reusePortControl}
+	for try := 0; try < maxRelayListenTries; try++ {
+		listener, err := listenConfig.Listen(context.Background(), network, lAddr.String())
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := relayListenerAddrs[listener.Addr().String()]; ok {
+			defer listener.Close() //nolint:errcheck,gosec
+
+			continue
+		}
+		relayListenerAddrs[listener.Addr().String()] = struct{}{}
+
+		return &relayListener{Listener: listener}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", errRelayAddressInUse, lAddr)
+}
+
+// dialTCP connects to a peer from the port of the relay listener, so the peer
+// sees the relayed transport address (RFC 6062 Section 5.2).
+func dialTCP(n transport.Net, network, address string, port int, rAddr *net.TCPAddr) (net.Conn, error) {
+	lAddr, err := n.ResolveTCPAddr(network, net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+
+	return n.CreateDialer(&net.Dialer{LocalAddr: lAddr, Control: reusePortControl}).Dial(network, rAddr.String())
+}
+
+// relayAddressForNetwork returns the listening address and the relayed IP of the
+// address family of network, IPv6 relays are served if addressIPv6 is set.
+func relayAddressForNetwork(
+	network, address string, relayIP net.IP,
+	addressIPv6 string, relayIPv6 net.IP,
+) (string, net.IP, error) {
+	if network != "udp6" {
+		return address, relayIP, nil
+	}
+	if addressIPv6 == "" {
+		return "", nil, ErrAddressFamilyNotSupported
+	}
+
+	return addressIPv6, relayIPv6, nil
+}
@@ -0,0 +1,15 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !unix && !windows
+
+package turn
+
+import (
+	"syscall"
+)
+
+// reusePortControl is a no-op, the platform does not share addresses.
+func reusePortControl(string, string, syscall.RawConn) error {
+	return nil
+}
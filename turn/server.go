@@ -8,25 +8,38 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/logging"
+	"github.com/pion/stun/v3"
+	"github.com/pion/transport/v3/udp"
 	"github.com/pion/turn/v4/internal/allocation"
 	"github.com/pion/turn/v4/internal/proto"
 	"github.com/pion/turn/v4/internal/server"
+	"golang.org/x/net/ipv4"
 )
 
 const (
-	defaultInboundMTU = 1600
+	defaultInboundMTU         = 1600
+	defaultBatchWriteInterval = time.Millisecond
 )
 
 // Server is an instance of the Pion TURN Server.
 type Server struct {
 	log                logging.LeveledLogger
 	authHandler        AuthHandler
+	accessTokenHandler AccessTokenHandler
+	authServer         string
+	redirectHandler    RedirectHandler
 	realm              string
 	channelBindTimeout time.Duration
 	nonceHash          *server.NonceHash
+	quotaHandler       QuotaHandler
+	quotas             *allocation.Quotas
+	eventHandlers      EventHandlers
+	mobilityHandler    MobilityHandler
+	peerACL            atomic.Pointer[PeerACL]
 
 	packetConnConfigs  []PacketConnConfig
 	listenerConfigs    []ListenerConfig
@@ -58,35 +71,65 @@ func NewServer(config ServerConfig) (*Server, error) { //nolint:gocognit,cyclop
 	server := &Server{
 		log:                loggerFactory.NewLogger("turn"),
 		authHandler:        config.AuthHandler,
+		accessTokenHandler: config.AccessTokenHandler,
+		authServer:         config.AuthorizationServer,
+		redirectHandler:    config.RedirectHandler,
 		realm:              config.Realm,
 		channelBindTimeout: config.ChannelBindTimeout,
-		packetConnConfigs:  config.PacketConnConfigs,
+		packetConnConfigs:  append([]PacketConnConfig{}, config.PacketConnConfigs...),
 		listenerConfigs:    config.ListenerConfigs,
 		nonceHash:          nonceHash,
 		inboundMTU:         mtu,
+		quotaHandler:       config.QuotaHandler,
+		quotas:             allocation.NewQuotas(),
+		eventHandlers:      config.EventHandlers,
+		mobilityHandler:    config.MobilityHandler,
+	}
+
+	peerACL := config.PeerACL
+	server.peerACL.Store(&peerACL)
+
+	if server.quotaHandler == nil {
+		quota := config.Quota
+		server.quotaHandler = func(string, string, net.Addr) Quota {
+			return quota
+		}
 	}
 
 	if server.channelBindTimeout == 0 {
 		server.channelBindTimeout = proto.DefaultLifetime
 	}
 
-	for _, cfg := range server.packetConnConfigs {
-		am, err := server.createAllocationManager(cfg.RelayAddressGenerator, cfg.PermissionHandler)
+	for i := range server.packetConnConfigs {
+		cfg := &server.packetConnConfigs[i]
+		am, err := server.createAllocationManager(cfg.RelayAddressGenerator, cfg.PermissionHandler, cfg.BatchSize)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create AllocationManager: %w", err)
 		}
 
+		if udpConn, ok := cfg.PacketConn.(*net.UDPConn); ok && cfg.BatchSize > 1 {
+			interval := cfg.BatchWriteInterval
+			if interval == 0 {
+				interval = defaultBatchWriteInterval
+			}
+			cfg.PacketConn = udp.NewBatchConn(udpConn, cfg.BatchSize, interval)
+		}
+
 		go func(cfg PacketConnConfig, am *allocation.Manager) {
-			server.readLoop(cfg.PacketConn, am)
+			if batchReader, ok := cfg.PacketConn.(udp.BatchReader); ok && cfg.BatchSize > 1 {
+				server.readBatches(cfg.PacketConn, batchReader, cfg.BatchSize, am)
+			} else {
+				server.readLoop(cfg.PacketConn, am, nil)
+			}
 
 			if err := am.Close(); err != nil {
 				server.log.Errorf("Failed to close AllocationManager: %s", err)
 			}
-		}(cfg, am)
+		}(*cfg, am)
 	}
 
 	for _, cfg := range server.listenerConfigs {
-		am, err := server.createAllocationManager(cfg.RelayAddressGenerator, cfg.PermissionHandler)
+		am, err := server.createAllocationManager(cfg.RelayAddressGenerator, cfg.PermissionHandler, 0)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create AllocationManager: %w", err)
 		}
@@ -153,7 +196,10 @@ func (s *Server) readListener(l net.Listener, am *allocation.Manager) {
 		}
 
 		go func() {
-			s.readLoop(NewSTUNConn(conn), am)
+			// A connection bound to a peer is relayed by its allocation
+			if bound := s.readLoop(NewSTUNConn(conn), am, conn); bound {
+				return
+			}
 
 			// Delete allocation
 			am.DeleteAllocation(&allocation.FiveTuple{
@@ -169,6 +215,33 @@ func (s *Server) readListener(l net.Listener, am *allocation.Manager) {
 	}
 }
 
+func (s *Server) quota(username, realm string, srcAddr net.Addr) allocation.Quota {
+	return allocation.Quota(s.quotaHandler(username, realm, srcAddr))
+}
+
+func (s *Server) redirect(username, realm string, srcAddr net.Addr) (*stun.AlternateServer, string) {
+	if s.redirectHandler == nil {
+		return nil, ""
+	}
+
+	alternate, ok := s.redirectHandler(username, realm, srcAddr)
+	if !ok {
+		return nil, ""
+	}
+
+	return &stun.AlternateServer{IP: alternate.IP, Port: alternate.Port}, alternate.Domain
+}
+
+// SetPeerACL replaces the PeerACL of the server. It applies to the requests and the packets
+// of existing allocations too, packets to peers it denies are dropped.
+func (s *Server) SetPeerACL(acl PeerACL) {
+	s.peerACL.Store(&acl)
+}
+
+func (s *Server) peerAllowed(username, realm string, peerIP net.IP) bool {
+	return s.peerACL.Load().Allowed(username, realm, peerIP)
+}
+
 type nilAddressGenerator struct{}
 
 func (n *nilAddressGenerator) Validate() error { return errRelayAddressGeneratorNil }
@@ -184,6 +257,7 @@ func (n *nilAddressGenerator) AllocateConn(string, int) (net.Conn, net.Addr, err
 func (s *Server) createAllocationManager(
 	addrGenerator RelayAddressGenerator,
 	handler PermissionHandler,
+	batchSize int,
 ) (*allocation.Manager, error) {
 	if handler == nil {
 		handler = DefaultPermissionHandler
@@ -192,12 +266,20 @@ func (s *Server) createAllocationManager(
 		addrGenerator = &nilAddressGenerator{}
 	}
 
-	am, err := allocation.NewManager(allocation.ManagerConfig{
+	config := allocation.ManagerConfig{
 		AllocatePacketConn: addrGenerator.AllocatePacketConn,
 		AllocateConn:       addrGenerator.AllocateConn,
 		PermissionHandler:  handler,
+		EventHandler:       &eventHandler{handlers: s.eventHandlers},
 		LeveledLogger:      s.log,
-	})
+		BatchSize:          batchSize,
+	}
+	if tcpAddrGenerator, ok := addrGenerator.(RelayAddressGeneratorTCP); ok {
+		config.AllocateListener = tcpAddrGenerator.AllocateListener
+		config.DialTCP = tcpAddrGenerator.DialTCP
+	}
+
+	am, err := allocation.NewManager(config)
 	if err != nil {
 		return am, err
 	}
@@ -207,33 +289,113 @@ func (s *Server) createAllocationManager(
 	return am, err
 }
 
-func (s *Server) readLoop(conn net.PacketConn, allocationManager *allocation.Manager) {
+// readLoop serves the requests received on conn. For TCP and TLS connections
+// streamConn is the underlying connection, readLoop returns true once it has
+// been bound to a peer of a TCP allocation.
+func (s *Server) readLoop(
+	conn net.PacketConn,
+	allocationManager *allocation.Manager,
+	streamConn net.Conn,
+) (bound bool) {
+	var onStreamConnBound func()
+	if streamConn != nil {
+		onStreamConnBound = func() {
+			bound = true
+		}
+	}
+
+	req := s.newRequest(conn, allocationManager, streamConn, onStreamConnBound)
 	buf := make([]byte, s.inboundMTU)
-	for {
+	for !bound {
 		n, addr, err := conn.ReadFrom(buf)
 		switch {
 		case err != nil:
 			s.log.Debugf("Exit read loop on error: %s", err)
 
-			return
+			return false
 		case n >= s.inboundMTU:
 			s.log.Debugf("Read bytes exceeded MTU, packet is possibly truncated")
 
 			continue
 		}
 
-		if err := server.HandleRequest(server.Request{
-			Conn:               conn,
-			SrcAddr:            addr,
-			Buff:               buf[:n],
-			Log:                s.log,
-			AuthHandler:        s.authHandler,
-			Realm:              s.realm,
-			AllocationManager:  allocationManager,
-			ChannelBindTimeout: s.channelBindTimeout,
-			NonceHash:          s.nonceHash,
-		}); err != nil {
+		req.SrcAddr = addr
+		req.Buff = buf[:n]
+		if err := server.HandleRequest(req); err != nil {
 			s.log.Errorf("Failed to handle datagram: %v", err)
 		}
 	}
+
+	return true
+}
+
+// readBatches serves the requests received on conn, reading up to batchSize
+// packets at once.
+func (s *Server) readBatches(
+	conn net.PacketConn,
+	batchReader udp.BatchReader,
+	batchSize int,
+	allocationManager *allocation.Manager,
+) {
+	msgs := make([]ipv4.Message, batchSize)
+	for i := range msgs {
+		msgs[i].Buffers = [][]byte{make([]byte, s.inboundMTU)}
+	}
+
+	req := s.newRequest(conn, allocationManager, nil, nil)
+	req.RelayBatch = allocation.NewRelayWriteBatch(batchSize)
+	for {
+		n, err := batchReader.ReadBatch(msgs, 0)
+		if err != nil {
+			s.log.Debugf("Exit read loop on error: %s", err)
+
+			return
+		}
+
+		for i := 0; i < n; i++ {
+			if msgs[i].N >= s.inboundMTU {
+				s.log.Debugf("Read bytes exceeded MTU, packet is possibly truncated")
+
+				continue
+			}
+
+			req.SrcAddr = msgs[i].Addr
+			req.Buff = msgs[i].Buffers[0][:msgs[i].N]
+			if err := server.HandleRequest(req); err != nil {
+				s.log.Errorf("Failed to handle datagram: %v", err)
+			}
+		}
+
+		if err := req.RelayBatch.Flush(); err != nil {
+			s.log.Errorf("Failed to relay datagrams: %v", err)
+		}
+	}
+}
+
+// newRequest returns the state of the requests received on conn, the source
+// address and the contents of each packet are set by the read loop.
+func (s *Server) newRequest(
+	conn net.PacketConn,
+	allocationManager *allocation.Manager,
+	streamConn net.Conn,
+	onStreamConnBound func(),
+) server.Request {
+	return server.Request{
+		Conn:               conn,
+		Log:                s.log,
+		AuthHandler:        s.authHandler,
+		AccessTokenHandler: s.accessTokenHandler,
+		AuthServer:         s.authServer,
+		Realm:              s.realm,
+		AllocationManager:  allocationManager,
+		ChannelBindTimeout: s.channelBindTimeout,
+		NonceHash:          s.nonceHash,
+		StreamConn:         streamConn,
+		OnStreamConnBound:  onStreamConnBound,
+		Quotas:             s.quotas,
+		QuotaHandler:       s.quota,
+		RedirectHandler:    s.redirect,
+		MobilityHandler:    s.mobilityHandler,
+		PeerACLHandler:     s.peerAllowed,
+	}
 }
@@ -19,13 +19,28 @@ type RelayAddressGenerator interface {
 	// Validate confirms that the RelayAddressGenerator is properly initialized
 	Validate() error
 
-	// Allocate a PacketConn (UDP) RelayAddress
+	// Allocate a PacketConn (UDP) RelayAddress. network is "udp4", or "udp6" for IPv6 and
+	// dual-stack allocations, which fail with ErrAddressFamilyNotSupported if it cannot be served.
 	AllocatePacketConn(network string, requestedPort int) (net.PacketConn, net.Addr, error)
 
 	// Allocate a Conn (TCP) RelayAddress
 	AllocateConn(network string, requestedPort int) (net.Conn, net.Addr, error)
 }
 
+// RelayAddressGeneratorTCP is implemented by a RelayAddressGenerator that supports
+// TCP allocations, see https://tools.ietf.org/html/rfc6062. Allocate requests for
+// TCP are rejected with 442 (Unsupported Transport Protocol) otherwise.
+type RelayAddressGeneratorTCP interface {
+	RelayAddressGenerator
+
+	// Allocate a Listener (TCP) RelayAddress that peers connect to
+	AllocateListener(network string, requestedPort int) (net.Listener, net.Addr, error)
+
+	// Dial a peer on behalf of a client that sent a Connect request, the connection
+	// comes from relayAddr, the relayed transport address of the allocation
+	DialTCP(network string, relayAddr, rAddr *net.TCPAddr) (net.Conn, error)
+}
+
 // PermissionHandler is a callback to filter incoming CreatePermission and ChannelBindRequest
 // requests based on the client IP address and port and the peer IP address the client intends to
 // connect to. If the client is behind a NAT then the filter acts on the server reflexive
@@ -52,6 +67,16 @@ type PacketConnConfig struct {
 	// case the DefaultPermissionHandler is automatically instantiated to admit all peer
 	// connections
 	PermissionHandler PermissionHandler
+
+	// BatchSize is the maximum number of packets read and written at once. Above 1, if
+	// PacketConn is a *net.UDPConn it is wrapped in a BatchConn of github.com/pion/transport/v3/udp,
+	// so that packets are read from clients with recvmmsg and written to them with sendmmsg on
+	// Linux, and the relay sockets of the allocations are read in batches too.
+	BatchSize int
+
+	// BatchWriteInterval is how long packets to clients are queued at most before a batch that
+	// is not full is written, 1ms by default.
+	BatchWriteInterval time.Duration
 }
 
 func (c *PacketConnConfig) validate() error {
@@ -108,6 +133,58 @@ func GenerateAuthKey(username, realm, password string) []byte {
 	return h.Sum(nil)
 }
 
+// AccessTokenHandler is a callback to look up the key that decrypts the self-contained ACCESS-TOKENs
+// of third-party authorization with key ID kid, see RFC 7635. Keys of 16 and 32 bytes select
+// AEAD_AES_128_GCM and AEAD_AES_256_GCM. The realm of the server is the associated data of the tokens,
+// which can be issued with stun.Token.Encrypt.
+type AccessTokenHandler func(kid string, srcAddr net.Addr) (key []byte, ok bool)
+
+// AlternateServer is a TURN server that clients are redirected to with a 300 (Try Alternate) error.
+type AlternateServer struct {
+	IP   net.IP
+	Port int
+
+	// Domain is the domain name of the server that TLS clients verify its certificate against,
+	// it is sent in ALTERNATE-DOMAIN if set
+	Domain string
+}
+
+// RedirectHandler is a callback to redirect an authenticated Allocate request to another server,
+// see RFC 5389 Section 11. It returns ok false to serve the request.
+type RedirectHandler func(username, realm string, srcAddr net.Addr) (alternate AlternateServer, ok bool)
+
+// Quota limits the allocations of a user and the traffic they relay. Zero values are unlimited.
+type Quota struct {
+	// MaxAllocationsPerUser and MaxAllocationsPerIP limit the allocations of a username and
+	// of a client IP address, further Allocate requests fail with 486 (Allocation Quota Reached)
+	MaxAllocationsPerUser int
+	MaxAllocationsPerIP   int
+
+	// MaxPermissions and MaxChannels limit the permissions and channels of an allocation,
+	// further CreatePermission and ChannelBind requests fail with 508 (Insufficient Capacity)
+	MaxPermissions int
+	MaxChannels    int
+
+	// AllocationBandwidth and UserBandwidth cap the traffic relayed by an allocation and by
+	// all allocations of a user in bytes per second, with bursts of up to one second.
+	// Datagrams over the cap are dropped, TCP connections are slowed down.
+	AllocationBandwidth int
+	UserBandwidth       int
+}
+
+// QuotaHandler is a callback to decide the Quota of a user at runtime. It is called for every
+// Allocate and Refresh request, changed limits apply to existing allocations when refreshed.
+type QuotaHandler func(username, realm string, srcAddr net.Addr) Quota
+
+// MobilityHandler is a callback to decide whether a user may move UDP allocations to a new client
+// address with a MOBILITY-TICKET, see RFC 8016. Allocate requests it refuses fail with 405 (Mobility Forbidden).
+type MobilityHandler func(username, realm string, srcAddr net.Addr) bool
+
+// AllowMobility is a convenience MobilityHandler that allows mobility for all users.
+func AllowMobility(string, string, net.Addr) bool {
+	return true
+}
+
 // ServerConfig configures the Pion TURN Server.
 type ServerConfig struct {
 	// PacketConnConfigs and ListenerConfigs are a list of all the turn listeners
@@ -125,11 +202,40 @@ type ServerConfig struct {
 	// allowing users to customize Pion TURN with custom behavior
 	AuthHandler AuthHandler
 
+	// AccessTokenHandler enables requests authorized with an ACCESS-TOKEN instead of AuthHandler.
+	// The mac_key of the token authenticates the requests of the allocation that omit it.
+	AccessTokenHandler AccessTokenHandler
+
+	// AuthorizationServer is the server name of the authorization server that issues the
+	// ACCESS-TOKENs, it is sent in the THIRD-PARTY-AUTHORIZATION of the 401 responses when
+	// AccessTokenHandler is set.
+	AuthorizationServer string
+
 	// ChannelBindTimeout sets the lifetime of channel binding. Defaults to 10 minutes.
 	ChannelBindTimeout time.Duration
 
 	// Sets the server inbound MTU(Maximum transmition unit). Defaults to 1600 bytes.
 	InboundMTU int
+
+	// Quota limits the allocations and bandwidth of every user. Unlimited by default.
+	Quota Quota
+
+	// QuotaHandler overrides Quota with a limit per user, which can change at runtime
+	QuotaHandler QuotaHandler
+
+	// RedirectHandler decides which Allocate requests are served by another server, for example by
+	// load, region or a hash of the username, see RedirectByUsernameHash and RedirectOverAllocations
+	RedirectHandler RedirectHandler
+
+	// EventHandlers are notified of the lifecycle of allocations, permissions and channels
+	EventHandlers EventHandlers
+
+	// MobilityHandler enables TURN mobility for UDP allocations, it is disabled when nil
+	MobilityHandler MobilityHandler
+
+	// PeerACL decides the peers that users may relay to, in addition to the PermissionHandler of
+	// the listener. By default it denies the peers in DefaultDeniedPeers.
+	PeerACL PeerACL
 }
 
 func (s *ServerConfig) validate() error {
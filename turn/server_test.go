@@ -7,13 +7,20 @@
 package turn
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
 
+	"github.com/pion/dtls/v3"
+	"github.com/pion/dtls/v3/pkg/crypto/selfsign"
 	"github.com/pion/logging"
+	"github.com/pion/stun/v3"
+	"github.com/pion/transport/v3/stdnet"
 	"github.com/pion/transport/v3/test"
 	"github.com/pion/transport/v3/vnet"
 	"github.com/pion/turn/v4/internal/allocation"
@@ -46,6 +53,7 @@ func TestServer(t *testing.T) { //nolint:maintidx
 
 				return nil, false
 			},
+			PeerACL: PeerACL{AllowInternalPeers: true},
 			PacketConnConfigs: []PacketConnConfig{
 				{
 					PacketConn: udpListener,
@@ -136,6 +144,7 @@ func TestServer(t *testing.T) { //nolint:maintidx
 
 				return nil, false
 			},
+			PeerACL: PeerACL{AllowInternalPeers: true},
 			ListenerConfigs: []ListenerConfig{
 				{
 					Listener: tcpListener,
@@ -214,6 +223,7 @@ func TestServer(t *testing.T) { //nolint:maintidx
 		udpListener, err := net.ListenPacket("udp4", "0.0.0.0:3478")
 		assert.NoError(t, err)
 
+		var permissionsCreated atomic.Int32
 		server, err := NewServer(ServerConfig{
 			AuthHandler: func(username, _ string, _ net.Addr) (key []byte, ok bool) {
 				if pw, ok := credMap[username]; ok {
@@ -222,6 +232,12 @@ func TestServer(t *testing.T) { //nolint:maintidx
 
 				return nil, false
 			},
+			PeerACL: PeerACL{AllowInternalPeers: true},
+			EventHandlers: EventHandlers{
+				OnPermissionCreated: func(AllocationInfo, net.IP) {
+					permissionsCreated.Add(1)
+				},
+			},
 			PacketConnConfigs: []PacketConnConfig{
 				{
 					PacketConn: udpListener,
@@ -266,9 +282,15 @@ func TestServer(t *testing.T) { //nolint:maintidx
 		blackAddr, errB1 := net.ResolveUDPAddr("udp", "127.0.0.5:12345")
 		assert.NoError(t, errB1, "should succeed")
 
+		// A request with a denied peer installs none of its permissions
+		err = client.CreatePermission(whiteAddr, blackAddr)
+		assert.ErrorContains(t, err, "error", "deny permission for mixed whitelisted and blacklisted peers")
+		assert.Zero(t, permissionsCreated.Load())
+
 		// Explicit CreatePermission
 		err = client.CreatePermission(whiteAddr)
 		assert.NoError(t, err, "grant permission for whitelisted peer")
+		assert.Equal(t, int32(1), permissionsCreated.Load())
 
 		err = client.CreatePermission(blackAddr)
 		assert.ErrorContains(t, err, "error", "deny permission for blacklisted peer address")
@@ -667,6 +689,7 @@ func RunBenchmarkServer(b *testing.B, clientNum int) { //nolint:cyclop
 
 			return nil, false
 		},
+		PeerACL: PeerACL{AllowInternalPeers: true},
 		PacketConnConfigs: []PacketConnConfig{{
 			PacketConn: serverConn,
 			RelayAddressGenerator: &RelayAddressGeneratorStatic{
@@ -761,3 +784,1420 @@ func BenchmarkServer(b *testing.B) {
 		})
 	}
 }
+
+// BenchmarkServerBatch compares the throughput of relaying on loopback with and without batched
+// I/O. Packets the sockets drop are not retransmitted, delivered is the share that was relayed.
+func BenchmarkServerBatch(b *testing.B) {
+	for _, batchSize := range []int{1, 64} {
+		for _, toPeer := range []bool{true, false} {
+			direction := "peer_to_client"
+			if toPeer {
+				direction = "client_to_peer"
+			}
+			b.Run(fmt.Sprintf("batch_size_%d/%s", batchSize, direction), func(b *testing.B) {
+				benchmarkServerBatch(b, batchSize, toPeer)
+			})
+		}
+	}
+}
+
+func benchmarkServerBatch(b *testing.B, batchSize int, toPeer bool) {
+	b.Helper()
+
+	serverConn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(b, err)
+
+	server, err := NewServer(ServerConfig{
+		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+			return GenerateAuthKey(username, realm, "pass"), true
+		},
+		PeerACL: PeerACL{AllowInternalPeers: true},
+		PacketConnConfigs: []PacketConnConfig{{
+			PacketConn: serverConn,
+			RelayAddressGenerator: &RelayAddressGeneratorStatic{
+				RelayAddress: net.ParseIP("127.0.0.1"),
+				Address:      "127.0.0.1",
+			},
+			BatchSize: batchSize,
+		}},
+		Realm:         "pion.ly",
+		LoggerFactory: logging.NewDefaultLoggerFactory(),
+	})
+	assert.NoError(b, err)
+	defer server.Close() //nolint:errcheck
+
+	clientConn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(b, err)
+	defer clientConn.Close() //nolint:errcheck
+
+	client, err := NewClient(&ClientConfig{
+		TURNServerAddr: serverConn.LocalAddr().String(),
+		Conn:           clientConn,
+		Username:       "user",
+		Password:       "pass",
+	})
+	assert.NoError(b, err)
+	defer client.Close()
+	assert.NoError(b, client.Listen())
+
+	relayConn, err := client.Allocate()
+	assert.NoError(b, err)
+	defer relayConn.Close() //nolint:errcheck
+
+	peer, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(b, err)
+	defer peer.Close() //nolint:errcheck
+
+	// Bind a channel to the peer, so that data is relayed in ChannelData messages
+	buf := make([]byte, 1600)
+	for i := 0; i < 2; i++ {
+		_, err = relayConn.WriteTo([]byte("bind"), peer.LocalAddr())
+		assert.NoError(b, err)
+		_, _, err = peer.ReadFrom(buf)
+		assert.NoError(b, err)
+	}
+
+	sender, senderAddr, receiver := relayConn, peer.LocalAddr(), peer
+	if !toPeer {
+		sender, senderAddr, receiver = peer, relayConn.LocalAddr(), relayConn
+	}
+
+	// At most a window of packets is in flight, so that the sockets do not drop
+	// the packets the server is too slow to read. A packet that is not relayed
+	// within lossTimeout is lost and leaves the window.
+	const (
+		window      = 64
+		lossTimeout = 10 * time.Millisecond
+	)
+	inFlight := make(chan struct{}, window)
+	var received atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for {
+			_ = receiver.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			if _, _, err := receiver.ReadFrom(buf); err != nil {
+				return
+			}
+			received.Add(1)
+			select {
+			case <-inFlight:
+			default:
+			}
+		}
+	}()
+
+	data := make([]byte, 1000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			select {
+			case inFlight <- struct{}{}:
+			case <-time.After(lossTimeout):
+			}
+		}
+
+		if _, err := sender.WriteTo(data, senderAddr); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	<-done
+
+	b.ReportMetric(100*float64(received.Load())/float64(b.N), "%delivered")
+}
+
+func TestListenTCPRelay(t *testing.T) {
+	stdNet, err := stdnet.NewNet()
+	assert.NoError(t, err)
+
+	listener, err := listenTCP(stdNet, "tcp4", "127.0.0.1", 0)
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert
+
+	// The address of a relay listener is not shared with another one
+	_, err = listenTCP(stdNet, "tcp4", "127.0.0.1", port)
+	assert.ErrorIs(t, err, errRelayAddressInUse)
+
+	// Peers are dialed from the port of the relay listener
+	peerListener, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	conn, err := dialTCP(stdNet, "tcp4", "127.0.0.1", port, peerListener.Addr().(*net.TCPAddr)) //nolint:forcetypeassert
+	assert.NoError(t, err)
+	assert.Equal(t, listener.Addr().String(), conn.LocalAddr().String())
+	assert.NoError(t, conn.Close())
+	assert.NoError(t, peerListener.Close())
+
+	assert.NoError(t, listener.Close())
+	listener, err = listenTCP(stdNet, "tcp4", "127.0.0.1", port)
+	assert.NoError(t, err)
+	assert.NoError(t, listener.Close())
+}
+
+func TestServerTCPAllocation(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	tcpListener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server, err := NewServer(ServerConfig{
+		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+			return GenerateAuthKey(username, realm, "pass"), true
+		},
+		PeerACL: PeerACL{AllowInternalPeers: true},
+		ListenerConfigs: []ListenerConfig{
+			{
+				Listener: tcpListener,
+				RelayAddressGenerator: &RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP("127.0.0.1"),
+					Address:      "127.0.0.1",
+				},
+			},
+		},
+		Realm:         "pion.ly",
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, server.Close())
+	}()
+
+	serverAddr := tcpListener.Addr().String()
+	conn, err := net.Dial("tcp4", serverAddr)
+	assert.NoError(t, err)
+
+	client, err := NewClient(&ClientConfig{
+		STUNServerAddr: serverAddr,
+		TURNServerAddr: serverAddr,
+		Conn:           NewSTUNConn(conn),
+		Username:       "user",
+		Password:       "pass",
+		LoggerFactory:  loggerFactory,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, client.Listen())
+	defer func() {
+		client.Close()
+		assert.NoError(t, conn.Close())
+	}()
+
+	relayConn, err := client.AllocateTCP()
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, relayConn.Close())
+	}()
+
+	// exchange writes a message on each side and reads it on the other.
+	exchange := func(t *testing.T, a, b net.Conn) {
+		t.Helper()
+
+		buf := make([]byte, 16)
+		for _, c := range [][2]net.Conn{{a, b}, {b, a}} {
+			_, err := c[0].Write([]byte("hello"))
+			assert.NoError(t, err)
+
+			n, err := c[1].Read(buf)
+			assert.NoError(t, err)
+			assert.Equal(t, "hello", string(buf[:n]))
+		}
+	}
+
+	t.Run("Connect", func(t *testing.T) {
+		peerListener, err := net.Listen("tcp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer func() {
+			assert.NoError(t, peerListener.Close())
+		}()
+
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			peerConn, err := peerListener.Accept()
+			assert.NoError(t, err)
+			accepted <- peerConn
+		}()
+
+		dataConn, err := relayConn.Dial("tcp4", peerListener.Addr().String())
+		assert.NoError(t, err)
+		peerConn := <-accepted
+
+		// The peer is connected from the relayed transport address
+		assert.Equal(t, relayConn.Addr().String(), peerConn.RemoteAddr().String())
+
+		exchange(t, dataConn, peerConn)
+
+		// A second connection to the same peer is rejected
+		_, err = relayConn.Connect(peerConn.LocalAddr())
+		assert.Error(t, err)
+
+		assert.NoError(t, dataConn.Close())
+		assert.NoError(t, peerConn.Close())
+	})
+
+	t.Run("Connect requires permission", func(t *testing.T) {
+		peerListener, err := net.Listen("tcp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer func() {
+			assert.NoError(t, peerListener.Close())
+		}()
+
+		_, err = relayConn.Connect(peerListener.Addr())
+		assert.NoError(t, err, "a permission to 127.0.0.1 was created by the previous Dial")
+
+		_, err = relayConn.Connect(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 1})
+		assert.Error(t, err)
+	})
+
+	t.Run("ConnectionAttempt", func(t *testing.T) {
+		peerConn, err := net.Dial("tcp4", relayConn.Addr().String())
+		assert.NoError(t, err)
+
+		dataConn, err := relayConn.Accept()
+		assert.NoError(t, err)
+
+		exchange(t, peerConn, dataConn)
+
+		assert.NoError(t, peerConn.Close())
+		assert.NoError(t, dataConn.Close())
+	})
+
+	t.Run("ConnectionBind with unknown CONNECTION-ID", func(t *testing.T) {
+		dataConn, err := net.Dial("tcp4", serverAddr)
+		assert.NoError(t, err)
+		defer func() {
+			assert.NoError(t, dataConn.Close())
+		}()
+
+		tcpConn, ok := dataConn.(*net.TCPConn)
+		assert.True(t, ok)
+		_, err = relayConn.DialTCPWithConn(tcpConn, "tcp4", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1})
+		assert.Error(t, err)
+	})
+}
+
+func TestServerQuota(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	udpListener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	var maxAllocations atomic.Int32
+	maxAllocations.Store(1)
+
+	server, err := NewServer(ServerConfig{
+		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+			return GenerateAuthKey(username, realm, "pass"), true
+		},
+		QuotaHandler: func(string, string, net.Addr) Quota {
+			return Quota{MaxAllocationsPerUser: int(maxAllocations.Load()), MaxPermissions: 1}
+		},
+		PeerACL: PeerACL{AllowInternalPeers: true},
+		PacketConnConfigs: []PacketConnConfig{
+			{
+				PacketConn: udpListener,
+				RelayAddressGenerator: &RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP("127.0.0.1"),
+					Address:      "127.0.0.1",
+				},
+			},
+		},
+		Realm:         "pion.ly",
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, server.Close())
+	}()
+
+	newClient := func() (*Client, net.PacketConn) {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		client, err := NewClient(&ClientConfig{
+			STUNServerAddr: udpListener.LocalAddr().String(),
+			TURNServerAddr: udpListener.LocalAddr().String(),
+			Conn:           conn,
+			Username:       "user",
+			Password:       "pass",
+			LoggerFactory:  loggerFactory,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, client.Listen())
+
+		return client, conn
+	}
+
+	client1, conn1 := newClient()
+	relayConn1, err := client1.Allocate()
+	assert.NoError(t, err)
+
+	// A second permission exceeds MaxPermissions
+	assert.NoError(t, client1.CreatePermission(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}))
+	assert.Error(t, client1.CreatePermission(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 5000}))
+
+	// A second allocation of the user exceeds MaxAllocationsPerUser
+	client2, conn2 := newClient()
+	_, err = client2.Allocate()
+	assert.ErrorContains(t, err, "486")
+	client2.Close()
+	assert.NoError(t, conn2.Close())
+
+	// The QuotaHandler is asked again for every allocation
+	maxAllocations.Store(2)
+	client3, conn3 := newClient()
+	relayConn3, err := client3.Allocate()
+	assert.NoError(t, err)
+
+	// Closing an allocation frees its quota
+	maxAllocations.Store(1)
+	assert.NoError(t, relayConn3.Close())
+	assert.NoError(t, relayConn1.Close())
+	client4, conn4 := newClient()
+	relayConn4, err := client4.Allocate()
+	assert.NoError(t, err)
+	assert.NoError(t, relayConn4.Close())
+
+	for _, c := range []struct {
+		client *Client
+		conn   net.PacketConn
+	}{{client1, conn1}, {client3, conn3}, {client4, conn4}} {
+		c.client.Close()
+		assert.NoError(t, c.conn.Close())
+	}
+}
+
+func TestServerEventHandlers(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	udpListener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	allocationCreated := make(chan AllocationInfo, 1)
+	allocationDeleted := make(chan AllocationInfo, 1)
+	permissionCreated := make(chan net.IP, 1)
+
+	server, err := NewServer(ServerConfig{
+		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+			return GenerateAuthKey(username, realm, "pass"), true
+		},
+		EventHandlers: EventHandlers{
+			OnAllocationCreated: func(info AllocationInfo) {
+				allocationCreated <- info
+			},
+			OnAllocationDeleted: func(info AllocationInfo, expired bool) {
+				assert.False(t, expired)
+				allocationDeleted <- info
+			},
+			OnPermissionCreated: func(_ AllocationInfo, peerIP net.IP) {
+				select {
+				case permissionCreated <- peerIP:
+				default:
+				}
+			},
+		},
+		PeerACL: PeerACL{AllowInternalPeers: true},
+		PacketConnConfigs: []PacketConnConfig{
+			{
+				PacketConn: udpListener,
+				RelayAddressGenerator: &RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP("127.0.0.1"),
+					Address:      "127.0.0.1",
+				},
+			},
+		},
+		Realm:         "pion.ly",
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, server.Close())
+	}()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	client, err := NewClient(&ClientConfig{
+		STUNServerAddr: udpListener.LocalAddr().String(),
+		TURNServerAddr: udpListener.LocalAddr().String(),
+		Conn:           conn,
+		Username:       "user",
+		Password:       "pass",
+		LoggerFactory:  loggerFactory,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, client.Listen())
+
+	relayConn, err := client.Allocate()
+	assert.NoError(t, err)
+
+	info := <-allocationCreated
+	assert.Equal(t, "user", info.Username)
+	assert.Equal(t, "udp", info.Protocol)
+	assert.Equal(t, conn.LocalAddr().String(), info.ClientAddr.String())
+	assert.Equal(t, relayConn.LocalAddr().String(), info.RelayedAddr.String())
+
+	peer, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	// Relay a packet to the peer and back
+	_, err = relayConn.WriteTo([]byte("ping"), peer.LocalAddr())
+	assert.NoError(t, err)
+	assert.True(t, net.IPv4(127, 0, 0, 1).Equal(<-permissionCreated))
+
+	buf := make([]byte, 1500)
+	n, _, err := peer.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(buf[:n]))
+
+	_, err = peer.WriteTo([]byte("pong"), relayConn.LocalAddr())
+	assert.NoError(t, err)
+	n, _, err = relayConn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "pong", string(buf[:n]))
+
+	allocations := server.Allocations()
+	assert.Len(t, allocations, 1)
+	assert.Equal(t, "user", allocations[0].Username)
+	assert.Equal(t, AllocationStats{
+		BytesSent:       4,
+		PacketsSent:     1,
+		BytesReceived:   4,
+		PacketsReceived: 1,
+	}, allocations[0].Stats)
+
+	assert.NoError(t, relayConn.Close())
+	info = <-allocationDeleted
+	assert.Equal(t, uint64(4), info.Stats.BytesReceived)
+	assert.Empty(t, server.Allocations())
+
+	client.Close()
+	assert.NoError(t, conn.Close())
+	assert.NoError(t, peer.Close())
+}
+
+func TestServerAccessToken(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	udpListener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	key := make([]byte, 32)
+	server, err := NewServer(ServerConfig{
+		AccessTokenHandler: func(kid string, _ net.Addr) ([]byte, bool) {
+			return key, kid == "kid"
+		},
+		AuthorizationServer: "auth.pion.ly",
+		PeerACL:             PeerACL{AllowInternalPeers: true},
+		PacketConnConfigs: []PacketConnConfig{
+			{
+				PacketConn: udpListener,
+				RelayAddressGenerator: &RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP("127.0.0.1"),
+					Address:      "127.0.0.1",
+				},
+			},
+		},
+		Realm:         "pion.ly",
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, server.Close())
+	}()
+
+	var closers []func()
+	defer func() {
+		for _, closeClient := range closers {
+			closeClient()
+		}
+	}()
+
+	allocate := func(kid string, token stun.Token) (net.PacketConn, error) {
+		accessToken, err := token.Encrypt(key, "pion.ly")
+		assert.NoError(t, err)
+
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		client, err := NewClient(&ClientConfig{
+			STUNServerAddr: udpListener.LocalAddr().String(),
+			TURNServerAddr: udpListener.LocalAddr().String(),
+			Conn:           conn,
+			Username:       kid,
+			AccessToken:    accessToken,
+			MACKey:         token.MACKey,
+			LoggerFactory:  loggerFactory,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, client.Listen())
+		closers = append(closers, func() {
+			client.Close()
+			assert.NoError(t, conn.Close())
+		})
+
+		relayConn, err := client.Allocate()
+		if err != nil {
+			return nil, err
+		}
+
+		// Requests without the token are authenticated with its mac_key
+		assert.NoError(t, client.CreatePermission(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}))
+
+		return relayConn, nil
+	}
+
+	// The 401 of an unauthenticated request names the authorization server
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	req, err := stun.Build(stun.TransactionID, stun.NewType(stun.MethodAllocate, stun.ClassRequest))
+	assert.NoError(t, err)
+	_, err = conn.WriteTo(req.Raw, udpListener.LocalAddr())
+	assert.NoError(t, err)
+	buf := make([]byte, 1500)
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	res := &stun.Message{Raw: buf[:n]}
+	assert.NoError(t, res.Decode())
+	var authServer stun.ThirdPartyAuthorization
+	assert.NoError(t, authServer.GetFrom(res))
+	assert.Equal(t, "auth.pion.ly", authServer.String())
+	assert.NoError(t, conn.Close())
+
+	macKey := []byte("0123456789abcdefghij")
+
+	relayConn, err := allocate("kid", stun.Token{MACKey: macKey, Timestamp: time.Now(), Lifetime: time.Hour})
+	assert.NoError(t, err)
+	assert.NoError(t, relayConn.Close())
+
+	_, err = allocate("other", stun.Token{MACKey: macKey, Timestamp: time.Now(), Lifetime: time.Hour})
+	assert.Error(t, err)
+
+	_, err = allocate("kid", stun.Token{MACKey: macKey, Timestamp: time.Now().Add(-2 * time.Hour), Lifetime: time.Hour})
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestServerRedirect(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	newServer := func(redirectHandler RedirectHandler) (*Server, *net.UDPAddr) {
+		udpListener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		server, err := NewServer(ServerConfig{
+			AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+				return GenerateAuthKey(username, realm, "pass"), true
+			},
+			RedirectHandler: redirectHandler,
+			PacketConnConfigs: []PacketConnConfig{
+				{
+					PacketConn: udpListener,
+					RelayAddressGenerator: &RelayAddressGeneratorStatic{
+						RelayAddress: net.ParseIP("127.0.0.1"),
+						Address:      "127.0.0.1",
+					},
+				},
+			},
+			Realm:         "pion.ly",
+			LoggerFactory: loggerFactory,
+		})
+		assert.NoError(t, err)
+
+		udpAddr, ok := udpListener.LocalAddr().(*net.UDPAddr)
+		assert.True(t, ok)
+
+		return server, udpAddr
+	}
+
+	var alternateOfA, alternateOfB atomic.Pointer[AlternateServer]
+	redirectTo := func(alternate *atomic.Pointer[AlternateServer]) RedirectHandler {
+		return func(string, string, net.Addr) (AlternateServer, bool) {
+			if a := alternate.Load(); a != nil {
+				return *a, true
+			}
+
+			return AlternateServer{}, false
+		}
+	}
+
+	serverA, addrA := newServer(redirectTo(&alternateOfA))
+	serverB, addrB := newServer(redirectTo(&alternateOfB))
+	alternateOfA.Store(&AlternateServer{IP: addrB.IP, Port: addrB.Port, Domain: "b.pion.ly"})
+
+	allocate := func() (*Client, net.PacketConn, error) {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		client, err := NewClient(&ClientConfig{
+			TURNServerAddr: addrA.String(),
+			Conn:           conn,
+			Username:       "user",
+			Password:       "pass",
+			LoggerFactory:  loggerFactory,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, client.Listen())
+
+		relayConn, err := client.Allocate()
+		if err == nil {
+			assert.Equal(t, 0, serverA.AllocationCount())
+			assert.Equal(t, 1, serverB.AllocationCount())
+			assert.NoError(t, relayConn.Close())
+		}
+
+		return client, conn, err
+	}
+
+	// The client follows the redirect from A to B
+	client, conn, err := allocate()
+	assert.NoError(t, err)
+	assert.Equal(t, addrB.String(), client.TURNServerAddr().String())
+	client.Close()
+	assert.NoError(t, conn.Close())
+
+	// B redirecting back to A is a loop
+	alternateOfB.Store(&AlternateServer{IP: addrA.IP, Port: addrA.Port})
+	client, conn, err = allocate()
+	assert.ErrorIs(t, err, errRedirectLoop)
+	client.Close()
+	assert.NoError(t, conn.Close())
+
+	// A TCP client is not redirected, it gets the alternate server and the
+	// ALTERNATE-DOMAIN to verify its certificate against
+	tcpListener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	serverTCP, err := NewServer(ServerConfig{
+		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+			return GenerateAuthKey(username, realm, "pass"), true
+		},
+		RedirectHandler: redirectTo(&alternateOfA),
+		ListenerConfigs: []ListenerConfig{
+			{
+				Listener: tcpListener,
+				RelayAddressGenerator: &RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP("127.0.0.1"),
+					Address:      "127.0.0.1",
+				},
+			},
+		},
+		Realm:         "pion.ly",
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+
+	tcpConn, err := net.Dial("tcp4", tcpListener.Addr().String())
+	assert.NoError(t, err)
+	client, err = NewClient(&ClientConfig{
+		TURNServerAddr: tcpListener.Addr().String(),
+		Conn:           NewSTUNConn(tcpConn),
+		Username:       "user",
+		Password:       "pass",
+		LoggerFactory:  loggerFactory,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, client.Listen())
+
+	_, err = client.Allocate()
+	var tryAlternate *TryAlternateError
+	assert.ErrorAs(t, err, &tryAlternate)
+	assert.Equal(t, addrB.String(), tryAlternate.Server.String())
+	assert.Equal(t, "b.pion.ly", tryAlternate.Domain)
+	assert.Equal(t, 0, serverB.AllocationCount())
+	client.Close()
+	assert.NoError(t, tcpConn.Close())
+
+	assert.NoError(t, serverTCP.Close())
+	assert.NoError(t, serverA.Close())
+	assert.NoError(t, serverB.Close())
+}
+
+func TestServerDualStack(t *testing.T) {
+	if ipv6Conn, err := net.ListenPacket("udp6", "[::1]:0"); err != nil {
+		t.Skip("IPv6 is not available")
+	} else {
+		assert.NoError(t, ipv6Conn.Close())
+	}
+
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	newServer := func(relayAddressGenerator RelayAddressGenerator) (*Server, net.Addr) {
+		udpListener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		server, err := NewServer(ServerConfig{
+			AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+				return GenerateAuthKey(username, realm, "pass"), true
+			},
+			PeerACL: PeerACL{AllowInternalPeers: true},
+			PacketConnConfigs: []PacketConnConfig{
+				{
+					PacketConn:            udpListener,
+					RelayAddressGenerator: relayAddressGenerator,
+				},
+			},
+			Realm:         "pion.ly",
+			LoggerFactory: loggerFactory,
+		})
+		assert.NoError(t, err)
+
+		return server, udpListener.LocalAddr()
+	}
+
+	newClient := func(serverAddr net.Addr) (*Client, net.PacketConn) {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		client, err := NewClient(&ClientConfig{
+			TURNServerAddr: serverAddr.String(),
+			Conn:           conn,
+			Username:       "user",
+			Password:       "pass",
+			LoggerFactory:  loggerFactory,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, client.Listen())
+
+		return client, conn
+	}
+
+	pingPong := func(relayConn net.PacketConn, peer net.PacketConn) {
+		_, err := relayConn.WriteTo([]byte("ping"), peer.LocalAddr())
+		assert.NoError(t, err)
+
+		buf := make([]byte, 1500)
+		n, from, err := peer.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "ping", string(buf[:n]))
+		assert.Equal(t, relayConn.LocalAddr().String(), from.String())
+
+		_, err = peer.WriteTo([]byte("pong"), relayConn.LocalAddr())
+		assert.NoError(t, err)
+		n, from, err = relayConn.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "pong", string(buf[:n]))
+		assert.Equal(t, peer.LocalAddr().String(), from.String())
+	}
+
+	peer4, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	peer6, err := net.ListenPacket("udp6", "[::1]:0")
+	assert.NoError(t, err)
+
+	t.Run("BothFamilies", func(t *testing.T) {
+		server, serverAddr := newServer(&RelayAddressGeneratorStatic{
+			RelayAddress:     net.ParseIP("127.0.0.1"),
+			Address:          "127.0.0.1",
+			RelayAddressIPv6: net.ParseIP("::1"),
+			AddressIPv6:      "::1",
+		})
+		client, conn := newClient(serverAddr)
+
+		ipv4, ipv6, err := client.AllocateDualStack()
+		assert.NoError(t, err)
+		assert.NotNil(t, ipv4)
+		assert.NotNil(t, ipv6)
+
+		allocations := server.Allocations()
+		assert.Len(t, allocations, 1)
+		assert.Equal(t, ipv4.LocalAddr().String(), allocations[0].RelayedAddr.String())
+		assert.Equal(t, ipv6.LocalAddr().String(), allocations[0].AdditionalRelayedAddr.String())
+
+		// Each relayed transport address relays to the peers of its address family
+		pingPong(ipv4, peer4)
+		pingPong(ipv6, peer6)
+		pingPong(ipv4, peer4)
+		pingPong(ipv6, peer6)
+
+		// Closing either conn deletes the allocation
+		assert.NoError(t, ipv6.Close())
+		_, _, err = ipv4.ReadFrom(make([]byte, 1500))
+		assert.Error(t, err)
+
+		client.Close()
+		assert.NoError(t, conn.Close())
+		assert.NoError(t, server.Close())
+	})
+
+	t.Run("IPv4Only", func(t *testing.T) {
+		server, serverAddr := newServer(&RelayAddressGeneratorStatic{
+			RelayAddress: net.ParseIP("127.0.0.1"),
+			Address:      "127.0.0.1",
+		})
+		client, conn := newClient(serverAddr)
+
+		ipv4, ipv6, err := client.AllocateDualStack()
+		assert.NoError(t, err)
+		assert.NotNil(t, ipv4)
+		assert.Nil(t, ipv6)
+
+		// An IPv6 peer is rejected with 443 (Peer Address Family Mismatch)
+		assert.ErrorContains(t, client.CreatePermission(peer6.LocalAddr()), "443")
+		pingPong(ipv4, peer4)
+
+		assert.NoError(t, ipv4.Close())
+		client.Close()
+		assert.NoError(t, conn.Close())
+		assert.NoError(t, server.Close())
+	})
+
+	assert.NoError(t, peer4.Close())
+	assert.NoError(t, peer6.Close())
+}
+
+// rebindingConn simulates a NAT rebinding of the client, after rebind it
+// sends and receives on the new socket.
+type rebindingConn struct {
+	original net.PacketConn
+	conn     net.PacketConn
+	mutex    sync.Mutex
+}
+
+func (c *rebindingConn) current() net.PacketConn {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.conn
+}
+
+func (c *rebindingConn) rebind(conn net.PacketConn) {
+	c.mutex.Lock()
+	previous := c.conn
+	c.conn = conn
+	c.mutex.Unlock()
+
+	// Unblock the read on the previous socket
+	_ = previous.SetReadDeadline(time.Now())
+}
+
+func (c *rebindingConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		conn := c.current()
+		n, addr, err := conn.ReadFrom(p)
+		if err != nil && conn != c.current() {
+			continue
+		}
+
+		return n, addr, err
+	}
+}
+
+func (c *rebindingConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	return c.current().WriteTo(p, addr)
+}
+
+func (c *rebindingConn) Close() error {
+	if conn := c.current(); conn != c.original {
+		_ = conn.Close()
+	}
+
+	return c.original.Close()
+}
+
+func (c *rebindingConn) LocalAddr() net.Addr                { return c.current().LocalAddr() }
+func (c *rebindingConn) SetDeadline(t time.Time) error      { return c.current().SetDeadline(t) }
+func (c *rebindingConn) SetReadDeadline(t time.Time) error  { return c.current().SetReadDeadline(t) }
+func (c *rebindingConn) SetWriteDeadline(t time.Time) error { return c.current().SetWriteDeadline(t) }
+
+func TestServerMobility(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	udpListener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server, err := NewServer(ServerConfig{
+		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+			return GenerateAuthKey(username, realm, "pass"), true
+		},
+		MobilityHandler: func(username, _ string, _ net.Addr) bool {
+			return username != "static"
+		},
+		PeerACL: PeerACL{AllowInternalPeers: true},
+		PacketConnConfigs: []PacketConnConfig{
+			{
+				PacketConn: udpListener,
+				RelayAddressGenerator: &RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP("127.0.0.1"),
+					Address:      "127.0.0.1",
+				},
+			},
+		},
+		Realm:         "pion.ly",
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+
+	newClient := func(username string) (*Client, *rebindingConn) {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+		rebinding := &rebindingConn{original: conn, conn: conn}
+
+		client, err := NewClient(&ClientConfig{
+			TURNServerAddr: udpListener.LocalAddr().String(),
+			Conn:           rebinding,
+			Username:       username,
+			Password:       "pass",
+			Mobility:       true,
+			LoggerFactory:  loggerFactory,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, client.Listen())
+
+		return client, rebinding
+	}
+
+	pingPong := func(relayConn net.PacketConn, peer net.PacketConn) {
+		_, err := relayConn.WriteTo([]byte("ping"), peer.LocalAddr())
+		assert.NoError(t, err)
+
+		buf := make([]byte, 1500)
+		n, _, err := peer.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "ping", string(buf[:n]))
+
+		_, err = peer.WriteTo([]byte("pong"), relayConn.LocalAddr())
+		assert.NoError(t, err)
+		n, from, err := relayConn.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "pong", string(buf[:n]))
+		assert.Equal(t, peer.LocalAddr().String(), from.String())
+	}
+
+	peer1, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	peer2, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	t.Run("MoveAllocation", func(t *testing.T) {
+		client, conn := newClient("user")
+
+		relayConn, err := client.Allocate()
+		assert.NoError(t, err)
+		pingPong(relayConn, peer1)
+
+		// The ChannelBind request for a new peer from the new address fails with 437
+		// (Allocation Mismatch), the client moves the allocation and sends it again.
+		// Data sent before the allocation is moved is lost.
+		rebound, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+		conn.rebind(rebound)
+		_, err = relayConn.WriteTo([]byte("lost"), peer2.LocalAddr())
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			allocations := server.Allocations()
+
+			return len(allocations) == 1 && allocations[0].ClientAddr.String() == rebound.LocalAddr().String()
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, relayConn.LocalAddr().String(), server.Allocations()[0].RelayedAddr.String())
+		pingPong(relayConn, peer2)
+
+		// The permissions and channels of the allocation are kept
+		pingPong(relayConn, peer1)
+
+		rebound, err = net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+		conn.rebind(rebound)
+		assert.NoError(t, client.MoveAllocation())
+		pingPong(relayConn, peer1)
+		pingPong(relayConn, peer2)
+
+		assert.NoError(t, relayConn.Close())
+		client.Close()
+		assert.NoError(t, conn.Close())
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		client, conn := newClient("static")
+
+		_, err := client.Allocate()
+		assert.ErrorContains(t, err, "405")
+
+		client.Close()
+		assert.NoError(t, conn.Close())
+	})
+
+	assert.NoError(t, peer1.Close())
+	assert.NoError(t, peer2.Close())
+	assert.NoError(t, server.Close())
+}
+
+func TestServerPeerACL(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	udpListener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server, err := NewServer(ServerConfig{
+		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+			return GenerateAuthKey(username, realm, "pass"), true
+		},
+		PeerACL: PeerACL{
+			Users: map[string]PeerACLRule{
+				"admin": {Allow: []*net.IPNet{{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(32, 32)}}},
+			},
+		},
+		PacketConnConfigs: []PacketConnConfig{
+			{
+				PacketConn: udpListener,
+				RelayAddressGenerator: &RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP("127.0.0.1"),
+					Address:      "127.0.0.1",
+				},
+			},
+		},
+		Realm:         "pion.ly",
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+
+	newClient := func(username string) (*Client, net.PacketConn) {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		client, err := NewClient(&ClientConfig{
+			TURNServerAddr: udpListener.LocalAddr().String(),
+			Conn:           conn,
+			Username:       username,
+			Password:       "pass",
+			LoggerFactory:  loggerFactory,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, client.Listen())
+
+		return client, conn
+	}
+
+	peersDenied := func(username string) uint64 {
+		for _, info := range server.Allocations() {
+			if info.Username == username {
+				return info.Stats.PeersDenied
+			}
+		}
+
+		return 0
+	}
+
+	peer, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	t.Run("DefaultDeny", func(t *testing.T) {
+		client, conn := newClient("user")
+
+		relayConn, err := client.Allocate()
+		assert.NoError(t, err)
+
+		// Loopback peers are denied with 403 (Forbidden) by default
+		assert.ErrorContains(t, client.CreatePermission(peer.LocalAddr()), "403")
+		_, err = relayConn.WriteTo([]byte("ping"), peer.LocalAddr())
+		assert.Error(t, err)
+		assert.Equal(t, uint64(2), peersDenied("user"))
+
+		assert.NoError(t, relayConn.Close())
+		client.Close()
+		assert.NoError(t, conn.Close())
+	})
+
+	t.Run("SetPeerACL", func(t *testing.T) {
+		client, conn := newClient("admin")
+
+		relayConn, err := client.Allocate()
+		assert.NoError(t, err)
+
+		// The rule of the user allows the peer
+		_, err = relayConn.WriteTo([]byte("ping"), peer.LocalAddr())
+		assert.NoError(t, err)
+		buf := make([]byte, 1500)
+		n, _, err := peer.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "ping", string(buf[:n]))
+
+		// A new ACL drops the packets of the existing permission
+		server.SetPeerACL(PeerACL{})
+		_, err = relayConn.WriteTo([]byte("ping"), peer.LocalAddr())
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			return peersDenied("admin") == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.NoError(t, peer.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+		_, _, err = peer.ReadFrom(buf)
+		assert.Error(t, err)
+
+		assert.NoError(t, relayConn.Close())
+		client.Close()
+		assert.NoError(t, conn.Close())
+	})
+
+	assert.NoError(t, peer.Close())
+	assert.NoError(t, server.Close())
+}
+
+func TestServerDTLS(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	certificate, err := selfsign.GenerateSelfSigned()
+	assert.NoError(t, err)
+
+	dtlsListener, err := dtls.Listen("udp4", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")}, &dtls.Config{
+		Certificates: []tls.Certificate{certificate},
+	})
+	assert.NoError(t, err)
+
+	server, err := NewServer(ServerConfig{
+		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+			return GenerateAuthKey(username, realm, "pass"), true
+		},
+		PeerACL: PeerACL{AllowInternalPeers: true},
+		PacketConnConfigs: []PacketConnConfig{
+			{
+				PacketConn: NewDTLSPacketConn(dtlsListener),
+				RelayAddressGenerator: &RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP("127.0.0.1"),
+					Address:      "127.0.0.1",
+				},
+			},
+		},
+		Realm:         "pion.ly",
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+
+	peer, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	newClient := func() (*Client, net.PacketConn) {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		client, err := NewClient(&ClientConfig{
+			TURNServerAddr: dtlsListener.Addr().String(),
+			Conn:           conn,
+			Username:       "user",
+			Password:       "pass",
+			DTLSConfig:     &dtls.Config{InsecureSkipVerify: true},
+			LoggerFactory:  loggerFactory,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, client.Listen())
+
+		return client, conn
+	}
+
+	// Each client has its own DTLS connection and allocation
+	for i := 0; i < 2; i++ {
+		client, conn := newClient()
+		relayConn, err := client.Allocate()
+		assert.NoError(t, err)
+
+		_, err = relayConn.WriteTo([]byte("ping"), peer.LocalAddr())
+		assert.NoError(t, err)
+
+		buf := make([]byte, 1500)
+		n, _, err := peer.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "ping", string(buf[:n]))
+
+		_, err = peer.WriteTo([]byte("pong"), relayConn.LocalAddr())
+		assert.NoError(t, err)
+		n, from, err := relayConn.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "pong", string(buf[:n]))
+		assert.Equal(t, peer.LocalAddr().String(), from.String())
+
+		assert.NoError(t, relayConn.Close())
+		client.Close()
+		assert.NoError(t, conn.Close())
+	}
+	assert.Eventually(t, func() bool {
+		return len(server.Allocations()) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Closing the client aborts the handshake with a server that never answers
+	silent, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	silentConn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	silentClient, err := NewClient(&ClientConfig{
+		TURNServerAddr: silent.LocalAddr().String(),
+		Conn:           silentConn,
+		Username:       "user",
+		Password:       "pass",
+		DTLSConfig:     &dtls.Config{InsecureSkipVerify: true},
+		LoggerFactory:  loggerFactory,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, silentClient.Listen())
+	time.AfterFunc(300*time.Millisecond, silentClient.Close)
+	_, err = silentClient.Allocate()
+	assert.Error(t, err)
+	silentClient.Close()
+	assert.NoError(t, silentConn.Close())
+	assert.NoError(t, silent.Close())
+
+	// The DTLS connection is dialed to the TURN server
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	_, err = NewClient(&ClientConfig{Conn: conn, DTLSConfig: &dtls.Config{}})
+	assert.ErrorIs(t, err, errDTLSTURNServerAddrUnset)
+	assert.NoError(t, conn.Close())
+
+	assert.NoError(t, peer.Close())
+	assert.NoError(t, server.Close())
+}
+
+func TestServerBatchIO(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	loggerFactory := logging.NewDefaultLoggerFactory()
+
+	udpListener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server, err := NewServer(ServerConfig{
+		AuthHandler: func(username, realm string, _ net.Addr) (key []byte, ok bool) {
+			return GenerateAuthKey(username, realm, "pass"), true
+		},
+		PeerACL: PeerACL{AllowInternalPeers: true},
+		PacketConnConfigs: []PacketConnConfig{
+			{
+				PacketConn: udpListener,
+				RelayAddressGenerator: &RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP("127.0.0.1"),
+					Address:      "127.0.0.1",
+				},
+				BatchSize: 16,
+			},
+		},
+		Realm:         "pion.ly",
+		LoggerFactory: loggerFactory,
+	})
+	assert.NoError(t, err)
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	client, err := NewClient(&ClientConfig{
+		TURNServerAddr: udpListener.LocalAddr().String(),
+		Conn:           conn,
+		Username:       "user",
+		Password:       "pass",
+		LoggerFactory:  loggerFactory,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, client.Listen())
+
+	relayConn, err := client.Allocate()
+	assert.NoError(t, err)
+
+	peer, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	// The first packets are relayed in Send and Data indications, the
+	// following ones in ChannelData once the channel is bound.
+	buf := make([]byte, 1500)
+	for i := 0; i < 64; i++ {
+		data := []byte(fmt.Sprintf("ping %d", i))
+		_, err = relayConn.WriteTo(data, peer.LocalAddr())
+		assert.NoError(t, err)
+
+		n, _, err := peer.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, data, buf[:n])
+
+		data = []byte(fmt.Sprintf("pong %d", i))
+		_, err = peer.WriteTo(data, relayConn.LocalAddr())
+		assert.NoError(t, err)
+
+		n, from, err := relayConn.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, data, buf[:n])
+		assert.Equal(t, peer.LocalAddr().String(), from.String())
+	}
+
+	// A burst from the peer is read from the relay socket in batches
+	for i := 0; i < 16; i++ {
+		_, err = peer.WriteTo([]byte("burst"), relayConn.LocalAddr())
+		assert.NoError(t, err)
+	}
+	for i := 0; i < 16; i++ {
+		n, _, err := relayConn.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "burst", string(buf[:n]))
+	}
+
+	// A burst from the client is written to the peer in batches
+	for i := 0; i < 16; i++ {
+		_, err = relayConn.WriteTo([]byte("burst"), peer.LocalAddr())
+		assert.NoError(t, err)
+	}
+	for i := 0; i < 16; i++ {
+		n, _, err := peer.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "burst", string(buf[:n]))
+	}
+
+	assert.NoError(t, relayConn.Close())
+	client.Close()
+	assert.NoError(t, conn.Close())
+	assert.NoError(t, peer.Close())
+	assert.NoError(t, server.Close())
+}
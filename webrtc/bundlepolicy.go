@@ -11,6 +11,10 @@ import (
 // endpoint is not bundle-aware, and what ICE candidates are gathered. If the
 // remote endpoint is bundle-aware, all media tracks and data channels are
 // bundled onto the same transport.
+//
+// The policy applies to the media sections of the initial offer or answer,
+// media sections added by a later negotiation use the transport of the first
+// media section.
 type BundlePolicy int
 
 const (
@@ -21,7 +21,8 @@ type Configuration struct {
 	ICETransportPolicy ICETransportPolicy `json:"iceTransportPolicy,omitempty"`
 
 	// BundlePolicy indicates which media-bundling policy to use when gathering
-	// ICE candidates.
+	// ICE candidates. Media sections that are not bundled get an ICE and DTLS
+	// transport of their own.
 	BundlePolicy BundlePolicy `json:"bundlePolicy,omitempty"`
 
 	// RTCPMuxPolicy indicates which rtcp-mux policy to use when gathering ICE
@@ -47,6 +48,9 @@ type Configuration struct {
 	Certificates []Certificate `json:"certificates,omitempty"`
 
 	// ICECandidatePoolSize describes the size of the prefetched ICE pool.
+	// When it is not zero, candidates are gathered as soon as the configuration
+	// is set and signaled on the first SetLocalDescription. As all media is
+	// carried on a single ICE transport, any size prefetches one set of candidates.
 	ICECandidatePoolSize uint8 `json:"iceCandidatePoolSize,omitempty"`
 
 	// SDPSemantics controls the type of SDP offers accepted by and
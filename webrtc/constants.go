@@ -38,6 +38,8 @@ const (
 
 	sdpAttributeSimulcast = "simulcast"
 
+	sdpAttributeRTCP = "rtcp"
+
 	rtpOutboundMTU = 1200
 
 	rtpPayloadTypeBitmask = 0x7F
@@ -310,7 +310,7 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error { //nolint:
 		}
 
 		t.srtpEndpoint = t.iceTransport.newEndpoint(mux.MatchSRTP)
-		t.srtcpEndpoint = t.iceTransport.newEndpoint(mux.MatchSRTCP)
+		t.srtcpEndpoint = t.iceTransport.newRTCPEndpoint(mux.MatchSRTCP)
 		t.remoteParameters = remoteParameters
 
 		cert := t.certificates[0]
@@ -53,6 +53,10 @@ var (
 	// RTCPMuxPolicy was made after PeerConnection has been initialized.
 	ErrModifyingRTCPMuxPolicy = errors.New("rtcp mux policy cannot be modified")
 
+	// ErrRTCPMuxPolicyNegotiateWithICEMux indicates that RTCPMuxPolicyNegotiate
+	// was used with an ICE TCP or UDP mux, which cannot carry an RTCP component.
+	ErrRTCPMuxPolicyNegotiateWithICEMux = errors.New("rtcp mux policy negotiate cannot be used with an ICE mux")
+
 	// ErrModifyingICECandidatePoolSize indicates that an attempt to modify
 	// ICECandidatePoolSize was made after PeerConnection has been initialized.
 	ErrModifyingICECandidatePoolSize = errors.New("ice candidate pool size cannot be modified")
@@ -14,6 +14,7 @@ import (
 	"github.com/pion/ice/v4"
 	"github.com/pion/logging"
 	"github.com/pion/stun/v3"
+	"github.com/pion/webrtc/v4/internal/util"
 )
 
 // ICEGatherer gathers local host, server reflexive and relay
@@ -30,18 +31,31 @@ type ICEGatherer struct {
 
 	agent *ice.Agent
 
+	// rtcpAgent gathers the candidates of the RTCP component when RTCP is not
+	// multiplexed with RTP, see RTCPMuxPolicyNegotiate.
+	gatherRTCP      bool
+	rtcpAgent       *ice.Agent
+	gatheringAgents atomic.Int32
+	rtcpGathered    atomic.Bool
+
 	onLocalCandidateHandler atomic.Value // func(candidate *ICECandidate)
 	onStateChangeHandler    atomic.Value // func(state ICEGathererState)
 
-	// Used for GatheringCompletePromise
-	onGatheringCompleteHandler atomic.Value // func()
-
 	api *API
 
 	// Used to set the corresponding media stream identification tag and media description index
 	// for ICE candidates generated by this gatherer.
 	sdpMid        atomic.Value  // string
 	sdpMLineIndex atomic.Uint32 // uint16
+
+	// Candidate pool, see gatherPool. The candidates and the state gathered
+	// while pooling are held back until Gather is called, and candidates keep
+	// queueing behind the pooled ones until those are delivered.
+	poolLock       sync.Mutex
+	pooling        bool
+	releasing      bool
+	poolState      ICEGathererState
+	poolCandidates []ice.Candidate
 }
 
 // NewICEGatherer creates a new NewICEGatherer.
@@ -130,8 +144,13 @@ func (g *ICEGatherer) createAgent() error { //nolint:cyclop
 		UDPMux:                 g.api.settingEngine.iceUDPMux,
 		ProxyDialer:            g.api.settingEngine.iceProxyDialer,
 		DisableActiveTCP:       g.api.settingEngine.iceDisableActiveTCP,
+		TCPSimultaneousOpen:    g.api.settingEngine.iceTCPSimultaneousOpen,
+		InsecureSkipVerify:     g.api.settingEngine.iceInsecureSkipVerify,
 		MaxBindingRequests:     g.api.settingEngine.iceMaxBindingRequests,
 		BindingRequestHandler:  g.api.settingEngine.iceBindingRequestHandler,
+		EventLogSize:           g.api.settingEngine.iceEventLogSize,
+
+		CandidatePairSelectionPolicy: g.api.settingEngine.iceCandidatePairSelectionPolicy,
 	}
 
 	requestedNetworkTypes := g.api.settingEngine.candidates.ICENetworkTypes
@@ -148,13 +167,46 @@ func (g *ICEGatherer) createAgent() error { //nolint:cyclop
 		return err
 	}
 
+	if g.gatherRTCP {
+		rtcpAgent, err := newRTCPAgent(agent, config)
+		if err != nil {
+			return util.FlattenErrs([]error{err, agent.Close()})
+		}
+		g.rtcpAgent = rtcpAgent
+	}
+
 	g.agent = agent
 
 	return nil
 }
 
-// Gather ICE candidates.
-func (g *ICEGatherer) Gather() error { //nolint:cyclop
+// newRTCPAgent creates the agent of the RTCP component, it shares the
+// credentials of the RTP agent.
+func newRTCPAgent(agent *ice.Agent, config *ice.AgentConfig) (*ice.Agent, error) {
+	ufrag, pwd, err := agent.GetLocalUserCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	rtcpConfig := *config
+	rtcpConfig.Component = ice.ComponentRTCP
+	rtcpConfig.LocalUfrag = ufrag
+	rtcpConfig.LocalPwd = pwd
+
+	return ice.NewAgent(&rtcpConfig)
+}
+
+// Gather ICE candidates. If the candidate pool of a PeerConnection is being
+// gathered, its candidates are signaled instead of gathering again.
+func (g *ICEGatherer) Gather() error {
+	if g.releasePool() {
+		return nil
+	}
+
+	return g.gather()
+}
+
+func (g *ICEGatherer) gather() error {
 	if err := g.createAgent(); err != nil {
 		return err
 	}
@@ -165,45 +217,187 @@ func (g *ICEGatherer) Gather() error { //nolint:cyclop
 		return fmt.Errorf("%w: unable to gather", errICEAgentNotExist)
 	}
 
-	g.setState(ICEGathererStateGathering)
-	if err := agent.OnCandidate(func(candidate ice.Candidate) {
-		onLocalCandidateHandler := func(*ICECandidate) {}
-		if handler, ok := g.onLocalCandidateHandler.Load().(func(candidate *ICECandidate)); ok && handler != nil {
-			onLocalCandidateHandler = handler
+	rtcpAgent := g.getRTCPAgent()
+	agents := []*ice.Agent{agent}
+	if rtcpAgent != nil {
+		agents = append(agents, rtcpAgent)
+	}
+	g.rtcpGathered.Store(rtcpAgent == nil)
+	g.gatheringAgents.Store(int32(len(agents))) //nolint:gosec // G115
+
+	g.updateState(ICEGathererStateGathering)
+	if err := agent.OnCandidate(g.onAgentCandidate); err != nil {
+		return err
+	}
+	if rtcpAgent != nil {
+		if err := rtcpAgent.OnCandidate(func(candidate ice.Candidate) {
+			if candidate == nil && !g.rtcpGathered.CompareAndSwap(false, true) {
+				return
+			}
+			g.onAgentCandidate(candidate)
+		}); err != nil {
+			return err
 		}
+	}
 
-		onGatheringCompleteHandler := func() {}
-		if handler, ok := g.onGatheringCompleteHandler.Load().(func()); ok && handler != nil {
-			onGatheringCompleteHandler = handler
+	for _, a := range agents {
+		if err := a.GatherCandidates(); err != nil {
+			return err
 		}
+	}
 
-		sdpMid := ""
+	return nil
+}
 
-		if mid, ok := g.sdpMid.Load().(string); ok {
-			sdpMid = mid
-		}
+func (g *ICEGatherer) onAgentCandidate(candidate ice.Candidate) {
+	// Gathering is complete once every agent is done.
+	if candidate == nil && g.gatheringAgents.Add(-1) > 0 {
+		return
+	}
+	if g.holdCandidate(candidate) {
+		return
+	}
+	g.onCandidate(candidate)
+}
 
-		sdpMLineIndex := uint16(g.sdpMLineIndex.Load()) //nolint:gosec // G115
+func (g *ICEGatherer) onCandidate(candidate ice.Candidate) {
+	onLocalCandidateHandler := func(*ICECandidate) {}
+	if handler, ok := g.onLocalCandidateHandler.Load().(func(candidate *ICECandidate)); ok && handler != nil {
+		onLocalCandidateHandler = handler
+	}
 
-		if candidate != nil {
-			c, err := newICECandidateFromICE(candidate, sdpMid, sdpMLineIndex)
-			if err != nil {
-				g.log.Warnf("Failed to convert ice.Candidate: %s", err)
+	sdpMid := ""
 
-				return
-			}
-			onLocalCandidateHandler(&c)
-		} else {
-			g.setState(ICEGathererStateComplete)
+	if mid, ok := g.sdpMid.Load().(string); ok {
+		sdpMid = mid
+	}
+
+	sdpMLineIndex := uint16(g.sdpMLineIndex.Load()) //nolint:gosec // G115
+
+	if candidate != nil {
+		c, err := newICECandidateFromICE(candidate, sdpMid, sdpMLineIndex)
+		if err != nil {
+			g.log.Warnf("Failed to convert ice.Candidate: %s", err)
 
-			onGatheringCompleteHandler()
-			onLocalCandidateHandler(nil)
+			return
 		}
-	}); err != nil {
+		onLocalCandidateHandler(&c)
+	} else {
+		g.setState(ICEGathererStateComplete)
+
+		onLocalCandidateHandler(nil)
+	}
+}
+
+// gatherPool starts gathering candidates ahead of Gather, which is how a
+// PeerConnection honors Configuration.ICECandidatePoolSize. The gatherer stays
+// in the new state and its handlers are not called until Gather is called.
+func (g *ICEGatherer) gatherPool() error {
+	g.poolLock.Lock()
+	if g.pooling || g.State() != ICEGathererStateNew || g.getAgent() != nil {
+		g.poolLock.Unlock()
+
+		return nil
+	}
+	g.pooling = true
+	g.poolLock.Unlock()
+
+	if err := g.gather(); err != nil {
+		g.poolLock.Lock()
+		g.pooling = false
+		g.poolCandidates = nil
+		g.poolLock.Unlock()
+
 		return err
 	}
 
-	return agent.GatherCandidates()
+	return nil
+}
+
+// holdCandidate keeps a candidate in the pool, it returns false if the pool
+// has been released.
+func (g *ICEGatherer) holdCandidate(candidate ice.Candidate) bool {
+	g.poolLock.Lock()
+	defer g.poolLock.Unlock()
+
+	if !g.pooling && !g.releasing {
+		return false
+	}
+	g.poolCandidates = append(g.poolCandidates, candidate)
+
+	return true
+}
+
+// releasePool signals the pooled candidates, it returns false if there is no pool.
+func (g *ICEGatherer) releasePool() bool {
+	g.poolLock.Lock()
+	if !g.pooling {
+		g.poolLock.Unlock()
+
+		return false
+	}
+
+	g.pooling = false
+	g.releasing = true
+
+	// The state is updated right away so that the local description includes
+	// the pooled candidates, the nil candidate ends the gathering.
+	state := g.poolState
+	if n := len(g.poolCandidates); n > 0 && g.poolCandidates[n-1] == nil {
+		state = ICEGathererStateComplete
+	}
+	atomicStoreICEGathererState(&g.state, state)
+	g.poolLock.Unlock()
+
+	// The handlers are called asynchronously like the ones of the agent.
+	go g.deliverPool()
+
+	return true
+}
+
+// deliverPool calls the handlers for the pooled candidates. The handlers run
+// without the pool lock held, candidates gathered meanwhile are queued and
+// delivered in order after the pooled ones.
+func (g *ICEGatherer) deliverPool() {
+	g.notifyState(ICEGathererStateGathering)
+
+	for {
+		g.poolLock.Lock()
+		candidates := g.poolCandidates
+		g.poolCandidates = nil
+		if len(candidates) == 0 || !g.releasing {
+			g.releasing = false
+			g.poolLock.Unlock()
+
+			return
+		}
+		g.poolLock.Unlock()
+
+		for _, candidate := range candidates {
+			g.onCandidate(candidate)
+		}
+	}
+}
+
+// dropRTCP stops gathering for the RTCP component, which is not needed once
+// rtcp-mux has been negotiated.
+func (g *ICEGatherer) dropRTCP() error {
+	g.lock.Lock()
+	rtcpAgent := g.rtcpAgent
+	g.gatherRTCP = false
+	g.rtcpAgent = nil
+	g.lock.Unlock()
+
+	if rtcpAgent == nil {
+		return nil
+	}
+
+	// Gathering may still be waiting for the RTCP agent.
+	if g.rtcpGathered.CompareAndSwap(false, true) && g.gatheringAgents.Load() > 0 {
+		g.onAgentCandidate(nil)
+	}
+
+	return rtcpAgent.Close()
 }
 
 // set media stream identification tag and media description index for this gatherer.
@@ -225,12 +419,25 @@ func (g *ICEGatherer) GracefulClose() error {
 }
 
 func (g *ICEGatherer) close(shouldGracefullyClose bool) error {
+	// Unused pooled candidates are released with the agent.
+	g.poolLock.Lock()
+	g.pooling = false
+	g.releasing = false
+	g.poolCandidates = nil
+	g.poolLock.Unlock()
+
 	g.lock.Lock()
 	defer g.lock.Unlock()
 
 	if g.agent == nil {
 		return nil
 	}
+	if g.rtcpAgent != nil {
+		if err := g.rtcpAgent.Close(); err != nil {
+			return err
+		}
+		g.rtcpAgent = nil
+	}
 	if shouldGracefullyClose {
 		if err := g.agent.GracefulClose(); err != nil {
 			return err
@@ -268,6 +475,7 @@ func (g *ICEGatherer) GetLocalParameters() (ICEParameters, error) {
 		UsernameFragment: frag,
 		Password:         pwd,
 		ICELite:          false,
+		Renomination:     g.api.settingEngine.candidates.Renomination,
 	}, nil
 }
 
@@ -288,6 +496,14 @@ func (g *ICEGatherer) GetLocalCandidates() ([]ICECandidate, error) {
 		return nil, err
 	}
 
+	if rtcpAgent := g.getRTCPAgent(); rtcpAgent != nil {
+		rtcpCandidates, err := rtcpAgent.GetLocalCandidates()
+		if err != nil {
+			return nil, err
+		}
+		iceCandidates = append(iceCandidates, rtcpCandidates...)
+	}
+
 	sdpMid := ""
 	if mid, ok := g.sdpMid.Load().(string); ok {
 		sdpMid = mid
@@ -316,7 +532,24 @@ func (g *ICEGatherer) State() ICEGathererState {
 
 func (g *ICEGatherer) setState(s ICEGathererState) {
 	atomicStoreICEGathererState(&g.state, s)
+	g.notifyState(s)
+}
 
+// updateState sets the state, or the state of the pool while pooling.
+func (g *ICEGatherer) updateState(s ICEGathererState) {
+	g.poolLock.Lock()
+	if g.pooling {
+		g.poolState = s
+		g.poolLock.Unlock()
+
+		return
+	}
+	g.poolLock.Unlock()
+
+	g.setState(s)
+}
+
+func (g *ICEGatherer) notifyState(s ICEGathererState) {
 	if handler, ok := g.onStateChangeHandler.Load().(func(state ICEGathererState)); ok && handler != nil {
 		handler(s)
 	}
@@ -329,6 +562,13 @@ func (g *ICEGatherer) getAgent() *ice.Agent {
 	return g.agent
 }
 
+func (g *ICEGatherer) getRTCPAgent() *ice.Agent {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	return g.rtcpAgent
+}
+
 func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 	agent := g.getAgent()
 	if agent == nil {
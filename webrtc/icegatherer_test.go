@@ -8,12 +8,17 @@ package webrtc
 
 import (
 	"context"
+	"crypto/tls"
+	"net"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/pion/dtls/v3"
+	"github.com/pion/dtls/v3/pkg/crypto/selfsign"
 	"github.com/pion/ice/v4"
 	"github.com/pion/transport/v3/test"
+	"github.com/pion/turn/v4"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -157,6 +162,73 @@ func TestICEGatherer_AlreadyClosed(t *testing.T) {
 	})
 }
 
+func TestICEGatherer_TURNOverDTLS(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	certificate, err := selfsign.GenerateSelfSigned()
+	assert.NoError(t, err)
+
+	dtlsListener, err := dtls.Listen("udp4", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")}, &dtls.Config{
+		Certificates: []tls.Certificate{certificate},
+	})
+	assert.NoError(t, err)
+
+	server, err := turn.NewServer(turn.ServerConfig{
+		AuthHandler: func(username, realm string, _ net.Addr) ([]byte, bool) {
+			return turn.GenerateAuthKey(username, realm, "password"), true
+		},
+		PacketConnConfigs: []turn.PacketConnConfig{
+			{
+				PacketConn: turn.NewDTLSPacketConn(dtlsListener),
+				RelayAddressGenerator: &turn.RelayAddressGeneratorStatic{
+					RelayAddress: net.ParseIP("127.0.0.1"),
+					Address:      "127.0.0.1",
+				},
+			},
+		},
+		Realm: "pion.ly",
+	})
+	assert.NoError(t, err)
+
+	settingEngine := SettingEngine{}
+	settingEngine.SetICEInsecureSkipVerify(true)
+	settingEngine.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+
+	gatherer, err := NewAPI(WithSettingEngine(settingEngine)).NewICEGatherer(ICEGatherOptions{
+		ICEServers: []ICEServer{{
+			URLs:       []string{"turns:" + dtlsListener.Addr().String() + "?transport=udp"},
+			Username:   "username",
+			Credential: "password",
+		}},
+		ICEGatherPolicy: ICETransportPolicyRelay,
+	})
+	assert.NoError(t, err)
+
+	gatherFinished := make(chan struct{})
+	gatherer.OnLocalCandidate(func(c *ICECandidate) {
+		if c == nil {
+			close(gatherFinished)
+		}
+	})
+	assert.NoError(t, gatherer.Gather())
+	<-gatherFinished
+
+	candidates, err := gatherer.GetLocalCandidates()
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, ICECandidateTypeRelay, candidates[0].Typ)
+	assert.Equal(t, ICEProtocolUDP, candidates[0].Protocol)
+	assert.Equal(t, "127.0.0.1", candidates[0].Address)
+
+	assert.NoError(t, gatherer.Close())
+	assert.NoError(t, server.Close())
+}
+
 func TestNewICEGathererSetMediaStreamIdentification(t *testing.T) { //nolint:cyclop
 	// Limit runtime in case of deadlocks
 	lim := test.TimeOut(time.Second * 20)
@@ -9,4 +9,6 @@ type ICEParameters struct {
 	UsernameFragment string `json:"usernameFragment"`
 	Password         string `json:"password"`
 	ICELite          bool   `json:"iceLite"`
+	// Renomination is set if the agent signaled the "renomination" ice-option.
+	Renomination bool `json:"renomination"`
 }
@@ -7,7 +7,9 @@
 package webrtc
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"strings"
 
 	"github.com/pion/stun/v3"
 	"github.com/pion/webrtc/v4/pkg/rtcerr"
@@ -59,7 +61,16 @@ func (s ICEServer) urls() ([]*stun.URI, error) { //nolint:cyclop
 
 			case ICECredentialTypeOauth:
 				// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11.3.4)
-				if _, ok := s.Credential.(OAuthCredential); !ok {
+				oauth, ok := s.Credential.(OAuthCredential)
+				if !ok {
+					return nil, &rtcerr.InvalidAccessError{Err: ErrTurnCredentials}
+				}
+
+				var err error
+				if url.MACKey, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(oauth.MACKey, "=")); err != nil {
+					return nil, &rtcerr.InvalidAccessError{Err: ErrTurnCredentials}
+				}
+				if url.AccessToken, err = base64.StdEncoding.DecodeString(oauth.AccessToken); err != nil {
 					return nil, &rtcerr.InvalidAccessError{Err: ErrTurnCredentials}
 				}
 
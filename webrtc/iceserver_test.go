@@ -33,6 +33,12 @@ func TestICEServer_validate(t *testing.T) {
 				Credential:     "placeholder",
 				CredentialType: ICECredentialTypePassword,
 			}, true},
+			{ICEServer{
+				URLs:           []string{"turns:turn.example.com?transport=udp"},
+				Username:       "unittest",
+				Credential:     "placeholder",
+				CredentialType: ICECredentialTypePassword,
+			}, true},
 			{ICEServer{
 				URLs:     []string{"turn:192.158.29.39?transport=udp"},
 				Username: "unittest",
@@ -98,6 +104,27 @@ func TestICEServer_validate(t *testing.T) {
 			)
 		}
 	})
+	t.Run("OAuth", func(t *testing.T) {
+		iceServer := ICEServer{
+			URLs:     []string{"turn:192.158.29.39?transport=udp"},
+			Username: "kid",
+			Credential: OAuthCredential{
+				MACKey:      "WmtzanB3ZW9peFhtdm42NzUzNG0=",
+				AccessToken: "AAwg3kPHWPfvk9bDFL936wYvkoctMADzQ5VhNDgeMR3+ZlZ35byg972fW8QjpEl7bx91YLBPFsIhsxloWcXPhA==",
+			},
+			CredentialType: ICECredentialTypeOauth,
+		}
+		urls, err := iceServer.urls()
+		assert.NoError(t, err)
+		assert.Len(t, urls, 1)
+		assert.Equal(t, "kid", urls[0].Username)
+		assert.Equal(t, []byte("ZksjpweoixXmvn67534m"), urls[0].MACKey)
+		assert.Len(t, urls[0].AccessToken, 64)
+
+		iceServer.Credential = OAuthCredential{MACKey: "!", AccessToken: "AAwg"}
+		_, err = iceServer.urls()
+		assert.EqualError(t, err, (&rtcerr.InvalidAccessError{Err: ErrTurnCredentials}).Error())
+	})
 	t.Run("JsonFailure", func(t *testing.T) {
 		//nolint:lll
 		testCases := [][]byte{
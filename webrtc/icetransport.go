@@ -9,6 +9,7 @@ package webrtc
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -36,6 +37,10 @@ type ICETransport struct {
 	conn     *ice.Conn
 	mux      *mux.Mux
 
+	// rtcpConn and rtcpMux carry RTCP when it is not multiplexed with RTP.
+	rtcpConn *ice.Conn
+	rtcpMux  *mux.Mux
+
 	ctxCancel func()
 
 	loggerFactory logging.LoggerFactory
@@ -69,6 +74,27 @@ func (t *ICETransport) GetSelectedCandidatePair() (*ICECandidatePair, error) {
 	return NewICECandidatePair(&local, &remote), nil
 }
 
+// GetEventLog returns the ICE events recorded by the agents of the transport,
+// oldest first. See SettingEngine.SetICEEventLogSize.
+func (t *ICETransport) GetEventLog() []ice.Event {
+	var events []ice.Event
+	if agent := t.gatherer.getAgent(); agent != nil {
+		events = append(events, agent.GetEventLog()...)
+	}
+	if rtcpAgent := t.gatherer.getRTCPAgent(); rtcpAgent != nil {
+		events = append(events, rtcpAgent.GetEventLog()...)
+	}
+	sortICEEvents(events)
+
+	return events
+}
+
+func sortICEEvents(events []ice.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+}
+
 // GetSelectedCandidatePairStats returns the selected candidate pair stats on which packets are sent
 // if there is no selected pair empty stats, false is returned to indicate stats not available.
 func (t *ICETransport) GetSelectedCandidatePairStats() (ICECandidatePairStats, bool) {
@@ -129,6 +155,17 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 		return err
 	}
 
+	// Renomination is used if both agents signaled the renomination ice-option.
+	renomination := params.Renomination && t.gatherer.api.settingEngine.candidates.Renomination
+	if err := agent.SetRenomination(renomination); err != nil {
+		return err
+	}
+	if rtcpAgent := t.gatherer.getRTCPAgent(); rtcpAgent != nil {
+		if err := rtcpAgent.SetRenomination(renomination); err != nil {
+			return err
+		}
+	}
+
 	if role == nil {
 		controlled := ICERoleControlled
 		role = &controlled
@@ -142,45 +179,70 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	// added so that the agent can complete a connection
 	t.lock.Unlock()
 
-	var iceConn *ice.Conn
-	var err error
-	switch *role {
-	case ICERoleControlling:
-		iceConn, err = agent.Dial(ctx,
-			params.UsernameFragment,
-			params.Password)
-
-	case ICERoleControlled:
-		iceConn, err = agent.Accept(ctx,
-			params.UsernameFragment,
-			params.Password)
-
-	default:
-		err = errICERoleUnknown
+	// The RTCP component connects alongside the RTP one.
+	var rtcpConn *ice.Conn
+	var rtcpErr error
+	rtcpDone := make(chan struct{})
+	if rtcpAgent := t.gatherer.getRTCPAgent(); rtcpAgent != nil {
+		go func() {
+			defer close(rtcpDone)
+			rtcpConn, rtcpErr = connectICEAgent(ctx, rtcpAgent, params, *role)
+		}()
+	} else {
+		close(rtcpDone)
 	}
 
+	iceConn, err := connectICEAgent(ctx, agent, params, *role)
+	<-rtcpDone
+
 	// Reacquire the lock to set the connection/mux
 	t.lock.Lock()
 	if err != nil {
 		return err
 	}
+	if rtcpErr != nil {
+		return rtcpErr
+	}
 
 	if t.State() == ICETransportStateClosed {
 		return errICETransportClosed
 	}
 
 	t.conn = iceConn
-
-	config := mux.Config{
-		Conn:          t.conn,
-		BufferSize:    int(t.gatherer.api.settingEngine.getReceiveMTU()), //nolint:gosec // G115
-		LoggerFactory: t.loggerFactory,
+	t.mux = t.newMux(t.conn)
+	if rtcpConn != nil {
+		t.rtcpConn = rtcpConn
+		t.rtcpMux = t.newMux(t.rtcpConn)
 	}
-	t.mux = mux.NewMux(config)
 
 	return nil
 }
 
+func connectICEAgent(ctx context.Context, agent *ice.Agent, params ICEParameters, role ICERole) (*ice.Conn, error) {
+	switch role {
+	case ICERoleControlling:
+		return agent.Dial(ctx,
+			params.UsernameFragment,
+			params.Password)
+
+	case ICERoleControlled:
+		return agent.Accept(ctx,
+			params.UsernameFragment,
+			params.Password)
+
+	default:
+		return nil, errICERoleUnknown
+	}
+}
+
+func (t *ICETransport) newMux(conn *ice.Conn) *mux.Mux {
+	return mux.NewMux(mux.Config{
+		Conn:          conn,
+		BufferSize:    int(t.gatherer.api.settingEngine.getReceiveMTU()), //nolint:gosec // G115
+		LoggerFactory: t.loggerFactory,
+	})
+}
+
 // restart is not exposed currently because ORTC has users create a whole new ICETransport
 // so for now lets keep it private so we don't cause ORTC users to depend on non-standard APIs.
 func (t *ICETransport) restart() error {
@@ -199,6 +261,16 @@ func (t *ICETransport) restart() error {
 		return err
 	}
 
+	if rtcpAgent := t.gatherer.getRTCPAgent(); rtcpAgent != nil {
+		ufrag, pwd, err := agent.GetLocalUserCredentials()
+		if err != nil {
+			return err
+		}
+		if err := rtcpAgent.Restart(ufrag, pwd); err != nil {
+			return err
+		}
+	}
+
 	return t.gatherer.Gather()
 }
 
@@ -224,6 +296,7 @@ func (t *ICETransport) stop(shouldGracefullyClose bool) error {
 
 	// mux and gatherer can only be set when ICETransport.State != Closed.
 	mux := t.mux
+	rtcpMux := t.rtcpMux
 	gatherer := t.gatherer
 	t.lock.Unlock()
 
@@ -234,6 +307,9 @@ func (t *ICETransport) stop(shouldGracefullyClose bool) error {
 			// mux's net.Conn Close so we call it earlier here.
 			closeErrs = append(closeErrs, gatherer.GracefulClose())
 		}
+		if rtcpMux != nil {
+			closeErrs = append(closeErrs, rtcpMux.Close())
+		}
 		closeErrs = append(closeErrs, mux.Close())
 
 		return util.FlattenErrs(closeErrs)
@@ -303,7 +379,7 @@ func (t *ICETransport) SetRemoteCandidates(remoteCandidates []ICECandidate) erro
 			return err
 		}
 
-		if err = agent.AddRemoteCandidate(i); err != nil {
+		if err = t.agentForCandidate(agent, i).AddRemoteCandidate(i); err != nil {
 			return err
 		}
 	}
@@ -336,7 +412,27 @@ func (t *ICETransport) AddRemoteCandidate(remoteCandidate *ICECandidate) error {
 		return fmt.Errorf("%w: unable to add remote candidates", errICEAgentNotExist)
 	}
 
-	return agent.AddRemoteCandidate(candidate)
+	// The end of candidates applies to both components.
+	if rtcpAgent := t.gatherer.getRTCPAgent(); candidate == nil && rtcpAgent != nil {
+		if err = rtcpAgent.AddRemoteCandidate(nil); err != nil {
+			return err
+		}
+	}
+
+	return t.agentForCandidate(agent, candidate).AddRemoteCandidate(candidate)
+}
+
+// agentForCandidate returns the agent of the candidate's component. Without
+// an RTCP agent every candidate is handed to the RTP agent.
+func (t *ICETransport) agentForCandidate(agent *ice.Agent, candidate ice.Candidate) *ice.Agent {
+	if candidate == nil || candidate.Component() != ice.ComponentRTCP {
+		return agent
+	}
+	if rtcpAgent := t.gatherer.getRTCPAgent(); rtcpAgent != nil {
+		return rtcpAgent
+	}
+
+	return agent
 }
 
 // State returns the current ice transport state.
@@ -391,6 +487,19 @@ func (t *ICETransport) newEndpoint(f mux.MatchFunc) *mux.Endpoint {
 	return t.mux.NewEndpoint(f)
 }
 
+// newRTCPEndpoint returns an endpoint on the RTCP component, or on the RTP
+// component when RTCP is multiplexed.
+func (t *ICETransport) newRTCPEndpoint(f mux.MatchFunc) *mux.Endpoint {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.rtcpMux != nil {
+		return t.rtcpMux.NewEndpoint(f)
+	}
+
+	return t.mux.NewEndpoint(f)
+}
+
 func (t *ICETransport) ensureGatherer() error {
 	if t.gatherer == nil {
 		return errICEGathererNotStarted
@@ -450,5 +559,11 @@ func (t *ICETransport) setRemoteCredentials(newUfrag, newPwd string) error {
 		return fmt.Errorf("%w: unable to SetRemoteCredentials", errICEAgentNotExist)
 	}
 
+	if rtcpAgent := t.gatherer.getRTCPAgent(); rtcpAgent != nil {
+		if err := rtcpAgent.SetRemoteCredentials(newUfrag, newPwd); err != nil {
+			return err
+		}
+	}
+
 	return agent.SetRemoteCredentials(newUfrag, newPwd)
 }
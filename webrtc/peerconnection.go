@@ -61,6 +61,7 @@ type PeerConnection struct {
 	isGracefulCloseDone                     chan struct{}
 	isNegotiationNeeded                     *atomicBool
 	updateNegotiationNeededFlagOnEmptyChain *atomicBool
+	rtcpMuxNegotiated                       *atomicBool
 
 	lastOffer  string
 	lastAnswer string
@@ -80,12 +81,28 @@ type PeerConnection struct {
 	onTrackHandler                    func(*TrackRemote, *RTPReceiver)
 	onDataChannelHandler              func(*DataChannel)
 	onNegotiationNeededHandler        atomic.Value // func()
+	onICECandidateHandler             atomic.Value // func(*ICECandidate)
+	onICEGatheringStateChangeHandler  atomic.Value // func(ICEGatheringState)
+	onGatheringCompleteHandler        atomic.Value // func()
 
 	iceGatherer   *ICEGatherer
 	iceTransport  *ICETransport
 	dtlsTransport *DTLSTransport
 	sctpTransport *SCTPTransport
 
+	// unbundledTransports carry the media sections that are not bundled, see
+	// BundlePolicy. The slice is replaced, never modified.
+	unbundledTransports atomic.Value // []*unbundledTransport
+
+	// rtcpTransports maps the SSRC of a stream to the transport it is carried
+	// by, RTCP about the stream is sent on it.
+	rtcpTransportsLock sync.RWMutex
+	rtcpTransports     map[SSRC]*DTLSTransport
+
+	iceConnectionStateLock sync.Mutex
+	iceGatheringLock       sync.Mutex
+	iceGatheringComplete   bool
+
 	// A reference to the associated API state used by this connection
 	api *API
 	log logging.LeveledLogger
@@ -129,6 +146,7 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		isGracefulCloseDone:                     make(chan struct{}),
 		isNegotiationNeeded:                     &atomicBool{},
 		updateNegotiationNeededFlagOnEmptyChain: &atomicBool{},
+		rtcpMuxNegotiated:                       &atomicBool{},
 		lastOffer:                               "",
 		lastAnswer:                              "",
 		greaterMid:                              -1,
@@ -166,9 +184,10 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 	if err != nil {
 		return nil, err
 	}
+	pc.attachICEGatherer(pc.iceGatherer)
 
 	// Create the ice transport
-	iceTransport := pc.createICETransport()
+	iceTransport := pc.createICETransport(pc.iceGatherer)
 	pc.iceTransport = iceTransport
 
 	// Create the DTLS transport
@@ -193,6 +212,12 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 
 	pc.interceptorRTCPWriter = pc.api.interceptor.BindRTCPWriter(interceptor.RTCPWriterFunc(pc.writeRTCP))
 
+	if pc.configuration.ICECandidatePoolSize != 0 {
+		if err = pc.iceGatherer.gatherPool(); err != nil {
+			return nil, util.FlattenErrs([]error{err, pc.Close()})
+		}
+	}
+
 	return pc, nil
 }
 
@@ -235,6 +260,13 @@ func (pc *PeerConnection) initConfiguration(configuration Configuration) error {
 		pc.configuration.RTCPMuxPolicy = configuration.RTCPMuxPolicy
 	}
 
+	// The ICE muxes tell the agents apart by their ufrag, which the RTP and
+	// RTCP components share.
+	if pc.configuration.RTCPMuxPolicy == RTCPMuxPolicyNegotiate &&
+		(pc.api.settingEngine.iceTCPMux != nil || pc.api.settingEngine.iceUDPMux != nil) {
+		return &rtcerr.InvalidAccessError{Err: ErrRTCPMuxPolicyNegotiateWithICEMux}
+	}
+
 	if configuration.ICECandidatePoolSize != 0 {
 		pc.configuration.ICECandidatePoolSize = configuration.ICECandidatePoolSize
 	}
@@ -439,23 +471,71 @@ func (pc *PeerConnection) checkNegotiationNeeded() bool { //nolint:gocognit,cycl
 // Take note that the handler will be called with a nil pointer when
 // gathering is finished.
 func (pc *PeerConnection) OnICECandidate(f func(*ICECandidate)) {
-	pc.iceGatherer.OnLocalCandidate(f)
+	pc.onICECandidateHandler.Store(f)
+}
+
+func (pc *PeerConnection) onICECandidate(c *ICECandidate) {
+	if handler, ok := pc.onICECandidateHandler.Load().(func(*ICECandidate)); ok && handler != nil {
+		handler(c)
+	}
 }
 
 // OnICEGatheringStateChange sets an event handler which is invoked when the
 // ICE candidate gathering state has changed.
 func (pc *PeerConnection) OnICEGatheringStateChange(f func(ICEGatheringState)) {
-	pc.iceGatherer.OnStateChange(
-		func(gathererState ICEGathererState) {
-			switch gathererState {
-			case ICEGathererStateGathering:
-				f(ICEGatheringStateGathering)
-			case ICEGathererStateComplete:
-				f(ICEGatheringStateComplete)
-			default:
-				// Other states ignored
+	pc.onICEGatheringStateChangeHandler.Store(f)
+}
+
+func (pc *PeerConnection) onICEGatheringStateChange(state ICEGatheringState) {
+	if handler, ok := pc.onICEGatheringStateChangeHandler.Load().(func(ICEGatheringState)); ok && handler != nil {
+		handler(state)
+	}
+}
+
+// attachICEGatherer forwards the events of one of the ICEGatherers of the
+// PeerConnection. Gathering is complete once all of them completed, only then
+// the nil candidate is signaled.
+func (pc *PeerConnection) attachICEGatherer(gatherer *ICEGatherer) {
+	gatherer.OnLocalCandidate(func(c *ICECandidate) {
+		if c != nil {
+			pc.onICECandidate(c)
+		}
+	})
+	gatherer.OnStateChange(func(state ICEGathererState) {
+		switch state {
+		case ICEGathererStateGathering:
+			pc.iceGatheringLock.Lock()
+			pc.iceGatheringComplete = false
+			pc.iceGatheringLock.Unlock()
+
+			if gatherer == pc.iceGatherer {
+				pc.onICEGatheringStateChange(ICEGatheringStateGathering)
 			}
-		})
+		case ICEGathererStateComplete:
+			pc.checkICEGatheringComplete()
+		default:
+			// Other states ignored
+		}
+	})
+}
+
+// checkICEGatheringComplete signals the end of gathering if all ICEGatherers
+// completed.
+func (pc *PeerConnection) checkICEGatheringComplete() {
+	pc.iceGatheringLock.Lock()
+	if pc.iceGatheringComplete || pc.ICEGatheringState() != ICEGatheringStateComplete {
+		pc.iceGatheringLock.Unlock()
+
+		return
+	}
+	pc.iceGatheringComplete = true
+	pc.iceGatheringLock.Unlock()
+
+	pc.onICEGatheringStateChange(ICEGatheringStateComplete)
+	if handler, ok := pc.onGatheringCompleteHandler.Load().(func()); ok && handler != nil {
+		handler()
+	}
+	pc.onICECandidate(nil)
 }
 
 // OnTrack sets an event handler which is called when remote track
@@ -561,6 +641,12 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 			return &rtcerr.InvalidModificationError{Err: ErrModifyingICECandidatePoolSize}
 		}
 		pc.configuration.ICECandidatePoolSize = configuration.ICECandidatePoolSize
+
+		if pc.LocalDescription() == nil {
+			if err := pc.iceGatherer.gatherPool(); err != nil {
+				return err
+			}
+		}
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #8)
@@ -630,6 +716,11 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 		if err := pc.iceTransport.restart(); err != nil {
 			return SessionDescription{}, err
 		}
+		for _, t := range pc.getUnbundledTransports() {
+			if err := t.iceTransport.restart(); err != nil {
+				return SessionDescription{}, err
+			}
+		}
 	}
 
 	var (
@@ -747,10 +838,33 @@ func (pc *PeerConnection) createICEGatherer() (*ICEGatherer, error) {
 	if err != nil {
 		return nil, err
 	}
+	g.gatherRTCP = pc.configuration.RTCPMuxPolicy == RTCPMuxPolicyNegotiate && !pc.rtcpMuxNegotiated.get()
 
 	return g, nil
 }
 
+// negotiateRTCPMux drops the RTCP component of the transports once the remote
+// description multiplexes RTP and RTCP. With RTCPMuxPolicyRequire there is no
+// RTCP component to begin with.
+func (pc *PeerConnection) negotiateRTCPMux(remote *sdp.SessionDescription) error {
+	if pc.configuration.RTCPMuxPolicy != RTCPMuxPolicyNegotiate || !haveRTCPMux(remote) {
+		return nil
+	}
+	pc.rtcpMuxNegotiated.set(true)
+
+	gatherers := []*ICEGatherer{pc.iceGatherer}
+	for _, t := range pc.getUnbundledTransports() {
+		gatherers = append(gatherers, t.iceGatherer)
+	}
+
+	var errs []error
+	for _, g := range gatherers {
+		errs = append(errs, g.dropRTCP())
+	}
+
+	return util.FlattenErrs(errs)
+}
+
 // Update the PeerConnectionState given the state of relevant transports
 // https://www.w3.org/TR/webrtc/#rtcpeerconnectionstate-enum
 //
@@ -801,37 +915,63 @@ func (pc *PeerConnection) updateConnectionState(
 	pc.onConnectionStateChange(connectionState)
 }
 
-func (pc *PeerConnection) createICETransport() *ICETransport {
-	transport := pc.api.NewICETransport(pc.iceGatherer)
+func (pc *PeerConnection) createICETransport(gatherer *ICEGatherer) *ICETransport {
+	transport := pc.api.NewICETransport(gatherer)
 	transport.internalOnConnectionStateChangeHandler.Store(func(state ICETransportState) {
-		var cs ICEConnectionState
-		switch state {
-		case ICETransportStateNew:
-			cs = ICEConnectionStateNew
-		case ICETransportStateChecking:
-			cs = ICEConnectionStateChecking
-		case ICETransportStateConnected:
-			cs = ICEConnectionStateConnected
-		case ICETransportStateCompleted:
-			cs = ICEConnectionStateCompleted
-		case ICETransportStateFailed:
-			cs = ICEConnectionStateFailed
-		case ICETransportStateDisconnected:
-			cs = ICEConnectionStateDisconnected
-		case ICETransportStateClosed:
-			cs = ICEConnectionStateClosed
-		default:
+		cs, ok := iceConnectionStateOfTransport(state)
+		if !ok {
 			pc.log.Warnf("OnConnectionStateChange: unhandled ICE state: %s", state)
 
 			return
 		}
-		pc.onICEConnectionStateChange(cs)
-		pc.updateConnectionState(cs, pc.dtlsTransport.State())
+		pc.updateICEConnectionState(cs)
 	})
 
 	return transport
 }
 
+// updateICEConnectionState updates the ICEConnectionState after the state of
+// one of the ICETransports changed to cs.
+func (pc *PeerConnection) updateICEConnectionState(cs ICEConnectionState) {
+	transports := pc.getUnbundledTransports()
+	if len(transports) == 0 {
+		pc.onICEConnectionStateChange(cs)
+		pc.updateConnectionState(cs, pc.dtlsTransportState())
+
+		return
+	}
+
+	pc.iceConnectionStateLock.Lock()
+	iceTransports := []*ICETransport{pc.iceTransport}
+	for _, t := range transports {
+		iceTransports = append(iceTransports, t.iceTransport)
+	}
+	states := make([]ICEConnectionState, 0, len(iceTransports))
+	for _, t := range iceTransports {
+		if state, ok := iceConnectionStateOfTransport(t.State()); ok {
+			states = append(states, state)
+		}
+	}
+	cs = aggregateICEConnectionState(states)
+	if cs != pc.ICEConnectionState() {
+		pc.onICEConnectionStateChange(cs)
+	}
+	pc.iceConnectionStateLock.Unlock()
+
+	pc.updateConnectionState(cs, pc.dtlsTransportState())
+}
+
+// dtlsTransportState returns the state of the DTLSTransports that determines
+// the PeerConnectionState.
+func (pc *PeerConnection) dtlsTransportState() DTLSTransportState {
+	states := []DTLSTransportState{pc.dtlsTransport.State()}
+	for _, t := range pc.getUnbundledTransports() {
+		states = append(states, t.dtlsTransport.State())
+	}
+
+	return aggregateDTLSTransportState(states)
+}
+
 // CreateAnswer starts the PeerConnection and generates the localDescription.
 //
 //nolint:cyclop
@@ -1167,7 +1307,7 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
         // fmt.Printf("  ICE候选数:%d\n", len(pc.iceGatherer.GetLocalCandidates()))
     }
 
-    return nil
+    return pc.gatherUnbundledTransports(desc.parsed)
 }
 
 // 辅助函数：获取Transceiver的MID列表
@@ -1405,6 +1545,10 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	}
 	fmt.Printf("【ICE凭证】成功提取 ICE ufrag=%q password=***%s (长度:%d)\n", 
 		iceDetails.Ufrag, iceDetails.Password[len(iceDetails.Password)-3:], len(iceDetails.Password))
+
+	if err = pc.negotiateRTCPMux(desc.parsed); err != nil {
+		return err
+	}
 	
 	if isRenegotiation && pc.iceTransport.haveRemoteCredentialsChange(iceDetails.Ufrag, iceDetails.Password) {
 		fmt.Println("\n【ICE重启】检测到ICE凭证变更，触发重启流程")
@@ -1444,6 +1588,17 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 		}
 	}
 	fmt.Printf(" 成功添加%d个远程候选\n", len(iceDetails.Candidates))
+
+	if !isRenegotiation {
+		if weOffer {
+			pc.negotiateOfferTransports(desc.parsed)
+		} else if err = pc.createAnswerTransports(desc.parsed); err != nil {
+			return err
+		}
+	}
+	if err = pc.setRemoteUnbundledTransports(desc.parsed, isRenegotiation, weOffer); err != nil {
+		return err
+	}
 	
 	currentTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
 	fmt.Printf("\n【Transceiver准备】当前活跃Transceiver数量: %d\n", len(currentTransceivers))
@@ -1532,6 +1687,7 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 			iceDetails.Password,
 			fingerprint,
 			fingerprintHash,
+			isICEOptionSet(desc.parsed, ice.RenominationOption),
 		)
 		fmt.Println("  底层传输已启动")
 	
@@ -1567,6 +1723,9 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 
 		return
 	}
+	for _, track := range receiver.Tracks() {
+		pc.setRTCPTransport(receiver.Transport(), track.SSRC(), track.RtxSSRC())
+	}
 
 	for _, track := range receiver.Tracks() {
 		if track.SSRC() == 0 || track.RID() != "" {
@@ -1736,7 +1895,7 @@ func (pc *PeerConnection) configureRTPReceivers(
 				continue
 			}
 
-			receiver, err := pc.api.NewRTPReceiver(receiver.kind, pc.dtlsTransport)
+			receiver, err := pc.api.NewRTPReceiver(receiver.kind, pc.dtlsTransportForMid(transceiver.Mid()))
 			if err != nil {
 				pc.log.Warnf("Failed to create new RtpReceiver: %s", err)
 
@@ -1815,6 +1974,7 @@ func (pc *PeerConnection) startRTPReceivers(remoteDesc *SessionDescription, curr
 
 // startRTPSenders starts all outbound RTP streams.
 func (pc *PeerConnection) startRTPSenders(currentTransceivers []*RTPTransceiver) error {
+	pc.bindRTPTransceivers(currentTransceivers)
 	for _, transceiver := range currentTransceivers {
 		if sender := transceiver.Sender(); sender != nil && sender.isNegotiated() && !sender.hasSent() {
 			err := sender.Send(sender.GetParameters())
@@ -1828,8 +1988,9 @@ func (pc *PeerConnection) startRTPSenders(currentTransceivers []*RTPTransceiver)
 }
 
 // Start SCTP subsystem.
-func (pc *PeerConnection) startSCTP(maxMessageSize uint32) {
+func (pc *PeerConnection) startSCTP(dtlsTransport *DTLSTransport, maxMessageSize uint32) {
 	// Start sctp
+	pc.sctpTransport.setTransport(dtlsTransport)
 	if err := pc.sctpTransport.Start(SCTPCapabilities{
 		MaxMessageSize: maxMessageSize,
 	}); err != nil {
@@ -1903,8 +2064,8 @@ func (pc *PeerConnection) handleUndeclaredSSRC(
 
 // Chrome sends probing traffic on SSRC 0. This reads the packets to ensure that we properly
 // generate TWCC reports for it. Since this isn't actually media we don't pass this to the user.
-func (pc *PeerConnection) handleNonMediaBandwidthProbe() {
-	nonMediaBandwidthProbe, err := pc.api.NewRTPReceiver(RTPCodecTypeVideo, pc.dtlsTransport)
+func (pc *PeerConnection) handleNonMediaBandwidthProbe(transport *DTLSTransport) {
+	nonMediaBandwidthProbe, err := pc.api.NewRTPReceiver(RTPCodecTypeVideo, transport)
 	if err != nil {
 		pc.log.Errorf("handleNonMediaBandwidthProbe failed to create RTPReceiver: %v", err)
 
@@ -1930,11 +2091,16 @@ func (pc *PeerConnection) handleNonMediaBandwidthProbe() {
 	}
 }
 
-func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) error { //nolint:gocognit,cyclop
+func (pc *PeerConnection) handleIncomingSSRC( //nolint:gocognit,cyclop
+	transport *DTLSTransport,
+	rtpStream io.Reader,
+	ssrc SSRC,
+) error {
 	remoteDescription := pc.RemoteDescription()
 	if remoteDescription == nil {
 		return errPeerConnRemoteDescriptionNil
 	}
+	pc.setRTCPTransport(transport, ssrc)
 
 	// If a SSRC already exists in the RemoteDescription don't perform heuristics upon it
 	for _, track := range trackDetailsFromSDP(pc.log, remoteDescription.parsed) {
@@ -1997,7 +2163,7 @@ func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) err
 		params.Codecs[0].RTPCodecCapability,
 		params.HeaderExtensions,
 	)
-	readStream, interceptor, rtcpReadStream, rtcpInterceptor, err := pc.dtlsTransport.streamsForSSRC(ssrc, *streamInfo)
+	readStream, interceptor, rtcpReadStream, rtcpInterceptor, err := transport.streamsForSSRC(ssrc, *streamInfo)
 	if err != nil {
 		return err
 	}
@@ -2067,22 +2233,22 @@ func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) err
 }
 
 // undeclaredMediaProcessor handles RTP/RTCP packets that don't match any a:ssrc lines.
-func (pc *PeerConnection) undeclaredMediaProcessor() {
-	go pc.undeclaredRTPMediaProcessor()
-	go pc.undeclaredRTCPMediaProcessor()
+func (pc *PeerConnection) undeclaredMediaProcessor(transport *DTLSTransport) {
+	go pc.undeclaredRTPMediaProcessor(transport)
+	go pc.undeclaredRTCPMediaProcessor(transport)
 }
 
-func (pc *PeerConnection) undeclaredRTPMediaProcessor() { //nolint:cyclop
+func (pc *PeerConnection) undeclaredRTPMediaProcessor(transport *DTLSTransport) { //nolint:cyclop
 	var simulcastRoutineCount uint64
 	for {
-		srtpSession, err := pc.dtlsTransport.getSRTPSession()
+		srtpSession, err := transport.getSRTPSession()
 		if err != nil {
 			pc.log.Warnf("undeclaredMediaProcessor failed to open SrtpSession: %v", err)
 
 			return
 		}
 
-		srtcpSession, err := pc.dtlsTransport.getSRTCPSession()
+		srtcpSession, err := transport.getSRTCPSession()
 		if err != nil {
 			pc.log.Warnf("undeclaredMediaProcessor failed to open SrtcpSession: %v", err)
 
@@ -2115,10 +2281,10 @@ func (pc *PeerConnection) undeclaredRTPMediaProcessor() { //nolint:cyclop
 			continue
 		}
 
-		pc.dtlsTransport.storeSimulcastStream(srtpReadStream, srtcpReadStream)
+		transport.storeSimulcastStream(srtpReadStream, srtcpReadStream)
 
 		if ssrc == 0 {
-			go pc.handleNonMediaBandwidthProbe()
+			go pc.handleNonMediaBandwidthProbe(transport)
 
 			continue
 		}
@@ -2131,7 +2297,7 @@ func (pc *PeerConnection) undeclaredRTPMediaProcessor() { //nolint:cyclop
 		}
 
 		go func(rtpStream io.Reader, ssrc SSRC) {
-			if err := pc.handleIncomingSSRC(rtpStream, ssrc); err != nil {
+			if err := pc.handleIncomingSSRC(transport, rtpStream, ssrc); err != nil {
 				pc.log.Errorf(incomingUnhandledRTPSsrc, ssrc, err)
 			}
 			atomic.AddUint64(&simulcastRoutineCount, ^uint64(0))
@@ -2139,7 +2305,7 @@ func (pc *PeerConnection) undeclaredRTPMediaProcessor() { //nolint:cyclop
 	}
 }
 
-func (pc *PeerConnection) undeclaredRTCPMediaProcessor() {
+func (pc *PeerConnection) undeclaredRTCPMediaProcessor(transport *DTLSTransport) {
 	var unhandledStreams []*srtp.ReadStreamSRTCP
 	defer func() {
 		for _, s := range unhandledStreams {
@@ -2147,7 +2313,7 @@ func (pc *PeerConnection) undeclaredRTCPMediaProcessor() {
 		}
 	}()
 	for {
-		srtcpSession, err := pc.dtlsTransport.getSRTCPSession()
+		srtcpSession, err := transport.getSRTCPSession()
 		if err != nil {
 			pc.log.Warnf("undeclaredMediaProcessor failed to open SrtcpSession: %v", err)
 
@@ -2209,7 +2375,23 @@ func (pc *PeerConnection) AddICECandidate(candidate ICECandidateInit) error {
 		iceCandidate = &c
 	}
 
-	return pc.iceTransport.AddRemoteCandidate(iceCandidate)
+	return pc.iceTransportForCandidate(candidate).AddRemoteCandidate(iceCandidate)
+}
+
+// iceTransportForCandidate returns the ICETransport of the media section a
+// remote candidate belongs to.
+func (pc *PeerConnection) iceTransportForCandidate(candidate ICECandidateInit) *ICETransport {
+	if candidate.SDPMid != nil && *candidate.SDPMid != "" {
+		return pc.iceTransportForMid(*candidate.SDPMid)
+	}
+
+	if remoteDesc := pc.RemoteDescription(); candidate.SDPMLineIndex != nil && remoteDesc != nil {
+		if index := int(*candidate.SDPMLineIndex); index < len(remoteDesc.parsed.MediaDescriptions) {
+			return pc.iceTransportForMid(getMidValue(remoteDesc.parsed.MediaDescriptions[index]))
+		}
+	}
+
+	return pc.iceTransport
 }
 
 // ICEConnectionState returns the ICE connection state of the
@@ -2634,7 +2816,34 @@ func (pc *PeerConnection) WriteRTCP(pkts []rtcp.Packet) error {
 }
 
 func (pc *PeerConnection) writeRTCP(pkts []rtcp.Packet, _ interceptor.Attributes) (int, error) {
-	return pc.dtlsTransport.WriteRTCP(pkts)
+	if len(pc.getUnbundledTransports()) == 0 {
+		return pc.dtlsTransport.WriteRTCP(pkts)
+	}
+
+	// Packets are sent on the transport of the stream they are about
+	var (
+		transports []*DTLSTransport
+		packets    = map[*DTLSTransport][]rtcp.Packet{}
+	)
+	for _, pkt := range pkts {
+		for _, transport := range pc.dtlsTransportsForRTCP(pkt) {
+			if _, ok := packets[transport]; !ok {
+				transports = append(transports, transport)
+			}
+			packets[transport] = append(packets[transport], pkt)
+		}
+	}
+
+	written := 0
+	for _, transport := range transports {
+		n, err := transport.WriteRTCP(packets[transport])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
 }
 
 // Close ends the PeerConnection.
@@ -2710,6 +2919,9 @@ func (pc *PeerConnection) close(shouldGracefullyClose bool) error { //nolint:cyc
 		if pc.iceTransport != nil {
 			gracefulCloseErrors = append(gracefulCloseErrors, pc.iceTransport.GracefulStop())
 		}
+		for _, t := range pc.getUnbundledTransports() {
+			gracefulCloseErrors = append(gracefulCloseErrors, t.iceTransport.GracefulStop())
+		}
 
 		pc.ops.GracefulClose()
 
@@ -2753,15 +2965,21 @@ func (pc *PeerConnection) close(shouldGracefullyClose bool) error { //nolint:cyc
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #7)
 	closeErrs = append(closeErrs, pc.dtlsTransport.Stop()) //nolint:makezero // todo fix
+	for _, t := range pc.getUnbundledTransports() {
+		closeErrs = append(closeErrs, t.dtlsTransport.Stop()) //nolint:makezero // todo fix
+	}
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #8, #9, #10)
 	if pc.iceTransport != nil && !shouldGracefullyClose {
 		// we will stop gracefully in doGracefulCloseOps
 		closeErrs = append(closeErrs, pc.iceTransport.Stop()) //nolint:makezero // todo fix
+		for _, t := range pc.getUnbundledTransports() {
+			closeErrs = append(closeErrs, t.iceTransport.Stop()) //nolint:makezero // todo fix
+		}
 	}
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #11)
-	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
+	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransportState())
 
 	closeErrs = append(closeErrs, doGracefulCloseOps()...) //nolint:makezero // todo fix
 
@@ -2775,6 +2993,7 @@ func (pc *PeerConnection) close(shouldGracefullyClose bool) error { //nolint:cyc
 // and fires onNegotiationNeeded;
 // caller of this method should hold `pc.mu` lock.
 func (pc *PeerConnection) addRTPTransceiver(t *RTPTransceiver) {
+	t.onStopped.Store(pc.deleteStreamRTCPTransports)
 	pc.rtpTransceivers = append(pc.rtpTransceivers, t)
 	pc.onNegotiationNeeded()
 }
@@ -2789,9 +3008,9 @@ func (pc *PeerConnection) CurrentLocalDescription() *SessionDescription {
 
 	localDescription := pc.currentLocalDescription
 	iceGather := pc.iceGatherer
-	iceGatheringState := pc.ICEGatheringState()
+	iceGatheringState := iceGatheringStateOf(iceGather)
 
-	return populateLocalCandidates(localDescription, iceGather, iceGatheringState)
+	return pc.populateUnbundledCandidates(populateLocalCandidates(localDescription, iceGather, iceGatheringState))
 }
 
 // PendingLocalDescription represents a local description that is in the
@@ -2804,9 +3023,9 @@ func (pc *PeerConnection) PendingLocalDescription() *SessionDescription {
 
 	localDescription := pc.pendingLocalDescription
 	iceGather := pc.iceGatherer
-	iceGatheringState := pc.ICEGatheringState()
+	iceGatheringState := iceGatheringStateOf(iceGather)
 
-	return populateLocalCandidates(localDescription, iceGather, iceGatheringState)
+	return pc.populateUnbundledCandidates(populateLocalCandidates(localDescription, iceGather, iceGatheringState))
 }
 
 // CurrentRemoteDescription represents the last remote description that was
@@ -2845,14 +3064,12 @@ func (pc *PeerConnection) ICEGatheringState() ICEGatheringState {
 		return ICEGatheringStateNew
 	}
 
-	switch pc.iceGatherer.State() {
-	case ICEGathererStateNew:
-		return ICEGatheringStateNew
-	case ICEGathererStateGathering:
-		return ICEGatheringStateGathering
-	default:
-		return ICEGatheringStateComplete
+	states := []ICEGatheringState{iceGatheringStateOf(pc.iceGatherer)}
+	for _, t := range pc.getUnbundledTransports() {
+		states = append(states, iceGatheringStateOf(t.iceGatherer))
 	}
+
+	return aggregateICEGatheringState(states)
 }
 
 // ConnectionState attribute returns the connection state of the
@@ -2880,6 +3097,9 @@ func (pc *PeerConnection) GetStats() StatsReport {
 	if pc.iceGatherer != nil {
 		pc.iceGatherer.collectStats(statsCollector)
 	}
+	for _, t := range pc.getUnbundledTransports() {
+		t.iceGatherer.collectStats(statsCollector)
+	}
 	if pc.iceTransport != nil {
 		pc.iceTransport.collectStats(statsCollector)
 	}
@@ -2901,6 +3121,12 @@ func (pc *PeerConnection) GetStats() StatsReport {
 	}
 	pc.sctpTransport.collectStats(statsCollector)
 
+	for _, transceiver := range pc.rtpTransceivers {
+		if receiver := transceiver.Receiver(); receiver != nil {
+			receiver.collectStats(statsCollector)
+		}
+	}
+
 	stats := PeerConnectionStats{
 		Timestamp:             statsTimestampNow(),
 		Type:                  StatsTypePeerConnection,
@@ -2931,24 +3157,49 @@ func (pc *PeerConnection) startTransports(
 	iceRole ICERole,
 	dtlsRole DTLSRole,
 	remoteUfrag, remotePwd, fingerprint, fingerprintHash string,
+	remoteRenomination bool,
 ) {
-	// Start the ice transport
-	err := pc.iceTransport.Start(
-		pc.iceGatherer,
-		ICEParameters{
+	// The unbundled transports connect alongside the primary one
+	var wg sync.WaitGroup
+	for _, t := range pc.getUnbundledTransports() {
+		wg.Add(1)
+		go func(t *unbundledTransport) {
+			defer wg.Done()
+			pc.startTransport(t.iceGatherer, t.iceTransport, t.dtlsTransport, iceRole, t.remote)
+		}(t)
+	}
+
+	pc.startTransport(pc.iceGatherer, pc.iceTransport, pc.dtlsTransport, iceRole, remoteTransportParameters{
+		ice: ICEParameters{
 			UsernameFragment: remoteUfrag,
 			Password:         remotePwd,
 			ICELite:          false,
+			Renomination:     remoteRenomination,
 		},
-		&iceRole,
-	)
+		dtls: DTLSParameters{
+			Role:         dtlsRole,
+			Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
+		},
+	})
+	wg.Wait()
+}
+
+func (pc *PeerConnection) startTransport(
+	iceGatherer *ICEGatherer,
+	iceTransport *ICETransport,
+	dtlsTransport *DTLSTransport,
+	iceRole ICERole,
+	remote remoteTransportParameters,
+) {
+	// Start the ice transport
+	err := iceTransport.Start(iceGatherer, remote.ice, &iceRole)
 	if err != nil {
 		pc.log.Warnf("Failed to start manager: %s", err)
 
 		return
 	}
 
-	pc.dtlsTransport.internalOnCloseHandler = func() {
+	dtlsTransport.internalOnCloseHandler = func() {
 		if pc.isClosed.get() || pc.api.settingEngine.disableCloseByDTLS {
 			return
 		}
@@ -2962,11 +3213,8 @@ func (pc *PeerConnection) startTransports(
 	}
 
 	// Start the dtls transport
-	err = pc.dtlsTransport.Start(DTLSParameters{
-		Role:         dtlsRole,
-		Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
-	})
-	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
+	err = dtlsTransport.Start(remote.dtls)
+	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransportState())
 	if err != nil {
 		pc.log.Warnf("Failed to start manager: %s", err)
 
@@ -2981,12 +3229,15 @@ func (pc *PeerConnection) startRTP(
 	currentTransceivers []*RTPTransceiver,
 ) {
 	if !isRenegotiation {
-		pc.undeclaredMediaProcessor()
+		pc.undeclaredMediaProcessor(pc.dtlsTransport)
+		for _, t := range pc.getUnbundledTransports() {
+			pc.undeclaredMediaProcessor(t.dtlsTransport)
+		}
 	}
 
 	pc.startRTPReceivers(remoteDesc, currentTransceivers)
 	if d := haveDataChannel(remoteDesc); d != nil {
-		pc.startSCTP(getMaxMessageSize(d))
+		pc.startSCTP(pc.dtlsTransportForMid(getMidValue(d)), getMaxMessageSize(d))
 	}
 }
 
@@ -3059,12 +3310,19 @@ func (pc *PeerConnection) generateUnmatchedSDP(
 		}
 	}
 
+	if err = pc.assignOfferTransports(mediaSections); err != nil {
+		return nil, err
+	}
+	if err = pc.setSectionTransports(mediaSections, true); err != nil {
+		return nil, err
+	}
+
 	dtlsFingerprints, err := pc.configuration.Certificates[0].GetFingerprints()
 	if err != nil {
 		return nil, err
 	}
 
-	return populateSDP(
+	desc, err = populateSDP(
 		desc,
 		isPlanB,
 		dtlsFingerprints,
@@ -3076,10 +3334,15 @@ func (pc *PeerConnection) generateUnmatchedSDP(
 		candidates,
 		iceParams,
 		mediaSections,
-		pc.ICEGatheringState(),
+		iceGatheringStateOf(pc.iceGatherer),
 		nil,
 		pc.api.settingEngine.getSCTPMaxMessageSize(),
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	return withICEOptions(desc, pc.api.settingEngine.candidates.Renomination), nil
 }
 
 // generateMatchedSDP generates a SDP and takes the remote state into account
@@ -3217,9 +3480,34 @@ func (pc *PeerConnection) generateMatchedSDP(
 			}
 		}
 	} else if remoteDescription != nil {
-		groupValue, _ := remoteDescription.parsed.Attribute(sdp.AttrKeyGroup)
+		groupValue, haveGroup := remoteDescription.parsed.Attribute(sdp.AttrKeyGroup)
 		groupValue = strings.TrimLeft(groupValue, "BUNDLE")
 		bundleGroup = &groupValue
+
+		// The media section the remote gathers on carries the primary transport,
+		// it is accepted even if it is not the first one or nothing is bundled.
+		primary, ok := selectCandidateMediaSection(remoteDescription.parsed)
+		for i := range mediaSections {
+			if ok && mediaSections[i].id == primary.SDPMid && (!haveGroup || i != 0) {
+				mediaSections[i].transport = &sectionTransport{
+					iceParams:         iceParams,
+					candidates:        candidates,
+					iceGatheringState: iceGatheringStateOf(pc.iceGatherer),
+					bundled:           haveGroup,
+				}
+			}
+		}
+	}
+	if err = pc.setSectionTransports(mediaSections, false); err != nil {
+		return nil, err
+	}
+
+	// Without rtcp-mux in the remote description RTCP keeps a component of its own.
+	if remoteDescription != nil && pc.configuration.RTCPMuxPolicy == RTCPMuxPolicyNegotiate &&
+		!haveRTCPMux(remoteDescription.parsed) {
+		for i := range mediaSections {
+			mediaSections[i].noRTCPMux = true
+		}
 	}
 
 	if pc.configuration.SDPSemantics == SDPSemanticsUnifiedPlanWithFallback && detectedPlanB {
@@ -3231,7 +3519,7 @@ func (pc *PeerConnection) generateMatchedSDP(
 		return nil, err
 	}
 
-	return populateSDP(
+	desc, err = populateSDP(
 		desc,
 		detectedPlanB,
 		dtlsFingerprints,
@@ -3243,14 +3531,40 @@ func (pc *PeerConnection) generateMatchedSDP(
 		candidates,
 		iceParams,
 		mediaSections,
-		pc.ICEGatheringState(),
+		iceGatheringStateOf(pc.iceGatherer),
 		bundleGroup,
 		pc.api.settingEngine.getSCTPMaxMessageSize(),
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	return withICEOptions(desc, pc.api.settingEngine.candidates.Renomination), nil
 }
 
 func (pc *PeerConnection) setGatherCompleteHandler(handler func()) {
-	pc.iceGatherer.onGatheringCompleteHandler.Store(handler)
+	pc.onGatheringCompleteHandler.Store(handler)
+}
+
+// GetICEEventLog returns the ICE events recorded by all ICE transports of the
+// PeerConnection, oldest first. The events marshal to JSON, so the timeline
+// of a failed session can be exported. It is empty unless
+// SettingEngine.SetICEEventLogSize is set.
+func (pc *PeerConnection) GetICEEventLog() []ice.Event {
+	pc.mu.RLock()
+	iceTransport := pc.iceTransport
+	pc.mu.RUnlock()
+
+	var events []ice.Event
+	if iceTransport != nil {
+		events = append(events, iceTransport.GetEventLog()...)
+	}
+	for _, t := range pc.getUnbundledTransports() {
+		events = append(events, t.iceTransport.GetEventLog()...)
+	}
+	sortICEEvents(events)
+
+	return events
 }
 
 // SCTP returns the SCTPTransport for this PeerConnection
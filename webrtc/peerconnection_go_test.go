@@ -15,6 +15,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"math/big"
+	"net"
 	"reflect"
 	"regexp"
 	"strings"
@@ -803,6 +804,146 @@ func TestPopulateLocalCandidates(t *testing.T) {
 	})
 }
 
+func TestPeerConnection_ICECandidatePoolSize(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := NewPeerConnection(Configuration{ICECandidatePoolSize: 1})
+	assert.NoError(t, err)
+
+	var gatheringStates []ICEGatheringState
+	var candidates []*ICECandidate
+	var mu sync.Mutex
+	pc.OnICEGatheringStateChange(func(s ICEGatheringState) {
+		mu.Lock()
+		defer mu.Unlock()
+		gatheringStates = append(gatheringStates, s)
+	})
+	pc.OnICECandidate(func(c *ICECandidate) {
+		mu.Lock()
+		defer mu.Unlock()
+		candidates = append(candidates, c)
+	})
+
+	// The pool is gathered before any local description is set.
+	assert.Eventually(t, func() bool {
+		pc.iceGatherer.poolLock.Lock()
+		defer pc.iceGatherer.poolLock.Unlock()
+
+		pool := pc.iceGatherer.poolCandidates
+
+		return len(pool) > 1 && pool[len(pool)-1] == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, ICEGatheringStateNew, pc.ICEGatheringState())
+	mu.Lock()
+	assert.Empty(t, gatheringStates)
+	assert.Empty(t, candidates)
+	mu.Unlock()
+
+	_, err = pc.CreateDataChannel("test-channel", nil)
+	assert.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	gatheringComplete := GatheringCompletePromise(pc)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+
+	// The local description is complete right away.
+	assert.Equal(t, ICEGatheringStateComplete, pc.ICEGatheringState())
+	assert.Contains(t, pc.LocalDescription().SDP, "a=candidate")
+	assert.Contains(t, pc.LocalDescription().SDP, "a=end-of-candidates")
+
+	<-gatheringComplete
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(candidates) > 1 && candidates[len(candidates)-1] == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []ICEGatheringState{ICEGatheringStateGathering, ICEGatheringStateComplete}, gatheringStates)
+	for _, c := range candidates[:len(candidates)-1] {
+		assert.Equal(t, "0", c.SDPMid)
+	}
+	mu.Unlock()
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_ICECandidatePoolSize_Error(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// The ICE agent of the pool can't be created, the transports are closed.
+	settingEngine := SettingEngine{}
+	settingEngine.SetNAT1To1IPs([]string{"invalid"}, ICECandidateTypeHost)
+	pc, err := NewAPI(WithSettingEngine(settingEngine)).NewPeerConnection(Configuration{ICECandidatePoolSize: 1})
+	assert.Error(t, err)
+	assert.Nil(t, pc)
+}
+
+func TestPeerConnection_ICECandidatePoolSize_Close(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.Nil(t, pc.iceGatherer.getAgent())
+
+	// Setting a pool size starts gathering, the pool is released on Close.
+	assert.NoError(t, pc.SetConfiguration(Configuration{ICECandidatePoolSize: 1}))
+	assert.NotNil(t, pc.iceGatherer.getAgent())
+	assert.NoError(t, pc.Close())
+	assert.Nil(t, pc.iceGatherer.getAgent())
+}
+
+func TestPeerConnection_ICECandidatePoolSize_CloseInHandler(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := NewPeerConnection(Configuration{ICECandidatePoolSize: 1})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		pc.iceGatherer.poolLock.Lock()
+		defer pc.iceGatherer.poolLock.Unlock()
+
+		return len(pc.iceGatherer.poolCandidates) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Closing from a handler of a pooled candidate must not deadlock.
+	closed := make(chan struct{})
+	var once sync.Once
+	pc.OnICECandidate(func(*ICECandidate) {
+		once.Do(func() {
+			assert.NoError(t, pc.Close())
+			close(closed)
+		})
+	})
+
+	_, err = pc.CreateDataChannel("test-channel", nil)
+	assert.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+
+	<-closed
+}
+
 // Assert that two agents that only generate mDNS candidates can connect.
 func TestMulticastDNSCandidates(t *testing.T) {
 	lim := test.TimeOut(time.Second * 30)
@@ -1890,3 +2031,125 @@ func Test_WriteRTCP_Disconnected(t *testing.T) {
 
 	assert.NoError(t, peerConnection.Close())
 }
+
+func TestPeerConnection_RTCPMuxPolicyNegotiate(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	cfg := Configuration{RTCPMuxPolicy: RTCPMuxPolicyNegotiate}
+	pcOffer, err := NewPeerConnection(cfg)
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(cfg)
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	pcAnswer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{
+			&rtcp.PictureLossIndication{MediaSSRC: uint32(trackRemote.SSRC())},
+		}))
+	})
+
+	pliReceived := make(chan struct{})
+	go func() {
+		for {
+			packets, _, readErr := sender.ReadRTCP()
+			if readErr != nil {
+				return
+			}
+			for _, packet := range packets {
+				if _, ok := packet.(*rtcp.PictureLossIndication); ok {
+					close(pliReceived)
+
+					return
+				}
+			}
+		}
+	}()
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+	assert.NoError(t, signalPairWithModification(pcOffer, pcAnswer, func(desc string) string {
+		return strings.ReplaceAll(desc, "a=rtcp-mux\r\n", "")
+	}))
+
+	// The answer keeps RTCP on a component of its own
+	answer := pcAnswer.LocalDescription().SDP
+	assert.NotContains(t, answer, "a=rtcp-mux")
+	assert.Regexp(t, "a=candidate:[^ ]+ 2 udp", answer)
+
+	// a=rtcp signals the address of an RTCP candidate
+	rtcpAttr := regexp.MustCompile(`a=rtcp:(\d+) IN IP[46] ([^\r]+)`).FindStringSubmatch(answer)
+	if assert.Len(t, rtcpAttr, 3) {
+		assert.NotEqual(t, "9", rtcpAttr[1])
+		candidate := fmt.Sprintf("a=candidate:[^ ]+ 2 udp [0-9]+ %s %s ", regexp.QuoteMeta(rtcpAttr[2]), rtcpAttr[1])
+		assert.Regexp(t, candidate, answer)
+	}
+	assert.NotNil(t, pcOffer.iceGatherer.getRTCPAgent())
+	assert.NotNil(t, pcAnswer.iceGatherer.getRTCPAgent())
+
+	connected.Wait()
+	assert.NotNil(t, pcOffer.iceTransport.rtcpConn)
+	assert.NotNil(t, pcAnswer.iceTransport.rtcpConn)
+
+	sendVideoUntilDone(t, pliReceived, []*TrackLocalStaticSample{track})
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_RTCPMuxPolicyNegotiate_ICEMux(t *testing.T) {
+	udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	udpMux := NewICEUDPMux(nil, udpConn)
+	defer func() {
+		assert.NoError(t, udpMux.Close())
+	}()
+
+	settingEngine := SettingEngine{}
+	settingEngine.SetICEUDPMux(udpMux)
+
+	// The RTP and RTCP components share their ufrag, the mux can't tell them apart
+	_, err = NewAPI(WithSettingEngine(settingEngine)).NewPeerConnection(Configuration{
+		RTCPMuxPolicy: RTCPMuxPolicyNegotiate,
+	})
+	assert.ErrorIs(t, err, ErrRTCPMuxPolicyNegotiateWithICEMux)
+}
+
+func TestPeerConnection_RTCPMuxPolicyNegotiate_Mux(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, err := NewPeerConnection(Configuration{RTCPMuxPolicy: RTCPMuxPolicyNegotiate})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// The RTCP component is dropped once rtcp-mux is answered
+	assert.Contains(t, pcAnswer.LocalDescription().SDP, "a=rtcp-mux")
+	assert.Nil(t, pcOffer.iceGatherer.getRTCPAgent())
+	assert.Nil(t, pcAnswer.iceGatherer.getRTCPAgent())
+
+	connected.Wait()
+	assert.Nil(t, pcOffer.iceTransport.rtcpConn)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
 	"github.com/pion/rtp/codecs/av1/obu"
+	"github.com/pion/webrtc/v4/pkg/media"
 )
 
 var (
@@ -40,6 +41,8 @@ type (
 		firstFrameTimestamp uint32
 		clockRate           uint64
 
+		presentationTime media.PresentationTimeFunc
+
 		// VP8, VP9
 		currentFrame []byte
 
@@ -165,10 +168,22 @@ func (i *IVFWriter) WriteRTP(packet *rtp.Packet) error {
 		return nil
 	}
 
-	if i.count == 0 {
-		i.firstFrameTimestamp = packet.Timestamp
+	var relativeTstampMs uint64
+	if i.presentationTime != nil {
+		// Frames are dropped until they can be placed on the shared timeline.
+		presentationTime, ok := i.presentationTime(packet.Timestamp)
+		if !ok {
+			return nil
+		}
+		if presentationTime > 0 {
+			relativeTstampMs = uint64(presentationTime.Milliseconds())
+		}
+	} else {
+		if i.count == 0 {
+			i.firstFrameTimestamp = packet.Timestamp
+		}
+		relativeTstampMs = 1000 * uint64(packet.Timestamp-i.firstFrameTimestamp) / i.clockRate
 	}
-	relativeTstampMs := 1000 * uint64(packet.Timestamp-i.firstFrameTimestamp) / i.clockRate
 
 	switch i.codec {
 	case codecVP8:
@@ -339,3 +354,15 @@ func WithCodec(mimeType string) Option {
 		return nil
 	}
 }
+
+// WithPresentationTime makes IVFWriter take the timestamp of each frame from f
+// instead of the RTP timestamp relative to the first frame. Writers sharing the
+// same reference, for example from a lipsync.Synchronizer, produce aligned files.
+// Frames are dropped while f doesn't know their presentation time.
+func WithPresentationTime(f media.PresentationTimeFunc) Option {
+	return func(i *IVFWriter) error {
+		i.presentationTime = f
+
+		return nil
+	}
+}
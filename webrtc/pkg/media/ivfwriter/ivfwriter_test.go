@@ -5,8 +5,10 @@ package ivfwriter
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
@@ -386,3 +388,25 @@ func TestIVFWriter_VP9(t *testing.T) {
 		0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xaa, 0xab,
 	})
 }
+
+func TestIVFWriter_PresentationTime(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	known := false
+	writer, err := NewWith(buffer, WithCodec(mimeTypeAV1), WithPresentationTime(func(uint32) (time.Duration, bool) {
+		return 3 * time.Second, known
+	}))
+	assert.NoError(t, err)
+
+	// N = 1, Length = 1, OBU_TYPE = 4
+	packet := &rtp.Packet{Header: rtp.Header{Marker: true, Timestamp: 1234}, Payload: []byte{0x08, 0x01, 0x20}}
+
+	// Dropped until the presentation time is known.
+	assert.NoError(t, writer.WriteRTP(packet))
+	assert.Equal(t, 32, buffer.Len())
+
+	known = true
+	assert.NoError(t, writer.WriteRTP(packet))
+	assert.Equal(t, uint64(3000/30), binary.LittleEndian.Uint64(buffer.Bytes()[36:44]))
+	assert.NoError(t, writer.Close())
+}
@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+// Package lipsync maps the RTP timestamps of the tracks of a MediaStream onto a
+// common clock, so audio and video can be played or recorded in sync.
+//
+// The mapping is built from the NTP and RTP timestamps of the RTCP Sender
+// Reports of every track. The RTP clock rate of each sender is estimated from
+// the recent reports, so clock drift between the sender's media clock and its
+// wallclock does not accumulate over long sessions.
+package lipsync
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v4/pkg/media"
+)
+
+var errUnknownSSRC = errors.New("lipsync: unknown SSRC")
+
+const (
+	ntpEpochOffset = 2208988800
+
+	defaultSenderReportHistory = 8
+
+	// maxDrift is the largest relative deviation of the estimated clock rate
+	// from the nominal one that is accepted, larger ones are measurement errors.
+	maxDrift = 0.01
+)
+
+// Synchronizer keeps the NTP to RTP mapping of every registered track.
+// Tracks are grouped by MediaStream id (the msid); the presentation times of
+// the tracks of a group share the same reference, the wallclock time of the
+// first Sender Report received for the group.
+type Synchronizer struct {
+	mu                  sync.Mutex
+	senderReportHistory int
+	streams             map[uint32]*stream
+	groups              map[string]*group
+}
+
+type group struct {
+	hasEpoch bool
+	epoch    float64 // seconds
+	tracks   int
+}
+
+type report struct {
+	ntp float64 // seconds
+	rtp int64   // unwrapped
+}
+
+type stream struct {
+	group     *group
+	groupID   string
+	clockRate float64
+
+	reports []report
+
+	// unwrapping state of the RTP timestamps of the Sender Reports
+	lastRTP uint32
+	cycles  int64
+
+	// seconds per RTP tick, estimated from reports
+	period float64
+}
+
+// Option configures a Synchronizer.
+type Option func(s *Synchronizer)
+
+// WithSenderReportHistory sets how many Sender Reports per track are used to
+// estimate the clock drift. Defaults to 8.
+func WithSenderReportHistory(n int) Option {
+	return func(s *Synchronizer) {
+		if n > 1 {
+			s.senderReportHistory = n
+		}
+	}
+}
+
+// New creates a new Synchronizer.
+func New(opts ...Option) *Synchronizer {
+	s := &Synchronizer{
+		senderReportHistory: defaultSenderReportHistory,
+		streams:             map[uint32]*stream{},
+		groups:              map[string]*group{},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+// AddTrack registers the track with the given SSRC as part of the MediaStream
+// streamID. With a webrtc.TrackRemote this is
+// AddTrack(track.StreamID(), uint32(track.SSRC()), track.Codec().ClockRate).
+func (s *Synchronizer) AddTrack(streamID string, ssrc uint32, clockRate uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.streams[ssrc]; ok {
+		return
+	}
+
+	g, ok := s.groups[streamID]
+	if !ok {
+		g = &group{}
+		s.groups[streamID] = g
+	}
+	g.tracks++
+
+	s.streams[ssrc] = &stream{
+		group:     g,
+		groupID:   streamID,
+		clockRate: float64(clockRate),
+		period:    1 / float64(clockRate),
+	}
+}
+
+// RemoveTrack unregisters the track with the given SSRC.
+func (s *Synchronizer) RemoveTrack(ssrc uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.streams[ssrc]
+	if !ok {
+		return
+	}
+	delete(s.streams, ssrc)
+
+	st.group.tracks--
+	if st.group.tracks == 0 {
+		delete(s.groups, st.groupID)
+	}
+}
+
+// HandleRTCP processes the Sender Reports found in pkts, other packets are ignored.
+// It is meant to be fed with the packets read from webrtc.RTPReceiver.ReadRTCP.
+func (s *Synchronizer) HandleRTCP(pkts []rtcp.Packet) {
+	for _, pkt := range pkts {
+		if sr, ok := pkt.(*rtcp.SenderReport); ok {
+			_ = s.HandleSenderReport(sr)
+		}
+	}
+}
+
+// HandleSenderReport updates the mapping of the track that sent sr.
+func (s *Synchronizer) HandleSenderReport(sr *rtcp.SenderReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.streams[sr.SSRC]
+	if !ok {
+		return errUnknownSSRC
+	}
+
+	ntp := ntpToSeconds(sr.NTPTime)
+	if !st.group.hasEpoch {
+		st.group.epoch = ntp
+		st.group.hasEpoch = true
+	}
+
+	if len(st.reports) > 0 && sr.RTPTime < st.lastRTP && st.lastRTP-sr.RTPTime > 1<<31 {
+		st.cycles++
+	}
+	st.lastRTP = sr.RTPTime
+	st.reports = append(st.reports, report{ntp: ntp, rtp: st.cycles<<32 | int64(sr.RTPTime)})
+	if len(st.reports) > s.senderReportHistory {
+		st.reports = st.reports[1:]
+	}
+	st.estimatePeriod()
+
+	return nil
+}
+
+// estimatePeriod fits the reports with a least squares line. The slope is the
+// duration of one RTP tick measured with the sender's wallclock.
+func (st *stream) estimatePeriod() {
+	nominal := 1 / st.clockRate
+	st.period = nominal
+	if len(st.reports) < 2 {
+		return
+	}
+
+	first := st.reports[0]
+	var sumX, sumY, sumXX, sumXY float64
+	for _, r := range st.reports {
+		x := float64(r.rtp - first.rtp)
+		y := r.ntp - first.ntp
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+
+	n := float64(len(st.reports))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return
+	}
+
+	period := (n*sumXY - sumX*sumY) / denominator
+	if period > nominal*(1-maxDrift) && period < nominal*(1+maxDrift) {
+		st.period = period
+	}
+}
+
+// PresentationTime returns the presentation time of the frame with the given
+// RTP timestamp on the track with the given SSRC. It returns false until a
+// Sender Report has been received for the track.
+func (s *Synchronizer) PresentationTime(ssrc uint32, rtpTimestamp uint32) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.streams[ssrc]
+	if !ok || len(st.reports) == 0 {
+		return 0, false
+	}
+
+	last := st.reports[len(st.reports)-1]
+	elapsed := float64(int32(rtpTimestamp - st.lastRTP)) //nolint:gosec // G115
+	ntp := last.ntp + elapsed*st.period
+
+	return time.Duration((ntp - st.group.epoch) * float64(time.Second)), true
+}
+
+// PresentationTimeFunc returns a media.PresentationTimeFunc for the track with
+// the given SSRC, to be used with the media writers.
+func (s *Synchronizer) PresentationTimeFunc(ssrc uint32) media.PresentationTimeFunc {
+	return func(rtpTimestamp uint32) (time.Duration, bool) {
+		return s.PresentationTime(ssrc, rtpTimestamp)
+	}
+}
+
+// ClockDrift returns the estimated drift of the media clock of the track with
+// the given SSRC, relative to its nominal clock rate. A positive value means
+// the media clock runs slow compared to the sender's wallclock.
+func (s *Synchronizer) ClockDrift(ssrc uint32) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.streams[ssrc]
+	if !ok || len(st.reports) < 2 {
+		return 0, false
+	}
+
+	return st.period*st.clockRate - 1, true
+}
+
+func ntpToSeconds(ntp uint64) float64 {
+	seconds := float64(ntp>>32) - ntpEpochOffset
+	fraction := float64(ntp&0xFFFFFFFF) / (1 << 32)
+
+	return seconds + fraction
+}
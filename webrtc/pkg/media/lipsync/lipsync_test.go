@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package lipsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func toNTP(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpEpochOffset)         //nolint:gosec // G115
+	fraction := uint64(t.Nanosecond()) * (1 << 32) / 1e9 //nolint:gosec // G115
+
+	return seconds<<32 | fraction
+}
+
+func TestSynchronizer_AudioVideo(t *testing.T) {
+	const (
+		audioSSRC = 1
+		videoSSRC = 2
+		otherSSRC = 3
+	)
+
+	s := New()
+	s.AddTrack("stream", audioSSRC, 48000)
+	s.AddTrack("stream", videoSSRC, 90000)
+	s.AddTrack("other", otherSSRC, 90000)
+
+	_, ok := s.PresentationTime(audioSSRC, 0)
+	assert.False(t, ok)
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// Audio and video RTP timestamps have unrelated random offsets.
+	s.HandleRTCP([]rtcp.Packet{
+		&rtcp.SenderReport{SSRC: audioSSRC, NTPTime: toNTP(start), RTPTime: 1000},
+		&rtcp.ReceiverReport{SSRC: 5},
+	})
+	assert.NoError(t, s.HandleSenderReport(&rtcp.SenderReport{
+		SSRC: videoSSRC, NTPTime: toNTP(start.Add(500 * time.Millisecond)), RTPTime: 4000000000,
+	}))
+	assert.ErrorIs(t, s.HandleSenderReport(&rtcp.SenderReport{SSRC: 42}), errUnknownSSRC)
+
+	// One second after the first report on both tracks, the video timestamp wrapped.
+	audio, ok := s.PresentationTime(audioSSRC, 1000+48000)
+	assert.True(t, ok)
+	video, ok := s.PresentationTime(videoSSRC, 4000000000+45000)
+	assert.True(t, ok)
+	assert.InDelta(t, time.Second, audio, float64(time.Microsecond))
+	assert.InDelta(t, time.Second, video, float64(time.Microsecond))
+
+	// Timestamps before the report map before it.
+	audioRTPTime := uint32(1000)
+	audio, ok = s.PresentationTimeFunc(audioSSRC)(audioRTPTime - 4800)
+	assert.True(t, ok)
+	assert.InDelta(t, -100*time.Millisecond, audio, float64(time.Microsecond))
+
+	// Other groups have their own reference.
+	_, ok = s.PresentationTime(otherSSRC, 0)
+	assert.False(t, ok)
+
+	s.RemoveTrack(audioSSRC)
+	_, ok = s.PresentationTime(audioSSRC, 1000)
+	assert.False(t, ok)
+}
+
+func TestSynchronizer_Drift(t *testing.T) {
+	const ssrc = 1
+
+	s := New(WithSenderReportHistory(4))
+	s.AddTrack("stream", ssrc, 90000)
+
+	_, ok := s.ClockDrift(ssrc)
+	assert.False(t, ok)
+
+	// The media clock runs 100ppm fast compared to the wallclock.
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rtpTime := uint32(4294000000)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.HandleSenderReport(&rtcp.SenderReport{
+			SSRC:    ssrc,
+			NTPTime: toNTP(start.Add(time.Duration(i) * time.Second)),
+			RTPTime: rtpTime + uint32(i)*90009,
+		}))
+	}
+
+	drift, ok := s.ClockDrift(ssrc)
+	assert.True(t, ok)
+	assert.InDelta(t, -100e-6, drift, 1e-6)
+
+	// A minute after the last report the nominal clock rate would be 6ms off.
+	presentationTime, ok := s.PresentationTime(ssrc, rtpTime+9*90009+60*90009)
+	assert.True(t, ok)
+	assert.InDelta(t, 69*time.Second, presentationTime, float64(100*time.Microsecond))
+}
@@ -24,6 +24,11 @@ type Sample struct {
 	RTPHeaders []*rtp.Header
 }
 
+// PresentationTimeFunc returns the presentation time of the frame with the
+// given RTP timestamp, relative to a reference shared by the tracks that are
+// played together. It returns false if the time is not known yet.
+type PresentationTimeFunc func(rtpTimestamp uint32) (time.Duration, bool)
+
 // Writer defines an interface to handle
 // the creation of media files.
 type Writer interface {
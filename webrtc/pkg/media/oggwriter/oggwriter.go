@@ -9,10 +9,12 @@ import (
 	"errors"
 	"io"
 	"os"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
 	"github.com/pion/webrtc/v4/internal/util"
+	"github.com/pion/webrtc/v4/pkg/media"
 )
 
 const (
@@ -42,15 +44,16 @@ type OggWriter struct {
 	previousGranulePosition uint64
 	previousTimestamp       uint32
 	lastPayloadSize         int
+	presentationTime        media.PresentationTimeFunc
 }
 
 // New builds a new OGG Opus writer.
-func New(fileName string, sampleRate uint32, channelCount uint16) (*OggWriter, error) {
+func New(fileName string, sampleRate uint32, channelCount uint16, opts ...Option) (*OggWriter, error) {
 	file, err := os.Create(fileName) //nolint:gosec
 	if err != nil {
 		return nil, err
 	}
-	writer, err := NewWith(file, sampleRate, channelCount)
+	writer, err := NewWith(file, sampleRate, channelCount, opts...)
 	if err != nil {
 		return nil, file.Close()
 	}
@@ -60,7 +63,7 @@ func New(fileName string, sampleRate uint32, channelCount uint16) (*OggWriter, e
 }
 
 // NewWith initialize a new OGG Opus writer with an io.Writer output.
-func NewWith(out io.Writer, sampleRate uint32, channelCount uint16) (*OggWriter, error) {
+func NewWith(out io.Writer, sampleRate uint32, channelCount uint16, opts ...Option) (*OggWriter, error) {
 	if out == nil {
 		return nil, errFileNotOpened
 	}
@@ -77,6 +80,9 @@ func NewWith(out io.Writer, sampleRate uint32, channelCount uint16) (*OggWriter,
 		previousTimestamp:       1,
 		previousGranulePosition: 1,
 	}
+	for _, o := range opts {
+		o(writer)
+	}
 	if err := writer.writeHeaders(); err != nil {
 		return nil, err
 	}
@@ -202,8 +208,21 @@ func (i *OggWriter) WriteRTP(packet *rtp.Packet) error {
 
 	payload := opusPacket.Payload[0:]
 
-	// Should be equivalent to sampleRate * duration
-	if i.previousTimestamp != 1 {
+	if i.presentationTime != nil {
+		// Packets are dropped until they can be placed on the shared timeline.
+		presentationTime, ok := i.presentationTime(packet.Timestamp)
+		if !ok {
+			return nil
+		}
+		if presentationTime > 0 {
+			granulePosition := 1 + uint64(presentationTime)*uint64(i.sampleRate)/uint64(time.Second)
+			// The granule position must not go backwards.
+			if granulePosition > i.previousGranulePosition {
+				i.previousGranulePosition = granulePosition
+			}
+		}
+	} else if i.previousTimestamp != 1 {
+		// Should be equivalent to sampleRate * duration
 		increment := packet.Timestamp - i.previousTimestamp
 		i.previousGranulePosition += uint64(increment)
 	}
@@ -284,3 +303,17 @@ func generateChecksumTable() *[256]uint32 {
 
 	return &table
 }
+
+// An Option configures an OggWriter.
+type Option func(i *OggWriter)
+
+// WithPresentationTime makes OggWriter derive the granule position of each
+// packet from f instead of the RTP timestamp relative to the first packet.
+// Writers sharing the same reference, for example from a lipsync.Synchronizer,
+// produce aligned files. Packets are dropped while f doesn't know their
+// presentation time.
+func WithPresentationTime(f media.PresentationTimeFunc) Option {
+	return func(i *OggWriter) {
+		i.presentationTime = f
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/stretchr/testify/assert"
@@ -163,3 +164,25 @@ func TestOggWriter_LargePayload(t *testing.T) {
 	data := writer.createPage(rawPkt, pageHeaderTypeContinuationOfStream, 0, 1)
 	assert.Equal(t, uint8(4), data[26])
 }
+
+func TestOggWriter_PresentationTime(t *testing.T) {
+	known := false
+	writer, err := NewWith(&bytes.Buffer{}, 48000, 2, WithPresentationTime(func(ts uint32) (time.Duration, bool) {
+		return time.Duration(ts) * time.Second / 48000, known
+	}))
+	assert.NoError(t, err)
+
+	// Dropped until the presentation time is known.
+	assert.NoError(t, writer.WriteRTP(&rtp.Packet{Header: rtp.Header{Timestamp: 960}, Payload: []byte{0x00}}))
+	assert.Equal(t, uint64(1), writer.previousGranulePosition)
+	assert.Equal(t, uint32(2), writer.pageIndex)
+
+	known = true
+	assert.NoError(t, writer.WriteRTP(&rtp.Packet{Header: rtp.Header{Timestamp: 48000}, Payload: []byte{0x00}}))
+	assert.Equal(t, uint64(48001), writer.previousGranulePosition)
+
+	// The granule position never goes backwards.
+	assert.NoError(t, writer.WriteRTP(&rtp.Packet{Header: rtp.Header{Timestamp: 47040}, Payload: []byte{0x00}}))
+	assert.Equal(t, uint64(48001), writer.previousGranulePosition)
+	assert.Equal(t, uint32(4), writer.pageIndex)
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+package webrtc
+
+import (
+	"math"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// RTPContributingSource contains information about a source that contributed
+// to the packets delivered by an RTPReceiver.
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpcontributingsource
+type RTPContributingSource struct {
+	// Timestamp is the arrival time of the most recent packet from this source.
+	Timestamp time.Time
+
+	// Source is the CSRC or SSRC identifier of the source.
+	Source SSRC
+
+	// AudioLevel is the audio level of the most recent packet from this source,
+	// between 0..1 (linear), where 1.0 represents 0 dBov and 0 represents silence.
+	// It is nil if the packet did not carry an audio level header extension.
+	AudioLevel *float64
+
+	// RTPTimestamp is the RTP timestamp of the most recent packet from this source.
+	RTPTimestamp uint32
+
+	// CaptureTimestamp is the capture time of the most recent packet from this
+	// source in the clock of the sender, taken from the abs-capture-time header
+	// extension. It is zero if the packet did not carry the extension.
+	CaptureTimestamp time.Time
+
+	// SenderCaptureTimeOffset is the offset between the capture clock and the
+	// sender's clock estimated by the sender, if it provided one.
+	SenderCaptureTimeOffset *time.Duration
+}
+
+// RTPSynchronizationSource contains information about a synchronization source
+// (SSRC) of the packets delivered by an RTPReceiver.
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpsynchronizationsource
+type RTPSynchronizationSource struct {
+	RTPContributingSource
+
+	// VoiceActivityFlag is the voice activity flag of the audio level header
+	// extension of the most recent packet. It is nil if the packet did not carry
+	// the extension.
+	VoiceActivityFlag *bool
+}
+
+const (
+	csrcAudioLevelURI = "urn:ietf:params:rtp-hdrext:csrc-audio-level"
+	absCaptureTimeURI = "http://www.webrtc.org/experiments/rtp-hdrext/abs-capture-time"
+
+	// rtpSourceLifetime is how long a source is reported after its last packet.
+	rtpSourceLifetime = 10 * time.Second
+)
+
+// audioLevelToLinear converts an audio level in -dBov to the linear scale used
+// by RTPContributingSource and the stats, 127 -dBov is silence.
+func audioLevelToLinear(level uint8) float64 {
+	level &= 0x7F
+	if level == 127 {
+		return 0
+	}
+
+	return math.Pow(10, -float64(level)/20)
+}
+
+// rtpSource is the most recent packet of a synchronization or contributing
+// source, it is kept by value so that the packets update it in place.
+type rtpSource struct {
+	timestamp                   time.Time
+	rtpTimestamp                uint32
+	captureTimestamp            time.Time
+	senderCaptureTimeOffset     time.Duration
+	haveSenderCaptureTimeOffset bool
+	audioLevel                  float64
+	voiceActivity               bool
+	haveAudioLevel              bool
+}
+
+func (s *rtpSource) update(
+	now time.Time, rtpTimestamp uint32, captureTime rtp.AbsCaptureTimeExtension, haveCaptureTime bool,
+) {
+	s.timestamp, s.rtpTimestamp = now, rtpTimestamp
+	s.captureTimestamp, s.haveSenderCaptureTimeOffset = time.Time{}, false
+	if !haveCaptureTime {
+		return
+	}
+
+	s.captureTimestamp = captureTime.CaptureTime()
+	if offset := captureTime.EstimatedCaptureClockOffsetDuration(); offset != nil {
+		s.senderCaptureTimeOffset, s.haveSenderCaptureTimeOffset = *offset, true
+	}
+}
+
+func (s *rtpSource) contributingSource(source SSRC) RTPContributingSource {
+	contributor := RTPContributingSource{
+		Timestamp:        s.timestamp,
+		Source:           source,
+		RTPTimestamp:     s.rtpTimestamp,
+		CaptureTimestamp: s.captureTimestamp,
+	}
+	if s.haveAudioLevel {
+		audioLevel := s.audioLevel
+		contributor.AudioLevel = &audioLevel
+	}
+	if s.haveSenderCaptureTimeOffset {
+		offset := s.senderCaptureTimeOffset
+		contributor.SenderCaptureTimeOffset = &offset
+	}
+
+	return contributor
+}
+
+func (s *rtpSource) synchronizationSource(source SSRC) RTPSynchronizationSource {
+	synchronizationSource := RTPSynchronizationSource{RTPContributingSource: s.contributingSource(source)}
+	if s.haveAudioLevel {
+		voiceActivity := s.voiceActivity
+		synchronizationSource.VoiceActivityFlag = &voiceActivity
+	}
+
+	return synchronizationSource
+}
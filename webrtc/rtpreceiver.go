@@ -10,11 +10,14 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/pion/interceptor"
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/srtp/v3"
 	"github.com/pion/webrtc/v4/internal/util"
 )
@@ -70,6 +73,21 @@ type RTPReceiver struct {
 	api *API
 
 	rtxPool sync.Pool
+
+	onAudioLevelHandler func(ssrc SSRC, level uint8, voice bool)
+
+	sourcesMu              sync.Mutex
+	synchronizationSources map[SSRC]*rtpSource
+	contributingSources    map[contributingSourceKey]*contributingSource
+}
+
+type contributingSourceKey struct {
+	ssrc, csrc SSRC
+}
+
+type contributingSource struct {
+	rtpSource
+	packetsContributedTo uint32
 }
 
 // NewRTPReceiver constructs a new RTPReceiver.
@@ -108,6 +126,15 @@ func (r *RTPReceiver) Transport() *DTLSTransport {
 	return r.transport
 }
 
+func (r *RTPReceiver) setTransport(transport *DTLSTransport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.haveReceived() {
+		r.transport = transport
+	}
+}
+
 func (r *RTPReceiver) getParameters() RTPParameters {
 	parameters := r.api.mediaEngine.getRTPParametersByKind(
 		r.kind,
@@ -412,12 +439,208 @@ func (r *RTPReceiver) readRTP(b []byte, reader *TrackRemote) (n int, a intercept
 	}
 
 	if t := r.streamsForTrack(reader); t != nil {
-		return t.rtpInterceptor.Read(b, a)
+		n, a, err = t.rtpInterceptor.Read(b, a)
+		if err == nil {
+			a = r.handleRTP(b[:n], a, t.streamInfo, time.Now())
+		}
+
+		return n, a, err
 	}
 
 	return 0, nil, fmt.Errorf("%w: %d", errRTPReceiverWithSSRCTrackStreamNotFound, reader.SSRC())
 }
 
+// OnAudioLevel sets an event handler which is called with the audio level of
+// each packet read from the tracks of this RTPReceiver that carries the
+// ssrc-audio-level header extension. level is in -dBov, 0 is the loudest.
+// The handler runs on the goroutine reading the track, so the track must be read.
+// It can be used to feed an activespeaker.Detector from pion/interceptor.
+func (r *RTPReceiver) OnAudioLevel(f func(ssrc SSRC, level uint8, voice bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onAudioLevelHandler = f
+}
+
+// handleRTP records the sources of a packet read from a track and reports its
+// audio level, the header is parsed once and cached in the attributes.
+func (r *RTPReceiver) handleRTP(
+	buf []byte, attributes interceptor.Attributes, info *interceptor.StreamInfo, now time.Time,
+) interceptor.Attributes {
+	if info == nil {
+		return attributes
+	}
+
+	var audioLevelID, csrcAudioLevelID, absCaptureTimeID uint8
+	r.mu.RLock()
+	handler := r.onAudioLevelHandler
+	for _, ext := range info.RTPHeaderExtensions {
+		switch ext.URI {
+		case sdp.AudioLevelURI:
+			audioLevelID = uint8(ext.ID) //nolint:gosec // G115
+		case csrcAudioLevelURI:
+			csrcAudioLevelID = uint8(ext.ID) //nolint:gosec // G115
+		case absCaptureTimeURI:
+			absCaptureTimeID = uint8(ext.ID) //nolint:gosec // G115
+		}
+	}
+	r.mu.RUnlock()
+
+	if attributes == nil {
+		attributes = make(interceptor.Attributes)
+	}
+	header, err := attributes.GetRTPHeader(buf)
+	if err != nil {
+		return attributes
+	}
+
+	var captureTime rtp.AbsCaptureTimeExtension
+	haveCaptureTime := false
+	if raw := header.GetExtension(absCaptureTimeID); absCaptureTimeID != 0 && raw != nil {
+		haveCaptureTime = captureTime.Unmarshal(raw) == nil
+	}
+
+	var level rtp.AudioLevelExtension
+	haveLevel := false
+	if raw := header.GetExtension(audioLevelID); audioLevelID != 0 && raw != nil {
+		haveLevel = level.Unmarshal(raw) == nil
+		if haveLevel && handler != nil && r.kind == RTPCodecTypeAudio {
+			handler(SSRC(header.SSRC), level.Level, level.Voice)
+		}
+	}
+
+	var csrcAudioLevels []byte
+	if csrcAudioLevelID != 0 {
+		csrcAudioLevels = header.GetExtension(csrcAudioLevelID)
+	}
+
+	r.sourcesMu.Lock()
+	defer r.sourcesMu.Unlock()
+
+	if r.synchronizationSources == nil {
+		r.synchronizationSources = map[SSRC]*rtpSource{}
+		r.contributingSources = map[contributingSourceKey]*contributingSource{}
+	}
+
+	// The sources are updated in place, a packet of a known source doesn't allocate.
+	source, ok := r.synchronizationSources[SSRC(header.SSRC)]
+	if !ok {
+		source = &rtpSource{}
+		r.synchronizationSources[SSRC(header.SSRC)] = source
+	}
+	source.update(now, header.Timestamp, captureTime, haveCaptureTime)
+	source.haveAudioLevel = haveLevel
+	if haveLevel {
+		source.audioLevel, source.voiceActivity = audioLevelToLinear(level.Level), level.Voice
+	}
+
+	for i, csrc := range header.CSRC {
+		key := contributingSourceKey{ssrc: SSRC(header.SSRC), csrc: SSRC(csrc)}
+		contributor, ok := r.contributingSources[key]
+		if !ok {
+			contributor = &contributingSource{}
+			r.contributingSources[key] = contributor
+		}
+		contributor.packetsContributedTo++
+		contributor.update(now, header.Timestamp, captureTime, haveCaptureTime)
+		// The mixer-to-client audio levels follow the order of the CSRC list.
+		contributor.haveAudioLevel = i < len(csrcAudioLevels)
+		if contributor.haveAudioLevel {
+			contributor.audioLevel = audioLevelToLinear(csrcAudioLevels[i])
+		}
+	}
+
+	return attributes
+}
+
+// GetSynchronizationSources returns the SSRCs of the packets delivered by the
+// tracks of this RTPReceiver in the last 10 seconds, with information about the
+// most recent packet of each.
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpreceiver-getsynchronizationsources
+func (r *RTPReceiver) GetSynchronizationSources() []RTPSynchronizationSource {
+	r.sourcesMu.Lock()
+	defer r.sourcesMu.Unlock()
+
+	r.expireSources(time.Now())
+
+	sources := make([]RTPSynchronizationSource, 0, len(r.synchronizationSources))
+	for ssrc, source := range r.synchronizationSources {
+		sources = append(sources, source.synchronizationSource(ssrc))
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Source < sources[j].Source
+	})
+
+	return sources
+}
+
+// GetContributingSources returns the CSRCs of the packets delivered by the
+// tracks of this RTPReceiver in the last 10 seconds, with information about the
+// most recent packet each contributed to.
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpreceiver-getcontributingsources
+func (r *RTPReceiver) GetContributingSources() []RTPContributingSource {
+	r.sourcesMu.Lock()
+	defer r.sourcesMu.Unlock()
+
+	r.expireSources(time.Now())
+
+	latest := map[SSRC]*rtpSource{}
+	for key, contributor := range r.contributingSources {
+		if source, ok := latest[key.csrc]; !ok || contributor.timestamp.After(source.timestamp) {
+			latest[key.csrc] = &contributor.rtpSource
+		}
+	}
+
+	sources := make([]RTPContributingSource, 0, len(latest))
+	for csrc, source := range latest {
+		sources = append(sources, source.contributingSource(csrc))
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Source < sources[j].Source
+	})
+
+	return sources
+}
+
+// expireSources must be called with sourcesMu held.
+func (r *RTPReceiver) expireSources(now time.Time) {
+	for ssrc, source := range r.synchronizationSources {
+		if now.Sub(source.timestamp) > rtpSourceLifetime {
+			delete(r.synchronizationSources, ssrc)
+		}
+	}
+	for key, contributor := range r.contributingSources {
+		if now.Sub(contributor.timestamp) > rtpSourceLifetime {
+			delete(r.contributingSources, key)
+		}
+	}
+}
+
+func (r *RTPReceiver) collectStats(collector *statsReportCollector) {
+	r.sourcesMu.Lock()
+	defer r.sourcesMu.Unlock()
+
+	now := time.Now()
+	r.expireSources(now)
+
+	for key, contributor := range r.contributingSources {
+		collector.Collecting()
+
+		// InboundRTPStreamID is left out, there are no inbound-rtp stats to link.
+		stats := RTPContributingSourceStats{
+			Timestamp:            statsTimestampFrom(now),
+			Type:                 StatsTypeCSRC,
+			ID:                   fmt.Sprintf("RTPContributingSource-%d-%d", key.ssrc, key.csrc),
+			ContributorSSRC:      key.csrc,
+			PacketsContributedTo: contributor.packetsContributedTo,
+		}
+		if contributor.haveAudioLevel {
+			stats.AudioLevel = contributor.audioLevel
+		}
+
+		collector.Collect(stats.ID, stats)
+	}
+}
+
 // receiveForRid is the sibling of Receive expect for RIDs instead of SSRCs
 // It populates all the internal state for the given RID.
 func (r *RTPReceiver) receiveForRid(
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/transport/v3/test"
 	"github.com/pion/webrtc/v4/pkg/media"
 	"github.com/stretchr/testify/assert"
@@ -64,3 +66,118 @@ func Test_RTPReceiver_SetReadDeadline(t *testing.T) {
 	assert.NoError(t, wan.Stop())
 	closePairNow(t, sender, receiver)
 }
+
+func Test_RTPReceiver_OnAudioLevel(t *testing.T) {
+	receiver := &RTPReceiver{kind: RTPCodecTypeAudio}
+	info := &interceptor.StreamInfo{
+		RTPHeaderExtensions: []interceptor.RTPHeaderExtension{{URI: sdp.AudioLevelURI, ID: 3}},
+	}
+
+	payload, err := (&rtp.AudioLevelExtension{Level: 30, Voice: true}).Marshal()
+	assert.NoError(t, err)
+	pkt := &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 5000}, Payload: []byte{0x00}}
+	assert.NoError(t, pkt.Header.SetExtension(3, payload))
+	buf, err := pkt.Marshal()
+	assert.NoError(t, err)
+
+	levels := 0
+	receiver.OnAudioLevel(func(ssrc SSRC, level uint8, voice bool) {
+		assert.Equal(t, SSRC(5000), ssrc)
+		assert.Equal(t, uint8(30), level)
+		assert.True(t, voice)
+		levels++
+	})
+
+	attributes := receiver.handleRTP(buf, nil, info, time.Now())
+	assert.Equal(t, 1, levels)
+	header, err := attributes.GetRTPHeader(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(5000), header.SSRC)
+
+	// Extension not negotiated.
+	receiver.handleRTP(buf, nil, &interceptor.StreamInfo{}, time.Now())
+	assert.Equal(t, 1, levels)
+}
+
+func Test_RTPReceiver_Sources(t *testing.T) {
+	receiver := &RTPReceiver{kind: RTPCodecTypeAudio}
+	info := &interceptor.StreamInfo{
+		RTPHeaderExtensions: []interceptor.RTPHeaderExtension{
+			{URI: sdp.AudioLevelURI, ID: 1},
+			{URI: csrcAudioLevelURI, ID: 2},
+			{URI: absCaptureTimeURI, ID: 3},
+		},
+	}
+
+	captureTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	marshal := func(ssrc uint32, timestamp uint32, csrc []uint32, csrcLevels []byte) []byte {
+		pkt := &rtp.Packet{
+			Header:  rtp.Header{Version: 2, SSRC: ssrc, Timestamp: timestamp, CSRC: csrc},
+			Payload: []byte{0x00},
+		}
+		level, err := (&rtp.AudioLevelExtension{Level: 0, Voice: true}).Marshal()
+		assert.NoError(t, err)
+		assert.NoError(t, pkt.Header.SetExtension(1, level))
+		assert.NoError(t, pkt.Header.SetExtension(2, csrcLevels))
+		absCaptureTime, err := rtp.NewAbsCaptureTimeExtension(captureTime).Marshal()
+		assert.NoError(t, err)
+		assert.NoError(t, pkt.Header.SetExtension(3, absCaptureTime))
+		buf, err := pkt.Marshal()
+		assert.NoError(t, err)
+
+		return buf
+	}
+
+	assert.Empty(t, receiver.GetSynchronizationSources())
+	assert.Empty(t, receiver.GetContributingSources())
+
+	now := time.Now()
+	receiver.handleRTP(marshal(5000, 1000, []uint32{1, 2}, []byte{20, 127}), nil, info, now.Add(-time.Minute))
+	receiver.handleRTP(marshal(5000, 2000, []uint32{1, 2}, []byte{20, 127}), nil, info, now)
+	receiver.handleRTP(marshal(5000, 3000, []uint32{1}, []byte{40}), nil, info, now)
+
+	ssrcs := receiver.GetSynchronizationSources()
+	assert.Len(t, ssrcs, 1)
+	assert.Equal(t, SSRC(5000), ssrcs[0].Source)
+	assert.Equal(t, uint32(3000), ssrcs[0].RTPTimestamp)
+	assert.Equal(t, now, ssrcs[0].Timestamp)
+	assert.InDelta(t, 1.0, *ssrcs[0].AudioLevel, 1e-9)
+	assert.True(t, *ssrcs[0].VoiceActivityFlag)
+	assert.WithinDuration(t, captureTime, ssrcs[0].CaptureTimestamp, time.Microsecond)
+
+	csrcs := receiver.GetContributingSources()
+	assert.Len(t, csrcs, 2)
+	assert.Equal(t, SSRC(1), csrcs[0].Source)
+	assert.Equal(t, uint32(3000), csrcs[0].RTPTimestamp)
+	assert.InDelta(t, 0.01, *csrcs[0].AudioLevel, 1e-9)
+	assert.Equal(t, SSRC(2), csrcs[1].Source)
+	assert.Equal(t, uint32(2000), csrcs[1].RTPTimestamp)
+	assert.InDelta(t, 0.0, *csrcs[1].AudioLevel, 1e-9)
+
+	collector := newStatsReportCollector()
+	receiver.collectStats(collector)
+	report := collector.Ready()
+	assert.Len(t, report, 2)
+	stats, ok := report["RTPContributingSource-5000-1"].(RTPContributingSourceStats)
+	assert.True(t, ok)
+	assert.Equal(t, StatsTypeCSRC, stats.Type)
+	assert.Equal(t, SSRC(1), stats.ContributorSSRC)
+	assert.Empty(t, stats.InboundRTPStreamID)
+	assert.Equal(t, uint32(3), stats.PacketsContributedTo)
+	assert.InDelta(t, 0.01, stats.AudioLevel, 1e-9)
+
+	// The packets of known sources update them in place.
+	buf := marshal(5000, 4000, []uint32{1, 2}, []byte{20, 127})
+	attributes := receiver.handleRTP(buf, nil, info, now)
+	allocs := testing.AllocsPerRun(100, func() {
+		receiver.handleRTP(buf, attributes, info, now)
+	})
+	assert.Zero(t, allocs)
+
+	// Sources expire 10 seconds after their last packet.
+	receiver.sourcesMu.Lock()
+	receiver.expireSources(now.Add(11 * time.Second))
+	receiver.sourcesMu.Unlock()
+	assert.Empty(t, receiver.GetSynchronizationSources())
+	assert.Empty(t, receiver.GetContributingSources())
+}
@@ -112,6 +112,15 @@ func (r *RTPSender) Transport() *DTLSTransport {
 	return r.transport
 }
 
+func (r *RTPSender) setTransport(transport *DTLSTransport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.hasSent() {
+		r.transport = transport
+	}
+}
+
 // GetParameters describes the current configuration for the encoding and
 // transmission of media on the sender's track.
 func (r *RTPSender) GetParameters() RTPSendParameters {
@@ -21,6 +21,7 @@ type RTPTransceiver struct {
 	receiver         atomic.Value // *RTPReceiver
 	direction        atomic.Value // RTPTransceiverDirection
 	currentDirection atomic.Value // RTPTransceiverDirection
+	onStopped        atomic.Value // func([]SSRC)
 
 	codecs []RTPCodecParameters // User provided codecs via SetCodecPreferences
 
@@ -161,6 +162,7 @@ func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
 
 // Stop irreversibly stops the RTPTransceiver.
 func (t *RTPTransceiver) Stop() error {
+	ssrcs := t.ssrcs()
 	if sender := t.Sender(); sender != nil {
 		if err := sender.Stop(); err != nil {
 			return err
@@ -175,6 +177,10 @@ func (t *RTPTransceiver) Stop() error {
 	t.setDirection(RTPTransceiverDirectionInactive)
 	t.setCurrentDirection(RTPTransceiverDirectionInactive)
 
+	if onStopped, ok := t.onStopped.Load().(func([]SSRC)); ok {
+		onStopped(ssrcs)
+	}
+
 	return nil
 }
 
@@ -190,6 +196,34 @@ func (t *RTPTransceiver) setReceiver(r *RTPReceiver) {
 	t.receiver.Store(r)
 }
 
+// setTransport moves the sender and receiver to transport unless they
+// already sent or received media.
+func (t *RTPTransceiver) setTransport(transport *DTLSTransport) {
+	if sender := t.Sender(); sender != nil {
+		sender.setTransport(transport)
+	}
+	if receiver := t.Receiver(); receiver != nil {
+		receiver.setTransport(transport)
+	}
+}
+
+// ssrcs returns the streams sent or received by the transceiver.
+func (t *RTPTransceiver) ssrcs() []SSRC {
+	var ssrcs []SSRC
+	if sender := t.Sender(); sender != nil {
+		for _, encoding := range sender.GetParameters().Encodings {
+			ssrcs = append(ssrcs, encoding.SSRC, encoding.RTX.SSRC, encoding.FEC.SSRC)
+		}
+	}
+	if receiver := t.Receiver(); receiver != nil {
+		for _, track := range receiver.Tracks() {
+			ssrcs = append(ssrcs, track.SSRC(), track.RtxSSRC())
+		}
+	}
+
+	return ssrcs
+}
+
 func (t *RTPTransceiver) setDirection(d RTPTransceiverDirection) {
 	t.direction.Store(d)
 }
@@ -82,6 +82,17 @@ func (r *SCTPTransport) Transport() *DTLSTransport {
 	return r.dtlsTransport
 }
 
+// setTransport changes the DTLSTransport the SCTPTransport is sending over,
+// it has no effect once the SCTPTransport started.
+func (r *SCTPTransport) setTransport(dtlsTransport *DTLSTransport) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.isStarted {
+		r.dtlsTransport = dtlsTransport
+	}
+}
+
 // GetCapabilities returns the SCTPCapabilities of the SCTPTransport.
 func (r *SCTPTransport) GetCapabilities() SCTPCapabilities {
 	var maxMessageSize uint32
@@ -9,6 +9,7 @@ package webrtc
 import (
 	"errors"
 	"fmt"
+	"net/netip"
 	"net/url"
 	"regexp"
 	"strconv"
@@ -285,6 +286,39 @@ func getRids(media *sdp.MediaDescription) []*simulcastRid {
 	return rids
 }
 
+// rtcpAttribute returns the value of the "a=rtcp" attribute of a media section
+// with an RTCP component, the address of its default candidate, the UDP
+// candidate with the highest priority. Until one is gathered it is the dummy
+// address of https://tools.ietf.org/html/rfc8840#section-4.1.1
+func rtcpAttribute(candidates []ICECandidate) string {
+	var defaultCandidate *ICECandidate
+	var defaultAddr netip.Addr
+	for i := range candidates {
+		candidate := &candidates[i]
+		if candidate.Component != ice.ComponentRTCP || candidate.Protocol != ICEProtocolUDP {
+			continue
+		}
+		// mDNS candidates have no address to signal
+		addr, err := netip.ParseAddr(candidate.Address)
+		if err != nil {
+			continue
+		}
+		if defaultCandidate == nil || candidate.Priority > defaultCandidate.Priority {
+			defaultCandidate, defaultAddr = candidate, addr.Unmap()
+		}
+	}
+	if defaultCandidate == nil {
+		return "9 IN IP4 0.0.0.0"
+	}
+
+	addrType := "IP4"
+	if defaultAddr.Is6() {
+		addrType = "IP6"
+	}
+
+	return fmt.Sprintf("%d IN %s %s", defaultCandidate.Port, addrType, defaultAddr.WithZone(""))
+}
+
 func addCandidatesToMediaDescriptions(
 	candidates []ICECandidate,
 	mediaDescr *sdp.MediaDescription,
@@ -301,12 +335,34 @@ func addCandidatesToMediaDescriptions(
 		mediaDescr.WithValueAttribute("candidate", marshaled)
 	}
 
+	// Candidates of an RTCP component are signaled as they are, otherwise
+	// RTP and RTCP share the candidates.
+	haveRTCPComponent := false
+	for _, c := range candidates {
+		if c.Component == ice.ComponentRTCP {
+			haveRTCPComponent = true
+		}
+	}
+	if haveRTCPComponent {
+		for i := range mediaDescr.Attributes {
+			if mediaDescr.Attributes[i].Key == sdpAttributeRTCP {
+				mediaDescr.Attributes[i].Value = rtcpAttribute(candidates)
+			}
+		}
+	}
+
 	for _, c := range candidates {
 		candidate, err := c.toICE()
 		if err != nil {
 			return err
 		}
 
+		if haveRTCPComponent {
+			appendCandidateIfNew(candidate, mediaDescr.Attributes)
+
+			continue
+		}
+
 		candidate.SetComponent(1)
 		appendCandidateIfNew(candidate, mediaDescr.Attributes)
 
@@ -505,9 +561,13 @@ func addTransceiverSDP(
 	media := sdp.NewJSEPMediaDescription(transceiver.kind.String(), []string{}).
 		WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String()).
 		WithValueAttribute(sdp.AttrKeyMID, midValue).
-		WithICECredentials(iceParams.UsernameFragment, iceParams.Password).
-		WithPropertyAttribute(sdp.AttrKeyRTCPMux).
-		WithPropertyAttribute(sdp.AttrKeyRTCPRsize)
+		WithICECredentials(iceParams.UsernameFragment, iceParams.Password)
+	if mediaSection.noRTCPMux {
+		media.WithValueAttribute(sdpAttributeRTCP, rtcpAttribute(candidates))
+	} else {
+		media.WithPropertyAttribute(sdp.AttrKeyRTCPMux)
+	}
+	media.WithPropertyAttribute(sdp.AttrKeyRTCPRsize)
 
 	codecs := transceiver.getCodecs()
 	for _, codec := range codecs {
@@ -619,6 +679,13 @@ type mediaSection struct {
 	data            bool
 	matchExtensions map[string]int
 	rids            []*simulcastRid
+
+	// transport is set if the media section does not use the ICE details
+	// of the first media section.
+	transport *sectionTransport
+
+	// noRTCPMux is set if RTCP is sent on a component of its own.
+	noRTCPMux bool
 }
 
 func bundleMatchFromRemote(matchBundleGroup *string) func(mid string) bool {
@@ -684,16 +751,23 @@ func populateSDP(
 
 		shouldAddID := true
 		shouldAddCandidates := i == 0
+		sectionICEParams, sectionCandidates, sectionICEGatheringState := iceParams, candidates, iceGatheringState
+		if section.transport != nil {
+			shouldAddCandidates = true
+			sectionICEParams = section.transport.iceParams
+			sectionCandidates = section.transport.candidates
+			sectionICEGatheringState = section.transport.iceGatheringState
+		}
 		if section.data {
 			if err = addDataMediaSection(
 				descr,
 				shouldAddCandidates,
 				mediaDtlsFingerprints,
 				section.id,
-				iceParams,
-				candidates,
+				sectionICEParams,
+				sectionCandidates,
 				connectionRole,
-				iceGatheringState,
+				sectionICEGatheringState,
 				sctpMaxMessageSize,
 			); err != nil {
 				return nil, err
@@ -706,10 +780,10 @@ func populateSDP(
 				mediaDtlsFingerprints,
 				mediaEngine,
 				section.id,
-				iceParams,
-				candidates,
+				sectionICEParams,
+				sectionCandidates,
 				connectionRole,
-				iceGatheringState,
+				sectionICEGatheringState,
 				section,
 			)
 			if err != nil {
@@ -718,9 +792,12 @@ func populateSDP(
 		}
 
 		if shouldAddID {
-			if bundleMatch(section.id) {
+			switch {
+			case section.transport != nil && !section.transport.bundled:
+				// The media section keeps its own transport.
+			case bundleMatch(section.id):
 				appendBundle(section.id)
-			} else {
+			default:
 				descr.MediaDescriptions[len(descr.MediaDescriptions)-1].MediaName.Port = sdp.RangedPort{Value: 0}
 			}
 		}
@@ -748,6 +825,20 @@ func populateSDP(
 	return descr, nil
 }
 
+// haveRTCPMux returns true if every RTP media section multiplexes RTP and RTCP.
+func haveRTCPMux(desc *sdp.SessionDescription) bool {
+	for _, media := range desc.MediaDescriptions {
+		if media.MediaName.Media == mediaSectionApplication || media.MediaName.Port.Value == 0 {
+			continue
+		}
+		if _, ok := media.Attribute(sdp.AttrKeyRTCPMux); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
 func getMidValue(media *sdp.MediaDescription) string {
 	for _, attr := range media.Attributes {
 		if attr.Key == "mid" {
@@ -857,6 +948,22 @@ func extractFingerprint(desc *sdp.SessionDescription) (string, string, error) {
 		}
 	}
 
+	return parseFingerprint(fingerprint)
+}
+
+// extractFingerprintForMedia returns the fingerprint of the transport of a
+// media section that is not bundled.
+func extractFingerprintForMedia(desc *sdp.SessionDescription, media *sdp.MediaDescription) (string, string, error) {
+	// Fingerprint on session level has highest priority
+	fingerprint, haveFingerprint := desc.Attribute("fingerprint")
+	if !haveFingerprint {
+		fingerprint, _ = media.Attribute("fingerprint")
+	}
+
+	return parseFingerprint(fingerprint)
+}
+
+func parseFingerprint(fingerprint string) (string, string, error) {
 	if fingerprint == "" {
 		return "", "", ErrSessionDescriptionNoFingerprint
 	}
@@ -998,6 +1105,44 @@ func extractICEDetails(
 }
 
 
+// extractICEDetailsForMedia returns the ICE details of the transport of a
+// media section that is not bundled.
+func extractICEDetailsForMedia(
+	desc *sdp.SessionDescription,
+	media *sdp.MediaDescription,
+	log logging.LeveledLogger,
+) (*sdpICEDetails, error) {
+	details := &sdpICEDetails{}
+	for mLineIndex, mediaDescr := range desc.MediaDescriptions {
+		if mediaDescr != media {
+			continue
+		}
+
+		ufrag, pwd, candidates, err := extractICEDetailsFromMedia(&identifiedMediaDescription{
+			MediaDescription: media,
+			SDPMid:           getMidValue(media),
+			SDPMLineIndex:    uint16(mLineIndex), //nolint:gosec // G115
+		}, log)
+		if err != nil {
+			return nil, err
+		}
+		details.Ufrag, details.Password, details.Candidates = ufrag, pwd, candidates
+	}
+
+	if details.Ufrag == "" {
+		details.Ufrag, _ = desc.Attribute("ice-ufrag")
+		details.Password, _ = desc.Attribute("ice-pwd")
+	}
+
+	if details.Ufrag == "" {
+		return nil, ErrSessionDescriptionMissingIceUfrag
+	} else if details.Password == "" {
+		return nil, ErrSessionDescriptionMissingIcePwd
+	}
+
+	return details, nil
+}
+
 // Select the first media section or the first bundle section
 // Currently Pion uses the first media section to gather candidates.
 // https://github.com/pion/webrtc/pull/2950
@@ -1149,6 +1294,48 @@ func isIceLiteSet(desc *sdp.SessionDescription) bool {
 	return false
 }
 
+// iceOptionsAttributeKey is the attribute listing the ICE extensions an agent supports (RFC 8839 5.6).
+const iceOptionsAttributeKey = "ice-options"
+
+// withICEOptions adds the ice-options the local agent supports to desc.
+func withICEOptions(desc *sdp.SessionDescription, renomination bool) *sdp.SessionDescription {
+	if renomination {
+		desc = desc.WithValueAttribute(iceOptionsAttributeKey, ice.RenominationOption)
+	}
+
+	return desc
+}
+
+// isICEOptionSet returns whether option is listed by an ice-options attribute
+// of the session or of one of its media sections.
+func isICEOptionSet(desc *sdp.SessionDescription, option string) bool {
+	hasOption := func(attributes []sdp.Attribute) bool {
+		for _, a := range attributes {
+			if strings.TrimSpace(a.Key) != iceOptionsAttributeKey {
+				continue
+			}
+			for _, value := range strings.Fields(a.Value) {
+				if value == option {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	if hasOption(desc.Attributes) {
+		return true
+	}
+	for _, media := range desc.MediaDescriptions {
+		if hasOption(media.Attributes) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func isExtMapAllowMixedSet(desc *sdp.SessionDescription) bool {
 	for _, a := range desc.Attributes {
 		if strings.TrimSpace(a.Key) == sdp.AttrKeyExtMapAllowMixed {
@@ -310,6 +310,28 @@ func TestExtractICEDetails(t *testing.T) {
 	})
 }
 
+func TestRTCPAttribute(t *testing.T) {
+	assert.Equal(t, "9 IN IP4 0.0.0.0", rtcpAttribute(nil))
+
+	candidates := []ICECandidate{
+		{Component: 1, Protocol: ICEProtocolUDP, Address: "192.168.0.1", Port: 1000, Priority: 300},
+		{Component: 2, Protocol: ICEProtocolTCP, Address: "192.168.0.1", Port: 1001, Priority: 300},
+		{Component: 2, Protocol: ICEProtocolUDP, Address: "xxxxxxxx.local", Port: 1002, Priority: 300},
+	}
+	assert.Equal(t, "9 IN IP4 0.0.0.0", rtcpAttribute(candidates))
+
+	candidates = append(candidates,
+		ICECandidate{Component: 2, Protocol: ICEProtocolUDP, Address: "203.0.113.1", Port: 2000, Priority: 100},
+		ICECandidate{Component: 2, Protocol: ICEProtocolUDP, Address: "192.168.0.1", Port: 1003, Priority: 200},
+	)
+	assert.Equal(t, "1003 IN IP4 192.168.0.1", rtcpAttribute(candidates))
+
+	candidates = append(candidates,
+		ICECandidate{Component: 2, Protocol: ICEProtocolUDP, Address: "2001:db8::1", Port: 1004, Priority: 250},
+	)
+	assert.Equal(t, "1004 IN IP6 2001:db8::1", rtcpAttribute(candidates))
+}
+
 func TestSelectCandidateMediaSection(t *testing.T) {
 	t.Run("no media section", func(t *testing.T) {
 		descr := &sdp.SessionDescription{}
@@ -47,6 +47,7 @@ type SettingEngine struct {
 	}
 	candidates struct {
 		ICELite                  bool
+		Renomination             bool
 		ICENetworkTypes          []NetworkType
 		InterfaceFilter          func(string) (keep bool)
 		IPFilter                 func(net.IP) (keep bool)
@@ -97,11 +98,15 @@ type SettingEngine struct {
 	iceUDPMux                                 ice.UDPMux
 	iceProxyDialer                            proxy.Dialer
 	iceDisableActiveTCP                       bool
+	iceTCPSimultaneousOpen                    bool
+	iceInsecureSkipVerify                     bool
+	iceEventLogSize                           int
 	iceBindingRequestHandler                  func(m *stun.Message, local, remote ice.Candidate, pair *ice.CandidatePair) bool //nolint:lll
 	disableMediaEngineCopy                    bool
 	srtpProtectionProfiles                    []dtls.SRTPProtectionProfile
 	receiveMTU                                uint
 	iceMaxBindingRequests                     *uint16
+	iceCandidatePairSelectionPolicy           ice.CandidatePairSelectionPolicy
 	fireOnTrackBeforeFirstRTP                 bool
 	disableCloseByDTLS                        bool
 	dataChannelBlockWrite                     bool
@@ -211,6 +216,14 @@ func (e *SettingEngine) SetLite(lite bool) {
 	e.candidates.ICELite = lite
 }
 
+// SetICERenomination configures whether the ice agent signals the "renomination"
+// ice-option in SDP. Renomination (draft-thatcher-ice-renomination) is used when
+// the remote peer signals it too, the controlling agent then keeps nominating
+// better candidate pairs after one has been selected.
+func (e *SettingEngine) SetICERenomination(enabled bool) {
+	e.candidates.Renomination = enabled
+}
+
 // SetNetworkTypes configures what types of candidate networks are supported
 // during local and server reflexive gathering.
 func (e *SettingEngine) SetNetworkTypes(candidateTypes []NetworkType) {
@@ -389,6 +402,28 @@ func (e *SettingEngine) DisableActiveTCP(isDisabled bool) {
 	e.iceDisableActiveTCP = isDisabled
 }
 
+// SetICETCPSimultaneousOpen enables simultaneous-open TCP candidates (tcptype so)
+// when TCP network types are used. Both peers dial each other's candidates, so that
+// they connect over TCP without a TCPMux or a TURN server.
+func (e *SettingEngine) SetICETCPSimultaneousOpen(enable bool) {
+	e.iceTCPSimultaneousOpen = enable
+}
+
+// SetICEInsecureSkipVerify skips the verification of the certificates of TURN
+// servers reached over TLS or DTLS (turns: URLs). It should only be used to test
+// against servers with self-signed certificates.
+func (e *SettingEngine) SetICEInsecureSkipVerify(skip bool) {
+	e.iceInsecureSkipVerify = skip
+}
+
+// SetICEEventLogSize sets the number of ICE events, such as gathered candidates,
+// connectivity checks and state changes, each ICE agent keeps to diagnose
+// sessions after the fact. They are returned by PeerConnection.GetICEEventLog.
+// The event log is disabled by default.
+func (e *SettingEngine) SetICEEventLogSize(size int) {
+	e.iceEventLogSize = size
+}
+
 // DisableMediaEngineCopy stops the MediaEngine from being copied. This allows a user to modify
 // the MediaEngine after the PeerConnection has been constructed. This is useful if you wish to
 // modify codecs after signaling. Make sure not to share MediaEngines between PeerConnections.
@@ -525,6 +560,14 @@ func (e *SettingEngine) SetICEBindingRequestHandler(
 	e.iceBindingRequestHandler = bindingRequestHandler
 }
 
+// SetICECandidatePairSelectionPolicy sets the policy that decides which candidate
+// pair the ICE Agent nominates when controlling and which nominations it accepts
+// when controlled. It can be used to avoid some networks, keep using TURN or
+// minimize cost. By default the highest priority pair is used.
+func (e *SettingEngine) SetICECandidatePairSelectionPolicy(policy ice.CandidatePairSelectionPolicy) {
+	e.iceCandidatePairSelectionPolicy = policy
+}
+
 // SetFireOnTrackBeforeFirstRTP sets if firing the OnTrack event should happen
 // before any RTP packets are received. Setting this to true will
 // have the Track's Codec and PayloadTypes be initially set to their
@@ -8,7 +8,9 @@ package webrtc
 
 import (
 	"context"
+	"encoding/json"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -291,6 +293,88 @@ func TestSetSCTPRTOMax(t *testing.T) {
 	assert.Equal(t, expSize, s.sctp.rtoMax)
 }
 
+type testCandidatePairSelectionPolicy struct{}
+
+func (testCandidatePairSelectionPolicy) Nominate(*ice.CandidatePairInfo, []ice.CandidatePairInfo) *ice.CandidatePair {
+	return nil
+}
+
+func (testCandidatePairSelectionPolicy) AcceptNomination(*ice.CandidatePairInfo, ice.CandidatePairInfo) bool {
+	return true
+}
+
+func TestSetICECandidatePairSelectionPolicy(t *testing.T) {
+	s := SettingEngine{}
+	assert.Nil(t, s.iceCandidatePairSelectionPolicy)
+
+	policy := testCandidatePairSelectionPolicy{}
+	s.SetICECandidatePairSelectionPolicy(policy)
+	assert.Equal(t, policy, s.iceCandidatePairSelectionPolicy)
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, gatherer.createAgent())
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestSetICETCPSimultaneousOpen(t *testing.T) {
+	s := SettingEngine{}
+	s.SetICETCPSimultaneousOpen(true)
+	s.SetNetworkTypes([]NetworkType{NetworkTypeTCP4})
+	s.SetIncludeLoopbackCandidate(true)
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+
+	gatheringDone := make(chan struct{})
+	gatherer.OnLocalCandidate(func(c *ICECandidate) {
+		if c == nil {
+			close(gatheringDone)
+		}
+	})
+	assert.NoError(t, gatherer.Gather())
+	<-gatheringDone
+
+	candidates, err := gatherer.GetLocalCandidates()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, candidates)
+	for _, c := range candidates {
+		assert.Equal(t, ice.TCPTypeSimultaneousOpen.String(), c.TCPType)
+	}
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestSetICEEventLogSize(t *testing.T) {
+	settingEngine := SettingEngine{}
+	settingEngine.SetICEEventLogSize(1000)
+
+	pcOffer, pcAnswer, err := NewAPI(WithSettingEngine(settingEngine)).newPair(Configuration{})
+	assert.NoError(t, err)
+	assert.Empty(t, pcOffer.GetICEEventLog())
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	connected.Wait()
+
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer} {
+		events := pc.GetICEEventLog()
+		selected := false
+		for i, event := range events {
+			selected = selected || event.Type == ice.EventTypePairSelected
+			if i > 0 {
+				assert.False(t, event.Time.Before(events[i-1].Time))
+			}
+		}
+		assert.True(t, selected)
+
+		out, err := json.Marshal(events)
+		assert.NoError(t, err)
+		assert.Contains(t, string(out), `"type":"pair-selected"`)
+	}
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
 func TestSetICEBindingRequestHandler(t *testing.T) {
 	seenICEControlled, seenICEControlledCancel := context.WithCancel(context.Background())
 	seenICEControlling, seenICEControllingCancel := context.WithCancel(context.Background())
@@ -320,6 +404,46 @@ func TestSetICEBindingRequestHandler(t *testing.T) {
 	closePairNow(t, pcOffer, pcAnswer)
 }
 
+func TestSetICERenomination(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for _, answerRenomination := range []bool{true, false} {
+		seenNomination := &atomicBool{}
+
+		offerSettingEngine := SettingEngine{}
+		offerSettingEngine.SetICERenomination(true)
+		answerSettingEngine := SettingEngine{}
+		answerSettingEngine.SetICERenomination(answerRenomination)
+		answerSettingEngine.SetICEBindingRequestHandler(func(m *stun.Message, _, _ ice.Candidate, _ *ice.CandidatePair) bool {
+			if m.Contains(ice.AttrNomination) {
+				seenNomination.set(true)
+			}
+
+			return false
+		})
+
+		pcOffer, err := NewAPI(WithSettingEngine(offerSettingEngine)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		pcAnswer, err := NewAPI(WithSettingEngine(answerSettingEngine)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+		assert.NoError(t, signalPair(pcOffer, pcAnswer))
+		connected.Wait()
+
+		// Renomination is only used if both peers signaled the ice-option.
+		assert.Contains(t, pcOffer.LocalDescription().SDP, "a=ice-options:renomination")
+		assert.Equal(t, answerRenomination, strings.Contains(pcAnswer.LocalDescription().SDP, "a=ice-options:renomination"))
+		assert.Equal(t, answerRenomination, seenNomination.get())
+
+		closePairNow(t, pcOffer, pcAnswer)
+	}
+}
+
 func TestSetHooks(t *testing.T) {
 	settingEngine := SettingEngine{}
 
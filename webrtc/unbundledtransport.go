@@ -0,0 +1,673 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+
+	"github.com/pion/ice/v4"
+	"github.com/pion/rtcp"
+	"github.com/pion/sdp/v3"
+)
+
+// unbundledTransport is an ICE and DTLS transport of a PeerConnection that
+// carries media sections which are not bundled on the transport of the first
+// media section, see BundlePolicy.
+type unbundledTransport struct {
+	mids []string
+
+	iceGatherer   *ICEGatherer
+	iceTransport  *ICETransport
+	dtlsTransport *DTLSTransport
+
+	// remote holds the parameters of the remote transport once the remote
+	// description is known.
+	remote remoteTransportParameters
+}
+
+// remoteTransportParameters are the parameters of the remote side of an ICE
+// and DTLS transport.
+type remoteTransportParameters struct {
+	ice  ICEParameters
+	dtls DTLSParameters
+}
+
+// sectionTransport holds the ICE details of a media section that is not
+// negotiated like the first media section of a description.
+type sectionTransport struct {
+	iceParams         ICEParameters
+	candidates        []ICECandidate
+	iceGatheringState ICEGatheringState
+
+	// bundled is set if the media section is in the BUNDLE group even though
+	// it has its own transport, which is the case in an initial offer.
+	bundled bool
+}
+
+func (t *unbundledTransport) hasMid(mid string) bool {
+	for _, m := range t.mids {
+		if m == mid {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *unbundledTransport) stop() error {
+	if err := t.dtlsTransport.Stop(); err != nil {
+		return err
+	}
+
+	return t.iceTransport.Stop()
+}
+
+func (pc *PeerConnection) getUnbundledTransports() []*unbundledTransport {
+	if transports, ok := pc.unbundledTransports.Load().([]*unbundledTransport); ok {
+		return transports
+	}
+
+	return nil
+}
+
+func (pc *PeerConnection) newUnbundledTransport(mids []string) (*unbundledTransport, error) {
+	gatherer, err := pc.createICEGatherer()
+	if err != nil {
+		return nil, err
+	}
+	pc.attachICEGatherer(gatherer)
+
+	iceTransport := pc.createICETransport(gatherer)
+	dtlsTransport, err := pc.api.NewDTLSTransport(iceTransport, pc.configuration.Certificates)
+	if err != nil {
+		return nil, err
+	}
+
+	return &unbundledTransport{
+		mids:          mids,
+		iceGatherer:   gatherer,
+		iceTransport:  iceTransport,
+		dtlsTransport: dtlsTransport,
+	}, nil
+}
+
+// updateUnbundledTransports replaces the unbundled transports, the ones that
+// are not kept are stopped.
+func (pc *PeerConnection) updateUnbundledTransports(kept []*unbundledTransport) {
+	previous := pc.getUnbundledTransports()
+	pc.unbundledTransports.Store(kept)
+
+	stopped := map[*DTLSTransport]bool{}
+	for _, t := range previous {
+		isKept := false
+		for _, k := range kept {
+			isKept = isKept || k.iceGatherer == t.iceGatherer
+		}
+		if isKept {
+			continue
+		}
+
+		stopped[t.dtlsTransport] = true
+		if err := t.stop(); err != nil {
+			pc.log.Warnf("Failed to stop transport of %v: %s", t.mids, err)
+		}
+	}
+
+	if len(stopped) > 0 {
+		pc.deleteRTCPTransports(stopped)
+
+		// Gathering may be complete without the stopped transports.
+		pc.checkICEGatheringComplete()
+	}
+}
+
+func (pc *PeerConnection) unbundledTransportForMid(mid string) *unbundledTransport {
+	for _, t := range pc.getUnbundledTransports() {
+		if t.hasMid(mid) {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// dtlsTransportForMid returns the DTLSTransport that carries the media section
+// of mid.
+func (pc *PeerConnection) dtlsTransportForMid(mid string) *DTLSTransport {
+	if t := pc.unbundledTransportForMid(mid); t != nil {
+		return t.dtlsTransport
+	}
+
+	return pc.dtlsTransport
+}
+
+// iceTransportForMid returns the ICETransport that carries the media section
+// of mid.
+func (pc *PeerConnection) iceTransportForMid(mid string) *ICETransport {
+	if t := pc.unbundledTransportForMid(mid); t != nil {
+		return t.iceTransport
+	}
+
+	return pc.iceTransport
+}
+
+// offerTransportKey returns which transport a media section of an initial
+// offer is gathered on. Sections with the key of the first one use the
+// primary transport.
+func offerTransportKey(policy BundlePolicy, section mediaSection) string {
+	switch policy {
+	case BundlePolicyMaxCompat:
+		return section.id
+	case BundlePolicyBalanced:
+		if section.data {
+			return mediaSectionApplication
+		}
+
+		return section.transceivers[0].kind.String()
+	default:
+		return ""
+	}
+}
+
+// assignOfferTransports creates the transports the media sections of an
+// initial offer are gathered on according to the BundlePolicy. Transports of
+// a previous offer are reused.
+//
+// caller of this method should hold `pc.mu` lock.
+func (pc *PeerConnection) assignOfferTransports(sections []mediaSection) error {
+	var (
+		keys   []string
+		groups = map[string][]string{}
+	)
+	for i, section := range sections {
+		if i == 0 || (!section.data && len(section.transceivers) == 0) {
+			continue
+		}
+
+		key := offerTransportKey(pc.configuration.BundlePolicy, section)
+		if key == offerTransportKey(pc.configuration.BundlePolicy, sections[0]) {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], section.id)
+	}
+
+	previous := pc.getUnbundledTransports()
+	transports := make([]*unbundledTransport, 0, len(keys))
+	for _, key := range keys {
+		mids := groups[key]
+
+		var transport *unbundledTransport
+		for _, t := range previous {
+			if t.mids[0] == mids[0] {
+				transport = &unbundledTransport{
+					mids:          mids,
+					iceGatherer:   t.iceGatherer,
+					iceTransport:  t.iceTransport,
+					dtlsTransport: t.dtlsTransport,
+				}
+			}
+		}
+		if transport == nil {
+			var err error
+			if transport, err = pc.newUnbundledTransport(mids); err != nil {
+				return err
+			}
+		}
+		transports = append(transports, transport)
+	}
+	pc.updateUnbundledTransports(transports)
+
+	return nil
+}
+
+// createAnswerTransports creates a transport for every media section of an
+// initial remote offer that is neither rejected nor bundled. With
+// BundlePolicyMaxBundle those sections are rejected instead.
+func (pc *PeerConnection) createAnswerTransports(offer *sdp.SessionDescription) error {
+	if pc.configuration.BundlePolicy == BundlePolicyMaxBundle {
+		return nil
+	}
+
+	primary, ok := selectCandidateMediaSection(offer)
+	if !ok {
+		return nil
+	}
+	bundleGroup := bundleGroupMids(offer)
+
+	var transports []*unbundledTransport
+	for _, media := range offer.MediaDescriptions {
+		mid := getMidValue(media)
+		if mid == "" || mid == primary.SDPMid || media.MediaName.Port.Value == 0 || bundleGroup[mid] {
+			continue
+		}
+
+		transport, err := pc.newUnbundledTransport([]string{mid})
+		if err != nil {
+			return err
+		}
+		transports = append(transports, transport)
+	}
+	pc.updateUnbundledTransports(transports)
+
+	return nil
+}
+
+// negotiateOfferTransports applies the remote answer to the transports of the
+// initial offer. Media sections the answer bundled move to the primary
+// transport, rejected ones are dropped, and transports left without media
+// sections are stopped.
+func (pc *PeerConnection) negotiateOfferTransports(answer *sdp.SessionDescription) {
+	bundleGroup := bundleGroupMids(answer)
+
+	var kept []*unbundledTransport
+	for _, t := range pc.getUnbundledTransports() {
+		var mids []string
+		for _, mid := range t.mids {
+			media := getMediaByMid(answer, mid)
+			if media == nil || media.MediaName.Port.Value == 0 || bundleGroup[mid] {
+				continue
+			}
+			mids = append(mids, mid)
+		}
+		if len(mids) == 0 {
+			continue
+		}
+
+		kept = append(kept, &unbundledTransport{
+			mids:          mids,
+			iceGatherer:   t.iceGatherer,
+			iceTransport:  t.iceTransport,
+			dtlsTransport: t.dtlsTransport,
+		})
+	}
+	pc.updateUnbundledTransports(kept)
+}
+
+// setRemoteUnbundledTransports applies the remote description to the
+// unbundled transports: it sets their remote parameters, adds the remote
+// candidates, and restarts them if the remote credentials changed.
+func (pc *PeerConnection) setRemoteUnbundledTransports(
+	desc *sdp.SessionDescription,
+	isRenegotiation, weOffer bool,
+) error {
+	for _, transport := range pc.getUnbundledTransports() {
+		media := getMediaByMid(desc, transport.mids[0])
+		if media == nil {
+			continue
+		}
+
+		iceDetails, err := extractICEDetailsForMedia(desc, media, pc.log)
+		if err != nil {
+			return err
+		}
+
+		if isRenegotiation &&
+			transport.iceTransport.haveRemoteCredentialsChange(iceDetails.Ufrag, iceDetails.Password) {
+			// An ICE Restart only happens implicitly for a SetRemoteDescription of type offer
+			if !weOffer {
+				if err = transport.iceTransport.restart(); err != nil {
+					return err
+				}
+			}
+
+			if err = transport.iceTransport.setRemoteCredentials(iceDetails.Ufrag, iceDetails.Password); err != nil {
+				return err
+			}
+		}
+
+		for i := range iceDetails.Candidates {
+			if err = transport.iceTransport.AddRemoteCandidate(&iceDetails.Candidates[i]); err != nil {
+				return err
+			}
+		}
+
+		if isRenegotiation {
+			continue
+		}
+
+		fingerprint, fingerprintHash, err := extractFingerprintForMedia(desc, media)
+		if err != nil {
+			return err
+		}
+		transport.remote = remoteTransportParameters{
+			ice: ICEParameters{
+				UsernameFragment: iceDetails.Ufrag,
+				Password:         iceDetails.Password,
+				Renomination:     isICEOptionSet(desc, ice.RenominationOption),
+			},
+			dtls: DTLSParameters{
+				Role: dtlsRoleFromRemoteSDP(&sdp.SessionDescription{
+					MediaDescriptions: []*sdp.MediaDescription{media},
+				}),
+				Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
+			},
+		}
+	}
+
+	return nil
+}
+
+// setSectionTransports sets the ICE details of the media sections that are
+// carried by an unbundled transport.
+func (pc *PeerConnection) setSectionTransports(sections []mediaSection, bundled bool) error {
+	for _, transport := range pc.getUnbundledTransports() {
+		iceParams, err := transport.iceGatherer.GetLocalParameters()
+		if err != nil {
+			return err
+		}
+
+		candidates, err := transport.iceGatherer.GetLocalCandidates()
+		if err != nil {
+			return err
+		}
+
+		for i := range sections {
+			if transport.hasMid(sections[i].id) {
+				sections[i].transport = &sectionTransport{
+					iceParams:         iceParams,
+					candidates:        candidates,
+					iceGatheringState: iceGatheringStateOf(transport.iceGatherer),
+					bundled:           bundled,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// gatherUnbundledTransports starts gathering on the unbundled transports, the
+// candidates are signaled for the first media section of each transport.
+func (pc *PeerConnection) gatherUnbundledTransports(desc *sdp.SessionDescription) error {
+	for _, transport := range pc.getUnbundledTransports() {
+		for mLineIndex, media := range desc.MediaDescriptions {
+			if getMidValue(media) == transport.mids[0] {
+				transport.iceGatherer.setMediaStreamIdentification(
+					transport.mids[0],
+					uint16(mLineIndex), //nolint:gosec // G115
+				)
+			}
+		}
+
+		if transport.iceGatherer.State() == ICEGathererStateNew {
+			if err := transport.iceGatherer.Gather(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// populateUnbundledCandidates adds the local candidates of the unbundled
+// transports to the media sections they carry.
+func (pc *PeerConnection) populateUnbundledCandidates(desc *SessionDescription) *SessionDescription {
+	transports := pc.getUnbundledTransports()
+	if desc == nil || len(transports) == 0 {
+		return desc
+	}
+
+	parsed := desc.parsed
+	for _, transport := range transports {
+		candidates, err := transport.iceGatherer.GetLocalCandidates()
+		if err != nil {
+			return desc
+		}
+
+		for _, mid := range transport.mids {
+			media := getMediaByMid(parsed, mid)
+			if media == nil {
+				continue
+			}
+			if err = addCandidatesToMediaDescriptions(
+				candidates, media, iceGatheringStateOf(transport.iceGatherer),
+			); err != nil {
+				return desc
+			}
+		}
+	}
+
+	raw, err := parsed.Marshal()
+	if err != nil {
+		return desc
+	}
+
+	return &SessionDescription{
+		SDP:    string(raw),
+		Type:   desc.Type,
+		parsed: parsed,
+	}
+}
+
+// bindRTPTransceivers moves the senders and receivers that have not been
+// started yet to the transport of the media section of their transceiver.
+func (pc *PeerConnection) bindRTPTransceivers(transceivers []*RTPTransceiver) {
+	for _, t := range transceivers {
+		if mid := t.Mid(); mid != "" {
+			transport := pc.dtlsTransportForMid(mid)
+			t.setTransport(transport)
+			pc.setRTCPTransport(transport, t.ssrcs()...)
+		}
+	}
+}
+
+// setRTCPTransport records the transport that carries the streams of ssrcs.
+func (pc *PeerConnection) setRTCPTransport(transport *DTLSTransport, ssrcs ...SSRC) {
+	if transport == nil {
+		return
+	}
+
+	pc.rtcpTransportsLock.Lock()
+	defer pc.rtcpTransportsLock.Unlock()
+
+	if pc.rtcpTransports == nil {
+		pc.rtcpTransports = map[SSRC]*DTLSTransport{}
+	}
+	for _, ssrc := range ssrcs {
+		if ssrc != 0 {
+			pc.rtcpTransports[ssrc] = transport
+		}
+	}
+}
+
+// deleteRTCPTransports forgets the streams carried by stopped transports.
+func (pc *PeerConnection) deleteRTCPTransports(stopped map[*DTLSTransport]bool) {
+	pc.rtcpTransportsLock.Lock()
+	defer pc.rtcpTransportsLock.Unlock()
+
+	for ssrc, transport := range pc.rtcpTransports {
+		if stopped[transport] {
+			delete(pc.rtcpTransports, ssrc)
+		}
+	}
+}
+
+// deleteStreamRTCPTransports forgets the streams of a stopped transceiver.
+func (pc *PeerConnection) deleteStreamRTCPTransports(ssrcs []SSRC) {
+	pc.rtcpTransportsLock.Lock()
+	defer pc.rtcpTransportsLock.Unlock()
+
+	for _, ssrc := range ssrcs {
+		delete(pc.rtcpTransports, ssrc)
+	}
+}
+
+// dtlsTransportsForRTCP returns the transports an RTCP packet is sent on: the
+// transport of the stream it is about, or every transport if it is not about
+// a stream. Unknown streams are carried by the primary transport.
+func (pc *PeerConnection) dtlsTransportsForRTCP(pkt rtcp.Packet) []*DTLSTransport {
+	ssrcs := pkt.DestinationSSRC()
+	if len(ssrcs) == 0 {
+		transports := []*DTLSTransport{pc.dtlsTransport}
+		for _, t := range pc.getUnbundledTransports() {
+			transports = append(transports, t.dtlsTransport)
+		}
+
+		return transports
+	}
+
+	pc.rtcpTransportsLock.RLock()
+	defer pc.rtcpTransportsLock.RUnlock()
+
+	for _, ssrc := range ssrcs {
+		if transport, ok := pc.rtcpTransports[SSRC(ssrc)]; ok {
+			return []*DTLSTransport{transport}
+		}
+	}
+
+	return []*DTLSTransport{pc.dtlsTransport}
+}
+
+// iceConnectionStateOfTransport maps the state of an ICETransport to an
+// ICEConnectionState.
+func iceConnectionStateOfTransport(state ICETransportState) (ICEConnectionState, bool) {
+	switch state {
+	case ICETransportStateNew:
+		return ICEConnectionStateNew, true
+	case ICETransportStateChecking:
+		return ICEConnectionStateChecking, true
+	case ICETransportStateConnected:
+		return ICEConnectionStateConnected, true
+	case ICETransportStateCompleted:
+		return ICEConnectionStateCompleted, true
+	case ICETransportStateFailed:
+		return ICEConnectionStateFailed, true
+	case ICETransportStateDisconnected:
+		return ICEConnectionStateDisconnected, true
+	case ICETransportStateClosed:
+		return ICEConnectionStateClosed, true
+	default:
+		return ICEConnectionStateUnknown, false
+	}
+}
+
+// aggregateICEConnectionState combines the states of the ICE transports of a
+// PeerConnection. https://www.w3.org/TR/webrtc/#rtciceconnectionstate-enum
+//
+//nolint:cyclop
+func aggregateICEConnectionState(states []ICEConnectionState) ICEConnectionState {
+	if len(states) == 1 {
+		return states[0]
+	}
+
+	count := map[ICEConnectionState]int{}
+	for _, state := range states {
+		count[state]++
+	}
+
+	switch {
+	case count[ICEConnectionStateFailed] > 0:
+		return ICEConnectionStateFailed
+	case count[ICEConnectionStateDisconnected] > 0:
+		return ICEConnectionStateDisconnected
+	case count[ICEConnectionStateClosed] == len(states):
+		return ICEConnectionStateClosed
+	case count[ICEConnectionStateNew]+count[ICEConnectionStateClosed] == len(states):
+		return ICEConnectionStateNew
+	case count[ICEConnectionStateNew]+count[ICEConnectionStateChecking] > 0:
+		return ICEConnectionStateChecking
+	case count[ICEConnectionStateCompleted]+count[ICEConnectionStateClosed] == len(states):
+		return ICEConnectionStateCompleted
+	default:
+		return ICEConnectionStateConnected
+	}
+}
+
+// aggregateDTLSTransportState reduces the states of the DTLS transports of a
+// PeerConnection to the one that determines the PeerConnectionState.
+func aggregateDTLSTransportState(states []DTLSTransportState) DTLSTransportState {
+	if len(states) == 1 {
+		return states[0]
+	}
+
+	count := map[DTLSTransportState]int{}
+	for _, state := range states {
+		count[state]++
+	}
+
+	switch {
+	case count[DTLSTransportStateFailed] > 0:
+		return DTLSTransportStateFailed
+	case count[DTLSTransportStateNew]+count[DTLSTransportStateClosed] == len(states):
+		if count[DTLSTransportStateNew] > 0 {
+			return DTLSTransportStateNew
+		}
+
+		return DTLSTransportStateClosed
+	case count[DTLSTransportStateNew]+count[DTLSTransportStateConnecting] > 0:
+		return DTLSTransportStateConnecting
+	default:
+		return DTLSTransportStateConnected
+	}
+}
+
+// aggregateICEGatheringState combines the gathering states of the ICE
+// transports of a PeerConnection.
+// https://www.w3.org/TR/webrtc/#rtcicegatheringstate-enum
+func aggregateICEGatheringState(states []ICEGatheringState) ICEGatheringState {
+	count := map[ICEGatheringState]int{}
+	for _, state := range states {
+		count[state]++
+	}
+
+	switch len(states) {
+	case count[ICEGatheringStateNew]:
+		return ICEGatheringStateNew
+	case count[ICEGatheringStateComplete]:
+		return ICEGatheringStateComplete
+	default:
+		return ICEGatheringStateGathering
+	}
+}
+
+func iceGatheringStateOf(g *ICEGatherer) ICEGatheringState {
+	if g == nil {
+		return ICEGatheringStateNew
+	}
+
+	switch g.State() {
+	case ICEGathererStateNew:
+		return ICEGatheringStateNew
+	case ICEGathererStateGathering:
+		return ICEGatheringStateGathering
+	default:
+		return ICEGatheringStateComplete
+	}
+}
+
+// bundleGroupMids returns the mids of the BUNDLE group of a description.
+func bundleGroupMids(desc *sdp.SessionDescription) map[string]bool {
+	mids := map[string]bool{}
+	for _, a := range desc.Attributes {
+		if a.Key != sdp.AttrKeyGroup {
+			continue
+		}
+
+		fields := strings.Fields(a.Value)
+		if len(fields) == 0 || fields[0] != "BUNDLE" {
+			continue
+		}
+		for _, mid := range fields[1:] {
+			mids[mid] = true
+		}
+	}
+
+	return mids
+}
+
+func getMediaByMid(desc *sdp.SessionDescription, mid string) *sdp.MediaDescription {
+	for _, media := range desc.MediaDescriptions {
+		if getMidValue(media) == mid {
+			return media
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2023 The Pion community <https://pion.ly>
+// SPDX-License-Identifier: MIT
+
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/transport/v3/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func mediaUfrags(t *testing.T, desc string) []string {
+	t.Helper()
+
+	parsed := &sdp.SessionDescription{}
+	assert.NoError(t, parsed.UnmarshalString(desc))
+
+	ufrags := []string{}
+	for _, media := range parsed.MediaDescriptions {
+		ufrag, _ := media.Attribute("ice-ufrag")
+		ufrags = append(ufrags, ufrag)
+	}
+
+	return ufrags
+}
+
+func TestBundlePolicy_Offer(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for _, tc := range []struct {
+		policy      BundlePolicy
+		sameAsFirst []bool
+	}{
+		{BundlePolicyMaxBundle, []bool{true, true, true, true}},
+		{BundlePolicyMaxCompat, []bool{true, false, false, false}},
+		{BundlePolicyBalanced, []bool{true, true, false, false}},
+	} {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			pc, err := NewPeerConnection(Configuration{BundlePolicy: tc.policy})
+			assert.NoError(t, err)
+
+			for _, mimeType := range []string{MimeTypeVP8, MimeTypeVP8, MimeTypeOpus} {
+				track, trackErr := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: mimeType}, "track", "pion")
+				assert.NoError(t, trackErr)
+				_, err = pc.AddTrack(track)
+				assert.NoError(t, err)
+			}
+			_, err = pc.CreateDataChannel("data", nil)
+			assert.NoError(t, err)
+
+			offer, err := pc.CreateOffer(nil)
+			assert.NoError(t, err)
+			assert.Contains(t, offer.SDP, "a=group:BUNDLE 0 1 2 3")
+
+			ufrags := mediaUfrags(t, offer.SDP)
+			assert.Len(t, ufrags, 4)
+			for i, same := range tc.sameAsFirst {
+				assert.Equal(t, same, ufrags[i] == ufrags[0], "media section %d", i)
+			}
+			if tc.policy != BundlePolicyMaxBundle {
+				assert.NotEqual(t, ufrags[2], ufrags[3])
+			}
+
+			assert.NoError(t, pc.Close())
+		})
+	}
+}
+
+func TestBundlePolicy_NoBundleRemote(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, err := NewPeerConnection(Configuration{BundlePolicy: BundlePolicyMaxCompat})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	audioTrack, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeOpus}, "audio", "pion")
+	assert.NoError(t, err)
+	audioSender, err := pcOffer.AddTrack(audioTrack)
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	dataChannelOpened := make(chan struct{})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnOpen(func() {
+			close(dataChannelOpened)
+		})
+	})
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+	noBundle := regexp.MustCompile("a=group:BUNDLE[^\r\n]*\r\n")
+	assert.NoError(t, signalPairWithModification(pcOffer, pcAnswer, func(desc string) string {
+		return noBundle.ReplaceAllString(desc, "")
+	}))
+
+	// Every media section of the answer has a transport of its own
+	answer := pcAnswer.LocalDescription().SDP
+	assert.NotContains(t, answer, "a=group:BUNDLE")
+	assert.NotContains(t, answer, "m=video 0")
+	assert.NotContains(t, answer, "m=application 0")
+	ufrags := mediaUfrags(t, answer)
+	assert.Len(t, ufrags, 3)
+	assert.NotEqual(t, ufrags[0], ufrags[1])
+	assert.NotEqual(t, ufrags[1], ufrags[2])
+	assert.Len(t, pcOffer.getUnbundledTransports(), 2)
+	assert.Len(t, pcAnswer.getUnbundledTransports(), 2)
+
+	// RTCP is sent on the transport of the stream it is about, or on every
+	// transport if it is not about a stream
+	audioTransport := pcOffer.dtlsTransportForMid("1")
+	assert.NotSame(t, pcOffer.dtlsTransport, audioTransport)
+	audioSSRC := uint32(audioSender.GetParameters().Encodings[0].SSRC)
+	transports := pcOffer.dtlsTransportsForRTCP(&rtcp.PictureLossIndication{MediaSSRC: audioSSRC})
+	assert.Len(t, transports, 1)
+	assert.Same(t, audioTransport, transports[0])
+	assert.Len(t, pcOffer.dtlsTransportsForRTCP(&rtcp.Goodbye{}), 3)
+
+	connected.Wait()
+	<-dataChannelOpened
+	assert.Equal(t, ICEConnectionStateConnected, pcOffer.ICEConnectionState())
+
+	// The data channel runs on the transport of its media section
+	assert.Equal(t, pcAnswer.getUnbundledTransports()[1].dtlsTransport, pcAnswer.SCTP().Transport())
+
+	// The streams of a stopped transceiver are no longer routed to its transport
+	assert.NoError(t, pcOffer.GetTransceivers()[1].Stop())
+	transports = pcOffer.dtlsTransportsForRTCP(&rtcp.PictureLossIndication{MediaSSRC: audioSSRC})
+	assert.Len(t, transports, 1)
+	assert.Same(t, pcOffer.dtlsTransport, transports[0])
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestBundlePolicy_BundleRemote(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, err := NewPeerConnection(Configuration{BundlePolicy: BundlePolicyMaxCompat})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// The answer bundled everything, the transport of the data channel is dropped
+	assert.True(t, strings.Contains(pcAnswer.LocalDescription().SDP, "a=group:BUNDLE 0 1"))
+	assert.Empty(t, pcOffer.getUnbundledTransports())
+	assert.Empty(t, pcAnswer.getUnbundledTransports())
+
+	connected.Wait()
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestAggregateICEConnectionState(t *testing.T) {
+	for _, tc := range []struct {
+		states   []ICEConnectionState
+		expected ICEConnectionState
+	}{
+		{[]ICEConnectionState{ICEConnectionStateChecking}, ICEConnectionStateChecking},
+		{[]ICEConnectionState{ICEConnectionStateNew, ICEConnectionStateNew}, ICEConnectionStateNew},
+		{[]ICEConnectionState{ICEConnectionStateNew, ICEConnectionStateClosed}, ICEConnectionStateNew},
+		{[]ICEConnectionState{ICEConnectionStateClosed, ICEConnectionStateClosed}, ICEConnectionStateClosed},
+		{[]ICEConnectionState{ICEConnectionStateConnected, ICEConnectionStateNew}, ICEConnectionStateChecking},
+		{[]ICEConnectionState{ICEConnectionStateConnected, ICEConnectionStateChecking}, ICEConnectionStateChecking},
+		{[]ICEConnectionState{ICEConnectionStateConnected, ICEConnectionStateCompleted}, ICEConnectionStateConnected},
+		{[]ICEConnectionState{ICEConnectionStateCompleted, ICEConnectionStateClosed}, ICEConnectionStateCompleted},
+		{[]ICEConnectionState{ICEConnectionStateConnected, ICEConnectionStateDisconnected}, ICEConnectionStateDisconnected},
+		{[]ICEConnectionState{ICEConnectionStateDisconnected, ICEConnectionStateFailed}, ICEConnectionStateFailed},
+	} {
+		assert.Equal(t, tc.expected, aggregateICEConnectionState(tc.states), "%v", tc.states)
+	}
+}